@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Fill colors for token leaves by highlighting category, shared by the
+// DOT and Mermaid outputs.
+var categoryColors = map[string]string{
+	"keyword":    "#cfe2ff",
+	"operator":   "#fff3cd",
+	"number":     "#d1ecf1",
+	"string":     "#d4edda",
+	"identifier": "#d4edda",
+}
+
+// nodeLabel returns the lines shown for a node in a graph: the rule name
+// and its action, or the token type and its text.
+func nodeLabel(node ASTNode) []string {
+	label := []string{node.Type}
+	if node.Token != nil {
+		label = append(label, fmt.Sprintf("%q", node.Value))
+	}
+	if node.Action != "" {
+		label = append(label, "→ "+node.Action)
+	}
+	return label
+}
+
+// outputDOT prints the tree as a Graphviz digraph, for example to render
+// with "dot -Tsvg".
+func (v *ASTViewer) outputDOT(ast ASTNode) error {
+	var b strings.Builder
+	b.WriteString("digraph AST {\n")
+	b.WriteString("\tnode [fontname=\"Helvetica\", fontsize=11];\n")
+	b.WriteString("\tedge [arrowhead=none];\n")
+
+	id := 0
+	var visit func(node ASTNode) int
+	visit = func(node ASTNode) int {
+		n := id
+		id++
+
+		lines := nodeLabel(node)
+		for i, line := range lines {
+			lines[i] = dotEscape(line)
+		}
+		attrs := fmt.Sprintf("label=\"%s\", tooltip=\"%d:%d [%d..%d]\"",
+			strings.Join(lines, "\\n"), node.Span.Line, node.Span.Col, node.Span.Start, node.Span.End)
+		if node.Token != nil {
+			attrs += ", shape=ellipse"
+			if color, ok := categoryColors[node.Token.Category]; ok {
+				attrs += fmt.Sprintf(", style=filled, fillcolor=\"%s\"", color)
+			}
+		} else {
+			attrs += ", shape=box, style=rounded"
+		}
+		fmt.Fprintf(&b, "\tn%d [%s];\n", n, attrs)
+
+		for _, child := range node.Children {
+			fmt.Fprintf(&b, "\tn%d -> n%d;\n", n, visit(child))
+		}
+		return n
+	}
+	visit(ast)
+
+	b.WriteString("}\n")
+	fmt.Print(b.String())
+	return nil
+}
+
+// dotEscape escapes text for a double-quoted DOT string.
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// outputMermaid prints the tree as a Mermaid flowchart, which renders in
+// Markdown on GitHub and GitLab.
+func (v *ASTViewer) outputMermaid(ast ASTNode) error {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	id := 0
+	styles := make(map[string][]string) // Node ids by category
+	var visit func(node ASTNode) int
+	visit = func(node ASTNode) int {
+		n := id
+		id++
+
+		lines := nodeLabel(node)
+		for i, line := range lines {
+			lines[i] = mermaidEscape(line)
+		}
+		label := strings.Join(lines, "<br/>")
+		if node.Token != nil {
+			fmt.Fprintf(&b, "    n%d([\"%s\"])\n", n, label)
+			if _, ok := categoryColors[node.Token.Category]; ok {
+				styles[node.Token.Category] = append(styles[node.Token.Category], fmt.Sprintf("n%d", n))
+			}
+		} else {
+			fmt.Fprintf(&b, "    n%d[\"%s\"]\n", n, label)
+		}
+
+		for _, child := range node.Children {
+			fmt.Fprintf(&b, "    n%d --- n%d\n", n, visit(child))
+		}
+		return n
+	}
+	visit(ast)
+
+	for _, category := range []string{"keyword", "operator", "number", "string", "identifier"} {
+		if ids := styles[category]; len(ids) > 0 {
+			fmt.Fprintf(&b, "    classDef %s fill:%s\n", category, categoryColors[category])
+			fmt.Fprintf(&b, "    class %s %s\n", strings.Join(ids, ","), category)
+		}
+	}
+
+	fmt.Print(b.String())
+	return nil
+}
+
+// mermaidEscape escapes text for a quoted Mermaid label using Mermaid's
+// entity codes.
+func mermaidEscape(s string) string {
+	return strings.NewReplacer("#", "#35;", `"`, "#quot;", "<", "#lt;", ">", "#gt;", "\n", " ").Replace(s)
+}
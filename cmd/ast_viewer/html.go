@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// htmlNode is a node as rendered in the HTML page. Start and End are
+// UTF-16 offsets, which is how JavaScript indexes strings.
+type htmlNode struct {
+	Label    string
+	Value    string
+	Action   string
+	Class    string
+	Start    int
+	End      int
+	Info     string
+	Children []htmlNode
+}
+
+// outputHTML prints a self-contained page with the tree next to the
+// source; clicking a node highlights the text it covers.
+func (v *ASTViewer) outputHTML(ast ASTNode, source string) error {
+	offsets := utf16Offsets(source)
+	var convert func(node ASTNode) htmlNode
+	convert = func(node ASTNode) htmlNode {
+		n := htmlNode{
+			Label:  node.Type,
+			Action: node.Action,
+			Class:  "rule",
+			Start:  offsets[node.Span.Start],
+			End:    offsets[node.Span.End],
+			Info:   fmt.Sprintf("%s @ %d:%d [%d..%d]", node.Type, node.Span.Line, node.Span.Col, node.Span.Start, node.Span.End),
+		}
+		if node.Token != nil {
+			n.Value = node.Value
+			n.Class = "token " + node.Token.Category
+		} else if node.Rule != nil {
+			n.Info += fmt.Sprintf(" · %s → %v (alternative %d)", node.Rule.Name, node.Rule.Pattern, node.Rule.Alternative)
+		}
+		for _, child := range node.Children {
+			n.Children = append(n.Children, convert(child))
+		}
+		return n
+	}
+
+	return htmlPage.Execute(os.Stdout, map[string]interface{}{
+		"Title":  v.dsl.Name(),
+		"Source": source,
+		"Root":   convert(ast),
+	})
+}
+
+// utf16Offsets maps every byte offset of source to the UTF-16 offset of
+// the same position.
+func utf16Offsets(source string) []int {
+	offsets := make([]int, len(source)+1)
+	n := 0
+	for i := 0; i < len(source); {
+		r, size := utf8.DecodeRuneInString(source[i:])
+		for j := 0; j < size; j++ {
+			offsets[i+j] = n
+		}
+		n += utf16.RuneLen(r)
+		i += size
+	}
+	offsets[len(source)] = n
+	return offsets
+}
+
+var htmlPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} parse tree</title>
+<style>
+body { margin: 0; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #24292f; }
+header { padding: 8px 16px; border-bottom: 1px solid #d0d7de; background: #f6f8fa; }
+h1 { font-size: 16px; margin: 0; }
+main { display: flex; height: calc(100vh - 40px); }
+.tree, .source { flex: 1; overflow: auto; padding: 12px 16px; }
+.tree { border-right: 1px solid #d0d7de; }
+ul { list-style: none; margin: 0; padding-left: 18px; border-left: 1px dotted #d0d7de; }
+.tree > ul { padding-left: 0; border-left: none; }
+.node { display: inline-block; margin: 1px 0; padding: 0 4px; border-radius: 4px; cursor: pointer; font-family: ui-monospace, Menlo, Consolas, monospace; font-size: 13px; }
+.node:hover { background: #eaeef2; }
+.node.selected { background: #ffd33d; }
+.rule { font-weight: bold; }
+.value { font-weight: normal; color: #57606a; }
+.action { font-weight: normal; color: #8250df; }
+.keyword .value { color: #0550ae; }
+.operator .value { color: #953800; }
+.number .value { color: #0a3069; }
+.string .value, .identifier .value { color: #116329; }
+pre { margin: 0; font-size: 13px; white-space: pre-wrap; }
+mark { background: #ffd33d; }
+mark:empty { border-left: 2px solid #cf222e; }
+#info { margin-bottom: 12px; color: #57606a; font-size: 13px; min-height: 1em; }
+</style>
+</head>
+<body>
+<header><h1>{{.Title}} parse tree</h1></header>
+<main>
+<section class="tree"><ul>{{template "node" .Root}}</ul></section>
+<section class="source"><div id="info">Click a node to highlight its source.</div><pre id="source"></pre></section>
+</main>
+<script>
+const source = {{.Source}};
+const pre = document.getElementById("source");
+const info = document.getElementById("info");
+
+function highlight(start, end) {
+  const mark = document.createElement("mark");
+  mark.textContent = source.slice(start, end);
+  pre.replaceChildren(source.slice(0, start), mark, source.slice(end));
+  mark.scrollIntoView({block: "nearest"});
+}
+
+document.querySelectorAll(".node").forEach(function (node) {
+  node.addEventListener("click", function () {
+    document.querySelectorAll(".node.selected").forEach(function (n) { n.classList.remove("selected"); });
+    node.classList.add("selected");
+    info.textContent = node.dataset.info;
+    highlight(Number(node.dataset.start), Number(node.dataset.end));
+  });
+});
+
+pre.textContent = source;
+</script>
+</body>
+</html>
+{{define "node"}}<li><span class="node {{.Class}}" data-start="{{.Start}}" data-end="{{.End}}" data-info="{{.Info}}">{{.Label}}{{if .Value}} <span class="value">{{printf "%q" .Value}}</span>{{end}}{{if .Action}} <span class="action">→ {{.Action}}</span>{{end}}</span>{{if .Children}}<ul>{{range .Children}}{{template "node" .}}{{end}}</ul>{{end}}</li>
+{{end}}`))
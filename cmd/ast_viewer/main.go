@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -12,25 +13,39 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// ASTNode is a node of the parse tree: a rule node with the alternative
+// that matched, or a token leaf. Span locates the node in the input.
 type ASTNode struct {
 	Type     string     `json:"type" yaml:"type"`
 	Value    string     `json:"value,omitempty" yaml:"value,omitempty"`
 	Action   string     `json:"action,omitempty" yaml:"action,omitempty"`
+	Span     Span       `json:"span" yaml:"span"`
 	Children []ASTNode  `json:"children,omitempty" yaml:"children,omitempty"`
 	Token    *TokenInfo `json:"token,omitempty" yaml:"token,omitempty"`
 	Rule     *RuleInfo  `json:"rule,omitempty" yaml:"rule,omitempty"`
 }
 
+// Span is the source range of a node: byte offsets and the 1-based line
+// and column of its start.
+type Span struct {
+	Start int `json:"start" yaml:"start"`
+	End   int `json:"end" yaml:"end"`
+	Line  int `json:"line" yaml:"line"`
+	Col   int `json:"col" yaml:"col"`
+}
+
 type TokenInfo struct {
-	Type  string `json:"type" yaml:"type"`
-	Value string `json:"value" yaml:"value"`
-	Line  int    `json:"line" yaml:"line"`
-	Col   int    `json:"col" yaml:"col"`
+	Type     string `json:"type" yaml:"type"`
+	Value    string `json:"value" yaml:"value"`
+	Category string `json:"category,omitempty" yaml:"category,omitempty"`
+	Line     int    `json:"line" yaml:"line"`
+	Col      int    `json:"col" yaml:"col"`
 }
 
 type RuleInfo struct {
-	Name    string   `json:"name" yaml:"name"`
-	Pattern []string `json:"pattern" yaml:"pattern"`
+	Name        string   `json:"name" yaml:"name"`
+	Alternative int      `json:"alternative" yaml:"alternative"`
+	Pattern     []string `json:"pattern" yaml:"pattern"`
 }
 
 type ASTViewer struct {
@@ -38,6 +53,9 @@ type ASTViewer struct {
 	format  string
 	indent  bool
 	verbose bool
+
+	alternatives map[string][]dslbuilder.AlternativeInfo // Alternatives by rule name
+	categories   map[string]string                       // Highlighting categories by token name
 }
 
 func main() {
@@ -53,7 +71,7 @@ func main() {
 	flag.StringVar(&dslFile, "dsl", "", "DSL configuration file (YAML or JSON)")
 	flag.StringVar(&input, "input", "", "Input string to parse")
 	flag.StringVar(&inputFile, "file", "", "Input file to parse")
-	flag.StringVar(&format, "format", "json", "Output format: json, yaml, or tree")
+	flag.StringVar(&format, "format", "json", "Output format: json, yaml, tree, dot, mermaid, or html")
 	flag.BoolVar(&indent, "indent", true, "Indent output (for json/yaml)")
 	flag.BoolVar(&verbose, "verbose", false, "Show detailed token and rule information")
 
@@ -66,6 +84,8 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  %s -dsl calculator.yaml -input \"10 + 20\"\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -dsl query.json -file queries.txt -format tree\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -dsl accounting.yaml -input \"venta de 1000 con iva\" -format yaml -verbose\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl calculator_advanced.yaml -input \"1 + 2 * 3\" -format dot | dot -Tsvg > ast.svg\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl calculator.yaml -file input.txt -format html > ast.html\n", os.Args[0])
 	}
 
 	flag.Parse()
@@ -81,14 +101,11 @@ func main() {
 	}
 
 	// Load DSL
-	dsl, err := loadDSL(dslFile)
+	dsl, err := dslbuilder.LoadFromFile(dslFile)
 	if err != nil {
 		log.Fatalf("Error loading DSL: %v", err)
 	}
 
-	// Register dummy actions to prevent parsing errors
-	registerDummyActions(dsl)
-
 	// Get input
 	if inputFile != "" {
 		content, err := os.ReadFile(inputFile)
@@ -111,47 +128,26 @@ func main() {
 	}
 }
 
-func loadDSL(filename string) (*dslbuilder.DSL, error) {
-	ext := strings.ToLower(filename[strings.LastIndex(filename, ".")+1:])
-
-	switch ext {
-	case "yaml", "yml":
-		return dslbuilder.LoadFromYAMLFile(filename)
-	case "json":
-		return dslbuilder.LoadFromJSONFile(filename)
-	default:
-		return nil, fmt.Errorf("unsupported file format: %s", ext)
-	}
-}
-
-func registerDummyActions(dsl *dslbuilder.DSL) {
-	// Register dummy actions for all rules to prevent parsing errors
-	// This is a simplified approach - in a real implementation,
-	// we would introspect the DSL to find all action names
-	actions := []string{
-		"passthrough", "add", "subtract", "multiply", "divide",
-		"number", "parentheses", "saleWithTax", "simpleSale",
-		"processEntry", "singleMovement", "multipleMovements",
-		"debitMovement", "creditMovement", "greet", "getValue",
-		"findField", "selectField", "whereCondition",
-	}
-
-	for _, action := range actions {
-		dsl.Action(action, func(args []interface{}) (interface{}, error) {
-			return args, nil
-		})
-	}
-}
-
 func (v *ASTViewer) visualize(input string) error {
-	// Parse the input
-	result, err := v.dsl.Parse(input)
+	// Parse the input into a concrete syntax tree; no actions run
+	tree, err := v.dsl.ParseTree(input)
 	if err != nil {
-		return fmt.Errorf("parsing error: %v", err)
+		// The expected tokens belong to their own offset; otherwise the
+		// parse error knows where parsing stopped, e.g. at leftover input
+		line, col := lineColumn(input, tree.ExpectedOffset)
+		var parseErr *dslbuilder.ParseError
+		if len(tree.Expected) == 0 && errors.As(err, &parseErr) {
+			line, col = parseErr.Line, parseErr.Column
+		}
+		if len(tree.Expected) > 0 {
+			return fmt.Errorf("parsing error at line %d, column %d: %v (expected %s)",
+				line, col, err, strings.Join(tree.Expected, ", "))
+		}
+		return fmt.Errorf("parsing error at line %d, column %d: %v", line, col, err)
 	}
 
 	// Build AST representation
-	ast := v.buildAST(result)
+	ast := v.buildAST(tree)
 
 	// Output based on format
 	switch v.format {
@@ -160,96 +156,74 @@ func (v *ASTViewer) visualize(input string) error {
 	case "yaml":
 		return v.outputYAML(ast)
 	case "tree":
-		return v.outputTree(ast, 0)
+		return v.outputTree(ast)
+	case "dot":
+		return v.outputDOT(ast)
+	case "mermaid":
+		return v.outputMermaid(ast)
+	case "html":
+		return v.outputHTML(ast, input)
 	default:
 		return fmt.Errorf("unsupported format: %s", v.format)
 	}
 }
 
-func (v *ASTViewer) buildAST(result interface{}) ASTNode {
-	return v.buildASTRecursive(result, "root", 0)
+// buildAST converts the parse tree, looking up the action of each matched
+// alternative and the category of each token in the grammar.
+func (v *ASTViewer) buildAST(tree *dslbuilder.Tree) ASTNode {
+	v.alternatives = make(map[string][]dslbuilder.AlternativeInfo)
+	for _, rule := range v.dsl.Rules() {
+		v.alternatives[rule.Name] = rule.Alternatives
+	}
+	v.categories = make(map[string]string)
+	for _, token := range v.dsl.Tokens() {
+		category := string(token.Category)
+		if _, literal := token.Literal(); category == "" && literal {
+			category = "operator" // Uncategorized fixed text is punctuation
+		}
+		v.categories[token.Name] = category
+	}
+	return v.buildNode(tree.Root, tree.Source)
 }
 
-func (av *ASTViewer) buildASTRecursive(data interface{}, nodeType string, depth int) ASTNode {
+func (v *ASTViewer) buildNode(n *dslbuilder.Node, source string) ASTNode {
+	line, col := lineColumn(source, n.Start)
 	node := ASTNode{
-		Type: nodeType,
+		Type: n.Name(),
+		Span: Span{Start: n.Start, End: n.End, Line: line, Col: col},
 	}
 
-	// Handle different types of data
-	switch v := data.(type) {
-
-	case []interface{}:
-		// Array of values (common in parsing results)
-		node.Type = nodeType
-		if nodeType == "root" {
-			node.Type = "expression"
-		}
-		for i, item := range v {
-			childType := "value"
-			// Try to determine better types based on position
-			if i%2 == 1 && len(v) > 2 {
-				// Likely an operator in expression
-				if str, ok := item.(string); ok && isOperator(str) {
-					childType = "operator"
-				}
-			}
-			child := av.buildASTRecursive(item, childType, depth+1)
-			node.Children = append(node.Children, child)
-		}
-
-	case map[string]interface{}:
-		// Object/map structure
-		node.Type = "object"
-		for key, value := range v {
-			child := av.buildASTRecursive(value, key, depth+1)
-			node.Children = append(node.Children, child)
-		}
-
-	case string:
-		node.Value = v
-		// Detect token types
-		if isNumber(v) {
-			node.Type = "number"
-		} else if isOperator(v) {
-			node.Type = "operator"
-		} else if nodeType == "value" {
-			node.Type = "identifier"
-		}
-
-	case int, int64, float64:
-		node.Type = "number"
-		node.Value = fmt.Sprintf("%v", v)
-
-	case bool:
-		node.Type = "boolean"
-		node.Value = fmt.Sprintf("%v", v)
-
-	default:
-		// Generic handling
-		node.Value = fmt.Sprintf("%v", v)
-		if node.Value == "" {
-			node.Value = fmt.Sprintf("<%T>", v)
+	if n.IsToken() {
+		node.Value = n.Token.Value
+		node.Token = &TokenInfo{
+			Type:     n.Token.TokenType,
+			Value:    n.Token.Value,
+			Category: v.categories[n.Token.TokenType],
+			Line:     line,
+			Col:      col,
 		}
+		return node
 	}
 
-	return node
-}
-
-func isOperator(s string) bool {
-	operators := []string{"+", "-", "*", "/", "=", "==", "!=", "<", ">", "<=", ">=", "&&", "||", "and", "or", "not"}
-	for _, op := range operators {
-		if s == op {
-			return true
-		}
+	node.Rule = &RuleInfo{Name: n.Rule, Alternative: n.Alternative}
+	if alternatives := v.alternatives[n.Rule]; n.Alternative >= 0 && n.Alternative < len(alternatives) {
+		node.Rule.Pattern = alternatives[n.Alternative].Sequence
+		node.Action = alternatives[n.Alternative].Action
+	}
+	for _, child := range n.Children {
+		node.Children = append(node.Children, v.buildNode(child, source))
 	}
-	return false
+	return node
 }
 
-func isNumber(s string) bool {
-	if _, err := fmt.Sscanf(s, "%f", new(float64)); err == nil {
-		return true
+// lineColumn returns the 1-based line and column of a byte offset.
+func lineColumn(source string, offset int) (int, int) {
+	if offset > len(source) {
+		offset = len(source)
 	}
-	return false
+	line := 1 + strings.Count(source[:offset], "\n")
+	col := offset - strings.LastIndex(source[:offset], "\n")
+	return line, col
 }
 
 func (v *ASTViewer) outputJSON(ast ASTNode) error {
@@ -280,47 +254,52 @@ func (v *ASTViewer) outputYAML(ast ASTNode) error {
 	return nil
 }
 
-func (v *ASTViewer) outputTree(ast ASTNode, depth int) error {
-	v.outputTreeNode(ast, "", depth == 0)
+func (v *ASTViewer) outputTree(ast ASTNode) error {
+	v.outputTreeNode(ast, "", "")
 	return nil
 }
 
-func (av *ASTViewer) outputTreeNode(node ASTNode, prefix string, isRoot bool) {
-	// Determine node symbol based on type
+// outputTreeNode prints a node after its branch ("├─ ", "└─ ", or "" for
+// the root) and then its children, indented by prefix.
+func (v *ASTViewer) outputTreeNode(node ASTNode, prefix, branch string) {
+	category := ""
+	if node.Token != nil {
+		category = node.Token.Category
+	}
+
+	// Determine node symbol: rules, then tokens by highlighting category
 	symbol := "○"
-	switch node.Type {
-	case "expression", "root":
+	switch {
+	case node.Rule != nil:
 		symbol = "◆"
-	case "operator":
+	case category == "keyword":
+		symbol = "◇"
+	case category == "operator":
 		symbol = "●"
-	case "number":
+	case category == "number":
 		symbol = "#"
-	case "identifier":
+	case category == "identifier":
 		symbol = "□"
-	case "object":
-		symbol = "{}"
-	case "boolean":
-		symbol = "?"
+	case category == "string":
+		symbol = "\""
 	}
 
 	// Print current node
-	if isRoot {
-		fmt.Printf("%s %s", symbol, node.Type)
-	} else {
-		fmt.Printf("%s%s %s", prefix, symbol, node.Type)
-	}
+	fmt.Printf("%s%s%s %s", prefix, branch, symbol, node.Type)
 
-	if node.Value != "" {
-		// Color-code values based on type
-		switch node.Type {
+	if node.Token != nil {
+		// Color-code values based on category
+		switch category {
 		case "number":
 			fmt.Printf(" \033[36m%s\033[0m", node.Value) // Cyan for numbers
 		case "operator":
 			fmt.Printf(" \033[33m%s\033[0m", node.Value) // Yellow for operators
 		case "string", "identifier":
-			fmt.Printf(" \033[32m\"%s\"\033[0m", node.Value) // Green for strings
+			fmt.Printf(" \033[32m%s\033[0m", node.Value) // Green for strings
+		case "keyword":
+			fmt.Printf(" \033[34m%s\033[0m", node.Value) // Blue for keywords
 		default:
-			fmt.Printf(": %s", node.Value)
+			fmt.Printf(" %q", node.Value)
 		}
 	}
 
@@ -329,41 +308,33 @@ func (av *ASTViewer) outputTreeNode(node ASTNode, prefix string, isRoot bool) {
 	}
 	fmt.Println()
 
+	switch branch {
+	case "├─ ":
+		prefix += "│  "
+	case "└─ ":
+		prefix += "   "
+	}
+
 	// Show additional info in verbose mode
-	if av.verbose {
-		if node.Token != nil {
-			fmt.Printf("%s  ├─ token: %s = \"%s\" @ %d:%d\n",
-				prefix, node.Token.Type, node.Token.Value, node.Token.Line, node.Token.Col)
+	if v.verbose {
+		detail := prefix + "│  "
+		if len(node.Children) == 0 {
+			detail = prefix + "   "
 		}
+		fmt.Printf("%s\033[90m@ %d:%d [%d..%d]\033[0m\n",
+			detail, node.Span.Line, node.Span.Col, node.Span.Start, node.Span.End)
 		if node.Rule != nil {
-			fmt.Printf("%s  ├─ rule: %s → %v\n",
-				prefix, node.Rule.Name, node.Rule.Pattern)
+			fmt.Printf("%s\033[90mrule: %s → %v (alternative %d)\033[0m\n",
+				detail, node.Rule.Name, node.Rule.Pattern, node.Rule.Alternative)
 		}
 	}
 
 	// Print children
 	for i, child := range node.Children {
-		isLast := i == len(node.Children)-1
-
-		var childPrefix string
-		if isRoot {
-			if isLast {
-				fmt.Print("└─ ")
-				childPrefix = "   "
-			} else {
-				fmt.Print("├─ ")
-				childPrefix = "│  "
-			}
+		if i == len(node.Children)-1 {
+			v.outputTreeNode(child, prefix, "└─ ")
 		} else {
-			if isLast {
-				fmt.Printf("%s└─ ", prefix)
-				childPrefix = prefix + "   "
-			} else {
-				fmt.Printf("%s├─ ", prefix)
-				childPrefix = prefix + "│  "
-			}
+			v.outputTreeNode(child, prefix, "├─ ")
 		}
-
-		av.outputTreeNode(child, childPrefix, false)
 	}
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/lsp"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/registry"
+)
+
+func main() {
+	var (
+		dslFile string
+		lang    string
+		perLine bool
+		logFile string
+	)
+
+	flag.StringVar(&dslFile, "dsl", "", "DSL configuration file (YAML or JSON)")
+	flag.StringVar(&lang, "lang", "", "Registered Go DSL to use instead of a configuration file"+registeredNames())
+	flag.BoolVar(&perLine, "lines", false, "Parse every line as a separate statement (# and // start comment lines)")
+	flag.StringVar(&logFile, "log", "", "Write protocol errors to this file")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "DSL LSP - Language Server Protocol server for your DSL\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "The server speaks LSP over stdin/stdout and is started by your editor.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s -dsl calculator.yaml\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl http.json -lines -log /tmp/dsl-lsp.log\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -lang http -lines   (custom builds with registered DSLs)\n", os.Args[0])
+	}
+
+	flag.Parse()
+
+	if (dslFile == "") == (lang == "") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// stdout carries the protocol, so diagnostics go to stderr or the log file
+	log.SetOutput(os.Stderr)
+	opts := lsp.Options{PerLine: perLine}
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Error opening log file: %v", err)
+		}
+		defer f.Close()
+		opts.Log = log.New(f, "dsl-lsp: ", log.LstdFlags)
+	}
+
+	var dsl *dslbuilder.DSL
+	var err error
+	if lang != "" {
+		dsl, err = registry.New(lang)
+	} else {
+		dsl, err = dslbuilder.LoadFromFile(dslFile)
+	}
+	if err != nil {
+		log.Fatalf("Error loading DSL: %v", err)
+	}
+
+	if err := lsp.Run(dsl, opts); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// registeredNames lists the registered DSLs for the -lang flag help.
+func registeredNames() string {
+	names := registry.Names()
+	if len(names) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(names, ", ") + ")"
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/docgen"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/export"
+)
+
+func main() {
+	var (
+		dslFile  string
+		format   string
+		output   string
+		rule     string
+		title    string
+		examples int
+		exts     string
+	)
+
+	flag.StringVar(&dslFile, "dsl", "", "DSL configuration file (YAML or JSON)")
+	flag.StringVar(&format, "format", "html", "Output format: html, markdown, svg, textmate, highlightjs, or prism")
+	flag.StringVar(&output, "o", "", "Output file (html/markdown) or directory (svg); defaults to stdout")
+	flag.StringVar(&rule, "rule", "", "Only render this rule (svg format)")
+	flag.StringVar(&title, "title", "", "Document title (defaults to the DSL name)")
+	flag.IntVar(&examples, "examples", 3, "Maximum example sentences per rule (0 disables)")
+	flag.StringVar(&exts, "ext", "", "Comma-separated file extensions for highlighting formats (e.g. .calc)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "DSL Doc - Generate railroad diagrams and reference docs from your grammar\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s -dsl calculator.yaml -o calculator.html\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl query.json -format markdown -o REFERENCE.md\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl query.json -format svg -o diagrams/\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl query.json -format svg -rule select > select.svg\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl query.json -format textmate -ext .query -o query.tmLanguage.json\n", os.Args[0])
+	}
+
+	flag.Parse()
+
+	if dslFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	dsl, err := dslbuilder.LoadFromFile(dslFile)
+	if err != nil {
+		log.Fatalf("Error loading DSL: %v", err)
+	}
+
+	if examples == 0 {
+		examples = -1 // docgen treats negative values as disabled
+	}
+	gen := docgen.New(dsl, docgen.Options{Title: title, Examples: examples})
+
+	switch format {
+	case "html":
+		err = writeOutput(output, gen.HTML())
+	case "markdown", "md":
+		err = writeOutput(output, gen.Markdown())
+	case "svg":
+		err = writeSVG(gen, dsl, output, rule)
+	case "textmate", "highlightjs", "hljs", "prism":
+		err = writeHighlighting(dsl, format, title, exts, output)
+	default:
+		err = fmt.Errorf("unsupported format: %s", format)
+	}
+
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+func writeOutput(filename, content string) error {
+	if filename == "" {
+		fmt.Print(content)
+		return nil
+	}
+	return os.WriteFile(filename, []byte(content), 0644)
+}
+
+// writeSVG writes one diagram to stdout when a rule is selected, or one
+// <rule>.svg file per rule into the output directory.
+func writeSVG(gen *docgen.Generator, dsl *dslbuilder.DSL, dir, rule string) error {
+	if rule != "" {
+		svg, err := gen.RuleSVG(rule)
+		if err != nil {
+			return err
+		}
+		if dir == "" {
+			fmt.Println(svg)
+			return nil
+		}
+		return writeOutput(filepath.Join(dir, rule+".svg"), svg)
+	}
+
+	if dir == "" {
+		return fmt.Errorf("svg format requires -rule or an output directory with -o")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, r := range dsl.Rules() {
+		svg, err := gen.RuleSVG(r.Name)
+		if err != nil {
+			return err
+		}
+		if err := writeOutput(filepath.Join(dir, r.Name+".svg"), svg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHighlighting exports a syntax highlighting definition.
+func writeHighlighting(dsl *dslbuilder.DSL, format, title, exts, output string) error {
+	opts := export.Options{Title: title}
+	if exts != "" {
+		opts.Extensions = strings.Split(exts, ",")
+	}
+	exp := export.New(dsl, opts)
+
+	var content string
+	switch format {
+	case "textmate":
+		data, err := exp.TextMate()
+		if err != nil {
+			return err
+		}
+		content = string(data)
+	case "prism":
+		js, err := exp.Prism()
+		if err != nil {
+			return err
+		}
+		content = js
+	default:
+		js, err := exp.HighlightJS()
+		if err != nil {
+			return err
+		}
+		content = js
+	}
+	return writeOutput(output, content)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/format"
+	"github.com/pmezard/go-difflib/difflib"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func main() {
+	var (
+		dslFile    string
+		layoutFile string
+		perLine    bool
+		write      bool
+		diff       bool
+		check      bool
+	)
+
+	flag.StringVar(&dslFile, "dsl", "", "DSL configuration file (YAML or JSON)")
+	flag.StringVar(&layoutFile, "layout", "", "Layout hints file (defaults to the format section of the DSL file)")
+	flag.BoolVar(&perLine, "lines", false, "Format every line as a separate statement (# and // start comment lines)")
+	flag.BoolVar(&write, "w", false, "Write the result to the source file instead of stdout")
+	flag.BoolVar(&diff, "d", false, "Print a diff instead of the formatted source")
+	flag.BoolVar(&check, "check", false, "List files that are not formatted and exit with status 1")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "DSL Fmt - Format source code of your DSL\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [files...]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Without files, the source is read from stdin.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s -dsl calculator.yaml script.calc\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl query.yaml -w queries/*.q\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl http.json -lines -check scripts/*.http\n", os.Args[0])
+	}
+
+	flag.Parse()
+
+	if dslFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	dsl, err := dslbuilder.LoadFromFile(dslFile)
+	if err != nil {
+		log.Fatalf("Error loading DSL: %v", err)
+	}
+	if layoutFile == "" {
+		layoutFile = dslFile
+	}
+	layout, err := loadLayout(layoutFile, layoutFile != dslFile)
+	if err != nil {
+		log.Fatalf("Error loading layout: %v", err)
+	}
+
+	fmtr := &formatter{
+		f:       format.New(dsl, format.Options{Layout: layout, PerLine: perLine}),
+		perLine: perLine,
+		write:   write,
+		diff:    diff,
+		check:   check,
+	}
+
+	if flag.NArg() == 0 {
+		if write {
+			log.Fatal("Error: -w requires files")
+		}
+		fmtr.process("<stdin>", os.Stdin)
+	}
+	for _, filename := range flag.Args() {
+		file, err := os.Open(filename)
+		if err != nil {
+			fmtr.fail(filename, err)
+			continue
+		}
+		fmtr.process(filename, file)
+		file.Close()
+	}
+
+	if fmtr.failed {
+		os.Exit(1)
+	}
+}
+
+// formatter formats files according to the command line flags.
+type formatter struct {
+	f       *format.Formatter
+	perLine bool
+	write   bool
+	diff    bool
+	check   bool
+	failed  bool
+}
+
+func (fm *formatter) process(filename string, r io.Reader) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		fm.fail(filename, err)
+		return
+	}
+	src := string(data)
+	out, err := fm.f.Format(src)
+	if err != nil {
+		fm.fail(filename, err)
+		return
+	}
+
+	changed := out != src
+	if fm.check && changed {
+		fmt.Println(filename)
+		fm.failed = true
+	}
+	if fm.diff && changed {
+		text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        splitLines(src),
+			B:        splitLines(out),
+			FromFile: filename + ".orig",
+			ToFile:   filename,
+			Context:  3,
+		})
+		if err != nil {
+			fm.fail(filename, err)
+			return
+		}
+		fmt.Print(text)
+	}
+	if fm.write && changed {
+		info, err := os.Stat(filename)
+		if err != nil {
+			fm.fail(filename, err)
+			return
+		}
+		if err := os.WriteFile(filename, []byte(out), info.Mode().Perm()); err != nil {
+			fm.fail(filename, err)
+		}
+	}
+	if !fm.write && !fm.diff && !fm.check {
+		fmt.Print(out)
+	}
+}
+
+// fail reports an error, with the position of parse errors in whole
+// documents (per-line errors already name their line).
+func (fm *formatter) fail(filename string, err error) {
+	var parseErr *dslbuilder.ParseError
+	if !fm.perLine && errors.As(err, &parseErr) {
+		fmt.Fprintf(os.Stderr, "%s:%d:%d: %v\n", filename, parseErr.Line, parseErr.Column, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+	}
+	fm.failed = true
+}
+
+// splitLines splits text into lines for the diff, each ending in a newline.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n"
+	}
+	return lines
+}
+
+// loadLayout reads layout hints from a layout file, or from the format
+// section of a DSL file. JSON files are read as YAML, which is a superset.
+func loadLayout(filename string, layoutFile bool) (format.Layout, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return format.Layout{}, err
+	}
+	if layoutFile {
+		var layout format.Layout
+		err := yaml.Unmarshal(data, &layout)
+		return layout, err
+	}
+	var config struct {
+		Format format.Layout `yaml:"format"`
+	}
+	err = yaml.Unmarshal(data, &config)
+	return config.Format, err
+}
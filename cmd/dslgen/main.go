@@ -0,0 +1,77 @@
+// Command dslgen generates a standalone Go parser from a DSL defined in
+// YAML or JSON. See the codegen package to generate parsers for DSLs
+// written in Go.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/codegen"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/registry"
+)
+
+func main() {
+	var (
+		dslFile string
+		lang    string
+		output  string
+		pkg     string
+	)
+
+	flag.StringVar(&dslFile, "dsl", "", "DSL configuration file (YAML or JSON)")
+	flag.StringVar(&lang, "lang", "", "Registered Go DSL to use instead of a configuration file"+registeredNames())
+	flag.StringVar(&output, "o", "", "Output file (default: stdout)")
+	flag.StringVar(&pkg, "package", "", "Package name (default: the output directory, or the DSL name)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "DSL Gen - Generate a standalone Go parser for your DSL\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s -dsl calculator.yaml -o calc/calc.go\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl query.json -package query > query.go\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -lang http -o httpdsl/parser.go   (custom builds with registered DSLs)\n", os.Args[0])
+	}
+
+	flag.Parse()
+
+	if (dslFile == "") == (lang == "") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var dsl *dslbuilder.DSL
+	var err error
+	if lang != "" {
+		dsl, err = registry.New(lang)
+	} else {
+		dsl, err = dslbuilder.LoadFromFile(dslFile)
+	}
+	if err != nil {
+		log.Fatalf("Error loading DSL: %v", err)
+	}
+
+	source := ""
+	if dslFile != "" {
+		source = filepath.Base(dslFile)
+	}
+	if err := codegen.Run(dsl, output, codegen.Options{Package: pkg, Source: source}); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// registeredNames lists the registered DSLs for the -lang flag help.
+func registeredNames() string {
+	names := registry.Names()
+	if len(names) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(names, ", ") + ")"
+}
@@ -0,0 +1,159 @@
+// Command dsltest runs the declarative tests of a grammar: the tests
+// section of the DSL file or of separate test files. See the dsltest
+// package for the format and for running the same tests from go test.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/dsltest"
+)
+
+func main() {
+	var (
+		dslFile   string
+		run       string
+		junitFile string
+		verbose   bool
+	)
+
+	flag.StringVar(&dslFile, "dsl", "", "DSL configuration file (YAML or JSON)")
+	flag.StringVar(&run, "run", "", "Run only the tests whose name matches the regular expression")
+	flag.StringVar(&junitFile, "junit", "", "Write a JUnit XML report to the file")
+	flag.BoolVar(&verbose, "v", false, "List every test, not only the failures")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "DSL Test - Run the declarative tests of your DSL\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [test files...]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Without test files, the tests section of the DSL file is run.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  %s -dsl calculator.yaml\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl query.yaml -v tests/*.yaml\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -dsl http.json -junit report.xml\n", os.Args[0])
+	}
+
+	flag.Parse()
+
+	if dslFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var filter *regexp.Regexp
+	if run != "" {
+		var err error
+		if filter, err = regexp.Compile(run); err != nil {
+			log.Fatalf("Error: invalid -run: %v", err)
+		}
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{dslFile}
+	}
+
+	passed := true
+	var suites []dsltest.Suite
+	for _, filename := range files {
+		cases, err := dsltest.Load(filename)
+		if err != nil {
+			log.Fatalf("Error: %s: %v", filename, err)
+		}
+		if filter != nil {
+			cases = selectCases(cases, filter)
+		}
+
+		// Each file gets a new DSL so contexts do not leak between files
+		dsl, err := dslbuilder.LoadFromFile(dslFile)
+		if err != nil {
+			log.Fatalf("Error loading DSL: %v", err)
+		}
+		registerPassthroughActions(dsl)
+
+		suite := dsltest.Suite{Name: filename, Results: dsltest.Run(dsl, cases)}
+		if !report(suite, verbose) {
+			passed = false
+		}
+		suites = append(suites, suite)
+	}
+
+	if junitFile != "" {
+		if err := writeJUnit(junitFile, suites); err != nil {
+			log.Fatalf("Error writing JUnit report: %v", err)
+		}
+	}
+
+	if !passed {
+		os.Exit(1)
+	}
+}
+
+func registerPassthroughActions(dsl *dslbuilder.DSL) {
+	// Grammar files have no Go actions: every action returns the values
+	// its alternative matched, so outputs are nested lists of token text
+	for _, action := range dsl.UnboundActions() {
+		dsl.Action(action, func(args []interface{}) (interface{}, error) {
+			return args, nil
+		})
+	}
+}
+
+func selectCases(cases []dsltest.Case, filter *regexp.Regexp) []dsltest.Case {
+	var selected []dsltest.Case
+	for _, c := range cases {
+		if filter.MatchString(c.Title()) {
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+// report prints the results of a suite the way go test does and reports
+// whether all its tests passed.
+func report(suite dsltest.Suite, verbose bool) bool {
+	failed := 0
+	var elapsed time.Duration
+	for _, r := range suite.Results {
+		elapsed += r.Duration
+		if r.Passed() {
+			if verbose {
+				fmt.Printf("--- PASS: %s (%.2fs)\n", r.Case.Title(), r.Duration.Seconds())
+			}
+			continue
+		}
+		failed++
+		fmt.Printf("--- FAIL: %s (%.2fs)\n", r.Case.Title(), r.Duration.Seconds())
+		fmt.Printf("    input: %q\n", r.Case.Input)
+		for _, line := range strings.Split(r.Failure, "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("FAIL\t%s\t%d of %d tests failed (%.3fs)\n", suite.Name, failed, len(suite.Results), elapsed.Seconds())
+		return false
+	}
+	fmt.Printf("ok  \t%s\t%d tests (%.3fs)\n", suite.Name, len(suite.Results), elapsed.Seconds())
+	return true
+}
+
+func writeJUnit(filename string, suites []dsltest.Suite) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := dsltest.WriteJUnit(f, suites...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -156,13 +156,16 @@ func validateDSL(filename string, verbose bool, strict bool) ValidationResult {
 
 	// Try to create DSL instance
 	if result.Valid {
-		if _, err := createDSLFromConfig(config); err != nil {
+		dsl, err := createDSLFromConfig(config)
+		if err != nil {
 			result.Valid = false
 			result.Errors = append(result.Errors, ValidationError{
 				Type:    "CreationError",
 				Message: "Failed to create DSL instance",
 				Details: err.Error(),
 			})
+		} else {
+			applyTokenPriorities(dsl, &result)
 		}
 	}
 
@@ -245,6 +248,20 @@ func validateTokens(config *DSLConfig, result *ValidationResult, strict bool) {
 	result.Info.TokenCount = tokenCount
 }
 
+// applyTokenPriorities replaces the heuristic token priorities with the
+// priorities the DSL actually assigned when the grammar was built.
+func applyTokenPriorities(dsl *dslbuilder.DSL, result *ValidationResult) {
+	priorities := make(map[string]int)
+	for _, token := range dsl.Tokens() {
+		priorities[token.Name] = token.Priority
+	}
+	for i := range result.Info.Tokens {
+		if priority, exists := priorities[result.Info.Tokens[i].Name]; exists {
+			result.Info.Tokens[i].Priority = priority
+		}
+	}
+}
+
 func checkTokenPattern(name, pattern string, result *ValidationResult, strict bool) {
 	// Check for overly broad patterns
 	if pattern == ".*" || pattern == ".+" {
@@ -656,11 +673,7 @@ func createDSLFromConfig(config *DSLConfig) (*dslbuilder.DSL, error) {
 	}
 
 	// Add dummy actions
-	actions := make(map[string]bool)
-	for _, rule := range config.Rules {
-		actions[rule.Action] = true
-	}
-	for action := range actions {
+	for _, action := range dsl.UnboundActions() {
 		dsl.Action(action, func(args []interface{}) (interface{}, error) {
 			return args, nil
 		})
@@ -0,0 +1,576 @@
+// Code generated by dslgen from calculator_advanced.yaml. DO NOT EDIT.
+
+// Package calc parses the AdvancedCalculator language.
+// It was generated from the grammar and does not depend on go-dsl at run time.
+package calc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Actions computes the value of each alternative from the values it
+// matched: token text as strings and the values of nested rules. An
+// error makes the alternative fail, as with the actions of a DSL.
+type Actions interface {
+	Passthrough(args []interface{}) (interface{}, error) // passthrough
+	Add(args []interface{}) (interface{}, error)         // add
+	Subtract(args []interface{}) (interface{}, error)    // subtract
+	Multiply(args []interface{}) (interface{}, error)    // multiply
+	Divide(args []interface{}) (interface{}, error)      // divide
+	Number(args []interface{}) (interface{}, error)      // number
+	Paren(args []interface{}) (interface{}, error)       // paren
+}
+
+// ActionFuncs implements Actions with functions keyed by action name,
+// such as the ones registered with DSL.Action. An action without a
+// function returns the values it matched.
+type ActionFuncs map[string]func(args []interface{}) (interface{}, error)
+
+func (a ActionFuncs) Passthrough(args []interface{}) (interface{}, error) {
+	return a.call("passthrough", args)
+}
+
+func (a ActionFuncs) Add(args []interface{}) (interface{}, error) {
+	return a.call("add", args)
+}
+
+func (a ActionFuncs) Subtract(args []interface{}) (interface{}, error) {
+	return a.call("subtract", args)
+}
+
+func (a ActionFuncs) Multiply(args []interface{}) (interface{}, error) {
+	return a.call("multiply", args)
+}
+
+func (a ActionFuncs) Divide(args []interface{}) (interface{}, error) {
+	return a.call("divide", args)
+}
+
+func (a ActionFuncs) Number(args []interface{}) (interface{}, error) {
+	return a.call("number", args)
+}
+
+func (a ActionFuncs) Paren(args []interface{}) (interface{}, error) {
+	return a.call("paren", args)
+}
+
+func (a ActionFuncs) call(name string, args []interface{}) (interface{}, error) {
+	if fn := a[name]; fn != nil {
+		return fn(args)
+	}
+	return args, nil
+}
+
+// Token types, in definition order.
+const (
+	tokDIVIDE   = iota // DIVIDE
+	tokLPAREN          // LPAREN
+	tokMINUS           // MINUS
+	tokMULTIPLY        // MULTIPLY
+	tokNUMBER          // NUMBER
+	tokPLUS            // PLUS
+	tokRPAREN          // RPAREN
+	numTokens   = 7
+)
+
+var tokenNames = [numTokens]string{"DIVIDE", "LPAREN", "MINUS", "MULTIPLY", "NUMBER", "PLUS", "RPAREN"}
+
+// skipped marks tokens that are matched but not passed to the parser.
+var skipped = [numTokens]bool{}
+
+// Token patterns, anchored at the start of the remaining input.
+var (
+	reNUMBER = regexp.MustCompile(`^(?:[0-9]+)`)
+)
+
+// Token is a token of the input.
+type Token struct {
+	Type  string // Token name from the grammar
+	Value string // Matched text
+	Start int    // Start position in the input
+	End   int    // End position (exclusive)
+}
+
+type token struct {
+	kind  int
+	value string
+	start int
+}
+
+// Tokenize returns the tokens of input that reach the parser, without
+// skipped tokens such as comments.
+func Tokenize(input string) ([]Token, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Token, len(tokens))
+	for i, t := range tokens {
+		result[i] = Token{Type: tokenNames[t.kind], Value: t.value, Start: t.start, End: t.start + len(t.value)}
+	}
+	return result, nil
+}
+
+// ParseError is a syntax error with its position in the input.
+type ParseError struct {
+	Message  string // Error description
+	Line     int    // 1-based line
+	Column   int    // 1-based column
+	Position int    // Byte offset in the input
+	Token    string // Token text at the error position
+}
+
+func (e *ParseError) Error() string {
+	return e.Message
+}
+
+func newParseError(message string, position int, token string, input string) *ParseError {
+	line, column := 1, 1
+	for i := 0; i < position && i < len(input); i++ {
+		if input[i] == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return &ParseError{Message: message, Line: line, Column: column, Position: position, Token: token}
+}
+
+// tokenize splits input into tokens. Whitespace separates tokens; at
+// each position the token with the highest priority wins, then the longest.
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(input) {
+		switch input[pos] {
+		case ' ', '\t', '\n', '\r':
+			pos++
+			continue
+		}
+
+		rest := input[pos:]
+		best, bestLength, bestPriority := -1, 0, -1
+		// DIVIDE: \/
+		if n := 1; strings.HasPrefix(rest, "/") && (bestPriority < 0 || bestPriority == 0 && n > bestLength) {
+			best, bestLength, bestPriority = tokDIVIDE, n, 0
+		}
+		// LPAREN: \(
+		if n := 1; strings.HasPrefix(rest, "(") && (bestPriority < 0 || bestPriority == 0 && n > bestLength) {
+			best, bestLength, bestPriority = tokLPAREN, n, 0
+		}
+		// MINUS: \-
+		if n := 1; strings.HasPrefix(rest, "-") && (bestPriority < 0 || bestPriority == 0 && n > bestLength) {
+			best, bestLength, bestPriority = tokMINUS, n, 0
+		}
+		// MULTIPLY: \*
+		if n := 1; strings.HasPrefix(rest, "*") && (bestPriority < 0 || bestPriority == 0 && n > bestLength) {
+			best, bestLength, bestPriority = tokMULTIPLY, n, 0
+		}
+		// NUMBER: [0-9]+
+		if n := matchRegexp(reNUMBER, rest); n >= 0 && (bestPriority < 0 || bestPriority == 0 && n > bestLength) {
+			best, bestLength, bestPriority = tokNUMBER, n, 0
+		}
+		// PLUS: \+
+		if n := 1; strings.HasPrefix(rest, "+") && (bestPriority < 0 || bestPriority == 0 && n > bestLength) {
+			best, bestLength, bestPriority = tokPLUS, n, 0
+		}
+		// RPAREN: \)
+		if n := 1; strings.HasPrefix(rest, ")") && (bestPriority < 0 || bestPriority == 0 && n > bestLength) {
+			best, bestLength, bestPriority = tokRPAREN, n, 0
+		}
+
+		if best < 0 {
+			return nil, newParseError(fmt.Sprintf("unexpected character: %c", input[pos]), pos, string(input[pos]), input)
+		}
+		if !skipped[best] {
+			tokens = append(tokens, token{kind: best, value: input[pos : pos+bestLength], start: pos})
+		}
+		pos += bestLength
+	}
+	return tokens, nil
+}
+
+// matchRegexp returns the length of the match of an anchored pattern, or
+// -1 if it does not match.
+func matchRegexp(re *regexp.Regexp, s string) int {
+	if loc := re.FindStringIndex(s); loc != nil {
+		return loc[1]
+	}
+	return -1
+}
+
+// Rules, in definition order.
+const (
+	ruleExpression = iota // expression
+	ruleTerm              // term
+	ruleFactor            // factor
+	numRules       = 3
+)
+
+// Parse parses input starting with the expression rule and returns the value
+// computed by the actions, like DSL.Parse. With nil actions every
+// alternative returns the values it matched.
+func Parse(input string, actions Actions) (interface{}, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, actions: actions}
+	result, ok := p.rule(ruleExpression, (*parser).matchExpression)
+	if !ok {
+		return nil, fmt.Errorf("parsing error: %s", "no alternative matched for rule expression")
+	}
+	if p.pos < len(tokens) {
+		t := tokens[p.pos]
+		return nil, newParseError("unexpected token: "+t.value, t.start, t.value, input)
+	}
+	return result, nil
+}
+
+type memoEntry struct {
+	result interface{}
+	end    int
+	ok     bool
+	set    bool
+}
+
+type parser struct {
+	tokens  []token
+	pos     int
+	actions Actions
+	memo    [numRules][]memoEntry // Outcome of each rule by start token
+	growing [numRules][]bool      // Left-recursive rules being grown by start token
+}
+
+// rule parses rule r with match, remembering the outcome for the position.
+func (p *parser) rule(r int, match func(*parser) (interface{}, bool)) (interface{}, bool) {
+	memo := p.memo[r]
+	if memo == nil {
+		memo = make([]memoEntry, len(p.tokens)+1)
+		p.memo[r] = memo
+	}
+	start := p.pos
+	if e := &memo[start]; e.set {
+		p.pos = e.end
+		return e.result, e.ok
+	}
+	result, ok := match(p)
+	memo[start] = memoEntry{result: result, end: p.pos, ok: ok, set: true}
+	return result, ok
+}
+
+// token consumes a token of the given kind.
+func (p *parser) token(kind int) (interface{}, bool) {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind {
+		p.pos++
+		return p.tokens[p.pos-1].value, true
+	}
+	return nil, false
+}
+
+// grow marks a left-recursive rule as being grown at start. It reports
+// false if it already is, when the rule recursed into itself without
+// consuming input.
+func (p *parser) grow(r, start int) bool {
+	if p.growing[r] == nil {
+		p.growing[r] = make([]bool, len(p.tokens)+1)
+	}
+	if p.growing[r][start] {
+		return false
+	}
+	p.growing[r][start] = true
+	return true
+}
+
+// matchExpression parses the left-recursive rule expression.
+func (p *parser) matchExpression() (interface{}, bool) {
+	start := p.pos
+	if !p.grow(ruleExpression, start) {
+		return nil, false
+	}
+	result, ok := p.growExpression(start)
+	p.growing[ruleExpression][start] = false
+	return result, ok
+}
+
+func (p *parser) growExpression(start int) (interface{}, bool) {
+	// Seed with the first alternative that does not start with expression
+	seed, ok := p.altExpression0()
+	if !ok {
+		return nil, false
+	}
+
+	// Extend the seed with the alternative that consumes the most input
+	seedPos := p.pos
+	memo := p.memo[ruleExpression]
+	for {
+		best, bestPos, improved := seed, seedPos, false
+
+		// expression → expression PLUS term {add}
+		memo[start] = memoEntry{result: seed, end: seedPos, ok: true, set: true}
+		p.pos = seedPos
+		if results, ok := p.altExpression1(seed); ok && p.pos > bestPos {
+			if result, ok := p.actAdd(results); ok {
+				best, bestPos, improved = result, p.pos, true
+			}
+		}
+
+		// expression → expression MINUS term {subtract}
+		memo[start] = memoEntry{result: seed, end: seedPos, ok: true, set: true}
+		p.pos = seedPos
+		if results, ok := p.altExpression2(seed); ok && p.pos > bestPos {
+			if result, ok := p.actSubtract(results); ok {
+				best, bestPos, improved = result, p.pos, true
+			}
+		}
+
+		if !improved {
+			p.pos = bestPos
+			return best, true
+		}
+		seed, seedPos = best, bestPos
+	}
+}
+
+// expression → term {passthrough}
+func (p *parser) altExpression0() (interface{}, bool) {
+	results := make([]interface{}, 0, 1)
+	var v interface{}
+	var ok bool
+	if v, ok = p.rule(ruleTerm, (*parser).matchTerm); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	return p.actPassthrough(results)
+}
+
+// expression → expression PLUS term {add}
+func (p *parser) altExpression1(seed interface{}) ([]interface{}, bool) {
+	results := append(make([]interface{}, 0, 3), seed)
+	var v interface{}
+	var ok bool
+	if v, ok = p.token(tokPLUS); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	if v, ok = p.rule(ruleTerm, (*parser).matchTerm); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	return results, true
+}
+
+// expression → expression MINUS term {subtract}
+func (p *parser) altExpression2(seed interface{}) ([]interface{}, bool) {
+	results := append(make([]interface{}, 0, 3), seed)
+	var v interface{}
+	var ok bool
+	if v, ok = p.token(tokMINUS); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	if v, ok = p.rule(ruleTerm, (*parser).matchTerm); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	return results, true
+}
+
+// matchTerm parses the left-recursive rule term.
+func (p *parser) matchTerm() (interface{}, bool) {
+	start := p.pos
+	if !p.grow(ruleTerm, start) {
+		return nil, false
+	}
+	result, ok := p.growTerm(start)
+	p.growing[ruleTerm][start] = false
+	return result, ok
+}
+
+func (p *parser) growTerm(start int) (interface{}, bool) {
+	// Seed with the first alternative that does not start with term
+	seed, ok := p.altTerm0()
+	if !ok {
+		return nil, false
+	}
+
+	// Extend the seed with the alternative that consumes the most input
+	seedPos := p.pos
+	memo := p.memo[ruleTerm]
+	for {
+		best, bestPos, improved := seed, seedPos, false
+
+		// term → term MULTIPLY factor {multiply}
+		memo[start] = memoEntry{result: seed, end: seedPos, ok: true, set: true}
+		p.pos = seedPos
+		if results, ok := p.altTerm1(seed); ok && p.pos > bestPos {
+			if result, ok := p.actMultiply(results); ok {
+				best, bestPos, improved = result, p.pos, true
+			}
+		}
+
+		// term → term DIVIDE factor {divide}
+		memo[start] = memoEntry{result: seed, end: seedPos, ok: true, set: true}
+		p.pos = seedPos
+		if results, ok := p.altTerm2(seed); ok && p.pos > bestPos {
+			if result, ok := p.actDivide(results); ok {
+				best, bestPos, improved = result, p.pos, true
+			}
+		}
+
+		if !improved {
+			p.pos = bestPos
+			return best, true
+		}
+		seed, seedPos = best, bestPos
+	}
+}
+
+// term → factor {passthrough}
+func (p *parser) altTerm0() (interface{}, bool) {
+	results := make([]interface{}, 0, 1)
+	var v interface{}
+	var ok bool
+	if v, ok = p.rule(ruleFactor, (*parser).matchFactor); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	return p.actPassthrough(results)
+}
+
+// term → term MULTIPLY factor {multiply}
+func (p *parser) altTerm1(seed interface{}) ([]interface{}, bool) {
+	results := append(make([]interface{}, 0, 3), seed)
+	var v interface{}
+	var ok bool
+	if v, ok = p.token(tokMULTIPLY); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	if v, ok = p.rule(ruleFactor, (*parser).matchFactor); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	return results, true
+}
+
+// term → term DIVIDE factor {divide}
+func (p *parser) altTerm2(seed interface{}) ([]interface{}, bool) {
+	results := append(make([]interface{}, 0, 3), seed)
+	var v interface{}
+	var ok bool
+	if v, ok = p.token(tokDIVIDE); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	if v, ok = p.rule(ruleFactor, (*parser).matchFactor); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	return results, true
+}
+
+// matchFactor tries the alternatives of factor in order.
+func (p *parser) matchFactor() (interface{}, bool) {
+	start := p.pos
+	result, ok := p.altFactor0()
+	if !ok {
+		p.pos = start
+		result, ok = p.altFactor1()
+	}
+	if !ok {
+		p.pos = start
+	}
+	return result, ok
+}
+
+// factor → NUMBER {number}
+func (p *parser) altFactor0() (interface{}, bool) {
+	results := make([]interface{}, 0, 1)
+	var v interface{}
+	var ok bool
+	if v, ok = p.token(tokNUMBER); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	return p.actNumber(results)
+}
+
+// factor → LPAREN expression RPAREN {paren}
+func (p *parser) altFactor1() (interface{}, bool) {
+	results := make([]interface{}, 0, 3)
+	var v interface{}
+	var ok bool
+	if v, ok = p.token(tokLPAREN); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	if v, ok = p.rule(ruleExpression, (*parser).matchExpression); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	if v, ok = p.token(tokRPAREN); !ok {
+		return nil, false
+	}
+	results = append(results, v)
+	return p.actParen(results)
+}
+
+func (p *parser) actPassthrough(args []interface{}) (interface{}, bool) {
+	if p.actions == nil {
+		return args, true
+	}
+	result, err := p.actions.Passthrough(args)
+	return result, err == nil
+}
+
+func (p *parser) actAdd(args []interface{}) (interface{}, bool) {
+	if p.actions == nil {
+		return args, true
+	}
+	result, err := p.actions.Add(args)
+	return result, err == nil
+}
+
+func (p *parser) actSubtract(args []interface{}) (interface{}, bool) {
+	if p.actions == nil {
+		return args, true
+	}
+	result, err := p.actions.Subtract(args)
+	return result, err == nil
+}
+
+func (p *parser) actMultiply(args []interface{}) (interface{}, bool) {
+	if p.actions == nil {
+		return args, true
+	}
+	result, err := p.actions.Multiply(args)
+	return result, err == nil
+}
+
+func (p *parser) actDivide(args []interface{}) (interface{}, bool) {
+	if p.actions == nil {
+		return args, true
+	}
+	result, err := p.actions.Divide(args)
+	return result, err == nil
+}
+
+func (p *parser) actNumber(args []interface{}) (interface{}, bool) {
+	if p.actions == nil {
+		return args, true
+	}
+	result, err := p.actions.Number(args)
+	return result, err == nil
+}
+
+func (p *parser) actParen(args []interface{}) (interface{}, bool) {
+	if p.actions == nil {
+		return args, true
+	}
+	result, err := p.actions.Paren(args)
+	return result, err == nil
+}
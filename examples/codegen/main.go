@@ -0,0 +1,114 @@
+// Example codegen compares the interpreter of a YAML grammar with the
+// parser that dslgen generates from it. Both use the same actions.
+package main
+
+//go:generate go run ../../cmd/dslgen -dsl ../declarative/calculator_advanced.yaml -o calc/calc.go
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arturoeanton/go-dsl/examples/codegen/calc"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// evaluator implements calc.Actions with integer arithmetic.
+type evaluator struct{}
+
+func (evaluator) Passthrough(args []interface{}) (interface{}, error) { return args[0], nil }
+func (evaluator) Paren(args []interface{}) (interface{}, error)       { return args[1], nil }
+
+func (evaluator) Number(args []interface{}) (interface{}, error) {
+	return strconv.Atoi(args[0].(string))
+}
+
+func (evaluator) Add(args []interface{}) (interface{}, error) {
+	return args[0].(int) + args[2].(int), nil
+}
+
+func (evaluator) Subtract(args []interface{}) (interface{}, error) {
+	return args[0].(int) - args[2].(int), nil
+}
+
+func (evaluator) Multiply(args []interface{}) (interface{}, error) {
+	return args[0].(int) * args[2].(int), nil
+}
+
+func (evaluator) Divide(args []interface{}) (interface{}, error) {
+	if args[2].(int) == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	return args[0].(int) / args[2].(int), nil
+}
+
+// newInterpreter loads the grammar the calc package was generated from and
+// binds the same actions.
+func newInterpreter() (*dslbuilder.DSL, error) {
+	dsl, err := dslbuilder.LoadFromYAMLFile("../declarative/calculator_advanced.yaml")
+	if err != nil {
+		return nil, err
+	}
+	var e evaluator
+	dsl.Action("passthrough", e.Passthrough)
+	dsl.Action("paren", e.Paren)
+	dsl.Action("number", e.Number)
+	dsl.Action("add", e.Add)
+	dsl.Action("subtract", e.Subtract)
+	dsl.Action("multiply", e.Multiply)
+	dsl.Action("divide", e.Divide)
+	return dsl, nil
+}
+
+// longExpression returns an expression with n operations.
+func longExpression(n int) string {
+	var b strings.Builder
+	b.WriteString("1")
+	ops := []string{" + ", " * ", " - ", " / "}
+	for i := 0; i < n; i++ {
+		b.WriteString(ops[i%len(ops)])
+		fmt.Fprintf(&b, "(%d + %d)", i%7+1, i%5+1)
+	}
+	return b.String()
+}
+
+func main() {
+	dsl, err := newInterpreter()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("=== Interpreter vs generated parser ===")
+	for _, input := range []string{"2 + 3 * 4", "(2 + 3) * 4", "100 / 5 / 2", "7 - (1 +", "8 / 0"} {
+		interpreted := "error"
+		if result, err := dsl.Parse(input); err == nil {
+			interpreted = fmt.Sprint(result.GetOutput())
+		}
+		generated := "error"
+		if result, err := calc.Parse(input, evaluator{}); err == nil {
+			generated = fmt.Sprint(result)
+		}
+		fmt.Printf("%-14s interpreter: %-6s generated: %s\n", input, interpreted, generated)
+	}
+
+	fmt.Println()
+	fmt.Println("=== Timing (1000 parses of a 100-operation expression) ===")
+	input := longExpression(100)
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		if _, err := dsl.Parse(input); err != nil {
+			log.Fatal(err)
+		}
+	}
+	interpreted := time.Since(start)
+	start = time.Now()
+	for i := 0; i < 1000; i++ {
+		if _, err := calc.Parse(input, evaluator{}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	generated := time.Since(start)
+	fmt.Printf("interpreter: %v\ngenerated:   %v (%.1fx faster)\n", interpreted, generated, float64(interpreted)/float64(generated))
+}
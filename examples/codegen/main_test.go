@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/examples/codegen/calc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGeneratedMatchesInterpreter checks that calc parses like the grammar
+// it was generated from.
+func TestGeneratedMatchesInterpreter(t *testing.T) {
+	dsl, err := newInterpreter()
+	require.NoError(t, err)
+
+	inputs := []string{
+		"42", "2 + 3 * 4", "(2 + 3) * 4", "100 / 5 / 2", "10 - 4 - 3",
+		"7 - (1 +", "8 / 0", "1 2", "", "3 $ 4", longExpression(50),
+	}
+	for _, input := range inputs {
+		want, wantErr := dsl.Parse(input)
+		got, err := calc.Parse(input, evaluator{})
+		if wantErr != nil {
+			assert.EqualError(t, err, wantErr.Error(), input)
+			continue
+		}
+		require.NoError(t, err, input)
+		assert.Equal(t, want.GetOutput(), got, input)
+	}
+}
+
+func BenchmarkInterpreter(b *testing.B) {
+	dsl, err := newInterpreter()
+	require.NoError(b, err)
+	input := longExpression(100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := dsl.Parse(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenerated(b *testing.B) {
+	input := longExpression(100)
+	for i := 0; i < b.N; i++ {
+		if _, err := calc.Parse(input, evaluator{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
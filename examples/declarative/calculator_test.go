@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/dsltest"
+)
+
+func newCalculator(t *testing.T) *dslbuilder.DSL {
+	dsl, err := dslbuilder.LoadFromYAMLFile("calculator.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	registerCalculatorActions(dsl)
+	return dsl
+}
+
+// TestCalculatorGrammar runs the tests section of calculator.yaml.
+func TestCalculatorGrammar(t *testing.T) {
+	dsltest.RunFile(t, newCalculator(t), "calculator.yaml")
+}
+
+// TestCalculatorOutputs checks the results of the Go actions.
+func TestCalculatorOutputs(t *testing.T) {
+	dsltest.RunCases(t, newCalculator(t), []dsltest.Case{
+		{Input: "5 + 3", Output: 8},
+		{Input: "10 - 4", Output: 6},
+		{Input: "6 * 7", Output: 42},
+		{Input: "20 / 4", Output: 5},
+		{Input: "1 / 0", Reject: true},
+	})
+}
+
+// TestBuiltinCalculator runs calculator_builtin.yaml, whose actions come
+// from the built-in action library, without registering any.
+func TestBuiltinCalculator(t *testing.T) {
+	dsl, err := dslbuilder.LoadFromYAMLFile("calculator_builtin.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsltest.RunFile(t, dsl, "calculator_builtin.yaml")
+}
@@ -19,29 +19,7 @@ func main() {
 		log.Printf("Error loading YAML: %v", err)
 	} else {
 		// Register actions for the calculator
-		yamlDSL.Action("add", func(args []interface{}) (interface{}, error) {
-			a, _ := strconv.Atoi(args[0].(string))
-			b, _ := strconv.Atoi(args[2].(string))
-			return a + b, nil
-		})
-		yamlDSL.Action("subtract", func(args []interface{}) (interface{}, error) {
-			a, _ := strconv.Atoi(args[0].(string))
-			b, _ := strconv.Atoi(args[2].(string))
-			return a - b, nil
-		})
-		yamlDSL.Action("multiply", func(args []interface{}) (interface{}, error) {
-			a, _ := strconv.Atoi(args[0].(string))
-			b, _ := strconv.Atoi(args[2].(string))
-			return a * b, nil
-		})
-		yamlDSL.Action("divide", func(args []interface{}) (interface{}, error) {
-			a, _ := strconv.Atoi(args[0].(string))
-			b, _ := strconv.Atoi(args[2].(string))
-			if b == 0 {
-				return nil, fmt.Errorf("division by zero")
-			}
-			return a / b, nil
-		})
+		registerCalculatorActions(yamlDSL)
 
 		// Test the YAML-loaded DSL
 		testCalculator(yamlDSL, "YAML-loaded")
@@ -135,6 +113,33 @@ func main() {
 	fmt.Println("=== All examples completed successfully! ===")
 }
 
+// registerCalculatorActions binds the actions of calculator.yaml.
+func registerCalculatorActions(dsl *dslbuilder.DSL) {
+	dsl.Action("add", func(args []interface{}) (interface{}, error) {
+		a, _ := strconv.Atoi(args[0].(string))
+		b, _ := strconv.Atoi(args[2].(string))
+		return a + b, nil
+	})
+	dsl.Action("subtract", func(args []interface{}) (interface{}, error) {
+		a, _ := strconv.Atoi(args[0].(string))
+		b, _ := strconv.Atoi(args[2].(string))
+		return a - b, nil
+	})
+	dsl.Action("multiply", func(args []interface{}) (interface{}, error) {
+		a, _ := strconv.Atoi(args[0].(string))
+		b, _ := strconv.Atoi(args[2].(string))
+		return a * b, nil
+	})
+	dsl.Action("divide", func(args []interface{}) (interface{}, error) {
+		a, _ := strconv.Atoi(args[0].(string))
+		b, _ := strconv.Atoi(args[2].(string))
+		if b == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return a / b, nil
+	})
+}
+
 func testCalculator(dsl *dslbuilder.DSL, name string) {
 	testCases := []string{
 		"5 + 3",
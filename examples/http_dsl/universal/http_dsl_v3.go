@@ -1328,6 +1328,13 @@ func (hd *HTTPDSLv3) ParseWithContext(input string) (interface{}, error) {
 	return result.Output, nil
 }
 
+// GetDSL returns the underlying DSL, with the HTTP actions registered.
+// It lets generic tools such as the REPL (see the registry package) run
+// HTTP DSL statements with their real actions.
+func (hd *HTTPDSLv3) GetDSL() *dslbuilder.DSL {
+	return hd.dsl
+}
+
 // GetEngine returns the underlying HTTP execution engine.
 // The engine handles actual HTTP requests, responses, and network operations.
 func (hd *HTTPDSLv3) GetEngine() *HTTPEngine {
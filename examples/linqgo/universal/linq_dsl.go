@@ -1210,6 +1210,11 @@ func (ul *UniversalLinqDSL) Use(query string, context map[string]interface{}) (*
 	return ul.dsl.Use(query, context)
 }
 
+// GetDSL returns the underlying DSL with the LINQ actions registered
+func (ul *UniversalLinqDSL) GetDSL() *dslbuilder.DSL {
+	return ul.dsl
+}
+
 // SetContext sets a context value
 func (ul *UniversalLinqDSL) SetContext(key string, value interface{}) {
 	ul.dsl.SetContext(key, value)
@@ -0,0 +1,48 @@
+// Example of a custom REPL build. DSLs written in Go are registered by name
+// and selected with -lang, so the REPL runs them with their real actions
+// instead of the generic ones used for YAML/JSON grammars:
+//
+//	go run ./examples/repl_plugin -lang linq -context examples/repl_plugin/data.json
+//	DSL> from employee order by salary select name
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/arturoeanton/go-dsl/examples/linqgo/universal"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/registry"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/repl"
+)
+
+func main() {
+	registry.Register("linq", func() *dslbuilder.DSL {
+		return universal.NewUniversalLinqDSL().GetDSL()
+	})
+
+	lang := flag.String("lang", "linq", "Registered DSL to use")
+	contextFile := flag.String("context", "", "Context file (JSON) to preload")
+	flag.Parse()
+
+	dsl, err := registry.New(*lang)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts := repl.Options{History: ".linq_history"}
+	if *contextFile != "" {
+		data, err := os.ReadFile(*contextFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &opts.Context); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := repl.Run(dsl, opts); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,356 @@
+// Package analysis computes static properties of go-dsl grammars: the
+// nullable rules, FIRST and FOLLOW sets, and LL(1) and LALR(1) parsing
+// tables. Conflicts in the tables are reported with the competing rules,
+// the lookahead tokens and an example input that reaches the conflict,
+// which tells whether a grammar is deterministic and where it is fragile.
+//
+// For LL(1) grammars, Predictive installs the prediction table on a DSL so
+// that Parse runs a table-driven parser with no backtracking.
+//
+// Lookahead and cut operators in patterns match no input and are left out
+// of the analysis, so a conflict that a lookahead resolves is still
+// reported.
+//
+// Example:
+//
+//	a, err := analysis.Analyze(dsl.Grammar())
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, c := range a.LL1().Conflicts {
+//	    fmt.Println(c) // LL(1) conflict in expr on NUMBER: ... (example: "1")
+//	}
+//
+//	if err := analysis.Predictive(dsl); err != nil {
+//	    log.Println(err) // not LL(1), keep the default parser
+//	}
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/gen"
+)
+
+// EndOfInput marks the end of the input in FOLLOW sets and lookaheads.
+const EndOfInput = dslbuilder.EndOfInput
+
+// Analysis holds the nullable rules and the FIRST and FOLLOW sets of a
+// grammar. The LL1 and LALR methods build parsing tables from them.
+type Analysis struct {
+	Start    string              // Start rule
+	Nullable map[string]bool     // Rules that can match the empty input
+	First    map[string][]string // Tokens that can start each rule, sorted
+	Follow   map[string][]string // Tokens that can follow each rule, sorted; EndOfInput marks the end
+
+	order   []string // Rule names in definition order
+	rules   map[string]dslbuilder.RuleInfo
+	tokens  map[string]dslbuilder.TokenInfo
+	first   map[string]map[string]bool
+	follow  map[string]map[string]bool
+	cost    map[string]int // Length of the shortest sentence of each rule
+	best    map[string]int // Alternative reaching that length
+	sampler *gen.Generator
+}
+
+// Analyze computes the nullable rules and the FIRST and FOLLOW sets of a
+// grammar. It fails if the grammar has no rules or a rule refers to an
+// undefined symbol.
+func Analyze(grammar *dslbuilder.Grammar) (*Analysis, error) {
+	a := &Analysis{
+		Start:    grammar.StartRule(),
+		Nullable: make(map[string]bool),
+		First:    make(map[string][]string),
+		Follow:   make(map[string][]string),
+		rules:    make(map[string]dslbuilder.RuleInfo),
+		tokens:   make(map[string]dslbuilder.TokenInfo),
+		first:    make(map[string]map[string]bool),
+		follow:   make(map[string]map[string]bool),
+		cost:     make(map[string]int),
+		best:     make(map[string]int),
+		sampler:  gen.New(grammar, gen.Options{}),
+	}
+	for _, token := range grammar.Tokens() {
+		a.tokens[token.Name] = token
+	}
+	for _, rule := range grammar.Rules() {
+		a.order = append(a.order, rule.Name)
+		a.rules[rule.Name] = rule
+		a.first[rule.Name] = make(map[string]bool)
+		a.follow[rule.Name] = make(map[string]bool)
+		a.cost[rule.Name] = math.MaxInt32
+	}
+	if len(a.order) == 0 {
+		return nil, fmt.Errorf("grammar has no rules")
+	}
+	for _, name := range a.order {
+		for i, alt := range a.rules[name].Alternatives {
+			for _, symbol := range alt.Sequence {
+				if target, _, ok := dslbuilder.LookaheadOf(symbol); ok {
+					symbol = target
+				} else if symbol == dslbuilder.Cut {
+					continue
+				}
+				if !a.isToken(symbol) && !a.isRule(symbol) {
+					return nil, fmt.Errorf("rule %s: undefined symbol %s", name, symbol)
+				}
+			}
+			// Lookaheads and cuts match no input, so the tables ignore them
+			a.rules[name].Alternatives[i].Sequence = alt.Symbols()
+		}
+	}
+
+	a.computeFirst()
+	a.computeFollow()
+	a.computeCosts()
+	for _, name := range a.order {
+		a.First[name] = sorted(a.first[name])
+		a.Follow[name] = sorted(a.follow[name])
+	}
+	return a, nil
+}
+
+func (a *Analysis) isToken(symbol string) bool {
+	_, ok := a.tokens[symbol]
+	return ok
+}
+
+func (a *Analysis) isRule(symbol string) bool {
+	_, ok := a.rules[symbol]
+	return ok
+}
+
+// computeFirst runs a fixpoint for the nullable rules and FIRST sets.
+func (a *Analysis) computeFirst() {
+	for changed := true; changed; {
+		changed = false
+		for _, name := range a.order {
+			for _, alt := range a.rules[name].Alternatives {
+				first, nullable := a.firstOf(alt.Sequence)
+				for token := range first {
+					if !a.first[name][token] {
+						a.first[name][token] = true
+						changed = true
+					}
+				}
+				if nullable && !a.Nullable[name] {
+					a.Nullable[name] = true
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+// computeFollow runs a fixpoint for the FOLLOW sets.
+func (a *Analysis) computeFollow() {
+	a.follow[a.Start][EndOfInput] = true
+	for changed := true; changed; {
+		changed = false
+		for _, name := range a.order {
+			for _, alt := range a.rules[name].Alternatives {
+				for i, symbol := range alt.Sequence {
+					if !a.isRule(symbol) {
+						continue
+					}
+					first, nullable := a.firstOf(alt.Sequence[i+1:])
+					if nullable {
+						for token := range a.follow[name] {
+							first[token] = true
+						}
+					}
+					for token := range first {
+						if !a.follow[symbol][token] {
+							a.follow[symbol][token] = true
+							changed = true
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// computeCosts finds the shortest sentence of every rule, as gen does, to
+// build example inputs.
+func (a *Analysis) computeCosts() {
+	for changed := true; changed; {
+		changed = false
+		for _, name := range a.order {
+			for i, alt := range a.rules[name].Alternatives {
+				if c := a.sequenceCost(alt.Sequence); c < a.cost[name] {
+					a.cost[name] = c
+					a.best[name] = i
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+func (a *Analysis) sequenceCost(sequence []string) int {
+	total := 0
+	for _, symbol := range sequence {
+		c := 1
+		if a.isRule(symbol) {
+			c = a.cost[symbol]
+		}
+		if c == math.MaxInt32 {
+			return c
+		}
+		total += c
+	}
+	return total
+}
+
+// firstOf returns the tokens that can start a symbol sequence and whether
+// the whole sequence can match the empty input.
+func (a *Analysis) firstOf(sequence []string) (map[string]bool, bool) {
+	first := make(map[string]bool)
+	for _, symbol := range sequence {
+		if a.isToken(symbol) {
+			first[symbol] = true
+			return first, false
+		}
+		for token := range a.first[symbol] {
+			first[token] = true
+		}
+		if !a.Nullable[symbol] {
+			return first, false
+		}
+	}
+	return first, true
+}
+
+// FirstOf returns the sorted tokens that can start a symbol sequence and
+// whether the whole sequence can match the empty input.
+func (a *Analysis) FirstOf(sequence []string) ([]string, bool) {
+	first, nullable := a.firstOf(sequence)
+	return sorted(first), nullable
+}
+
+// yield appends the tokens of the shortest sentence of symbols to tokens.
+func (a *Analysis) yield(tokens []string, symbols ...string) []string {
+	for _, symbol := range symbols {
+		if a.isToken(symbol) {
+			tokens = append(tokens, symbol)
+			continue
+		}
+		if a.cost[symbol] == math.MaxInt32 {
+			continue // Cannot derive a sentence; the example is incomplete
+		}
+		tokens = a.yield(tokens, a.rules[symbol].Alternatives[a.best[symbol]].Sequence...)
+	}
+	return tokens
+}
+
+// prefixes returns, for every rule reachable from the start rule, the
+// shortest tokens that can precede it in a sentence.
+func (a *Analysis) prefixes() map[string][]string {
+	prefix := map[string][]string{a.Start: {}}
+	for changed := true; changed; {
+		changed = false
+		for _, name := range a.order {
+			before, ok := prefix[name]
+			if !ok {
+				continue
+			}
+			for _, alt := range a.rules[name].Alternatives {
+				for i, symbol := range alt.Sequence {
+					if !a.isRule(symbol) {
+						continue
+					}
+					candidate := a.yield(append([]string(nil), before...), alt.Sequence[:i]...)
+					if current, ok := prefix[symbol]; !ok || len(candidate) < len(current) {
+						prefix[symbol] = candidate
+						changed = true
+					}
+				}
+			}
+		}
+	}
+	return prefix
+}
+
+// render turns example tokens into input text.
+func (a *Analysis) render(tokens []string) string {
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		value, err := a.sampler.ShortestToken(token)
+		if err != nil {
+			value = token
+		}
+		words = append(words, value)
+	}
+	return strings.Join(words, " ")
+}
+
+// Item is an alternative with a position: the symbols before Dot have
+// matched. LL(1) conflicts use Dot 0.
+type Item struct {
+	Rule        string   // Rule name
+	Alternative int      // Index of the alternative in the rule
+	Sequence    []string // Symbols of the alternative
+	Dot         int      // Number of symbols matched
+}
+
+// String returns the item in the form "expr -> expr . PLUS term".
+func (i Item) String() string {
+	parts := []string{i.Rule, "->"}
+	parts = append(parts, i.Sequence[:i.Dot]...)
+	parts = append(parts, ".")
+	parts = append(parts, i.Sequence[i.Dot:]...)
+	return strings.Join(parts, " ")
+}
+
+// Conflict is a table entry with more than one possible action.
+type Conflict struct {
+	Kind      string   // "LL(1)", "shift/reduce" or "reduce/reduce"
+	Rule      string   // Rule whose alternatives compete, or the rule being reduced
+	State     int      // LALR(1) state, -1 for LL(1) conflicts
+	Items     []Item   // Competing alternatives or LR items
+	Lookahead []string // Tokens on which the conflict occurs, sorted
+	Example   []string // Tokens of an input reaching the conflict, ending with a lookahead
+	Input     string   // Example rendered as text
+}
+
+// String describes the conflict on one line.
+func (c Conflict) String() string {
+	items := make([]string, len(c.Items))
+	for i, item := range c.Items {
+		items[i] = item.String()
+	}
+	where := "in " + c.Rule
+	if c.State >= 0 {
+		where = fmt.Sprintf("in state %d", c.State)
+	}
+	return fmt.Sprintf("%s conflict %s on %s: %s (example: %q)",
+		c.Kind, where, strings.Join(c.Lookahead, ", "), strings.Join(items, " | "), c.Input)
+}
+
+// ConflictError is returned by Predictive for grammars that are not LL(1).
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	lines := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		lines[i] = c.String()
+	}
+	return "grammar is not LL(1): " + strings.Join(lines, "; ")
+}
+
+// sorted returns the keys of a map in order.
+func sorted[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
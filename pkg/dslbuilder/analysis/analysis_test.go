@@ -0,0 +1,226 @@
+package analysis
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLL1Calculator builds a calculator without left recursion:
+//
+//	expr -> term rest
+//	rest -> PLUS term rest | ε
+//	term -> NUMBER | LPAREN expr RPAREN
+func newLL1Calculator(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("ll1")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("LPAREN", "\\("))
+	require.NoError(t, dsl.Token("RPAREN", "\\)"))
+	dsl.Rule("expr", []string{"term", "rest"}, "expr")
+	dsl.Rule("rest", []string{"PLUS", "term", "rest"}, "rest")
+	dsl.Rule("rest", []string{}, "")
+	dsl.Rule("term", []string{"NUMBER"}, "number")
+	dsl.Rule("term", []string{"LPAREN", "expr", "RPAREN"}, "paren")
+
+	dsl.Action("expr", func(args []interface{}) (interface{}, error) {
+		if rest, ok := args[1].(int); ok {
+			return args[0].(int) + rest, nil
+		}
+		return args[0], nil
+	})
+	dsl.Action("rest", func(args []interface{}) (interface{}, error) {
+		if rest, ok := args[2].(int); ok {
+			return args[1].(int) + rest, nil
+		}
+		return args[1], nil
+	})
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		return strconv.Atoi(args[0].(string))
+	})
+	dsl.Action("paren", func(args []interface{}) (interface{}, error) {
+		return args[1], nil
+	})
+	return dsl
+}
+
+func TestAnalyze(t *testing.T) {
+	a, err := Analyze(newLL1Calculator(t).Grammar())
+	require.NoError(t, err)
+
+	assert.Equal(t, "expr", a.Start)
+	assert.Equal(t, map[string]bool{"rest": true}, a.Nullable)
+	assert.Equal(t, map[string][]string{
+		"expr": {"LPAREN", "NUMBER"},
+		"rest": {"PLUS"},
+		"term": {"LPAREN", "NUMBER"},
+	}, a.First)
+	assert.Equal(t, map[string][]string{
+		"expr": {"$", "RPAREN"},
+		"rest": {"$", "RPAREN"},
+		"term": {"$", "PLUS", "RPAREN"},
+	}, a.Follow)
+
+	first, nullable := a.FirstOf([]string{"rest", "term"})
+	assert.Equal(t, []string{"LPAREN", "NUMBER", "PLUS"}, first)
+	assert.False(t, nullable)
+	_, nullable = a.FirstOf([]string{"rest"})
+	assert.True(t, nullable)
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+	_, err := Analyze(dslbuilder.New("empty").Grammar())
+	assert.EqualError(t, err, "grammar has no rules")
+
+	dsl := dslbuilder.New("undefined")
+	dsl.Rule("s", []string{"MISSING"}, "")
+	_, err = Analyze(dsl.Grammar())
+	assert.EqualError(t, err, "rule s: undefined symbol MISSING")
+}
+
+func TestLL1(t *testing.T) {
+	a, err := Analyze(newLL1Calculator(t).Grammar())
+	require.NoError(t, err)
+	ll1 := a.LL1()
+	assert.Empty(t, ll1.Conflicts)
+	assert.Equal(t, dslbuilder.PredictionTable{
+		"expr": {"NUMBER": 0, "LPAREN": 0},
+		"rest": {"PLUS": 0, "RPAREN": 1, "$": 1},
+		"term": {"NUMBER": 0, "LPAREN": 1},
+	}, ll1.Table)
+}
+
+// newLeftRecursive builds stmt -> LET ID ASSIGN expr | expr with a
+// left-recursive expr, which is LALR(1) but not LL(1).
+func newLeftRecursive(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("left")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+	dsl.Rule("stmt", []string{"LET", "ID", "ASSIGN", "expr"}, "let")
+	dsl.Rule("stmt", []string{"expr"}, "")
+	dsl.Rule("expr", []string{"expr", "PLUS", "term"}, "add")
+	dsl.Rule("expr", []string{"term"}, "")
+	dsl.Rule("term", []string{"NUMBER"}, "")
+	dsl.Rule("term", []string{"ID"}, "")
+	return dsl
+}
+
+func TestLL1Conflicts(t *testing.T) {
+	a, err := Analyze(newLeftRecursive(t).Grammar())
+	require.NoError(t, err)
+	ll1 := a.LL1()
+
+	require.Len(t, ll1.Conflicts, 1)
+	c := ll1.Conflicts[0]
+	assert.Equal(t, "LL(1)", c.Kind)
+	assert.Equal(t, "expr", c.Rule)
+	assert.Equal(t, -1, c.State)
+	assert.Equal(t, []string{"ID", "NUMBER"}, c.Lookahead)
+	assert.Equal(t, []string{"ID"}, c.Example)
+	assert.Equal(t, "a", c.Input)
+	assert.Equal(t, `LL(1) conflict in expr on ID, NUMBER: expr -> . expr PLUS term | expr -> . term (example: "a")`, c.String())
+
+	// The table keeps the first alternative, as ordered choice does
+	assert.Equal(t, 0, ll1.Table["expr"]["NUMBER"])
+}
+
+func TestLALR(t *testing.T) {
+	a, err := Analyze(newLeftRecursive(t).Grammar())
+	require.NoError(t, err)
+	lalr := a.LALR()
+	assert.Empty(t, lalr.Conflicts)
+
+	// Run the tables by hand on "let x = 1 + y"
+	input := []string{"LET", "ID", "ASSIGN", "NUMBER", "PLUS", "ID", EndOfInput}
+	stack := []int{0}
+	for len(input) > 0 {
+		action, ok := lalr.Actions[stack[len(stack)-1]][input[0]]
+		require.True(t, ok, "no action for %s in state %d", input[0], stack[len(stack)-1])
+		if action.Kind == Accept {
+			break
+		}
+		if action.Kind == Shift {
+			stack = append(stack, action.State)
+			input = input[1:]
+			continue
+		}
+		rule := a.rules[action.Rule]
+		stack = stack[:len(stack)-len(rule.Alternatives[action.Alternative].Sequence)]
+		stack = append(stack, lalr.Gotos[stack[len(stack)-1]][action.Rule])
+	}
+	assert.Equal(t, []string{EndOfInput}, input, "accepted at end of input")
+	assert.Equal(t, "shift 3", Action{Kind: Shift, State: 3}.String())
+	assert.Equal(t, "reduce expr/1", Action{Kind: Reduce, Rule: "expr", Alternative: 1}.String())
+}
+
+func TestLALRConflicts(t *testing.T) {
+	dsl := dslbuilder.New("ambiguous")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("X", "x"))
+	require.NoError(t, dsl.Token("Y", "y"))
+	dsl.Rule("s", []string{"e"}, "")
+	dsl.Rule("s", []string{"a", "X"}, "")
+	dsl.Rule("s", []string{"b", "X"}, "")
+	dsl.Rule("e", []string{"e", "PLUS", "e"}, "")
+	dsl.Rule("e", []string{"NUMBER"}, "")
+	dsl.Rule("a", []string{"Y"}, "")
+	dsl.Rule("b", []string{"Y"}, "")
+
+	a, err := Analyze(dsl.Grammar())
+	require.NoError(t, err)
+	conflicts := a.LALR().Conflicts
+	require.Len(t, conflicts, 2)
+
+	var lines []string
+	for _, c := range conflicts {
+		lines = append(lines, c.String())
+	}
+	assert.ElementsMatch(t, []string{
+		`shift/reduce conflict in state 10 on PLUS: e -> e PLUS e . | e -> e . PLUS e (example: "1 + 1 +")`,
+		`reduce/reduce conflict in state 6 on X: a -> Y . | b -> Y . (example: "y x")`,
+	}, lines)
+}
+
+func TestPredictive(t *testing.T) {
+	dsl := newLL1Calculator(t)
+	peg, err := dsl.Parse("1 + (2 + 3) + 4")
+	require.NoError(t, err)
+
+	require.NoError(t, Predictive(dsl))
+	assert.Equal(t, dslbuilder.BackendPredictive, dsl.Backend())
+	result, err := dsl.Parse("1 + (2 + 3) + 4")
+	require.NoError(t, err)
+	assert.Equal(t, peg.GetOutput(), result.GetOutput())
+	assert.Equal(t, 10, result.GetOutput())
+
+	tree, err := dsl.ParseTree("(1)")
+	require.NoError(t, err)
+	assert.Equal(t, `(expr (term LPAREN:"(" (expr (term NUMBER:"1") (rest)) RPAREN:")") (rest))`, tree.Root.String())
+
+	_, err = dsl.Parse("1 + + 2")
+	assert.EqualError(t, err, "no alternative matched for rule term")
+	tree, err = dsl.ParseTree("1 + (2")
+	assert.EqualError(t, err, "unexpected end of input")
+	assert.Equal(t, []string{"RPAREN"}, tree.Expected)
+	_, err = dsl.Parse("1 2")
+	assert.EqualError(t, err, "no alternative matched for rule rest")
+}
+
+func TestPredictiveRejectsConflicts(t *testing.T) {
+	dsl := newLeftRecursive(t)
+	err := Predictive(dsl)
+	var conflicts *ConflictError
+	require.True(t, errors.As(err, &conflicts))
+	assert.Len(t, conflicts.Conflicts, 1)
+	assert.Contains(t, err.Error(), "grammar is not LL(1): LL(1) conflict in expr")
+	assert.Equal(t, dslbuilder.BackendPEG, dsl.Backend())
+	assert.Nil(t, dsl.PredictionTable())
+}
@@ -0,0 +1,332 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ActionKind is the kind of an LALR(1) parsing action.
+type ActionKind int
+
+const (
+	Shift  ActionKind = iota // Consume the token and go to State
+	Reduce                   // Reduce by Rule's Alternative
+	Accept                   // The input is complete
+)
+
+// Action is an entry of the LALR(1) action table.
+type Action struct {
+	Kind        ActionKind
+	State       int    // Target state of a shift
+	Rule        string // Rule of a reduce
+	Alternative int    // Alternative of a reduce
+}
+
+// String returns the action in the form "shift 3", "reduce expr/0" or
+// "accept".
+func (a Action) String() string {
+	switch a.Kind {
+	case Shift:
+		return fmt.Sprintf("shift %d", a.State)
+	case Reduce:
+		return fmt.Sprintf("reduce %s/%d", a.Rule, a.Alternative)
+	}
+	return "accept"
+}
+
+// LALR is the LALR(1) parsing table of a grammar. The grammar is LALR(1)
+// when Conflicts is empty. On conflicts the table prefers shifting, then
+// the earliest rule and alternative, as yacc does.
+type LALR struct {
+	States    int                 // Number of states; state 0 is the initial state
+	Actions   []map[string]Action // Action of each state per lookahead token
+	Gotos     []map[string]int    // Target state of each state per rule
+	Conflicts []Conflict
+}
+
+// production is an alternative of the augmented grammar. Production 0 is
+// the added start production "-> start".
+type production struct {
+	rule        string
+	alternative int
+	sequence    []string
+}
+
+// lrItem is a production with a position.
+type lrItem struct {
+	production int
+	dot        int
+}
+
+// lrState is a state of the LR(0) automaton with LALR(1) lookaheads.
+type lrState struct {
+	items     []lrItem // Kernel items first, then the closure
+	lookahead map[lrItem]map[string]bool
+	next      map[string]int // Transitions on symbols
+	symbols   []string       // Transition symbols in discovery order
+}
+
+// lalrBuilder builds the automaton of one grammar.
+type lalrBuilder struct {
+	a           *Analysis
+	productions []production
+	byRule      map[string][]int // Productions of each rule
+	states      []*lrState
+	kernels     map[string]int // Kernel key -> state
+}
+
+// LALR builds the LALR(1) table: the LR(0) automaton of the grammar, with
+// lookaheads computed by propagation until they no longer change.
+func (a *Analysis) LALR() *LALR {
+	b := &lalrBuilder{
+		a:           a,
+		productions: []production{{alternative: -1, sequence: []string{a.Start}}},
+		byRule:      make(map[string][]int),
+		kernels:     make(map[string]int),
+	}
+	for _, name := range a.order {
+		for i, alt := range a.rules[name].Alternatives {
+			b.byRule[name] = append(b.byRule[name], len(b.productions))
+			b.productions = append(b.productions, production{rule: name, alternative: i, sequence: alt.Sequence})
+		}
+	}
+
+	b.state([]lrItem{{production: 0}})
+	for i := 0; i < len(b.states); i++ {
+		b.expand(b.states[i])
+	}
+	b.states[0].lookahead[lrItem{production: 0}][EndOfInput] = true
+	for b.propagate() {
+	}
+	return b.table()
+}
+
+// state returns the state with the given kernel, creating it if needed.
+func (b *lalrBuilder) state(kernel []lrItem) int {
+	sort.Slice(kernel, func(i, j int) bool {
+		if kernel[i].production != kernel[j].production {
+			return kernel[i].production < kernel[j].production
+		}
+		return kernel[i].dot < kernel[j].dot
+	})
+	key := fmt.Sprint(kernel)
+	if index, exists := b.kernels[key]; exists {
+		return index
+	}
+
+	s := &lrState{lookahead: make(map[lrItem]map[string]bool), next: make(map[string]int)}
+	seen := make(map[lrItem]bool)
+	add := func(item lrItem) {
+		if !seen[item] {
+			seen[item] = true
+			s.items = append(s.items, item)
+			s.lookahead[item] = make(map[string]bool)
+		}
+	}
+	for _, item := range kernel {
+		add(item)
+	}
+	for i := 0; i < len(s.items); i++ {
+		if symbol, ok := b.after(s.items[i]); ok && b.a.isRule(symbol) {
+			for _, p := range b.byRule[symbol] {
+				add(lrItem{production: p})
+			}
+		}
+	}
+
+	b.kernels[key] = len(b.states)
+	b.states = append(b.states, s)
+	return len(b.states) - 1
+}
+
+// expand adds the transitions of a state.
+func (b *lalrBuilder) expand(s *lrState) {
+	kernels := make(map[string][]lrItem)
+	for _, item := range s.items {
+		symbol, ok := b.after(item)
+		if !ok {
+			continue
+		}
+		if _, exists := kernels[symbol]; !exists {
+			s.symbols = append(s.symbols, symbol)
+		}
+		kernels[symbol] = append(kernels[symbol], lrItem{item.production, item.dot + 1})
+	}
+	for _, symbol := range s.symbols {
+		s.next[symbol] = b.state(kernels[symbol])
+	}
+}
+
+// after returns the symbol after the dot of an item.
+func (b *lalrBuilder) after(item lrItem) (string, bool) {
+	sequence := b.productions[item.production].sequence
+	if item.dot < len(sequence) {
+		return sequence[item.dot], true
+	}
+	return "", false
+}
+
+// propagate runs one round of lookahead propagation: within each state
+// from items to the closure items they predict, and across transitions to
+// the advanced items. It reports whether any lookahead was added.
+func (b *lalrBuilder) propagate() bool {
+	changed := false
+	addAll := func(to, from map[string]bool) bool {
+		added := false
+		for token := range from {
+			if !to[token] {
+				to[token] = true
+				added = true
+			}
+		}
+		return added
+	}
+
+	for _, s := range b.states {
+		// Closure items can predict each other, so repeat until stable
+		for local := true; local; {
+			local = false
+			for _, item := range s.items {
+				symbol, ok := b.after(item)
+				if !ok || !b.a.isRule(symbol) {
+					continue
+				}
+				sequence := b.productions[item.production].sequence
+				first, nullable := b.a.firstOf(sequence[item.dot+1:])
+				for _, p := range b.byRule[symbol] {
+					predicted := s.lookahead[lrItem{production: p}]
+					if addAll(predicted, first) {
+						local = true
+					}
+					if nullable && addAll(predicted, s.lookahead[item]) {
+						local = true
+					}
+				}
+			}
+			changed = changed || local
+		}
+
+		for _, item := range s.items {
+			if symbol, ok := b.after(item); ok {
+				target := b.states[s.next[symbol]]
+				if addAll(target.lookahead[lrItem{item.production, item.dot + 1}], s.lookahead[item]) {
+					changed = true
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// table builds the action and goto tables and collects the conflicts.
+func (b *lalrBuilder) table() *LALR {
+	result := &LALR{States: len(b.states)}
+	paths := b.paths()
+
+	for index, s := range b.states {
+		actions := make(map[string]Action)
+		gotos := make(map[string]int)
+		shifts := make(map[string][]lrItem)
+		reduces := make(map[string][]lrItem)
+		for _, item := range s.items {
+			symbol, ok := b.after(item)
+			switch {
+			case ok && b.a.isRule(symbol):
+				gotos[symbol] = s.next[symbol]
+			case ok:
+				shifts[symbol] = append(shifts[symbol], item)
+			default:
+				for token := range s.lookahead[item] {
+					reduces[token] = append(reduces[token], item)
+				}
+			}
+		}
+
+		for token := range shifts {
+			actions[token] = Action{Kind: Shift, State: s.next[token]}
+		}
+		for token, items := range reduces {
+			sort.Slice(items, func(i, j int) bool { return items[i].production < items[j].production })
+			if _, shifted := actions[token]; shifted {
+				continue
+			}
+			if items[0].production == 0 {
+				actions[token] = Action{Kind: Accept}
+				continue
+			}
+			p := b.productions[items[0].production]
+			actions[token] = Action{Kind: Reduce, Rule: p.rule, Alternative: p.alternative}
+		}
+		result.Actions = append(result.Actions, actions)
+		result.Gotos = append(result.Gotos, gotos)
+		result.Conflicts = append(result.Conflicts, b.conflicts(index, shifts, reduces, paths[index])...)
+	}
+	return result
+}
+
+// conflicts reports the tokens of a state with more than one action,
+// grouping tokens with the same competing items.
+func (b *lalrBuilder) conflicts(index int, shifts, reduces map[string][]lrItem, path []string) []Conflict {
+	groups := make(map[string]*Conflict)
+	var keys []string
+	for _, token := range sorted(reduces) {
+		items := reduces[token]
+		if len(items) < 2 && len(shifts[token]) == 0 {
+			continue
+		}
+		kind := "reduce/reduce"
+		if len(shifts[token]) > 0 {
+			kind = "shift/reduce"
+			items = append(append([]lrItem(nil), items...), shifts[token]...)
+		}
+		key := kind + fmt.Sprint(items)
+		conflict, exists := groups[key]
+		if !exists {
+			p := b.productions[reduces[token][0].production]
+			conflict = &Conflict{Kind: kind, Rule: p.rule, State: index}
+			for _, item := range items {
+				p := b.productions[item.production]
+				conflict.Items = append(conflict.Items, Item{Rule: p.rule, Alternative: p.alternative, Sequence: p.sequence, Dot: item.dot})
+			}
+			groups[key] = conflict
+			keys = append(keys, key)
+		}
+		conflict.Lookahead = append(conflict.Lookahead, token)
+	}
+
+	var result []Conflict
+	for _, key := range keys {
+		conflict := groups[key]
+		example := b.a.yield(nil, path...)
+		for _, token := range conflict.Lookahead {
+			if token != EndOfInput {
+				example = append(example, token)
+				break
+			}
+		}
+		conflict.Example = example
+		conflict.Input = b.a.render(example)
+		result = append(result, *conflict)
+	}
+	return result
+}
+
+// paths returns the shortest symbol sequence reaching each state.
+func (b *lalrBuilder) paths() [][]string {
+	paths := make([][]string, len(b.states))
+	paths[0] = []string{}
+	queue := []int{0}
+	for len(queue) > 0 {
+		index := queue[0]
+		queue = queue[1:]
+		s := b.states[index]
+		for _, symbol := range s.symbols {
+			target := s.next[symbol]
+			if paths[target] == nil {
+				paths[target] = append(append([]string(nil), paths[index]...), symbol)
+				queue = append(queue, target)
+			}
+		}
+	}
+	return paths
+}
@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// LL1 is the LL(1) prediction table of a grammar. The grammar is LL(1)
+// when Conflicts is empty. On conflicts the table keeps the earliest
+// alternative, as ordered choice does.
+type LL1 struct {
+	Table     dslbuilder.PredictionTable
+	Conflicts []Conflict
+}
+
+// LL1 builds the prediction table: alternative i of rule A is predicted
+// on the tokens of FIRST(alternative), plus FOLLOW(A) when the alternative
+// can match the empty input. Left-recursive rules always conflict.
+func (a *Analysis) LL1() *LL1 {
+	result := &LL1{Table: make(dslbuilder.PredictionTable)}
+	prefix := a.prefixes()
+
+	for _, name := range a.order {
+		rule := a.rules[name]
+		row := make(map[string]int)
+		predicted := make(map[string][]int) // Token -> alternatives predicting it
+		for i, alt := range rule.Alternatives {
+			first, nullable := a.firstOf(alt.Sequence)
+			if nullable {
+				for token := range a.follow[name] {
+					first[token] = true
+				}
+			}
+			for token := range first {
+				predicted[token] = append(predicted[token], i)
+				if _, taken := row[token]; !taken {
+					row[token] = i
+				}
+			}
+		}
+		result.Table[name] = row
+
+		// One conflict per set of competing alternatives
+		groups := make(map[string]*Conflict)
+		var keys []string
+		for _, token := range sorted(predicted) {
+			alts := predicted[token]
+			if len(alts) < 2 {
+				continue
+			}
+			key := fmt.Sprint(alts)
+			conflict, exists := groups[key]
+			if !exists {
+				conflict = &Conflict{Kind: "LL(1)", Rule: name, State: -1}
+				for _, i := range alts {
+					conflict.Items = append(conflict.Items, Item{Rule: name, Alternative: i, Sequence: rule.Alternatives[i].Sequence})
+				}
+				groups[key] = conflict
+				keys = append(keys, key)
+			}
+			conflict.Lookahead = append(conflict.Lookahead, token)
+		}
+		for _, key := range keys {
+			conflict := groups[key]
+			example := append([]string(nil), prefix[name]...)
+			for _, token := range conflict.Lookahead {
+				if token != EndOfInput {
+					example = append(example, token)
+					break
+				}
+			}
+			conflict.Example = example
+			conflict.Input = a.render(example)
+			result.Conflicts = append(result.Conflicts, *conflict)
+		}
+	}
+	return result
+}
+
+// Predictive enables table-driven parsing for an LL(1) grammar: it
+// installs the prediction table and selects dslbuilder.BackendPredictive,
+// so Parse never backtracks. For other grammars it returns a
+// *ConflictError and leaves the DSL unchanged.
+//
+// Call it again after changing the grammar.
+func Predictive(dsl *dslbuilder.DSL) error {
+	a, err := Analyze(dsl.Grammar())
+	if err != nil {
+		return err
+	}
+	ll1 := a.LL1()
+	if len(ll1.Conflicts) > 0 {
+		return &ConflictError{Conflicts: ll1.Conflicts}
+	}
+	dsl.SetPredictionTable(ll1.Table)
+	dsl.SetBackend(dslbuilder.BackendPredictive)
+	return nil
+}
@@ -0,0 +1,599 @@
+// Package antlr converts between ANTLR4 grammars (.g4) and go-dsl grammars.
+//
+// Import reads a combined or lexer grammar into a DSLConfig. It supports
+// lexer rules, fragments, parser rules with alternatives, the ? * + and
+// non-greedy lexer operators, groups, ranges and character sets, the
+// -> skip and -> channel(HIDDEN) lexer commands, element labels (dropped)
+// and alternative labels, which become action names:
+//
+//	expr : expr '+' term  # add
+//	     | term           # pass
+//	     ;
+//
+// Literals used in parser rules become tokens, reusing a lexer rule with
+// the same text when there is one. Optional and repeated elements and
+// groups become helper rules named after the enclosing rule, such as
+// args_star. Actions, semantic predicates, options, modes, rule arguments
+// and other constructs that have no go-dsl equivalent are reported as
+// errors with their line and column.
+//
+// Export writes a *DSL as a combined grammar, with actions as labels
+// where ANTLR allows them. Token patterns are translated from Go regular
+// expressions; keywords become case-insensitive character sets such as
+// [sS][eE][tT].
+//
+// The two tools resolve some ambiguities differently: literal words are
+// go-dsl keywords, which match case-insensitively, and tokens of equal
+// priority that match the same text are not ordered in go-dsl. ANTLR
+// orders binary left-recursive alternatives by precedence; go-dsl grows
+// them in the order written, so precedence should be expressed with one
+// rule per level.
+//
+// Example:
+//
+//	config, err := antlr.ImportFile("Query.g4")
+//	if err != nil {
+//	    return err
+//	}
+//	dsl, err := dslbuilder.LoadFromConfig(config)
+package antlr
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// Error is an error in a .g4 grammar, at a 1-based line and column.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// ImportFile reads a .g4 file and converts it with Import.
+func ImportFile(filename string) (dslbuilder.DSLConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return dslbuilder.DSLConfig{}, err
+	}
+	return Import(data)
+}
+
+// Import converts an ANTLR4 combined or lexer grammar to a DSLConfig.
+// The first parser rule is the start rule.
+func Import(data []byte) (dslbuilder.DSLConfig, error) {
+	s := &scanner{src: string(data), line: 1, col: 1}
+	tokens, err := s.tokens()
+	if err != nil {
+		return dslbuilder.DSLConfig{}, err
+	}
+	g, err := (&parser{tokens: tokens}).file()
+	if err != nil {
+		return dslbuilder.DSLConfig{}, err
+	}
+
+	c := &converter{
+		config: dslbuilder.DSLConfig{Name: g.name, Tokens: make(map[string]string)},
+		lexer:  make(map[string]*lexerRule),
+		parser: make(map[string]*parserRule),
+		used:   make(map[string]bool),
+	}
+	if err := c.convert(g); err != nil {
+		return dslbuilder.DSLConfig{}, err
+	}
+	return c.config, nil
+}
+
+type converter struct {
+	config   dslbuilder.DSLConfig
+	lexer    map[string]*lexerRule
+	parser   map[string]*parserRule
+	used     map[string]bool   // Token and rule names
+	literals map[string]string // Token names by literal text
+	implicit int               // Number of T__n tokens
+	helpers  []dslbuilder.RuleConfig
+}
+
+func (c *converter) errorf(line, col int, format string, args ...interface{}) error {
+	return &Error{Line: line, Column: col, Message: fmt.Sprintf(format, args...)}
+}
+
+func (c *converter) convert(g *grammarFile) error {
+	for _, r := range g.lexerRules {
+		if c.used[r.name] {
+			return c.errorf(r.line, r.col, "rule %s redefined", r.name)
+		}
+		c.lexer[r.name] = r
+		c.used[r.name] = true
+	}
+	for _, r := range g.parserRules {
+		if c.used[r.name] {
+			return c.errorf(r.line, r.col, "rule %s redefined", r.name)
+		}
+		c.parser[r.name] = r
+		c.used[r.name] = true
+	}
+
+	c.literals = make(map[string]string)
+	for _, r := range g.lexerRules {
+		if r.fragment {
+			continue
+		}
+		if err := c.token(r); err != nil {
+			return err
+		}
+		if len(r.alts) == 1 && len(r.alts[0].elements) == 1 {
+			e := r.alts[0].elements[0]
+			if e.kind == eLiteral && !e.not && e.suffix == "" && c.literals[e.text] == "" {
+				c.literals[e.text] = r.name
+			}
+		}
+	}
+
+	for _, r := range g.parserRules {
+		c.helpers = nil
+		for _, alt := range r.alts {
+			sequence, err := c.sequence(r.name, alt.elements, true)
+			if err != nil {
+				return err
+			}
+			c.config.Rules = append(c.config.Rules, dslbuilder.RuleConfig{Name: r.name, Pattern: sequence, Action: alt.label})
+		}
+		c.config.Rules = append(c.config.Rules, c.helpers...)
+	}
+	return nil
+}
+
+// token converts a lexer rule to a token pattern.
+func (c *converter) token(r *lexerRule) error {
+	for _, cmd := range r.commands {
+		switch {
+		case cmd.name == "skip" && cmd.arg == "",
+			cmd.name == "channel" && cmd.arg == "HIDDEN":
+			c.config.SkipTokens = append(c.config.SkipTokens, r.name)
+		default:
+			name := cmd.name
+			if cmd.arg != "" {
+				name += "(" + cmd.arg + ")"
+			}
+			return c.errorf(cmd.line, cmd.col, "lexer command %s is not supported", name)
+		}
+	}
+
+	if keyword, ok := c.keyword(r.alts); ok {
+		c.config.Tokens[r.name] = keyword
+		return nil
+	}
+	pattern, err := c.regex(r.alts, map[string]bool{r.name: true})
+	if err != nil {
+		return err
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return c.errorf(r.line, r.col, "token %s: %v", r.name, err)
+	}
+	if re.MatchString("") {
+		return c.errorf(r.line, r.col, "token %s matches the empty string", r.name)
+	}
+	c.config.Tokens[r.name] = pattern
+	return nil
+}
+
+// keyword returns the word matched by a lexer rule made of literals and
+// case-insensitive letter sets such as [sS], directly or through fragments
+// as in S E T, which go-dsl turns into a keyword token.
+func (c *converter) keyword(alts []*alternative) (string, bool) {
+	if len(alts) != 1 || len(alts[0].elements) == 0 {
+		return "", false
+	}
+	var b strings.Builder
+	for _, e := range alts[0].elements {
+		if e.not || e.suffix != "" {
+			return "", false
+		}
+		switch e.kind {
+		case eLiteral:
+			b.WriteString(e.text)
+		case eSet:
+			r, ok := caseSet(e.text)
+			if !ok {
+				return "", false
+			}
+			b.WriteRune(r)
+		case eRef:
+			r := c.lexer[e.text]
+			if r == nil || !r.fragment || len(r.alts) != 1 || len(r.alts[0].elements) != 1 || r.alts[0].elements[0].kind == eRef {
+				return "", false
+			}
+			word, ok := c.keyword(r.alts)
+			if !ok {
+				return "", false
+			}
+			b.WriteString(word)
+		default:
+			return "", false
+		}
+	}
+	word := b.String()
+	for _, r := range word {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return "", false
+		}
+	}
+	if strings.IndexFunc(word, unicode.IsLetter) < 0 {
+		return "", false
+	}
+	return word, true
+}
+
+// caseSet reports whether a set holds the case variants of one ASCII
+// letter, such as [sS], and returns the lower-case letter.
+func caseSet(raw string) (rune, bool) {
+	var runes []rune
+	for i := 0; i < len(raw); {
+		r, n, err := escaped(raw, i)
+		if err != nil || raw[i] == '-' {
+			return 0, false
+		}
+		runes = append(runes, r)
+		i += n
+	}
+	if len(runes) < 2 {
+		return 0, false
+	}
+	lower := unicode.ToLower(runes[0])
+	if lower > unicode.MaxASCII || !containsRune(runes, lower) || !containsRune(runes, unicode.ToUpper(lower)) {
+		return 0, false
+	}
+	for _, r := range runes {
+		if !unicode.In(r, unicode.Letter) || !strings.EqualFold(string(r), string(lower)) {
+			return 0, false
+		}
+	}
+	return lower, true
+}
+
+func containsRune(runes []rune, r rune) bool {
+	for _, x := range runes {
+		if x == r {
+			return true
+		}
+	}
+	return false
+}
+
+// regex converts lexer alternatives to a Go regular expression, inlining
+// fragments and other lexer rules. stack holds the rules being inlined.
+func (c *converter) regex(alts []*alternative, stack map[string]bool) (string, error) {
+	parts := make([]string, len(alts))
+	for i, alt := range alts {
+		var b strings.Builder
+		for _, e := range alt.elements {
+			piece, err := c.regexElement(e, stack)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(piece)
+		}
+		parts[i] = b.String()
+	}
+	return strings.Join(parts, "|"), nil
+}
+
+func (c *converter) regexElement(e *element, stack map[string]bool) (string, error) {
+	var piece string
+	atomic := true
+	switch e.kind {
+	case eLiteral:
+		if e.not {
+			if utf8.RuneCountInString(e.text) != 1 {
+				return "", c.errorf(e.line, e.col, "~ applies to single characters, not %q", e.text)
+			}
+			piece = "[^" + classRune(e.text) + "]"
+			break
+		}
+		piece = regexp.QuoteMeta(e.text)
+		atomic = utf8.RuneCountInString(e.text) == 1
+	case eRange:
+		if utf8.RuneCountInString(e.text) != 1 || utf8.RuneCountInString(e.to) != 1 {
+			return "", c.errorf(e.line, e.col, "ranges apply to single characters")
+		}
+		piece = classRune(e.text) + "-" + classRune(e.to)
+		if e.not {
+			piece = "[^" + piece + "]"
+		} else {
+			piece = "[" + piece + "]"
+		}
+	case eSet:
+		class, err := setClass(e.text)
+		if err != nil {
+			return "", c.errorf(e.line, e.col, "%v", err)
+		}
+		if e.not {
+			piece = "[^" + class + "]"
+		} else {
+			piece = "[" + class + "]"
+		}
+	case eAny:
+		if e.not {
+			return "", c.errorf(e.line, e.col, "~ cannot apply to .")
+		}
+		piece = "(?s:.)"
+	case eRef:
+		if e.not {
+			return "", c.errorf(e.line, e.col, "~ applies to sets and characters, not to %s", e.text)
+		}
+		r := c.lexer[e.text]
+		switch {
+		case r == nil && c.parser[e.text] != nil:
+			return "", c.errorf(e.line, e.col, "lexer rules cannot refer to parser rule %s", e.text)
+		case r == nil:
+			return "", c.errorf(e.line, e.col, "undefined lexer rule %s", e.text)
+		case stack[e.text]:
+			return "", c.errorf(e.line, e.col, "recursive lexer rule %s is not supported", e.text)
+		}
+		stack[e.text] = true
+		inner, err := c.regex(r.alts, stack)
+		delete(stack, e.text)
+		if err != nil {
+			return "", err
+		}
+		piece = "(?:" + inner + ")"
+	case eGroup:
+		if e.not {
+			class, err := c.notGroup(e)
+			if err != nil {
+				return "", err
+			}
+			piece = "[^" + class + "]"
+			break
+		}
+		inner, err := c.regex(e.alts, stack)
+		if err != nil {
+			return "", err
+		}
+		piece = "(?:" + inner + ")"
+	}
+
+	if e.suffix == "" {
+		return piece, nil
+	}
+	if !atomic {
+		piece = "(?:" + piece + ")"
+	}
+	return piece + e.suffix, nil
+}
+
+// notGroup converts the alternatives of ~( ... ) to a class body; each
+// alternative must be a single character, range or set.
+func (c *converter) notGroup(e *element) (string, error) {
+	var b strings.Builder
+	for _, alt := range e.alts {
+		if len(alt.elements) != 1 {
+			return "", c.errorf(alt.line, alt.col, "~( ... ) applies to sets and characters")
+		}
+		x := alt.elements[0]
+		switch {
+		case x.not || x.suffix != "":
+			return "", c.errorf(x.line, x.col, "~( ... ) applies to sets and characters")
+		case x.kind == eLiteral && utf8.RuneCountInString(x.text) == 1:
+			b.WriteString(classRune(x.text))
+		case x.kind == eRange && utf8.RuneCountInString(x.text) == 1 && utf8.RuneCountInString(x.to) == 1:
+			b.WriteString(classRune(x.text) + "-" + classRune(x.to))
+		case x.kind == eSet:
+			class, err := setClass(x.text)
+			if err != nil {
+				return "", c.errorf(x.line, x.col, "%v", err)
+			}
+			b.WriteString(class)
+		default:
+			return "", c.errorf(x.line, x.col, "~( ... ) applies to sets and characters")
+		}
+	}
+	return b.String(), nil
+}
+
+// setClass converts the body of an ANTLR set such as [a-z_é] to the
+// body of a Go character class.
+func setClass(raw string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(raw); {
+		if strings.HasPrefix(raw[i:], `\p{`) || strings.HasPrefix(raw[i:], `\P{`) {
+			end := strings.IndexByte(raw[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("invalid escape in [%s]", raw)
+			}
+			b.WriteString(raw[i : i+end+1])
+			i += end + 1
+			continue
+		}
+		r, n, err := escaped(raw, i)
+		if err != nil {
+			return "", err
+		}
+		i += n
+		b.WriteString(classRune(string(r)))
+		if i+1 < len(raw) && raw[i] == '-' {
+			to, n, err := escaped(raw, i+1)
+			if err != nil {
+				return "", err
+			}
+			i += 1 + n
+			b.WriteString("-" + classRune(string(to)))
+		}
+	}
+	if b.Len() == 0 {
+		return "", fmt.Errorf("empty character set")
+	}
+	return b.String(), nil
+}
+
+// classRune writes a character for use inside a Go character class.
+func classRune(s string) string {
+	r, _ := utf8.DecodeRuneInString(s)
+	switch {
+	case strings.ContainsRune(`\[]^-`, r):
+		return `\` + string(r)
+	case !unicode.IsGraphic(r):
+		return fmt.Sprintf(`\x{%x}`, r)
+	}
+	return string(r)
+}
+
+// sequence converts parser rule elements to a symbol sequence, adding
+// helper rules for groups and repetitions. EOF is accepted at the end of
+// top-level alternatives, since go-dsl always parses the whole input.
+func (c *converter) sequence(rule string, elements []*element, top bool) ([]string, error) {
+	sequence := []string{}
+	for i, e := range elements {
+		if e.kind == eRef && e.text == "EOF" && c.lexer["EOF"] == nil {
+			if !top || i != len(elements)-1 || e.suffix != "" {
+				return nil, c.errorf(e.line, e.col, "EOF is only supported at the end of an alternative")
+			}
+			continue
+		}
+		if strings.HasSuffix(e.suffix, "?") && len(e.suffix) == 2 {
+			return nil, c.errorf(e.line, e.col, "non-greedy operators are not supported in parser rules")
+		}
+
+		alts, err := c.alternatives(rule, e)
+		if err != nil {
+			return nil, err
+		}
+		switch e.suffix {
+		case "":
+			if len(alts) == 1 {
+				sequence = append(sequence, alts[0]...)
+				continue
+			}
+			sequence = append(sequence, c.helper(rule, "group", alts))
+		case "?":
+			sequence = append(sequence, c.helper(rule, "opt", append(alts, []string{})))
+		case "*":
+			// Like RuleWithRepetition: name → ε | name element
+			name := c.name(rule, "star")
+			rules := [][]string{{}}
+			for _, alt := range alts {
+				rules = append(rules, append([]string{name}, alt...))
+			}
+			sequence = append(sequence, c.helperNamed(name, rules))
+		case "+":
+			// Like RuleWithPlusRepetition: name → element | name element
+			name := c.name(rule, "plus")
+			rules := append([][]string(nil), alts...)
+			for _, alt := range alts {
+				rules = append(rules, append([]string{name}, alt...))
+			}
+			sequence = append(sequence, c.helperNamed(name, rules))
+		}
+	}
+	return sequence, nil
+}
+
+// alternatives returns the symbol sequences an element stands for: one for
+// a reference or literal, one per alternative for a group.
+func (c *converter) alternatives(rule string, e *element) ([][]string, error) {
+	if e.not {
+		return nil, c.errorf(e.line, e.col, "~ is not supported in parser rules")
+	}
+	switch e.kind {
+	case eRef:
+		if r := c.lexer[e.text]; r != nil && r.fragment {
+			return nil, c.errorf(e.line, e.col, "fragment %s cannot be used in parser rules", e.text)
+		}
+		if c.lexer[e.text] == nil && c.parser[e.text] == nil {
+			return nil, c.errorf(e.line, e.col, "undefined rule or token %s", e.text)
+		}
+		return [][]string{{e.text}}, nil
+	case eLiteral:
+		return [][]string{{c.literal(e.text)}}, nil
+	case eGroup:
+		var alts [][]string
+		for _, alt := range e.alts {
+			sequence, err := c.sequence(rule, alt.elements, false)
+			if err != nil {
+				return nil, err
+			}
+			alts = append(alts, sequence)
+		}
+		return alts, nil
+	case eSet:
+		return nil, c.errorf(e.line, e.col, "character sets are not supported in parser rules")
+	case eRange:
+		return nil, c.errorf(e.line, e.col, "ranges are not supported in parser rules")
+	default:
+		return nil, c.errorf(e.line, e.col, "wildcards are not supported in parser rules")
+	}
+}
+
+// helper adds a helper rule named after rule and kind and returns its name.
+func (c *converter) helper(rule, kind string, alts [][]string) string {
+	return c.helperNamed(c.name(rule, kind), alts)
+}
+
+func (c *converter) helperNamed(name string, alts [][]string) string {
+	for _, alt := range alts {
+		c.helpers = append(c.helpers, dslbuilder.RuleConfig{Name: name, Pattern: alt})
+	}
+	return name
+}
+
+// name returns an unused name such as args_star or args_star2.
+func (c *converter) name(rule, kind string) string {
+	base := rule + "_" + kind
+	name := base
+	for n := 2; c.used[name]; n++ {
+		name = fmt.Sprintf("%s%d", base, n)
+	}
+	c.used[name] = true
+	return name
+}
+
+// punctuation names the tokens of common literals used in parser rules.
+var punctuation = map[string]string{
+	"+": "PLUS", "-": "MINUS", "*": "STAR", "/": "SLASH", "%": "PERCENT",
+	"(": "LPAREN", ")": "RPAREN", "[": "LBRACKET", "]": "RBRACKET",
+	"{": "LBRACE", "}": "RBRACE", ",": "COMMA", ";": "SEMI", ":": "COLON",
+	".": "DOT", "=": "ASSIGN", "==": "EQ", "!=": "NEQ", "<": "LT", ">": "GT",
+	"<=": "LE", ">=": "GE", "!": "NOT", "&&": "AND", "||": "OR", "?": "QUESTION",
+	"^": "CARET", "&": "AMP", "|": "PIPE", "->": "ARROW", "=>": "FAT_ARROW",
+	"@": "AT", "#": "HASH", "$": "DOLLAR", "~": "TILDE",
+}
+
+// literal returns the token for a literal used in a parser rule: a lexer
+// rule that matches exactly the literal, or a new token.
+func (c *converter) literal(text string) string {
+	if name := c.literals[text]; name != "" {
+		return name
+	}
+
+	pattern := regexp.QuoteMeta(text)
+	var name string
+	if word, ok := c.keyword([]*alternative{{elements: []*element{{kind: eLiteral, text: text}}}}); ok {
+		pattern = word
+		name = strings.ToUpper(strings.ReplaceAll(word, "-", "_"))
+	} else {
+		name = punctuation[text]
+	}
+	if name == "" || c.used[name] {
+		name = ""
+		for name == "" || c.used[name] {
+			name = fmt.Sprintf("T__%d", c.implicit)
+			c.implicit++
+		}
+	}
+	c.used[name] = true
+	c.literals[text] = name
+	c.config.Tokens[name] = pattern
+	return name
+}
@@ -0,0 +1,296 @@
+package antlr
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const queryGrammar = `grammar Query;
+
+/* A small query language */
+query  : SELECT fields FROM ID (WHERE cond)? ';'? EOF  # select ;
+fields : '*'                # all
+       | f=ID (',' fs+=ID)* # list
+       ;
+cond   : ID '=' value (AND ID '=' value)* ;
+value  : NUMBER | STRING ;
+
+SELECT : S E L E C T ;
+FROM   : 'from' ;
+WHERE  : [wW][hH][eE][rR][eE] ;
+AND    : 'and' ;
+ID     : [a-zA-Z_] [a-zA-Z_0-9]* ;
+NUMBER : DIGIT+ ('.' DIGIT+)? ;
+STRING : '\'' (~['\\] | '\\' .)* '\'' ;
+COMMENT : '--' ~[\r\n]* -> skip ;
+WS     : [ \t\r\n]+ -> channel(HIDDEN) ;
+
+fragment DIGIT : '0'..'9' ;
+fragment S : [sS] ;
+fragment E : [eE] ;
+fragment L : [lL] ;
+fragment C : [cC] ;
+fragment T : [tT] ;
+`
+
+func TestImport(t *testing.T) {
+	config, err := Import([]byte(queryGrammar))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Query", config.Name)
+	assert.Equal(t, map[string]string{
+		"SELECT":  "select",
+		"FROM":    "from",
+		"WHERE":   "where",
+		"AND":     "and",
+		"ID":      "[a-zA-Z_][a-zA-Z_0-9]*",
+		"NUMBER":  `(?:[0-9])+(?:\.(?:[0-9])+)?`,
+		"STRING":  `'(?:[^'\\]|\\(?s:.))*'`,
+		"COMMENT": `--[^\x{d}\x{a}]*`,
+		"WS":      `[ \x{9}\x{d}\x{a}]+`,
+		"STAR":    `\*`,
+		"COMMA":   ",",
+		"ASSIGN":  "=",
+		"SEMI":    ";",
+	}, config.Tokens)
+	assert.Equal(t, []string{"COMMENT", "WS"}, config.SkipTokens)
+	assert.Equal(t, []dslbuilder.RuleConfig{
+		{Name: "query", Pattern: []string{"SELECT", "fields", "FROM", "ID", "query_opt", "query_opt2"}, Action: "select"},
+		{Name: "query_opt", Pattern: []string{"WHERE", "cond"}},
+		{Name: "query_opt", Pattern: []string{}},
+		{Name: "query_opt2", Pattern: []string{"SEMI"}},
+		{Name: "query_opt2", Pattern: []string{}},
+		{Name: "fields", Pattern: []string{"STAR"}, Action: "all"},
+		{Name: "fields", Pattern: []string{"ID", "fields_star"}, Action: "list"},
+		{Name: "fields_star", Pattern: []string{}},
+		{Name: "fields_star", Pattern: []string{"fields_star", "COMMA", "ID"}},
+		{Name: "cond", Pattern: []string{"ID", "ASSIGN", "value", "cond_star"}},
+		{Name: "cond_star", Pattern: []string{}},
+		{Name: "cond_star", Pattern: []string{"cond_star", "AND", "ID", "ASSIGN", "value"}},
+		{Name: "value", Pattern: []string{"NUMBER"}},
+		{Name: "value", Pattern: []string{"STRING"}},
+	}, config.Rules)
+}
+
+// tagged binds every action to one that records its name, so outputs show
+// which labeled alternatives matched.
+func tagged(dsl *dslbuilder.DSL) *dslbuilder.DSL {
+	for _, name := range dsl.UnboundActions() {
+		name := name
+		dsl.Action(name, func(args []interface{}) (interface{}, error) {
+			return map[string]interface{}{name: args}, nil
+		})
+	}
+	return dsl
+}
+
+func parseJSON(t *testing.T, dsl *dslbuilder.DSL, input string) string {
+	result, err := dsl.Parse(input)
+	require.NoError(t, err, input)
+	data, err := json.Marshal(result.GetOutput())
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestImportedGrammarParses(t *testing.T) {
+	config, err := Import([]byte(queryGrammar))
+	require.NoError(t, err)
+	dsl, err := dslbuilder.LoadFromConfig(config)
+	require.NoError(t, err)
+	tagged(dsl)
+
+	assert.Equal(t, `{"select":["select",{"all":["*"]},"from","users",null,null]}`,
+		parseJSON(t, dsl, "select * from users"))
+	assert.Equal(t, `{"select":["Select",{"list":["a",[null,",","b"]]},"FROM","t",["where",["x","=",["1.5"],[null,"and","y","=",["'it\\'s'"]]]],[";"]]}`,
+		parseJSON(t, dsl, "Select a, b FROM t where x = 1.5 and y = 'it\\'s'; -- done"))
+
+	_, err = dsl.Parse("select from t")
+	assert.Error(t, err)
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		grammar string
+		err     string
+	}{
+		{"parser grammar P;\ns : A ;", "1:1: parser grammars are not supported; combine the lexer and parser in one grammar"},
+		{"grammar G;\noptions { caseInsensitive = true; }\ns : 'a' ;", "2:1: options are not supported"},
+		{"grammar G;\nimport Common;", "2:1: grammar imports are not supported"},
+		{"grammar G;\ntokens { A }", "2:1: tokens sections are not supported"},
+		{"grammar G;\n@header { package p; }", "2:1: named actions are not supported"},
+		{"grammar G;\ns : 'a' {System.out.println(1);} ;", "2:9: actions are not supported"},
+		{"grammar G;\ns : {isType()}? ID ;\nID : [a-z]+ ;", "2:5: semantic predicates are not supported"},
+		{"grammar G;\ns[int x] : 'a' ;", "2:2: rule arguments are not supported"},
+		{"grammar G;\ns returns [int v] : 'a' ;", "2:3: rule return values are not supported"},
+		{"grammar G;\ne : <assoc=right> e '^' e | 'a' ;", "2:5: element options such as <assoc=right> are not supported"},
+		{"grammar G;\ns : 'a' ;\ncatch [Exception e] { }", "3:1: exception handlers are not supported"},
+		{"lexer grammar L;\nA : 'a' ;\nmode Inside;\nB : 'b' ;", "3:1: lexer modes are not supported"},
+		{"lexer grammar L;\nA : '\"' -> pushMode(STR) ;", "2:12: lexer command pushMode(STR) is not supported"},
+		{"grammar G;\ns : ID ;", "2:5: undefined rule or token ID"},
+		{"grammar G;\ns : A ;\nA : 'a' B ;\nfragment B : A ;", "4:14: recursive lexer rule A is not supported"},
+		{"grammar G;\ns : A ;\nA : [a-z]* ;", "3:1: token A matches the empty string"},
+		{"grammar G;\ns : A*? ;\nA : 'a' ;", "2:5: non-greedy operators are not supported in parser rules"},
+		{"grammar G;\ns : . ;", "2:5: wildcards are not supported in parser rules"},
+		{"grammar G;\ns : A EOF A ;\nA : 'a' ;", "2:7: EOF is only supported at the end of an alternative"},
+		{"grammar G;\ns : D ;\nfragment D : [0-9] ;", "2:5: fragment D cannot be used in parser rules"},
+		{"grammar G;\ns : 'a' ;\ns : 'b' ;", "3:1: rule s redefined"},
+		{"grammar G;\ns : 'a ;", "2:5: unterminated string literal"},
+		{"grammar G;\ns : 'a' ", `2:9: expected ";", found end of file`},
+	}
+	for _, tt := range tests {
+		_, err := Import([]byte(tt.grammar))
+		assert.EqualError(t, err, tt.err, tt.grammar)
+	}
+}
+
+func TestImportLiterals(t *testing.T) {
+	config, err := Import([]byte(`grammar G;
+s : 'let' ID '=' ID '==' ID '<>' '+' ;
+PLUS : '+' | '-' ;
+ID : [a-z]+ ;
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"LET", "ID", "ASSIGN", "ID", "EQ", "ID", "T__0", "T__1"}, config.Rules[0].Pattern)
+	assert.Equal(t, "let", config.Tokens["LET"])
+	assert.Equal(t, "<>", config.Tokens["T__0"])
+	assert.Equal(t, `\+`, config.Tokens["T__1"])
+	assert.Equal(t, `\+|-`, config.Tokens["PLUS"])
+}
+
+func newCalculator(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("Calc DSL")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.Token("COMMENT", "#[^\\n]*"))
+	require.NoError(t, dsl.SkipToken("COMMENT"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+(\\.[0-9]+)?"))
+	require.NoError(t, dsl.Token("id", "[a-z_]\\w*"))
+	require.NoError(t, dsl.Token("OP", "[-+]"))
+	require.NoError(t, dsl.Token("TIMES", "\\*"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+	require.NoError(t, dsl.Token("LPAREN", "\\("))
+	require.NoError(t, dsl.Token("RPAREN", "\\)"))
+
+	dsl.Rule("stmt", []string{"LET", "id", "ASSIGN", "expr"}, "let")
+	dsl.Rule("stmt", []string{"expr"}, "eval")
+	dsl.Rule("expr", []string{"expr", "OP", "Term"}, "binary")
+	dsl.Rule("expr", []string{"Term"}, "pass")
+	dsl.Rule("Term", []string{"Term", "TIMES", "factor"}, "binary")
+	dsl.Rule("Term", []string{"factor"}, "pass")
+	dsl.Rule("factor", []string{"NUMBER"}, "number")
+	dsl.Rule("factor", []string{"id"}, "")
+	dsl.Rule("factor", []string{"LPAREN", "expr", "RPAREN"}, "paren")
+	return dsl
+}
+
+func TestExport(t *testing.T) {
+	g4, err := Export(newCalculator(t))
+	require.NoError(t, err)
+	assert.Equal(t, `// Exported from the Calc DSL DSL by go-dsl.
+grammar CalcDSL;
+
+stmt
+    : LET ID ASSIGN expr  # let
+    | expr                # eval
+    ;
+
+expr
+    : expr OP term  # binary
+    | term          # pass
+    ;
+
+term
+    : term TIMES factor  // binary
+    | factor             // pass
+    ;
+
+factor
+    : NUMBER              // number
+    | ID
+    | LPAREN expr RPAREN  // paren
+    ;
+
+LET : [lL] [eE] [tT] ;
+COMMENT : '#' ~[\n]* -> skip ;
+NUMBER : [0-9]+ ('.' [0-9]+)? ;
+ID : [_a-z] [0-9A-Z_a-z]* ;
+OP : [+\-] ;
+TIMES : '*' ;
+ASSIGN : '=' ;
+LPAREN : '(' ;
+RPAREN : ')' ;
+WS : [ \t\r\n]+ -> skip ;
+`, string(g4))
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	original := newCalculator(t)
+	g4, err := Export(original)
+	require.NoError(t, err)
+	config, err := Import(g4)
+	require.NoError(t, err)
+	imported, err := dslbuilder.LoadFromConfig(config)
+	require.NoError(t, err)
+
+	assert.Equal(t, "let", config.Tokens["LET"])
+	assert.Equal(t, []string{"COMMENT", "WS"}, config.SkipTokens)
+
+	// Actions written as comments are lost, so compare without them
+	for _, dsl := range []*dslbuilder.DSL{original, imported} {
+		for _, name := range dsl.RequiredActions() {
+			dsl.Action(name, func(args []interface{}) (interface{}, error) { return args, nil })
+		}
+	}
+	for _, input := range []string{"1 + 2 * 3", "LET x = (y - 1) * 2 # comment", "a * b * c"} {
+		assert.Equal(t, parseJSON(t, original, input), parseJSON(t, imported, input), input)
+	}
+}
+
+func TestExportExternalTokens(t *testing.T) {
+	dsl := dslbuilder.New("blobs")
+	require.NoError(t, dsl.Token("WORD", "[a-z]+"))
+	dsl.ExternalToken("BLOB")
+	dsl.ExternalToken("raw")
+	dsl.Rule("s", []string{"WORD", "BLOB", "raw"}, "")
+	data, err := Export(dsl)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "grammar Blobs;\n\ntokens { BLOB, RAW }\n")
+	assert.Contains(t, string(data), "s\n    : WORD BLOB RAW\n")
+	assert.NotContains(t, string(data), "BLOB :")
+}
+
+func TestExportErrors(t *testing.T) {
+	dsl := dslbuilder.New("bad")
+	require.NoError(t, dsl.Token("WORD", `\bword\b`))
+	dsl.Rule("s", []string{"WORD"}, "")
+	_, err := Export(dsl)
+	assert.EqualError(t, err, "token WORD: word boundaries are not supported by ANTLR lexers")
+
+	dsl = dslbuilder.New("bad")
+	dsl.Rule("s", []string{"MISSING"}, "")
+	_, err = Export(dsl)
+	assert.EqualError(t, err, "rule s: undefined symbol MISSING")
+}
+
+func TestLexerPattern(t *testing.T) {
+	tests := map[string]string{
+		`[0-9]+`:                `[0-9]+`,
+		`"[^"]*"`:               `'"' ~["]* '"'`,
+		`(?i)select`:            `[Ssſ] [Ee] [Ll] [Ee] [Cc] [Tt]`,
+		`a{2,3}`:                `'a' 'a' 'a'?`,
+		`(ab)+?`:                `('ab')+?`,
+		`x|y|zz`:                `[x-y] | 'zz'`,
+		`.`:                     `~[\n]`,
+		`(?s).`:                 `.`,
+		`\t\\'`:                 `'\t\\\''`,
+		`[\x{1F600}-\x{1F64F}]`: `[😀-🙏]`,
+	}
+	for pattern, want := range tests {
+		got, err := lexerPattern(pattern)
+		require.NoError(t, err, pattern)
+		assert.Equal(t, want, got, pattern)
+	}
+}
@@ -0,0 +1,445 @@
+package antlr
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// reserved are the words ANTLR does not accept as rule names.
+var reserved = map[string]bool{
+	"grammar": true, "lexer": true, "parser": true, "fragment": true,
+	"options": true, "tokens": true, "channels": true, "import": true,
+	"returns": true, "locals": true, "throws": true, "catch": true,
+	"finally": true, "mode": true, "public": true, "private": true,
+	"protected": true, "EOF": true,
+}
+
+// Export writes the grammar of a DSL as an ANTLR4 combined grammar, or a
+// lexer grammar if it has no rules. Rule and token names that ANTLR does
+// not accept are renamed: token names must start with an upper-case
+// letter and rule names with a lower-case one. Actions become alternative
+// labels when every alternative of a rule has one and no other rule uses
+// the same labels; otherwise they are written as comments. Tokens declared
+// with ExternalToken are listed in a tokens section, for a custom lexer to
+// produce.
+func Export(dsl *dslbuilder.DSL) ([]byte, error) {
+	tokens := dsl.Tokens()
+	rules := dsl.Rules()
+
+	used := make(map[string]bool)
+	names := make(map[string]string)
+	for _, t := range tokens {
+		names[t.Name] = antlrName(t.Name, true, used)
+	}
+	for _, r := range rules {
+		names[r.Name] = antlrName(r.Name, false, used)
+	}
+	for _, r := range rules {
+		for _, alt := range r.Alternatives {
+			for _, symbol := range alt.Sequence {
+				if dslbuilder.IsOperator(symbol) {
+					return nil, fmt.Errorf("rule %s: lookahead and cut operators are not supported", r.Name)
+				}
+				if names[symbol] == "" {
+					return nil, fmt.Errorf("rule %s: undefined symbol %s", r.Name, symbol)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Exported from the %s DSL by go-dsl.\n", dsl.Name())
+	kind := "grammar"
+	if len(rules) == 0 {
+		kind = "lexer grammar"
+	}
+	fmt.Fprintf(&buf, "%s %s;\n", kind, grammarName(dsl.Name()))
+	var external []string
+	for _, t := range tokens {
+		if t.External {
+			external = append(external, names[t.Name])
+		}
+	}
+	if len(external) > 0 {
+		fmt.Fprintf(&buf, "\ntokens { %s }\n", strings.Join(external, ", "))
+	}
+
+	labels := labelRules(rules, names)
+	for _, r := range rules {
+		writeRule(&buf, r, names, labels[r.Name])
+	}
+
+	// Keywords first, so they win over identifiers of the same length as
+	// their priority makes them win in go-dsl
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].Priority > tokens[j].Priority
+	})
+	buf.WriteString("\n")
+	for _, t := range tokens {
+		if t.External {
+			continue
+		}
+		var pattern string
+		if t.IsKeyword() {
+			pattern = keywordPattern(t.Keyword)
+		} else {
+			var err error
+			if pattern, err = lexerPattern(t.Pattern); err != nil {
+				return nil, fmt.Errorf("token %s: %w", t.Name, err)
+			}
+		}
+		if t.Skip {
+			pattern += " -> skip"
+		}
+		fmt.Fprintf(&buf, "%s : %s ;\n", names[t.Name], pattern)
+	}
+	// go-dsl skips whitespace between tokens
+	if !skipsWhitespace(tokens) {
+		fmt.Fprintf(&buf, "%s : [ \\t\\r\\n]+ -> skip ;\n", antlrName("WS", true, used))
+	}
+	return buf.Bytes(), nil
+}
+
+// skipsWhitespace reports whether a skipped token matches spaces and line
+// breaks, as a WS rule would.
+func skipsWhitespace(tokens []dslbuilder.TokenInfo) bool {
+	for _, t := range tokens {
+		if !t.Skip {
+			continue
+		}
+		if re, err := regexp.Compile("^(?:" + t.Pattern + ")$"); err == nil && re.MatchString(" \t\r\n") {
+			return true
+		}
+	}
+	return false
+}
+
+// labelRules reports the rules whose actions can be written as labels:
+// ANTLR requires labels on all alternatives or none, and a label may not
+// be used by another rule or match a rule name once capitalized.
+func labelRules(rules []dslbuilder.RuleInfo, names map[string]string) map[string]bool {
+	taken := make(map[string]string) // Capitalized label or rule name → rule
+	for _, r := range rules {
+		taken[capitalize(names[r.Name])] = r.Name
+	}
+
+	labels := make(map[string]bool)
+	for _, r := range rules {
+		ok := true
+		for _, alt := range r.Alternatives {
+			owner, exists := taken[capitalize(alt.Action)]
+			if !isIdentifier(alt.Action) || exists && owner != r.Name+"#" {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		labels[r.Name] = true
+		for _, alt := range r.Alternatives {
+			taken[capitalize(alt.Action)] = r.Name + "#"
+		}
+	}
+	return labels
+}
+
+func writeRule(buf *bytes.Buffer, r dslbuilder.RuleInfo, names map[string]string, labels bool) {
+	sequences := make([]string, len(r.Alternatives))
+	width := 0
+	for i, alt := range r.Alternatives {
+		symbols := make([]string, len(alt.Sequence))
+		for j, symbol := range alt.Sequence {
+			symbols[j] = names[symbol]
+		}
+		sequences[i] = strings.Join(symbols, " ")
+		if len(sequences[i]) > width {
+			width = len(sequences[i])
+		}
+	}
+
+	fmt.Fprintf(buf, "\n%s\n", names[r.Name])
+	for i, alt := range r.Alternatives {
+		separator := "|"
+		if i == 0 {
+			separator = ":"
+		}
+		line := fmt.Sprintf("    %s %-*s", separator, width, sequences[i])
+		switch {
+		case labels:
+			line += "  # " + alt.Action
+		case alt.Action != "":
+			line += "  // " + alt.Action
+		}
+		buf.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	buf.WriteString("    ;\n")
+}
+
+// antlrName returns a name ANTLR accepts for a token or rule and marks it
+// used.
+func antlrName(name string, token bool, used map[string]bool) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	id := b.String()
+	first := []rune(id + "_")[0]
+	switch {
+	case !unicode.IsLetter(first) && token:
+		id = "T_" + id
+	case !unicode.IsLetter(first):
+		id = "r_" + id
+	case token && !unicode.IsUpper(first):
+		id = strings.ToUpper(id)
+	case !token && unicode.IsUpper(first):
+		if strings.ToUpper(id) == id {
+			id = strings.ToLower(id)
+		} else {
+			id = string(unicode.ToLower(first)) + id[len(string(first)):]
+		}
+	}
+	for reserved[id] || used[id] {
+		id += "_"
+	}
+	used[id] = true
+	return id
+}
+
+// grammarName turns a DSL name into a grammar name, such as "Calc DSL"
+// into CalcDSL.
+func grammarName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	id := b.String()
+	if id == "" || !unicode.IsLetter([]rune(id)[0]) {
+		id = "G" + id
+	}
+	return capitalize(id)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// keywordPattern matches a keyword case-insensitively, as go-dsl does.
+func keywordPattern(keyword string) string {
+	var parts []string
+	for _, r := range keyword {
+		if lower, upper := unicode.ToLower(r), unicode.ToUpper(r); lower != upper && lower <= unicode.MaxASCII {
+			parts = append(parts, "["+string(lower)+string(upper)+"]")
+		} else {
+			parts = append(parts, quoteLiteral(string(r)))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// lexerPattern translates a Go regular expression to ANTLR lexer syntax.
+func lexerPattern(pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	text, err := writeRegexp(re.Simplify(), true)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("pattern %q matches the empty string", pattern)
+	}
+	return text, nil
+}
+
+func writeRegexp(re *syntax.Regexp, top bool) (string, error) {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return "", fmt.Errorf("pattern matches nothing")
+	case syntax.OpEmptyMatch:
+		return "", nil
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase == 0 {
+			return quoteLiteral(string(re.Rune)), nil
+		}
+		parts := make([]string, len(re.Rune))
+		for i, r := range re.Rune {
+			orbit := []rune{r}
+			for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+				orbit = append(orbit, f)
+			}
+			if len(orbit) == 1 {
+				parts[i] = quoteLiteral(string(r))
+				continue
+			}
+			sort.Slice(orbit, func(i, j int) bool { return orbit[i] < orbit[j] })
+			var ranges []rune
+			for _, o := range orbit {
+				ranges = append(ranges, o, o)
+			}
+			parts[i] = setText(ranges)
+		}
+		return strings.Join(parts, " "), nil
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return "", fmt.Errorf("pattern matches nothing")
+		}
+		return setText(re.Rune), nil
+	case syntax.OpAnyCharNotNL:
+		return `~[\n]`, nil
+	case syntax.OpAnyChar:
+		return ".", nil
+	case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText:
+		return "", fmt.Errorf("anchors are not supported by ANTLR lexers")
+	case syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return "", fmt.Errorf("word boundaries are not supported by ANTLR lexers")
+	case syntax.OpCapture:
+		inner, err := writeRegexp(re.Sub[0], true)
+		if err != nil {
+			return "", err
+		}
+		return "(" + inner + ")", nil
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
+		sub := re.Sub[0]
+		inner, err := writeRegexp(sub, false)
+		if err != nil {
+			return "", err
+		}
+		if sub.Op == syntax.OpConcat || sub.Op == syntax.OpLiteral && (len(sub.Rune) > 1 || sub.Flags&syntax.FoldCase != 0) {
+			inner = "(" + inner + ")"
+		}
+		suffix := map[syntax.Op]string{syntax.OpStar: "*", syntax.OpPlus: "+", syntax.OpQuest: "?"}[re.Op]
+		if re.Flags&syntax.NonGreedy != 0 {
+			suffix += "?"
+		}
+		return inner + suffix, nil
+	case syntax.OpConcat:
+		var parts []string
+		for _, sub := range re.Sub {
+			part, err := writeRegexp(sub, false)
+			if err != nil {
+				return "", err
+			}
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return strings.Join(parts, " "), nil
+	case syntax.OpAlternate:
+		parts := make([]string, len(re.Sub))
+		for i, sub := range re.Sub {
+			part, err := writeRegexp(sub, true)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = part
+		}
+		text := strings.Join(parts, " | ")
+		if !top {
+			text = "(" + text + ")"
+		}
+		return text, nil
+	}
+	return "", fmt.Errorf("unsupported pattern %s", re)
+}
+
+// setText writes the ranges of a character class as an ANTLR set, negated
+// when the class is the complement of a smaller one, such as [^"].
+func setText(ranges []rune) string {
+	if len(ranges) == 2 && ranges[0] == 0 && ranges[1] == unicode.MaxRune {
+		return "."
+	}
+	prefix := ""
+	if ranges[0] == 0 && ranges[len(ranges)-1] == unicode.MaxRune {
+		var complement []rune
+		for i := 1; i+1 < len(ranges); i += 2 {
+			complement = append(complement, ranges[i]+1, ranges[i+1]-1)
+		}
+		prefix, ranges = "~", complement
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix + "[")
+	for i := 0; i < len(ranges); i += 2 {
+		b.WriteString(setRune(ranges[i]))
+		if ranges[i+1] != ranges[i] {
+			b.WriteString("-" + setRune(ranges[i+1]))
+		}
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+func setRune(r rune) string {
+	switch r {
+	case '\\', ']', '-':
+		return `\` + string(r)
+	}
+	return escapeRune(r)
+}
+
+func quoteLiteral(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\\', '\'':
+			b.WriteString(`\` + string(r))
+		default:
+			b.WriteString(escapeRune(r))
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// escapeRune writes control and other invisible characters as escapes.
+func escapeRune(r rune) string {
+	switch r {
+	case '\n':
+		return `\n`
+	case '\r':
+		return `\r`
+	case '\t':
+		return `\t`
+	case '\b':
+		return `\b`
+	case '\f':
+		return `\f`
+	}
+	switch {
+	case r == ' ' || unicode.IsGraphic(r):
+		return string(r)
+	case r > 0xFFFF:
+		return fmt.Sprintf(`\u{%X}`, r)
+	}
+	return fmt.Sprintf(`\u%04X`, r)
+}
@@ -0,0 +1,632 @@
+package antlr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Syntax of a .g4 file, limited to what Import converts. Unsupported
+// constructs are still recognized so they can be reported by name.
+
+type tokenKind int
+
+const (
+	tEOF       tokenKind = iota
+	tID                  // Rule, token or keyword name
+	tString              // 'literal', with quotes
+	tSet                 // [...] set or argument list, without brackets
+	tAction              // {...}
+	tPredicate           // {...}?
+	tPunct               // : ; | ( ) ? * + ~ . .. -> # = += , < > @ ::
+)
+
+type g4Token struct {
+	kind tokenKind
+	text string
+	line int
+	col  int
+}
+
+// scanner splits a .g4 file into tokens, skipping comments.
+type scanner struct {
+	src  string
+	pos  int
+	line int
+	col  int
+}
+
+func (s *scanner) errorf(line, col int, format string, args ...interface{}) error {
+	return &Error{Line: line, Column: col, Message: fmt.Sprintf(format, args...)}
+}
+
+func (s *scanner) advance(n int) {
+	for _, r := range s.src[s.pos : s.pos+n] {
+		if r == '\n' {
+			s.line++
+			s.col = 1
+		} else {
+			s.col++
+		}
+	}
+	s.pos += n
+}
+
+func (s *scanner) tokens() ([]g4Token, error) {
+	var tokens []g4Token
+	for {
+		// Whitespace and comments
+		for s.pos < len(s.src) {
+			rest := s.src[s.pos:]
+			switch {
+			case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r' || rest[0] == '\f':
+				s.advance(1)
+				continue
+			case strings.HasPrefix(rest, "//"):
+				end := strings.IndexByte(rest, '\n')
+				if end < 0 {
+					end = len(rest)
+				}
+				s.advance(end)
+				continue
+			case strings.HasPrefix(rest, "/*"):
+				end := strings.Index(rest[2:], "*/")
+				if end < 0 {
+					return nil, s.errorf(s.line, s.col, "unterminated comment")
+				}
+				s.advance(end + 4)
+				continue
+			}
+			break
+		}
+		if s.pos >= len(s.src) {
+			return append(tokens, g4Token{kind: tEOF, line: s.line, col: s.col}), nil
+		}
+
+		line, col := s.line, s.col
+		rest := s.src[s.pos:]
+		r, _ := utf8.DecodeRuneInString(rest)
+		var kind tokenKind
+		var n int
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+			kind = tID
+			n = strings.IndexFunc(rest, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+			})
+			if n < 0 {
+				n = len(rest)
+			}
+		case r == '\'':
+			kind = tString
+			n = closing(rest, '\'')
+			if n < 0 {
+				return nil, s.errorf(line, col, "unterminated string literal")
+			}
+		case r == '[':
+			kind = tSet
+			n = closing(rest, ']')
+			if n < 0 {
+				return nil, s.errorf(line, col, "unterminated character set")
+			}
+		case r == '{':
+			kind = tAction
+			n = braces(rest)
+			if n < 0 {
+				return nil, s.errorf(line, col, "unterminated action")
+			}
+			if strings.HasPrefix(rest[n:], "?") {
+				kind = tPredicate
+				n++
+			}
+		default:
+			kind = tPunct
+			n = 1
+			for _, p := range []string{"..", "->", "+=", "::"} {
+				if strings.HasPrefix(rest, p) {
+					n = 2
+				}
+			}
+			if n == 1 && !strings.ContainsRune(":;|()?*+~.#=,<>@", r) {
+				return nil, s.errorf(line, col, "unexpected character %q", r)
+			}
+		}
+
+		text := rest[:n]
+		if kind == tSet {
+			text = text[1 : n-1]
+		}
+		tokens = append(tokens, g4Token{kind: kind, text: text, line: line, col: col})
+		s.advance(n)
+	}
+}
+
+// closing returns the length of s up to and including the unescaped
+// delimiter that closes it, or -1.
+func closing(s string, delim byte) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case delim:
+			return i + 1
+		case '\n':
+			if delim == '\'' {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// braces returns the length of a {...} action with nested braces, or -1.
+func braces(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"', '\'':
+			quote := s[i]
+			for i++; i < len(s) && s[i] != quote; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+type grammarFile struct {
+	name        string
+	lexer       bool // lexer grammar
+	lexerRules  []*lexerRule
+	parserRules []*parserRule
+}
+
+type lexerRule struct {
+	name     string
+	fragment bool
+	alts     []*alternative
+	commands []*command
+	line     int
+	col      int
+}
+
+type parserRule struct {
+	name string
+	alts []*alternative
+	line int
+	col  int
+}
+
+type alternative struct {
+	elements []*element
+	label    string // # Label
+	line     int
+	col      int
+}
+
+type elementKind int
+
+const (
+	eLiteral elementKind = iota // 'text'
+	eRef                        // Rule or token reference
+	eSet                        // [a-z]
+	eAny                        // .
+	eRange                      // 'a'..'z'
+	eGroup                      // ( ... )
+)
+
+type element struct {
+	kind   elementKind
+	text   string         // Decoded literal, reference name or raw set
+	to     string         // Decoded end of a range
+	not    bool           // ~ prefix
+	alts   []*alternative // Group alternatives
+	suffix string         // "", "?", "*", "+", or non-greedy "??", "*?", "+?"
+	line   int
+	col    int
+}
+
+type command struct {
+	name string
+	arg  string
+	line int
+	col  int
+}
+
+// parser builds a grammarFile from tokens.
+type parser struct {
+	tokens []g4Token
+	pos    int
+}
+
+func (p *parser) peek() g4Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() g4Token {
+	t := p.tokens[p.pos]
+	if t.kind != tEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return (t.kind == tPunct || t.kind == tID) && t.text == text
+}
+
+func (p *parser) errorf(t g4Token, format string, args ...interface{}) error {
+	return &Error{Line: t.line, Column: t.col, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) expect(text string) (g4Token, error) {
+	t := p.next()
+	if (t.kind != tPunct && t.kind != tID) || t.text != text {
+		return t, p.errorf(t, "expected %q, found %s", text, describe(t))
+	}
+	return t, nil
+}
+
+func (p *parser) ident() (g4Token, error) {
+	t := p.next()
+	if t.kind != tID {
+		return t, p.errorf(t, "expected a name, found %s", describe(t))
+	}
+	return t, nil
+}
+
+func describe(t g4Token) string {
+	switch t.kind {
+	case tEOF:
+		return "end of file"
+	case tSet:
+		return "[" + t.text + "]"
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// unsupported reports constructs Import does not convert, by the token
+// that introduces them.
+func (p *parser) unsupported(t g4Token) error {
+	switch t.kind {
+	case tAction:
+		return p.errorf(t, "actions are not supported")
+	case tPredicate:
+		return p.errorf(t, "semantic predicates are not supported")
+	}
+	switch t.text {
+	case "options":
+		return p.errorf(t, "options are not supported")
+	case "import":
+		return p.errorf(t, "grammar imports are not supported")
+	case "tokens":
+		return p.errorf(t, "tokens sections are not supported")
+	case "channels":
+		return p.errorf(t, "channels sections are not supported")
+	case "mode":
+		return p.errorf(t, "lexer modes are not supported")
+	case "@":
+		return p.errorf(t, "named actions are not supported")
+	case "<":
+		return p.errorf(t, "element options such as <assoc=right> are not supported")
+	case "returns":
+		return p.errorf(t, "rule return values are not supported")
+	case "locals":
+		return p.errorf(t, "rule locals are not supported")
+	case "throws", "catch", "finally":
+		return p.errorf(t, "exception handlers are not supported")
+	case "public", "private", "protected":
+		return p.errorf(t, "rule modifiers are not supported")
+	}
+	return nil
+}
+
+func (p *parser) file() (*grammarFile, error) {
+	g := &grammarFile{}
+	t := p.peek()
+	switch {
+	case p.is("lexer"):
+		p.next()
+		g.lexer = true
+	case p.is("parser"):
+		return nil, p.errorf(t, "parser grammars are not supported; combine the lexer and parser in one grammar")
+	}
+	if _, err := p.expect("grammar"); err != nil {
+		return nil, err
+	}
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	g.name = name.text
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind != tEOF {
+		t := p.peek()
+		if err := p.unsupported(t); err != nil {
+			return nil, err
+		}
+		fragment := false
+		if p.is("fragment") {
+			p.next()
+			fragment = true
+		}
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if unicode.IsUpper([]rune(name.text)[0]) {
+			rule, err := p.lexerRule(name, fragment)
+			if err != nil {
+				return nil, err
+			}
+			g.lexerRules = append(g.lexerRules, rule)
+		} else {
+			if fragment {
+				return nil, p.errorf(name, "parser rule %s cannot be a fragment", name.text)
+			}
+			if g.lexer {
+				return nil, p.errorf(name, "lexer grammar %s cannot have parser rule %s", g.name, name.text)
+			}
+			rule, err := p.parserRule(name)
+			if err != nil {
+				return nil, err
+			}
+			g.parserRules = append(g.parserRules, rule)
+		}
+	}
+	return g, nil
+}
+
+func (p *parser) lexerRule(name g4Token, fragment bool) (*lexerRule, error) {
+	rule := &lexerRule{name: name.text, fragment: fragment, line: name.line, col: name.col}
+	if err := p.unsupported(p.peek()); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	alts, err := p.alternatives(false)
+	if err != nil {
+		return nil, err
+	}
+	rule.alts = alts
+
+	if p.is("->") {
+		p.next()
+		for {
+			t, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			c := &command{name: t.text, line: t.line, col: t.col}
+			if p.is("(") {
+				p.next()
+				arg, err := p.ident()
+				if err != nil {
+					return nil, err
+				}
+				c.arg = arg.text
+				if _, err := p.expect(")"); err != nil {
+					return nil, err
+				}
+			}
+			rule.commands = append(rule.commands, c)
+			if !p.is(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (p *parser) parserRule(name g4Token) (*parserRule, error) {
+	rule := &parserRule{name: name.text, line: name.line, col: name.col}
+	t := p.peek()
+	if t.kind == tSet {
+		return nil, p.errorf(t, "rule arguments are not supported")
+	}
+	if err := p.unsupported(t); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	alts, err := p.alternatives(true)
+	if err != nil {
+		return nil, err
+	}
+	rule.alts = alts
+	if _, err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	if p.is("catch") || p.is("finally") {
+		return nil, p.unsupported(p.peek())
+	}
+	return rule, nil
+}
+
+// alternatives parses alternatives separated by |, up to ; ) or ->.
+// Only the top level of parser rules has # labels.
+func (p *parser) alternatives(labels bool) ([]*alternative, error) {
+	var alts []*alternative
+	for {
+		t := p.peek()
+		alt := &alternative{line: t.line, col: t.col}
+		for !p.is("|") && !p.is(";") && !p.is(")") && !p.is("->") && !p.is("#") && p.peek().kind != tEOF {
+			e, err := p.element()
+			if err != nil {
+				return nil, err
+			}
+			alt.elements = append(alt.elements, e)
+		}
+		if p.is("#") {
+			hash := p.next()
+			if !labels {
+				return nil, p.errorf(hash, "alternative labels are only supported at the top level of parser rules")
+			}
+			label, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			alt.label = label.text
+		}
+		alts = append(alts, alt)
+		if !p.is("|") {
+			return alts, nil
+		}
+		p.next()
+	}
+}
+
+func (p *parser) element() (*element, error) {
+	t := p.peek()
+	if err := p.unsupported(t); err != nil {
+		return nil, err
+	}
+
+	// Element labels such as x=expr or xs+=expr are dropped
+	if t.kind == tID && p.tokens[p.pos+1].kind == tPunct {
+		if op := p.tokens[p.pos+1].text; op == "=" || op == "+=" {
+			p.pos += 2
+			t = p.peek()
+		}
+	}
+
+	e := &element{line: t.line, col: t.col}
+	if p.is("~") {
+		p.next()
+		e.not = true
+		t = p.peek()
+	}
+
+	switch {
+	case t.kind == tString:
+		p.next()
+		text, err := unquote(t)
+		if err != nil {
+			return nil, err
+		}
+		e.kind, e.text = eLiteral, text
+		if p.is("..") {
+			p.next()
+			end := p.next()
+			if end.kind != tString {
+				return nil, p.errorf(end, "expected a literal after .., found %s", describe(end))
+			}
+			to, err := unquote(end)
+			if err != nil {
+				return nil, err
+			}
+			e.kind, e.to = eRange, to
+		}
+	case t.kind == tSet:
+		p.next()
+		e.kind, e.text = eSet, t.text
+	case t.kind == tID:
+		p.next()
+		e.kind, e.text = eRef, t.text
+	case p.is("."):
+		p.next()
+		e.kind = eAny
+	case p.is("("):
+		p.next()
+		alts, err := p.alternatives(false)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		e.kind, e.alts = eGroup, alts
+	default:
+		return nil, p.errorf(t, "unexpected %s", describe(t))
+	}
+
+	if p.is("<") {
+		return nil, p.unsupported(p.peek())
+	}
+	if p.is("?") || p.is("*") || p.is("+") {
+		e.suffix = p.next().text
+		if p.is("?") {
+			e.suffix += p.next().text
+		}
+	}
+	return e, nil
+}
+
+// unquote decodes an ANTLR string literal.
+func unquote(t g4Token) (string, error) {
+	s := t.text[1 : len(t.text)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size, err := escaped(s, i)
+		if err != nil {
+			return "", &Error{Line: t.line, Column: t.col, Message: err.Error()}
+		}
+		b.WriteRune(r)
+		i += size
+	}
+	return b.String(), nil
+}
+
+// escaped decodes the character at s[i], which may be an escape such as
+// \n or \u00E9, and returns it with its length in s.
+func escaped(s string, i int) (rune, int, error) {
+	r, size := utf8.DecodeRuneInString(s[i:])
+	if r != '\\' {
+		return r, size, nil
+	}
+	if i+1 >= len(s) {
+		return 0, 0, fmt.Errorf("invalid escape at end of %q", s)
+	}
+	switch c := s[i+1]; c {
+	case 'n':
+		return '\n', 2, nil
+	case 'r':
+		return '\r', 2, nil
+	case 't':
+		return '\t', 2, nil
+	case 'b':
+		return '\b', 2, nil
+	case 'f':
+		return '\f', 2, nil
+	case 'u':
+		hex, size := "", 2
+		if strings.HasPrefix(s[i+2:], "{") {
+			if end := strings.IndexByte(s[i+2:], '}'); end > 0 {
+				hex, size = s[i+3:i+2+end], 3+end
+			}
+		} else if i+6 <= len(s) {
+			hex, size = s[i+2:i+6], 6
+		}
+		var code rune
+		if _, err := fmt.Sscanf(hex, "%x", &code); err != nil || len(hex) == 0 || code > unicode.MaxRune {
+			return 0, 0, fmt.Errorf("invalid escape \\u%s", hex)
+		}
+		return code, size, nil
+	default:
+		r, n := utf8.DecodeRuneInString(s[i+1:])
+		return r, 1 + n, nil
+	}
+}
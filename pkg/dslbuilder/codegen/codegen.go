@@ -0,0 +1,849 @@
+// Package codegen generates a standalone Go parser from a go-dsl grammar.
+// The generated package has a lexer with the token patterns compiled once
+// and anchored, and a packrat parser with one function per rule and
+// alternative, so parsing needs no map lookups and no dslbuilder at run
+// time. Actions are methods of a generated Actions interface.
+//
+// The generated parser follows the interpreter of DSL.Parse: the same
+// ordered choice, memoization and growing of left-recursive rules, and the
+// same results and errors. Tokens of equal priority that match the same
+// length are resolved in definition order. Lookaheads are supported; cuts
+// are ignored, so errors after a cut are reported as if it were absent.
+// Built-in action expressions (UseBuiltinActions) are not generated: the
+// ActionFuncs passed to Parse supply every action by name.
+//
+// Example:
+//
+//	code, err := codegen.Generate(dsl, codegen.Options{Package: "calc"})
+//	if err != nil {
+//	    return err
+//	}
+//	os.WriteFile("calc/calc.go", code, 0644)
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// Options controls the generated package.
+type Options struct {
+	Package string // Package name (defaults to the DSL name in lower case)
+	Source  string // Grammar file named in the generated header, if any
+}
+
+// token is a token definition with the identifiers used in generated code.
+type token struct {
+	info    dslbuilder.TokenInfo
+	ident   string
+	literal string // Fixed text matched with strings.HasPrefix
+	keyword bool   // Keyword matched by matchKeyword
+}
+
+// rule is a rule definition with the identifiers used in generated code.
+type rule struct {
+	info    dslbuilder.RuleInfo
+	ident   string
+	leftRec bool // Some alternative starts with the rule itself
+}
+
+type generator struct {
+	dsl     *dslbuilder.DSL
+	opts    Options
+	buf     bytes.Buffer
+	tokens  []*token
+	rules   []*rule
+	actions []string          // Action names in order of first use
+	byToken map[string]*token // Tokens by name
+	byRule  map[string]*rule  // Rules by name
+	methods map[string]string // Actions interface methods by action name
+}
+
+// Generate returns the source of a Go package that parses the language of
+// the DSL. It fails if the grammar has no rules, a rule refers to an
+// undefined symbol, or tokens come from a custom lexer (SetLexer,
+// ExternalToken), since the generated lexer only knows token patterns.
+func Generate(dsl *dslbuilder.DSL, opts Options) ([]byte, error) {
+	if opts.Package == "" {
+		opts.Package = packageName(dsl.Name())
+	}
+	g := &generator{
+		dsl:     dsl,
+		opts:    opts,
+		byToken: make(map[string]*token),
+		byRule:  make(map[string]*rule),
+		methods: make(map[string]string),
+	}
+	if dsl.Grammar().Lexer() != nil {
+		return nil, fmt.Errorf("grammar %s uses a custom lexer", dsl.Name())
+	}
+	if err := g.load(); err != nil {
+		return nil, err
+	}
+
+	g.header()
+	g.actionTypes()
+	g.lexer()
+	g.parser()
+
+	code, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return code, nil
+}
+
+// Run generates the parser for a DSL and writes it to the output file, or
+// to the standard output when output is empty. Without opts.Package, the
+// package is named after the directory of the output file. It is the
+// library side of cmd/dslgen, for programs run by go generate and custom
+// builds with DSLs written in Go.
+//
+// Example:
+//
+//	//go:generate go run ./gen
+//	func main() {
+//	    if err := codegen.Run(calc.New(), "calc/calc.go", codegen.Options{}); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+func Run(dsl *dslbuilder.DSL, output string, opts Options) error {
+	if opts.Package == "" && output != "" {
+		dir := filepath.Dir(output)
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		opts.Package = packageName(filepath.Base(dir))
+	}
+	code, err := Generate(dsl, opts)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(output, code, 0644)
+}
+
+// load collects the grammar and assigns identifiers.
+func (g *generator) load() error {
+	used := make(map[string]bool)
+	for _, info := range g.dsl.Tokens() {
+		if info.External {
+			return fmt.Errorf("token %s has no pattern", info.Name)
+		}
+		t := &token{info: info, ident: unique(identifier(info.Name), used)}
+		if info.IsKeyword() {
+			t.keyword = isWordKeyword(info.Keyword)
+		} else if text, ok := info.Literal(); ok {
+			t.literal = text
+		}
+		g.tokens = append(g.tokens, t)
+		g.byToken[info.Name] = t
+	}
+
+	used = make(map[string]bool)
+	for _, info := range g.dsl.Rules() {
+		r := &rule{info: info, ident: unique(identifier(info.Name), used)}
+		for _, alt := range info.Alternatives {
+			if len(alt.Sequence) > 0 && alt.Sequence[0] == info.Name {
+				r.leftRec = true
+			}
+		}
+		g.rules = append(g.rules, r)
+		g.byRule[info.Name] = r
+	}
+	if len(g.rules) == 0 {
+		return fmt.Errorf("grammar %s has no rules", g.dsl.Name())
+	}
+	if _, ok := g.byRule[g.dsl.StartRule()]; !ok {
+		return fmt.Errorf("start rule %s is not defined", g.dsl.StartRule())
+	}
+
+	used = make(map[string]bool)
+	for _, r := range g.rules {
+		for _, alt := range r.info.Alternatives {
+			for _, symbol := range alt.Sequence {
+				if target, _, ok := dslbuilder.LookaheadOf(symbol); ok {
+					symbol = target
+				} else if symbol == dslbuilder.Cut {
+					continue
+				}
+				if g.byToken[symbol] == nil && g.byRule[symbol] == nil {
+					return fmt.Errorf("rule %s: undefined symbol %s", r.info.Name, symbol)
+				}
+			}
+			if alt.Action != "" && g.methods[alt.Action] == "" {
+				g.methods[alt.Action] = unique(exported(identifier(alt.Action)), used)
+				g.actions = append(g.actions, alt.Action)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) header() {
+	source := ""
+	if g.opts.Source != "" {
+		source = " from " + g.opts.Source
+	}
+	g.printf("// Code generated by dslgen%s. DO NOT EDIT.\n\n", source)
+	g.printf("// Package %s parses the %s language.\n", g.opts.Package, g.dsl.Name())
+	g.printf("// It was generated from the grammar and does not depend on go-dsl at run time.\n")
+	g.printf("package %s\n\n", g.opts.Package)
+
+	imports := []string{"fmt"}
+	if g.needsRegexp() {
+		imports = append(imports, "regexp")
+	}
+	if g.needsStrings() {
+		imports = append(imports, "strings")
+	}
+	g.printf("import (\n")
+	for _, imp := range imports {
+		g.printf("\t%q\n", imp)
+	}
+	g.printf(")\n\n")
+}
+
+func (g *generator) needsRegexp() bool {
+	for _, t := range g.tokens {
+		if t.literal == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) needsStrings() bool {
+	for _, t := range g.tokens {
+		if t.literal != "" || t.keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// actionTypes writes the Actions interface and the ActionFuncs adapter.
+func (g *generator) actionTypes() {
+	g.printf("// Actions computes the value of each alternative from the values it\n")
+	g.printf("// matched: token text as strings and the values of nested rules. An\n")
+	g.printf("// error makes the alternative fail, as with the actions of a DSL.\n")
+	g.printf("type Actions interface {\n")
+	for _, name := range g.actions {
+		g.printf("\t%s(args []interface{}) (interface{}, error) // %s\n", g.methods[name], name)
+	}
+	g.printf("}\n\n")
+
+	g.printf("// ActionFuncs implements Actions with functions keyed by action name,\n")
+	g.printf("// such as the ones registered with DSL.Action. An action without a\n")
+	g.printf("// function returns the values it matched.\n")
+	g.printf("type ActionFuncs map[string]func(args []interface{}) (interface{}, error)\n\n")
+	for _, name := range g.actions {
+		g.printf("func (a ActionFuncs) %s(args []interface{}) (interface{}, error) {\n\treturn a.call(%q, args)\n}\n\n", g.methods[name], name)
+	}
+	g.printf(`
+func (a ActionFuncs) call(name string, args []interface{}) (interface{}, error) {
+	if fn := a[name]; fn != nil {
+		return fn(args)
+	}
+	return args, nil
+}
+
+`)
+}
+
+// lexer writes the token table, Tokenize and the error type.
+func (g *generator) lexer() {
+	g.printf("// Token types, in definition order.\nconst (\n")
+	for i, t := range g.tokens {
+		if i == 0 {
+			g.printf("\ttok%s = iota // %s\n", t.ident, t.info.Name)
+		} else {
+			g.printf("\ttok%s // %s\n", t.ident, t.info.Name)
+		}
+	}
+	g.printf("\tnumTokens = %d\n)\n\n", len(g.tokens))
+
+	g.printf("var tokenNames = [numTokens]string{")
+	for _, t := range g.tokens {
+		g.printf("%q, ", t.info.Name)
+	}
+	g.printf("}\n\n")
+
+	g.printf("// skipped marks tokens that are matched but not passed to the parser.\n")
+	g.printf("var skipped = [numTokens]bool{")
+	for _, t := range g.tokens {
+		if t.info.Skip {
+			g.printf("tok%s: true, ", t.ident)
+		}
+	}
+	g.printf("}\n\n")
+
+	if g.needsRegexp() {
+		g.printf("// Token patterns, anchored at the start of the remaining input.\nvar (\n")
+		for _, t := range g.tokens {
+			if t.literal == "" {
+				g.printf("\tre%s = regexp.MustCompile(%s)\n", t.ident, quote("^(?:"+t.info.Pattern+")"))
+			}
+		}
+		g.printf(")\n\n")
+	}
+
+	g.printf("%s", lexerTypes)
+
+	g.printf(`// tokenize splits input into tokens. Whitespace separates tokens; at
+// each position the token with the highest priority wins, then the longest.
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(input) {
+		switch input[pos] {
+		case ' ', '\t', '\n', '\r':
+			pos++
+			continue
+		}
+
+		rest := input[pos:]
+		best, bestLength, bestPriority := -1, 0, -1
+`)
+	for _, t := range g.tokens {
+		priority := t.info.Priority
+		better := fmt.Sprintf("(bestPriority < %d || bestPriority == %d && n > bestLength)", priority, priority)
+		g.printf("\t\t// %s: %s\n", t.info.Name, t.info.Pattern)
+		switch {
+		case t.literal != "":
+			g.printf("\t\tif n := %d; strings.HasPrefix(rest, %q) && %s {\n", len(t.literal), t.literal, better)
+		case t.keyword:
+			g.printf("\t\tif n := matchKeyword(rest, %q, re%s); n >= 0 && %s {\n", t.info.Keyword, t.ident, better)
+		default:
+			g.printf("\t\tif n := matchRegexp(re%s, rest); n >= 0 && %s {\n", t.ident, better)
+		}
+		g.printf("\t\t\tbest, bestLength, bestPriority = tok%s, n, %d\n\t\t}\n", t.ident, priority)
+	}
+	g.printf(`
+		if best < 0 {
+			return nil, newParseError(fmt.Sprintf("unexpected character: %%c", input[pos]), pos, string(input[pos]), input)
+		}
+		if !skipped[best] {
+			tokens = append(tokens, token{kind: best, value: input[pos : pos+bestLength], start: pos})
+		}
+		pos += bestLength
+	}
+	return tokens, nil
+}
+
+`)
+	if g.needsRegexp() {
+		g.printf("%s", matchRegexpFunc)
+	}
+	if g.hasKeywordFastPath() {
+		g.printf("%s", matchKeywordFunc)
+	}
+}
+
+func (g *generator) hasKeywordFastPath() bool {
+	for _, t := range g.tokens {
+		if t.keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// parser writes Parse and the functions of every rule and alternative.
+func (g *generator) parser() {
+	start := g.byRule[g.dsl.StartRule()]
+
+	g.printf("// Rules, in definition order.\nconst (\n")
+	for i, r := range g.rules {
+		if i == 0 {
+			g.printf("\trule%s = iota // %s\n", r.ident, r.info.Name)
+		} else {
+			g.printf("\trule%s // %s\n", r.ident, r.info.Name)
+		}
+	}
+	g.printf("\tnumRules = %d\n)\n\n", len(g.rules))
+
+	g.printf(`// Parse parses input starting with the %s rule and returns the value
+// computed by the actions, like DSL.Parse. With nil actions every
+// alternative returns the values it matched.
+func Parse(input string, actions Actions) (interface{}, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, actions: actions}
+	result, ok := p.rule(rule%s, (*parser).match%s)
+	if !ok {
+`, start.info.Name, start.ident, start.ident)
+	message := "no alternative matched for rule " + start.info.Name
+	if start.leftRec {
+		g.printf("\t\treturn nil, fmt.Errorf(\"parsing error: %%s\", %q)\n", message)
+	} else {
+		g.printf(`		if len(tokens) == 0 {
+			return nil, newParseError(%q, len(input), "<end of input>", input)
+		}
+		return nil, newParseError(%q, tokens[0].start, tokens[0].value, input)
+`, message, message)
+	}
+	g.printf(`	}
+	if p.pos < len(tokens) {
+		t := tokens[p.pos]
+		return nil, newParseError("unexpected token: "+t.value, t.start, t.value, input)
+	}
+	return result, nil
+}
+
+`)
+	g.printf("%s", parserTypes)
+
+	for _, r := range g.rules {
+		if r.leftRec {
+			g.leftRecursiveRule(r)
+		} else {
+			g.regularRule(r)
+		}
+	}
+
+	for _, name := range g.actions {
+		method := g.methods[name]
+		g.printf(`func (p *parser) act%s(args []interface{}) (interface{}, bool) {
+	if p.actions == nil {
+		return args, true
+	}
+	result, err := p.actions.%s(args)
+	return result, err == nil
+}
+
+`, method, method)
+	}
+}
+
+// regularRule writes a rule that tries its alternatives in order.
+func (g *generator) regularRule(r *rule) {
+	g.printf("// match%s tries the alternatives of %s in order.\n", r.ident, r.info.Name)
+	g.printf("func (p *parser) match%s() (interface{}, bool) {\n\tstart := p.pos\n", r.ident)
+	if len(r.info.Alternatives) == 0 {
+		g.printf("\treturn nil, false\n}\n\n")
+		return
+	}
+	for i := range r.info.Alternatives {
+		if i == 0 {
+			g.printf("\tresult, ok := p.alt%s%d()\n", r.ident, i)
+		} else {
+			g.printf("\tif !ok {\n\t\tp.pos = start\n\t\tresult, ok = p.alt%s%d()\n\t}\n", r.ident, i)
+		}
+	}
+	g.printf("\tif !ok {\n\t\tp.pos = start\n\t}\n\treturn result, ok\n}\n\n")
+
+	for i, alt := range r.info.Alternatives {
+		g.alternative(r, i, alt)
+	}
+}
+
+// leftRecursiveRule writes a rule that first matches an alternative not
+// starting with the rule as a seed, then repeatedly extends the seed with
+// the left-recursive alternatives while they consume more input.
+func (g *generator) leftRecursiveRule(r *rule) {
+	g.printf("// match%s parses the left-recursive rule %s.\n", r.ident, r.info.Name)
+	g.printf(`func (p *parser) match%s() (interface{}, bool) {
+	start := p.pos
+	if !p.grow(rule%s, start) {
+		return nil, false
+	}
+	result, ok := p.grow%s(start)
+	p.growing[rule%s][start] = false
+	return result, ok
+}
+
+`, r.ident, r.ident, r.ident, r.ident)
+
+	g.printf("func (p *parser) grow%s(start int) (interface{}, bool) {\n", r.ident)
+	g.printf("\t// Seed with the first alternative that does not start with %s\n", r.info.Name)
+	first := true
+	for i, alt := range r.info.Alternatives {
+		if g.isLeftRecursive(r, alt) {
+			continue
+		}
+		if first {
+			g.printf("\tseed, ok := p.alt%s%d()\n", r.ident, i)
+			first = false
+		} else {
+			g.printf("\tif !ok {\n\t\tp.pos = start\n\t\tseed, ok = p.alt%s%d()\n\t}\n", r.ident, i)
+		}
+	}
+	if first {
+		g.printf("\tvar seed interface{}\n\tok := false\n")
+	}
+	g.printf("\tif !ok {\n\t\treturn nil, false\n\t}\n\n")
+
+	g.printf(`	// Extend the seed with the alternative that consumes the most input
+	seedPos := p.pos
+	memo := p.memo[rule%s]
+	for {
+		best, bestPos, improved := seed, seedPos, false
+`, r.ident)
+	for i, alt := range r.info.Alternatives {
+		if !g.isLeftRecursive(r, alt) {
+			continue
+		}
+		g.printf("\n\t\t// %s\n", describe(r.info.Name, alt))
+		g.printf("\t\tmemo[start] = memoEntry{result: seed, end: seedPos, ok: true, set: true}\n")
+		g.printf("\t\tp.pos = seedPos\n")
+		g.printf("\t\tif results, ok := p.alt%s%d(seed); ok && p.pos > bestPos {\n", r.ident, i)
+		if alt.Action != "" {
+			g.printf("\t\t\tif result, ok := p.act%s(results); ok {\n", g.methods[alt.Action])
+			g.printf("\t\t\t\tbest, bestPos, improved = result, p.pos, true\n\t\t\t}\n")
+		} else {
+			g.printf("\t\t\tbest, bestPos, improved = results, p.pos, true\n")
+		}
+		g.printf("\t\t}\n")
+	}
+	g.printf(`
+		if !improved {
+			p.pos = bestPos
+			return best, true
+		}
+		seed, seedPos = best, bestPos
+	}
+}
+
+`)
+
+	for i, alt := range r.info.Alternatives {
+		if g.isLeftRecursive(r, alt) {
+			g.growAlternative(r, i, alt)
+		} else {
+			g.alternative(r, i, alt)
+		}
+	}
+}
+
+func (g *generator) isLeftRecursive(r *rule, alt dslbuilder.AlternativeInfo) bool {
+	return len(alt.Sequence) > 0 && alt.Sequence[0] == r.info.Name
+}
+
+// alternative writes a function that matches every symbol of an
+// alternative and applies its action.
+func (g *generator) alternative(r *rule, index int, alt dslbuilder.AlternativeInfo) {
+	g.printf("// %s\n", describe(r.info.Name, alt))
+	g.printf("func (p *parser) alt%s%d() (interface{}, bool) {\n", r.ident, index)
+	if len(alt.Sequence) == 0 {
+		g.printf("\tvar results []interface{}\n")
+	} else {
+		g.printf("\tresults := make([]interface{}, 0, %d)\n", len(alt.Sequence))
+		g.symbols(alt.Sequence)
+	}
+	if alt.Action != "" {
+		g.printf("\treturn p.act%s(results)\n}\n\n", g.methods[alt.Action])
+	} else {
+		g.printf("\treturn results, true\n}\n\n")
+	}
+}
+
+// growAlternative writes a function that matches the symbols of a
+// left-recursive alternative after the seed; the caller applies the action.
+func (g *generator) growAlternative(r *rule, index int, alt dslbuilder.AlternativeInfo) {
+	g.printf("// %s\n", describe(r.info.Name, alt))
+	g.printf("func (p *parser) alt%s%d(seed interface{}) ([]interface{}, bool) {\n", r.ident, index)
+	g.printf("\tresults := append(make([]interface{}, 0, %d), seed)\n", len(alt.Sequence))
+	g.symbols(alt.Sequence[1:])
+	g.printf("\treturn results, true\n}\n\n")
+}
+
+// symbols writes the matching of a symbol sequence, appending to results.
+// Lookaheads restore the position after matching; the generated parser
+// treats cuts as no-ops, so they only change the errors of the
+// interpreter.
+func (g *generator) symbols(sequence []string) {
+	consuming, testing := false, false
+	for _, symbol := range sequence {
+		if _, _, ok := dslbuilder.LookaheadOf(symbol); ok {
+			testing = true
+		} else if symbol != dslbuilder.Cut {
+			consuming = true
+		}
+	}
+	if consuming {
+		g.printf("\tvar v interface{}\n")
+	}
+	if consuming || testing {
+		g.printf("\tvar ok bool\n")
+	}
+	for _, symbol := range sequence {
+		if symbol == dslbuilder.Cut {
+			continue
+		}
+		if target, negative, ok := dslbuilder.LookaheadOf(symbol); ok {
+			g.printf("\t{\n\t\tmark := p.pos\n\t\t_, ok = %s\n\t\tp.pos = mark\n\t}\n", g.match(target))
+			if negative {
+				g.printf("\tif ok {\n")
+			} else {
+				g.printf("\tif !ok {\n")
+			}
+			g.printf("\t\treturn nil, false\n\t}\n")
+			continue
+		}
+		g.printf("\tif v, ok = %s; !ok {\n", g.match(symbol))
+		g.printf("\t\treturn nil, false\n\t}\n\tresults = append(results, v)\n")
+	}
+}
+
+// match returns the call that matches a token or rule.
+func (g *generator) match(symbol string) string {
+	if t := g.byToken[symbol]; t != nil {
+		return fmt.Sprintf("p.token(tok%s)", t.ident)
+	}
+	r := g.byRule[symbol]
+	return fmt.Sprintf("p.rule(rule%s, (*parser).match%s)", r.ident, r.ident)
+}
+
+// describe returns an alternative in grammar notation for comments.
+func describe(name string, alt dslbuilder.AlternativeInfo) string {
+	sequence := strings.Join(alt.Sequence, " ")
+	if sequence == "" {
+		sequence = "ε"
+	}
+	if alt.Action != "" {
+		sequence += " {" + alt.Action + "}"
+	}
+	return name + " → " + sequence
+}
+
+// identifier converts a grammar name to a Go identifier in camel case,
+// such as "http_request" to "HttpRequest".
+func identifier(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	id := b.String()
+	if id == "" || unicode.IsDigit([]rune(id)[0]) {
+		id = "X" + id
+	}
+	return id
+}
+
+// exported makes an identifier exported, for names whose first letter has
+// no upper case.
+func exported(id string) string {
+	if !unicode.IsUpper([]rune(id)[0]) {
+		return "X" + id
+	}
+	return id
+}
+
+// unique returns id, or id with a number appended if it is already used.
+func unique(id string, used map[string]bool) string {
+	candidate := id
+	for n := 2; used[candidate]; n++ {
+		candidate = id + strconv.Itoa(n)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// packageName derives a package name from the DSL name.
+func packageName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) && b.Len() > 0) {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "parser"
+	}
+	return b.String()
+}
+
+var asciiWord = regexp.MustCompile(`^[0-9A-Za-z_](?:[\x00-\x7f]*[0-9A-Za-z_])?$`)
+
+// isWordKeyword reports whether a keyword can be matched without its
+// regular expression: it is ASCII and starts and ends with a word character,
+// so the \b around it only depends on the next character.
+func isWordKeyword(keyword string) bool {
+	return asciiWord.MatchString(keyword)
+}
+
+// quote returns a Go string literal, raw when possible.
+func quote(s string) string {
+	if !strings.ContainsAny(s, "`\r") && strconv.CanBackquote(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+const lexerTypes = `// Token is a token of the input.
+type Token struct {
+	Type  string // Token name from the grammar
+	Value string // Matched text
+	Start int    // Start position in the input
+	End   int    // End position (exclusive)
+}
+
+type token struct {
+	kind  int
+	value string
+	start int
+}
+
+// Tokenize returns the tokens of input that reach the parser, without
+// skipped tokens such as comments.
+func Tokenize(input string) ([]Token, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Token, len(tokens))
+	for i, t := range tokens {
+		result[i] = Token{Type: tokenNames[t.kind], Value: t.value, Start: t.start, End: t.start + len(t.value)}
+	}
+	return result, nil
+}
+
+// ParseError is a syntax error with its position in the input.
+type ParseError struct {
+	Message  string // Error description
+	Line     int    // 1-based line
+	Column   int    // 1-based column
+	Position int    // Byte offset in the input
+	Token    string // Token text at the error position
+}
+
+func (e *ParseError) Error() string {
+	return e.Message
+}
+
+func newParseError(message string, position int, token string, input string) *ParseError {
+	line, column := 1, 1
+	for i := 0; i < position && i < len(input); i++ {
+		if input[i] == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return &ParseError{Message: message, Line: line, Column: column, Position: position, Token: token}
+}
+
+`
+
+const matchRegexpFunc = `// matchRegexp returns the length of the match of an anchored pattern, or
+// -1 if it does not match.
+func matchRegexp(re *regexp.Regexp, s string) int {
+	if loc := re.FindStringIndex(s); loc != nil {
+		return loc[1]
+	}
+	return -1
+}
+
+`
+
+const matchKeywordFunc = `// matchKeyword matches a keyword case-insensitively at the start of s,
+// followed by a word boundary, and returns its length or -1. Input with
+// non-ASCII letters, which may fold to ASCII ones, goes through re.
+func matchKeyword(s, keyword string, re *regexp.Regexp) int {
+	n := len(keyword)
+	for i := 0; i < n && i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return matchRegexp(re, s)
+		}
+	}
+	if len(s) < n || !strings.EqualFold(s[:n], keyword) {
+		return -1
+	}
+	if n < len(s) && isWordByte(s[n]) {
+		return -1
+	}
+	return n
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+`
+
+const parserTypes = `type memoEntry struct {
+	result interface{}
+	end    int
+	ok     bool
+	set    bool
+}
+
+type parser struct {
+	tokens  []token
+	pos     int
+	actions Actions
+	memo    [numRules][]memoEntry // Outcome of each rule by start token
+	growing [numRules][]bool      // Left-recursive rules being grown by start token
+}
+
+// rule parses rule r with match, remembering the outcome for the position.
+func (p *parser) rule(r int, match func(*parser) (interface{}, bool)) (interface{}, bool) {
+	memo := p.memo[r]
+	if memo == nil {
+		memo = make([]memoEntry, len(p.tokens)+1)
+		p.memo[r] = memo
+	}
+	start := p.pos
+	if e := &memo[start]; e.set {
+		p.pos = e.end
+		return e.result, e.ok
+	}
+	result, ok := match(p)
+	memo[start] = memoEntry{result: result, end: p.pos, ok: ok, set: true}
+	return result, ok
+}
+
+// token consumes a token of the given kind.
+func (p *parser) token(kind int) (interface{}, bool) {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind {
+		p.pos++
+		return p.tokens[p.pos-1].value, true
+	}
+	return nil, false
+}
+
+// grow marks a left-recursive rule as being grown at start. It reports
+// false if it already is, when the rule recursed into itself without
+// consuming input.
+func (p *parser) grow(r, start int) bool {
+	if p.growing[r] == nil {
+		p.growing[r] = make([]bool, len(p.tokens)+1)
+	}
+	if p.growing[r][start] {
+		return false
+	}
+	p.growing[r][start] = true
+	return true
+}
+
+`
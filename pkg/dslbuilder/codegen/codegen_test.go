@@ -0,0 +1,390 @@
+package codegen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCalculator returns a left-recursive grammar with a keyword that also
+// matches the identifier pattern and an action that can fail.
+func newCalculator(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("calculator")
+	require.NoError(t, dsl.KeywordToken("SET", "set"))
+	require.NoError(t, dsl.Token("ID", "[a-zA-Z_]+"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+(\\.[0-9]+)?"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("MINUS", "-"))
+	require.NoError(t, dsl.Token("TIMES", "\\*"))
+	require.NoError(t, dsl.Token("DIVIDE", "/"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+	require.NoError(t, dsl.Token("LPAREN", "\\("))
+	require.NoError(t, dsl.Token("RPAREN", "\\)"))
+
+	dsl.Rule("stmt", []string{"SET", "ID", "ASSIGN", "expr"}, "set")
+	dsl.Rule("stmt", []string{"expr"}, "")
+	dsl.Rule("expr", []string{"expr", "PLUS", "term"}, "add")
+	dsl.Rule("expr", []string{"expr", "MINUS", "term"}, "sub")
+	dsl.Rule("expr", []string{"term"}, "pass")
+	dsl.Rule("term", []string{"term", "TIMES", "factor"}, "mul")
+	dsl.Rule("term", []string{"term", "DIVIDE", "factor"}, "div")
+	dsl.Rule("term", []string{"factor"}, "pass")
+	dsl.Rule("factor", []string{"NUMBER"}, "number")
+	dsl.Rule("factor", []string{"ID"}, "var")
+	dsl.Rule("factor", []string{"MINUS", "factor"}, "neg")
+	dsl.Rule("factor", []string{"LPAREN", "expr", "RPAREN"}, "paren")
+	return dsl
+}
+
+// newConfig returns a grammar with skipped comments, ε alternatives,
+// repetitions and right recursion.
+func newConfig(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("config-file")
+	require.NoError(t, dsl.Token("COMMENT", "#[^\\n]*"))
+	require.NoError(t, dsl.SkipToken("COMMENT"))
+	require.NoError(t, dsl.KeywordToken("SECTION", "section"))
+	require.NoError(t, dsl.KeywordToken("TRUE", "true"))
+	require.NoError(t, dsl.KeywordToken("END", "end"))
+	require.NoError(t, dsl.Token("STRING", "\"[^\"]*\""))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("KEY", "[a-z][a-z0-9_.]*"))
+	require.NoError(t, dsl.Token("EQ", "="))
+	require.NoError(t, dsl.Token("ARROW", "=>"))
+	require.NoError(t, dsl.Token("LBRACKET", "\\["))
+	require.NoError(t, dsl.Token("RBRACKET", "\\]"))
+	require.NoError(t, dsl.Token("COMMA", ","))
+
+	dsl.RuleWithRepetition("file", "section", "collectArgs")
+	dsl.Rule("section", []string{"SECTION", "KEY", "entries", "END"}, "section")
+	dsl.Rule("entries", []string{"entry", "entries"}, "cons")
+	dsl.Rule("entries", []string{}, "")
+	dsl.Rule("entry", []string{"KEY", "EQ", "value"}, "entry")
+	dsl.Rule("entry", []string{"KEY", "ARROW", "KEY"}, "alias")
+	dsl.Rule("value", []string{"STRING"}, "")
+	dsl.Rule("value", []string{"NUMBER"}, "")
+	dsl.Rule("value", []string{"TRUE"}, "")
+	dsl.Rule("value", []string{"LBRACKET", "items", "RBRACKET"}, "list")
+	dsl.RuleWithPlusRepetition("items", "item", "items")
+	dsl.Rule("item", []string{"value", "COMMA"}, "")
+	dsl.Rule("item", []string{"value"}, "")
+	return dsl
+}
+
+// newEntries returns a grammar with negative and positive lookaheads.
+func newEntries(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("entries")
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("COLON", ":"))
+	require.NoError(t, dsl.Token("LPAREN", "\\("))
+	require.NoError(t, dsl.Token("RPAREN", "\\)"))
+	dsl.RuleWithPlusRepetition("entries", "entry", "entries")
+	dsl.Rule("entry", []string{"ID", "COLON", "values"}, "entry")
+	dsl.RuleWithPlusRepetition("values", "value", "values")
+	dsl.Rule("value", []string{"&call", "ID", "LPAREN", "RPAREN"}, "call")
+	dsl.Rule("value", []string{"ID", "!COLON"}, "")
+	dsl.Rule("call", []string{"ID", "LPAREN"}, "")
+	return dsl
+}
+
+var grammars = []struct {
+	name   string
+	new    func(t *testing.T) *dslbuilder.DSL
+	inputs []string
+}{
+	{"calculator", newCalculator, []string{
+		"1 + 2 * 3",
+		"(1 + 2) * 3 - -4",
+		"10 / 2 / 5",
+		"10 / 0",
+		"10 / 0 + 1",
+		"1 + 10 / 0",
+		"set x = 4 * y",
+		"SeT x = 1",
+		"settle + 1",
+		"ſet x = 1",
+		"set = 1",
+		"1 +",
+		"1 2",
+		"(1",
+		")",
+		"",
+		"   ",
+		"1 $ 2",
+		"1 +\n\n  * 2",
+	}},
+	{"config", newConfig, []string{
+		"",
+		"# nothing here",
+		"section db host = \"localhost\" port = 5432 end",
+		"section a end section b x => y tls = true end",
+		"section list hosts = [\"a\", \"b\", 3,] end # trailing comma",
+		"section a x = [] end",
+		"section a x = end",
+		"section a\n  x = 1\n  y => z\nsection b end",
+		"section a x = 1 end extra",
+		"sections a end",
+		"section a x = \"unterminated end",
+	}},
+	{"entries", newEntries, []string{
+		"a: b c d: e",
+		"a: f() g",
+		"a: b:",
+		"a: f(",
+		":",
+		"",
+	}},
+}
+
+// outcome is the result of parsing one input, comparable across the
+// interpreter and the generated parser.
+type outcome struct {
+	Output interface{} `json:"output"`
+	Error  string      `json:"error,omitempty"`
+	Line   int         `json:"line,omitempty"`
+	Column int         `json:"column,omitempty"`
+}
+
+// tag returns an action that records its name and arguments, so outputs
+// show which alternatives matched. The div action fails on division by
+// zero.
+func tag(name string) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if name == "div" && args[2] == "0" {
+			return nil, errors.New("division by zero")
+		}
+		return map[string]interface{}{"action": name, "args": args}, nil
+	}
+}
+
+func interpret(t *testing.T, dsl *dslbuilder.DSL, inputs []string) []outcome {
+	for _, name := range dsl.UnboundActions() {
+		dsl.Action(name, tag(name))
+	}
+	var outcomes []outcome
+	for _, input := range inputs {
+		var o outcome
+		result, err := dsl.Parse(input)
+		if err != nil {
+			o.Error = err.Error()
+			var perr *dslbuilder.ParseError
+			if errors.As(err, &perr) {
+				o.Line, o.Column = perr.Line, perr.Column
+			}
+		} else {
+			o.Output = result.GetOutput()
+		}
+		outcomes = append(outcomes, o)
+	}
+	return normalize(t, outcomes)
+}
+
+// normalize round-trips outcomes through JSON like the driver output.
+func normalize(t *testing.T, outcomes []outcome) []outcome {
+	data, err := json.Marshal(outcomes)
+	require.NoError(t, err)
+	var result []outcome
+	require.NoError(t, json.Unmarshal(data, &result))
+	return result
+}
+
+const driver = `package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"gentest/%s"
+)
+
+type outcome struct {
+	Output interface{} ` + "`json:\"output\"`" + `
+	Error  string      ` + "`json:\"error,omitempty\"`" + `
+	Line   int         ` + "`json:\"line,omitempty\"`" + `
+	Column int         ` + "`json:\"column,omitempty\"`" + `
+}
+
+func tag(name string) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if name == "div" && args[2] == "0" {
+			return nil, errors.New("division by zero")
+		}
+		return map[string]interface{}{"action": name, "args": args}, nil
+	}
+}
+
+func main() {
+	var inputs []string
+	data, _ := os.ReadFile(os.Args[1])
+	if err := json.Unmarshal(data, &inputs); err != nil {
+		panic(err)
+	}
+	actions := %[1]s.ActionFuncs{}
+	for _, name := range %#[2]v {
+		actions[name] = tag(name)
+	}
+	var outcomes []outcome
+	for _, input := range inputs {
+		var o outcome
+		result, err := %[1]s.Parse(input, actions)
+		if err != nil {
+			o.Error = err.Error()
+			var perr *%[1]s.ParseError
+			if errors.As(err, &perr) {
+				o.Line, o.Column = perr.Line, perr.Column
+			}
+		} else {
+			o.Output = result
+		}
+		outcomes = append(outcomes, o)
+	}
+	json.NewEncoder(os.Stdout).Encode(outcomes)
+}
+`
+
+// runGenerated generates a package for the DSL in a temporary module and
+// parses the inputs with it.
+func runGenerated(t *testing.T, dsl *dslbuilder.DSL, pkg string, inputs []string) []outcome {
+	code, err := Generate(dsl, Options{Package: pkg})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, pkg), 0755))
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("go.mod", "module gentest\n\ngo 1.21\n")
+	write(filepath.Join(pkg, pkg+".go"), string(code))
+	write("main.go", fmt.Sprintf(driver, pkg, dsl.RequiredActions()))
+	data, err := json.Marshal(inputs)
+	require.NoError(t, err)
+	write("inputs.json", string(data))
+
+	cmd := exec.Command("go", "run", ".", "inputs.json")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			t.Fatalf("running generated parser: %v\n%s", err, exitErr.Stderr)
+		}
+		t.Fatal(err)
+	}
+
+	var outcomes []outcome
+	require.NoError(t, json.Unmarshal(out, &outcomes))
+	return outcomes
+}
+
+// TestGeneratedParserMatchesInterpreter parses the same inputs, including
+// random sentences and their prefixes, with DSL.Parse and with the
+// generated parser and compares results and errors.
+func TestGeneratedParserMatchesInterpreter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds generated code")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+
+	for _, g := range grammars {
+		t.Run(g.name, func(t *testing.T) {
+			inputs := append([]string(nil), g.inputs...)
+			sentences := gen.New(g.new(t).Grammar(), gen.Options{Seed: 1, MaxDepth: 5})
+			for i := 0; i < 40; i++ {
+				sentence, err := sentences.Generate()
+				require.NoError(t, err)
+				inputs = append(inputs, sentence, sentence[:len(sentence)/2])
+			}
+
+			want := interpret(t, g.new(t), inputs)
+			got := runGenerated(t, g.new(t), g.name, inputs)
+			require.Len(t, got, len(inputs))
+			for i, input := range inputs {
+				assert.Equal(t, want[i], got[i], "input %q", input)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	dsl := dslbuilder.New("empty")
+	require.NoError(t, dsl.Token("A", "a"))
+	_, err := Generate(dsl, Options{})
+	assert.EqualError(t, err, "grammar empty has no rules")
+
+	dsl.Rule("start", []string{"A", "missing"}, "")
+	_, err = Generate(dsl, Options{})
+	assert.EqualError(t, err, "rule start: undefined symbol missing")
+
+	dsl = dslbuilder.New("blobs")
+	dsl.ExternalToken("BLOB")
+	dsl.Rule("start", []string{"BLOB"}, "")
+	_, err = Generate(dsl, Options{})
+	assert.EqualError(t, err, "token BLOB has no pattern")
+	dsl.SetLexer(dslbuilder.NewRegexLexer(dsl.Grammar()))
+	_, err = Generate(dsl, Options{})
+	assert.EqualError(t, err, "grammar blobs uses a custom lexer")
+}
+
+func TestGenerateNames(t *testing.T) {
+	dsl := newConfig(t)
+	code, err := Generate(dsl, Options{Source: "config.yaml"})
+	require.NoError(t, err)
+	src := string(code)
+
+	assert.True(t, strings.HasPrefix(src, "// Code generated by dslgen from config.yaml. DO NOT EDIT.\n"))
+	assert.Contains(t, src, "\npackage configfile\n")
+	assert.Contains(t, src, "\tCollectArgsEmpty(args []interface{}) (interface{}, error)")
+	assert.Contains(t, src, "return a.call(\"items_single\", args)")
+	assert.NotContains(t, src, "dslbuilder")
+
+	code, err = Generate(dsl, Options{Package: "cfg"})
+	require.NoError(t, err)
+	assert.Contains(t, string(code), "\npackage cfg\n")
+}
+
+func TestRun(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "calc", "parser.go")
+	require.NoError(t, os.MkdirAll(filepath.Dir(output), 0755))
+	require.NoError(t, Run(newCalculator(t), output, Options{Source: "calc.yaml"}))
+
+	code, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(code), "// Code generated by dslgen from calc.yaml. DO NOT EDIT.\n"))
+	assert.Contains(t, string(code), "\npackage calc\n")
+
+	err = Run(dslbuilder.New("empty"), output, Options{})
+	assert.EqualError(t, err, "grammar empty has no rules")
+}
+
+func TestIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"expr":              "Expr",
+		"collectArgs_empty": "CollectArgsEmpty",
+		"http-request":      "HttpRequest",
+		"NUMBER":            "NUMBER",
+		"2nd":               "X2nd",
+		"año":               "Año",
+		"_":                 "X",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, identifier(name), name)
+	}
+
+	used := map[string]bool{}
+	assert.Equal(t, "Add", unique("Add", used))
+	assert.Equal(t, "Add2", unique("Add", used))
+	assert.Equal(t, "Add3", unique("Add", used))
+}
@@ -0,0 +1,238 @@
+// Package coverage reports which parts of a grammar a test suite exercises.
+// Enable recording with DSL.EnableCoverage, parse your test inputs, then
+// build a Report to see unexercised rules, alternatives and tokens as text,
+// JSON or HTML. The covtest package fails a test when coverage drops below
+// a threshold.
+//
+// Example:
+//
+//	dsl.EnableCoverage()
+//	for _, input := range inputs {
+//	    dsl.Parse(input)
+//	}
+//	report := coverage.NewReport(dsl)
+//	fmt.Printf("%.1f%% of alternatives exercised\n", report.Percent())
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// Report is a snapshot of grammar coverage for one DSL.
+type Report struct {
+	DSL    string          `json:"dsl"`
+	Rules  []RuleReport    `json:"rules"`
+	Tokens []TokenReport   `json:"tokens"`
+	Totals map[string]Stat `json:"totals"` // "rules", "alternatives" and "tokens"
+}
+
+// RuleReport holds coverage for one rule.
+type RuleReport struct {
+	Name         string              `json:"name"`
+	Hits         int                 `json:"hits"`
+	Alternatives []AlternativeReport `json:"alternatives"`
+}
+
+// AlternativeReport holds coverage for one alternative of a rule.
+type AlternativeReport struct {
+	Index    int      `json:"index"`
+	Sequence []string `json:"sequence"`
+	Action   string   `json:"action,omitempty"`
+	Hits     int      `json:"hits"`
+}
+
+// TokenReport holds coverage for one token.
+type TokenReport struct {
+	Name string `json:"name"`
+	Hits int    `json:"hits"`
+}
+
+// Stat counts covered elements out of a total.
+type Stat struct {
+	Covered int `json:"covered"`
+	Total   int `json:"total"`
+}
+
+// Percent returns the covered percentage, or 100 when there is nothing to cover.
+func (s Stat) Percent() float64 {
+	if s.Total == 0 {
+		return 100
+	}
+	return float64(s.Covered) * 100 / float64(s.Total)
+}
+
+// NewReport builds a report from the DSL grammar and its recorded coverage.
+// Elements are listed in grammar definition order. If coverage was never
+// enabled, everything is reported as unexercised.
+func NewReport(dsl *dslbuilder.DSL) *Report {
+	cov := dsl.Coverage()
+	if cov == nil {
+		cov = dslbuilder.NewCoverage()
+	}
+
+	report := &Report{DSL: dsl.Name(), Totals: make(map[string]Stat)}
+	var rules, alternatives, tokens Stat
+
+	for _, rule := range dsl.Rules() {
+		hits := cov.AlternativeHits(rule.Name)
+		rr := RuleReport{Name: rule.Name, Hits: cov.RuleHits(rule.Name)}
+		for i, alt := range rule.Alternatives {
+			ar := AlternativeReport{Index: i, Sequence: alt.Sequence, Action: alt.Action}
+			if i < len(hits) {
+				ar.Hits = hits[i]
+			}
+			rr.Alternatives = append(rr.Alternatives, ar)
+			alternatives.Total++
+			if ar.Hits > 0 {
+				alternatives.Covered++
+			}
+		}
+		report.Rules = append(report.Rules, rr)
+		rules.Total++
+		if rr.Hits > 0 {
+			rules.Covered++
+		}
+	}
+
+	for _, token := range dsl.Tokens() {
+		tr := TokenReport{Name: token.Name, Hits: cov.TokenHits(token.Name)}
+		report.Tokens = append(report.Tokens, tr)
+		tokens.Total++
+		if tr.Hits > 0 {
+			tokens.Covered++
+		}
+	}
+
+	report.Totals["rules"] = rules
+	report.Totals["alternatives"] = alternatives
+	report.Totals["tokens"] = tokens
+	return report
+}
+
+// Percent returns the alternative coverage percentage, the headline metric
+// used by Require.
+func (r *Report) Percent() float64 {
+	return r.Totals["alternatives"].Percent()
+}
+
+// Uncovered returns a description of every unexercised alternative and token,
+// such as "expr[1]: expr MINUS term {subtract}" or "token MINUS".
+func (r *Report) Uncovered() []string {
+	missing := []string{}
+	for _, rule := range r.Rules {
+		for _, alt := range rule.Alternatives {
+			if alt.Hits == 0 {
+				missing = append(missing, fmt.Sprintf("%s[%d]: %s", rule.Name, alt.Index, describe(alt)))
+			}
+		}
+	}
+	for _, token := range r.Tokens {
+		if token.Hits == 0 {
+			missing = append(missing, "token "+token.Name)
+		}
+	}
+	return missing
+}
+
+// WriteText writes a human readable report. Unexercised alternatives and
+// tokens are marked with "✗".
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Grammar coverage for %s\n", r.DSL)
+	for _, kind := range []string{"rules", "alternatives", "tokens"} {
+		stat := r.Totals[kind]
+		fmt.Fprintf(&b, "  %-13s %5.1f%% (%d/%d)\n", kind+":", stat.Percent(), stat.Covered, stat.Total)
+	}
+
+	b.WriteString("\nRules:\n")
+	for _, rule := range r.Rules {
+		fmt.Fprintf(&b, "  %s (%d hits)\n", rule.Name, rule.Hits)
+		for _, alt := range rule.Alternatives {
+			fmt.Fprintf(&b, "    %s %-40s %d\n", mark(alt.Hits), describe(alt), alt.Hits)
+		}
+	}
+
+	b.WriteString("\nTokens:\n")
+	for _, token := range r.Tokens {
+		fmt.Fprintf(&b, "  %s %-20s %d\n", mark(token.Hits), token.Name, token.Hits)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteHTML writes a self-contained HTML page highlighting unexercised
+// alternatives and tokens.
+func (r *Report) WriteHTML(w io.Writer) error {
+	var b strings.Builder
+	title := html.EscapeString("Grammar coverage for " + r.DSL)
+
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n", title, htmlStyle)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<ul>\n", title)
+	for _, kind := range []string{"rules", "alternatives", "tokens"} {
+		stat := r.Totals[kind]
+		fmt.Fprintf(&b, "<li>%s: %.1f%% (%d/%d)</li>\n", kind, stat.Percent(), stat.Covered, stat.Total)
+	}
+	b.WriteString("</ul>\n<h2>Rules</h2>\n<table>\n<tr><th>Rule</th><th>Alternative</th><th>Hits</th></tr>\n")
+	for _, rule := range r.Rules {
+		for _, alt := range rule.Alternatives {
+			fmt.Fprintf(&b, "<tr class=\"%s\"><td>%s</td><td><code>%s</code></td><td>%d</td></tr>\n",
+				class(alt.Hits), html.EscapeString(rule.Name), html.EscapeString(describe(alt)), alt.Hits)
+		}
+	}
+	b.WriteString("</table>\n<h2>Tokens</h2>\n<table>\n<tr><th>Token</th><th>Hits</th></tr>\n")
+	for _, token := range r.Tokens {
+		fmt.Fprintf(&b, "<tr class=\"%s\"><td><code>%s</code></td><td>%d</td></tr>\n",
+			class(token.Hits), html.EscapeString(token.Name), token.Hits)
+	}
+	b.WriteString("</table>\n</body>\n</html>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func describe(alt AlternativeReport) string {
+	sequence := strings.Join(alt.Sequence, " ")
+	if sequence == "" {
+		sequence = "ε"
+	}
+	if alt.Action != "" {
+		sequence += " {" + alt.Action + "}"
+	}
+	return sequence
+}
+
+func mark(hits int) string {
+	if hits > 0 {
+		return "✓"
+	}
+	return "✗"
+}
+
+func class(hits int) string {
+	if hits > 0 {
+		return "hit"
+	}
+	return "miss"
+}
+
+const htmlStyle = `body{font-family:sans-serif;max-width:960px;margin:2em auto;padding:0 1em;color:#222}` +
+	`table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:4px 8px;text-align:left}` +
+	`tr.hit{background:#e8f4e8}tr.miss{background:#fbe3e3}`
@@ -0,0 +1,79 @@
+package coverage
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCalculator(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("calculator")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("MINUS", "-"))
+
+	dsl.Rule("expr", []string{"expr", "PLUS", "NUMBER"}, "add")
+	dsl.Rule("expr", []string{"expr", "MINUS", "NUMBER"}, "sub")
+	dsl.Rule("expr", []string{"NUMBER"}, "number")
+
+	for _, name := range []string{"add", "sub", "number"} {
+		dsl.Action(name, func(args []interface{}) (interface{}, error) { return args[0], nil })
+	}
+	dsl.EnableCoverage()
+	return dsl
+}
+
+func TestReport(t *testing.T) {
+	dsl := newCalculator(t)
+	_, err := dsl.Parse("1 + 2")
+	require.NoError(t, err)
+
+	report := NewReport(dsl)
+	assert.Equal(t, "calculator", report.DSL)
+	assert.Equal(t, Stat{Covered: 1, Total: 1}, report.Totals["rules"])
+	assert.Equal(t, Stat{Covered: 2, Total: 3}, report.Totals["alternatives"])
+	assert.Equal(t, Stat{Covered: 2, Total: 3}, report.Totals["tokens"])
+	assert.InDelta(t, 66.6, report.Percent(), 0.1)
+
+	assert.Equal(t, []string{
+		"expr[1]: expr MINUS NUMBER {sub}",
+		"token MINUS",
+	}, report.Uncovered())
+}
+
+func TestReportWithoutCoverage(t *testing.T) {
+	dsl := newCalculator(t)
+	dsl.DisableCoverage()
+
+	report := NewReport(dsl)
+	assert.Equal(t, 0.0, report.Percent())
+	assert.Len(t, report.Uncovered(), 6)
+}
+
+func TestWriters(t *testing.T) {
+	dsl := newCalculator(t)
+	_, err := dsl.Parse("1 + 2")
+	require.NoError(t, err)
+	report := NewReport(dsl)
+
+	var text bytes.Buffer
+	require.NoError(t, report.WriteText(&text))
+	assert.Contains(t, text.String(), "alternatives:  66.7% (2/3)")
+	assert.Contains(t, text.String(), "✗ expr MINUS NUMBER {sub}")
+
+	var out bytes.Buffer
+	require.NoError(t, report.WriteJSON(&out))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, report.Totals, decoded.Totals)
+
+	var page bytes.Buffer
+	require.NoError(t, report.WriteHTML(&page))
+	assert.True(t, strings.HasPrefix(page.String(), "<!DOCTYPE html>"))
+	assert.Contains(t, page.String(), `<tr class="miss"><td>expr</td>`)
+}
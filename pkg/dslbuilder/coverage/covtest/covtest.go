@@ -0,0 +1,34 @@
+// Package covtest fails tests whose grammar coverage drops below a
+// threshold. It is kept apart from coverage so tools that render coverage
+// reports do not link the testing package.
+//
+// Example:
+//
+//	func TestHTTPDSL(t *testing.T) {
+//	    dsl := newHTTPDSL()
+//	    dsl.EnableCoverage()
+//	    for _, input := range inputs {
+//	        dsl.Parse(input)
+//	    }
+//	    covtest.Require(t, dsl, 80)
+//	}
+package covtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/coverage"
+)
+
+// Require fails the test when alternative coverage is below threshold
+// (a percentage between 0 and 100), listing what was not exercised.
+func Require(t testing.TB, dsl *dslbuilder.DSL, threshold float64) {
+	t.Helper()
+	report := coverage.NewReport(dsl)
+	if percent := report.Percent(); percent < threshold {
+		t.Errorf("grammar coverage %.1f%% is below %.1f%%; unexercised:\n  %s",
+			percent, threshold, strings.Join(report.Uncovered(), "\n  "))
+	}
+}
@@ -0,0 +1,57 @@
+package covtest
+
+import (
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCalculator(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("calculator")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("MINUS", "-"))
+
+	dsl.Rule("expr", []string{"expr", "PLUS", "NUMBER"}, "add")
+	dsl.Rule("expr", []string{"expr", "MINUS", "NUMBER"}, "sub")
+	dsl.Rule("expr", []string{"NUMBER"}, "number")
+
+	for _, name := range []string{"add", "sub", "number"} {
+		dsl.Action(name, func(args []interface{}) (interface{}, error) { return args[0], nil })
+	}
+	dsl.EnableCoverage()
+	return dsl
+}
+
+// recorder captures failures reported by Require.
+type recorder struct {
+	testing.TB
+	failed  bool
+	message string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failed = true
+	r.message = format
+}
+
+func TestRequire(t *testing.T) {
+	dsl := newCalculator(t)
+	_, err := dsl.Parse("1 + 2")
+	require.NoError(t, err)
+
+	rec := &recorder{TB: t}
+	Require(rec, dsl, 50)
+	assert.False(t, rec.failed)
+
+	Require(rec, dsl, 80)
+	assert.True(t, rec.failed)
+
+	_, err = dsl.Parse("3 - 4")
+	require.NoError(t, err)
+	Require(t, dsl, 100)
+}
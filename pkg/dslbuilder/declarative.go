@@ -8,6 +8,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	yaml "gopkg.in/yaml.v3"
@@ -24,6 +26,12 @@ import (
 //	  NUMBER: "[0-9]+"
 //	  PLUS: "\\+"
 //	  TIMES: "\\*"
+//	  COMMENT: "#[^\\n]*"
+//	skip_tokens: [COMMENT]
+//	token_categories:
+//	  NUMBER: number
+//	  PLUS: operator
+//	  TIMES: operator
 //	rules:
 //	  - name: expr
 //	    pattern: [expr, PLUS, expr]
@@ -34,10 +42,14 @@ import (
 //	context:
 //	  debug: true
 type DSLConfig struct {
-	Name    string                 `yaml:"name" json:"name"`                           // DSL identifier
-	Tokens  map[string]string      `yaml:"tokens" json:"tokens"`                       // Token definitions
-	Rules   []RuleConfig           `yaml:"rules" json:"rules"`                         // Grammar rules
-	Context map[string]interface{} `yaml:"context,omitempty" json:"context,omitempty"` // Runtime context
+	Name            string                   `yaml:"name" json:"name"`                                             // DSL identifier
+	Tokens          map[string]string        `yaml:"tokens" json:"tokens"`                                         // Token definitions
+	TokenCategories map[string]TokenCategory `yaml:"token_categories,omitempty" json:"token_categories,omitempty"` // Highlighting categories by token name
+	ExternalTokens  []string                 `yaml:"external_tokens,omitempty" json:"external_tokens,omitempty"`   // Tokens only a custom lexer produces (ExternalToken)
+	SkipTokens      []string                 `yaml:"skip_tokens,omitempty" json:"skip_tokens,omitempty"`           // Tokens the parser skips, such as comments
+	Rules           []RuleConfig             `yaml:"rules" json:"rules"`                                           // Grammar rules
+	BuiltinActions  bool                     `yaml:"builtin_actions,omitempty" json:"builtin_actions,omitempty"`   // Resolve actions with the built-in library (UseBuiltinActions)
+	Context         map[string]interface{}   `yaml:"context,omitempty" json:"context,omitempty"`                   // Runtime context
 }
 
 // RuleConfig represents a rule in the declarative configuration.
@@ -46,20 +58,25 @@ type DSLConfig struct {
 // Fields:
 //   - Name: Rule identifier (can have multiple rules with same name)
 //   - Pattern: Sequence of tokens/rules to match
-//   - Action: Name of the action function to execute
+//   - Action: Name of the action function to execute, or a built-in
+//     action expression when builtin_actions is set
+//   - Predicate: Name of a predicate that must accept the match (optional)
 //
 // Multiple RuleConfig entries with the same Name create alternatives.
 type RuleConfig struct {
-	Name    string   `yaml:"name" json:"name"`       // Rule identifier
-	Pattern []string `yaml:"pattern" json:"pattern"` // Symbol sequence
-	Action  string   `yaml:"action" json:"action"`   // Action name
+	Name      string   `yaml:"name" json:"name"`                               // Rule identifier
+	Pattern   []string `yaml:"pattern" json:"pattern"`                         // Symbol sequence
+	Action    string   `yaml:"action" json:"action"`                           // Action name
+	Predicate string   `yaml:"predicate,omitempty" json:"predicate,omitempty"` // Predicate name
 }
 
 // LoadFromYAML creates a DSL from a YAML configuration.
 // This allows defining grammars in YAML files for better maintainability.
 //
 // The YAML data should conform to the DSLConfig structure.
-// Actions must be registered separately after loading.
+// Actions must be registered separately after loading, unless the
+// configuration sets builtin_actions: true and writes its actions as
+// built-in expressions such as add($1, $3) (see UseBuiltinActions).
 //
 // Example:
 //
@@ -101,7 +118,8 @@ func LoadFromYAMLFile(filename string) (*DSL, error) {
 // Similar to LoadFromYAML but uses JSON format.
 //
 // The JSON data should conform to the DSLConfig structure.
-// Actions must be registered separately after loading.
+// Actions must be registered separately after loading, unless the
+// configuration sets builtin_actions (see LoadFromYAML).
 //
 // Example JSON:
 //
@@ -146,6 +164,42 @@ func LoadFromJSONFile(filename string) (*DSL, error) {
 	return LoadFromJSON(data)
 }
 
+// LoadFromFile creates a DSL from a YAML (.yaml, .yml) or JSON (.json)
+// file, chosen by the file extension. Command-line tools use it to load
+// the grammar named by their -dsl flag.
+//
+// Example:
+//
+//	dsl, err := LoadFromFile("grammar/calculator.yaml")
+//	if err != nil {
+//	    return err
+//	}
+func LoadFromFile(filename string) (*DSL, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch ext {
+	case ".yaml", ".yml":
+		return LoadFromYAMLFile(filename)
+	case ".json":
+		return LoadFromJSONFile(filename)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", strings.TrimPrefix(ext, "."))
+	}
+}
+
+// LoadFromConfig creates a DSL from an in-memory configuration.
+// It is useful for tools that build or transform a DSLConfig before loading it.
+//
+// Example:
+//
+//	dsl, err := LoadFromConfig(DSLConfig{
+//	    Name:   "greeting",
+//	    Tokens: map[string]string{"HELLO": "hello"},
+//	    Rules:  []RuleConfig{{Name: "start", Pattern: []string{"HELLO"}}},
+//	})
+func LoadFromConfig(config DSLConfig) (*DSL, error) {
+	return createDSLFromConfig(config)
+}
+
 // createDSLFromConfig creates a DSL instance from a configuration.
 // This is the core function that transforms declarative configuration
 // into a working DSL instance.
@@ -153,8 +207,9 @@ func LoadFromJSONFile(filename string) (*DSL, error) {
 // Process:
 //  1. Create new DSL with the specified name
 //  2. Add all tokens (detects keywords automatically)
-//  3. Add all rules in order
-//  4. Set context values if provided
+//  3. Mark skipped tokens and set token categories
+//  4. Add all rules in order
+//  5. Set context values if provided
 //
 // The function intelligently detects keyword tokens that were saved
 // with word boundary patterns and extracts the actual keyword.
@@ -162,8 +217,15 @@ func createDSLFromConfig(config DSLConfig) (*DSL, error) {
 	// Create DSL instance
 	dsl := New(config.Name)
 
-	// Add tokens
-	for name, pattern := range config.Tokens {
+	// Add tokens in name order so token introspection is stable across loads
+	names := make([]string, 0, len(config.Tokens))
+	for name := range config.Tokens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pattern := config.Tokens[name]
 		// Check if this is a keyword token saved with word boundaries
 		if isKeywordTokenPattern(pattern) {
 			// Extract the actual keyword from the pattern
@@ -183,9 +245,34 @@ func createDSLFromConfig(config DSLConfig) (*DSL, error) {
 		}
 	}
 
+	dsl.UseBuiltinActions(config.BuiltinActions)
+
+	for _, name := range config.ExternalTokens {
+		dsl.ExternalToken(name)
+	}
+
+	for _, name := range config.SkipTokens {
+		if err := dsl.SkipToken(name); err != nil {
+			return nil, fmt.Errorf("failed to skip token: %w", err)
+		}
+	}
+
+	// Set token categories, in name order so errors are deterministic
+	names = names[:0]
+	for name := range config.TokenCategories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := dsl.SetTokenCategory(name, config.TokenCategories[name]); err != nil {
+			return nil, fmt.Errorf("failed to set token category: %w", err)
+		}
+	}
+
 	// Add rules
 	for _, rule := range config.Rules {
-		dsl.Rule(rule.Name, rule.Pattern, rule.Action)
+		dsl.RuleWithPredicate(rule.Name, rule.Pattern, rule.Action, rule.Predicate)
 	}
 
 	// Set context
@@ -331,6 +418,7 @@ func (d *DSL) SaveToJSONFile(filename string) error {
 // The configuration includes:
 //   - DSL name
 //   - All token definitions with their patterns
+//   - External tokens, skipped tokens and token categories
 //   - All rules with their alternatives
 //   - Context variables
 //
@@ -338,24 +426,42 @@ func (d *DSL) SaveToJSONFile(filename string) error {
 // They must be re-registered when loading the configuration.
 func (d *DSL) toConfig() DSLConfig {
 	config := DSLConfig{
-		Name:    d.name,
-		Tokens:  make(map[string]string),
-		Rules:   []RuleConfig{},
-		Context: d.context,
+		Name:           d.name,
+		Tokens:         make(map[string]string),
+		Rules:          []RuleConfig{},
+		BuiltinActions: d.UsesBuiltinActions(),
+		Context:        d.context,
 	}
 
 	// Export tokens
 	for name, token := range d.grammar.tokens {
-		config.Tokens[name] = token.pattern
+		if token.regex == nil {
+			config.ExternalTokens = append(config.ExternalTokens, name)
+		} else {
+			config.Tokens[name] = token.pattern
+		}
+		if token.category != CategoryNone {
+			if config.TokenCategories == nil {
+				config.TokenCategories = make(map[string]TokenCategory)
+			}
+			config.TokenCategories[name] = token.category
+		}
+		if token.skip {
+			config.SkipTokens = append(config.SkipTokens, name)
+		}
 	}
+	sort.Strings(config.ExternalTokens)
+	sort.Strings(config.SkipTokens)
 
-	// Export rules
-	for name, rule := range d.grammar.rules {
+	// Export rules in definition order so the start rule stays first
+	for _, name := range d.grammar.ruleOrder {
+		rule := d.grammar.rules[name]
 		for _, alt := range rule.alternatives {
 			config.Rules = append(config.Rules, RuleConfig{
-				Name:    name,
-				Pattern: alt.sequence,
-				Action:  alt.action,
+				Name:      name,
+				Pattern:   alt.sequence,
+				Action:    alt.action,
+				Predicate: alt.predicate,
 			})
 		}
 	}
@@ -64,6 +64,11 @@ func TestYAMLOperations(t *testing.T) {
 	fileDSL, err := LoadFromYAMLFile(yamlFile)
 	assert.NoError(t, err)
 	assert.Equal(t, "YAMLTest", fileDSL.name)
+
+	// Test LoadFromFile
+	fileDSL, err = LoadFromFile(yamlFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "YAMLTest", fileDSL.name)
 }
 
 // Test JSON loading and saving
@@ -121,6 +126,11 @@ func TestJSONOperations(t *testing.T) {
 	fileDSL, err := LoadFromJSONFile(jsonFile)
 	assert.NoError(t, err)
 	assert.Equal(t, "JSONTest", fileDSL.name)
+
+	// Test LoadFromFile
+	fileDSL, err = LoadFromFile(jsonFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "JSONTest", fileDSL.name)
 }
 
 // Test complex declarative configurations
@@ -228,6 +238,9 @@ func TestDeclarativeErrorHandling(t *testing.T) {
 	_, err = LoadFromJSONFile("/non/existent/file.json")
 	assert.Error(t, err)
 
+	_, err = LoadFromFile("grammar.toml")
+	assert.EqualError(t, err, "unsupported file format: toml")
+
 	// Test invalid token pattern in config
 	config := DSLConfig{
 		Name: "ErrorDSL",
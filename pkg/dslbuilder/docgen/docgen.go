@@ -0,0 +1,225 @@
+// Package docgen generates reference documentation for go-dsl grammars.
+// It renders railroad diagrams (SVG) for every rule, a self-contained HTML
+// page, and a Markdown reference listing tokens, rules and example sentences
+// derived from the grammar itself, so documentation never drifts from the
+// grammar it describes.
+//
+// Example:
+//
+//	gen := docgen.New(dsl, docgen.Options{})
+//	os.WriteFile("reference.md", []byte(gen.Markdown()), 0644)
+//	os.WriteFile("reference.html", []byte(gen.HTML()), 0644)
+package docgen
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/gen"
+)
+
+// Options controls the generated documentation.
+type Options struct {
+	Title    string // Document title (defaults to the DSL name)
+	Examples int    // Maximum example sentences per rule (defaults to 3, negative disables)
+}
+
+// Generator produces documentation for one DSL.
+type Generator struct {
+	dsl      *dslbuilder.DSL
+	opts     Options
+	examples *gen.Generator
+}
+
+// New creates a documentation generator for a DSL.
+func New(dsl *dslbuilder.DSL, opts Options) *Generator {
+	if opts.Title == "" {
+		opts.Title = dsl.Name()
+	}
+	if opts.Examples == 0 {
+		opts.Examples = 3
+	}
+	return &Generator{
+		dsl:      dsl,
+		opts:     opts,
+		examples: gen.New(dsl.Grammar(), gen.Options{}),
+	}
+}
+
+// NewFromConfig creates a documentation generator from a declarative
+// configuration, without registering any actions.
+func NewFromConfig(config dslbuilder.DSLConfig, opts Options) (*Generator, error) {
+	dsl, err := dslbuilder.LoadFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return New(dsl, opts), nil
+}
+
+// RuleSVG returns a self-contained SVG railroad diagram for a rule.
+func (g *Generator) RuleSVG(name string) (string, error) {
+	for _, rule := range g.dsl.Rules() {
+		if rule.Name == name {
+			return renderSVG(buildDiagram(g.dsl.Grammar(), rule)), nil
+		}
+	}
+	return "", fmt.Errorf("rule %s not found", name)
+}
+
+// Examples returns example sentences for a rule, one per alternative
+// up to Options.Examples. Each sentence is the shortest derivation of its
+// alternative, so examples stay small and deterministic between runs.
+func (g *Generator) Examples(rule string) []string {
+	if g.opts.Examples < 0 {
+		return nil
+	}
+
+	var info dslbuilder.RuleInfo
+	found := false
+	for _, r := range g.dsl.Rules() {
+		if r.Name == rule {
+			info, found = r, true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	sentences := []string{}
+	seen := make(map[string]bool)
+	for i := range info.Alternatives {
+		if len(sentences) >= g.opts.Examples {
+			break
+		}
+		sentence, ok := g.examples.Shortest(rule, i)
+		if !ok || seen[sentence] {
+			continue
+		}
+		seen[sentence] = true
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
+
+// Markdown returns a Markdown reference with a token table, every rule in
+// EBNF-like notation and example sentences.
+func (g *Generator) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", g.opts.Title)
+	fmt.Fprintf(&b, "Start rule: `%s`\n\n", g.dsl.StartRule())
+
+	b.WriteString("## Tokens\n\n")
+	b.WriteString("| Token | Kind | Pattern |\n")
+	b.WriteString("|-------|------|---------|\n")
+	for _, token := range g.dsl.Tokens() {
+		kind := "regex"
+		pattern := token.Pattern
+		if token.IsKeyword() {
+			kind = "keyword"
+			pattern = token.Keyword
+		} else if token.External {
+			kind = "external"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | `%s` |\n", token.Name, kind, markdownCell(pattern))
+	}
+
+	b.WriteString("\n## Rules\n")
+	for _, rule := range g.dsl.Rules() {
+		fmt.Fprintf(&b, "\n### %s\n\n", rule.Name)
+		b.WriteString("```\n")
+		b.WriteString(ebnf(rule))
+		b.WriteString("```\n")
+
+		if sentences := g.Examples(rule.Name); len(sentences) > 0 {
+			b.WriteString("\nExamples:\n\n")
+			for _, sentence := range sentences {
+				fmt.Fprintf(&b, "- `%s`\n", sentence)
+			}
+		}
+	}
+	return b.String()
+}
+
+// HTML returns a self-contained HTML page with a railroad diagram,
+// EBNF notation and examples for every rule, followed by the token table.
+func (g *Generator) HTML() string {
+	var b strings.Builder
+	title := html.EscapeString(g.opts.Title)
+
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", title)
+	b.WriteString("<style>" + pageStyle + "</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", title)
+	fmt.Fprintf(&b, "<p>Start rule: <a href=\"#rule-%[1]s\"><code>%[1]s</code></a></p>\n",
+		html.EscapeString(g.dsl.StartRule()))
+
+	b.WriteString("<h2>Rules</h2>\n")
+	for _, rule := range g.dsl.Rules() {
+		name := html.EscapeString(rule.Name)
+		fmt.Fprintf(&b, "<section id=\"rule-%s\">\n<h3>%s</h3>\n", name, name)
+		b.WriteString(renderSVG(buildDiagram(g.dsl.Grammar(), rule)))
+		fmt.Fprintf(&b, "\n<pre>%s</pre>\n", html.EscapeString(ebnf(rule)))
+		if sentences := g.Examples(rule.Name); len(sentences) > 0 {
+			b.WriteString("<ul class=\"examples\">\n")
+			for _, sentence := range sentences {
+				fmt.Fprintf(&b, "<li><code>%s</code></li>\n", html.EscapeString(sentence))
+			}
+			b.WriteString("</ul>\n")
+		}
+		b.WriteString("</section>\n")
+	}
+
+	b.WriteString("<h2>Tokens</h2>\n<table>\n<tr><th>Token</th><th>Kind</th><th>Pattern</th></tr>\n")
+	for _, token := range g.dsl.Tokens() {
+		kind := "regex"
+		pattern := token.Pattern
+		if token.IsKeyword() {
+			kind = "keyword"
+			pattern = token.Keyword
+		} else if token.External {
+			kind = "external"
+		}
+		fmt.Fprintf(&b, "<tr><td><code>%s</code></td><td>%s</td><td><code>%s</code></td></tr>\n",
+			html.EscapeString(token.Name), kind, html.EscapeString(pattern))
+	}
+	b.WriteString("</table>\n</body>\n</html>\n")
+	return b.String()
+}
+
+// ebnf formats a rule in EBNF-like notation, one alternative per line,
+// with the action name in braces.
+func ebnf(rule dslbuilder.RuleInfo) string {
+	var b strings.Builder
+	indent := strings.Repeat(" ", len(rule.Name))
+	for i, alt := range rule.Alternatives {
+		if i == 0 {
+			fmt.Fprintf(&b, "%s ::= ", rule.Name)
+		} else {
+			fmt.Fprintf(&b, "%s   | ", indent)
+		}
+		sequence := strings.Join(alt.Sequence, " ")
+		if sequence == "" {
+			sequence = "ε"
+		}
+		b.WriteString(sequence)
+		if alt.Action != "" {
+			fmt.Fprintf(&b, "  {%s}", alt.Action)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// markdownCell escapes characters that would break a Markdown table cell.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+const pageStyle = `body{font-family:sans-serif;max-width:960px;margin:2em auto;padding:0 1em;color:#222}` +
+	`section{border-top:1px solid #ddd;padding:0.5em 0}` +
+	`svg.railroad{display:block;overflow:visible;margin:0.5em 0}` +
+	`pre{background:#f6f6f6;padding:0.5em}` +
+	`table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:4px 8px;text-align:left}`
@@ -0,0 +1,118 @@
+package docgen
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCalculator(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("calculator")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+	dsl.Rule("stmt", []string{"LET", "ID", "ASSIGN", "expr"}, "let")
+	dsl.Rule("stmt", []string{"expr"}, "")
+	dsl.Rule("expr", []string{"expr", "PLUS", "term"}, "add")
+	dsl.Rule("expr", []string{"term"}, "")
+	dsl.Rule("term", []string{"NUMBER"}, "number")
+	dsl.Rule("term", []string{"ID"}, "variable")
+	return dsl
+}
+
+func TestExamples(t *testing.T) {
+	gen := New(newCalculator(t), Options{})
+
+	assert.Equal(t, []string{"let a = 1", "1"}, gen.Examples("stmt"))
+	assert.Equal(t, []string{"1 + 1", "1"}, gen.Examples("expr"))
+	assert.Equal(t, []string{"1", "a"}, gen.Examples("term"))
+	assert.Nil(t, gen.Examples("missing"))
+
+	limited := New(newCalculator(t), Options{Examples: 1})
+	assert.Equal(t, []string{"let a = 1"}, limited.Examples("stmt"))
+
+	disabled := New(newCalculator(t), Options{Examples: -1})
+	assert.Empty(t, disabled.Examples("stmt"))
+}
+
+func TestExamplesParse(t *testing.T) {
+	dsl := newCalculator(t)
+	gen := New(dsl, Options{})
+
+	for _, sentence := range gen.Examples("stmt") {
+		_, err := dsl.Parse(sentence)
+		assert.NoError(t, err, sentence)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	md := New(newCalculator(t), Options{Title: "Calculator Reference"}).Markdown()
+
+	assert.Contains(t, md, "# Calculator Reference")
+	assert.Contains(t, md, "Start rule: `stmt`")
+	assert.Contains(t, md, "| `LET` | keyword | `let` |")
+	assert.Contains(t, md, "| `NUMBER` | regex | `[0-9]+` |")
+	assert.Contains(t, md, "expr ::= expr PLUS term  {add}\n       | term\n")
+	assert.Contains(t, md, "- `let a = 1`")
+
+	// Rules appear in definition order
+	assert.Less(t, strings.Index(md, "### stmt"), strings.Index(md, "### expr"))
+	assert.Less(t, strings.Index(md, "### expr"), strings.Index(md, "### term"))
+}
+
+func TestRuleSVG(t *testing.T) {
+	gen := New(newCalculator(t), Options{})
+
+	svg, err := gen.RuleSVG("expr")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.Contains(t, svg, `href="#rule-expr"`)
+	assert.Contains(t, svg, `href="#rule-term"`)
+	assert.Contains(t, svg, ">PLUS<")
+
+	// Keywords are drawn with their literal text
+	svg, err = gen.RuleSVG("stmt")
+	require.NoError(t, err)
+	assert.Contains(t, svg, "&#34;let&#34;")
+
+	// The SVG is well-formed XML
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			break
+		}
+	}
+
+	_, err = gen.RuleSVG("missing")
+	assert.Error(t, err)
+}
+
+func TestHTML(t *testing.T) {
+	page := New(newCalculator(t), Options{}).HTML()
+
+	assert.True(t, strings.HasPrefix(page, "<!DOCTYPE html>"))
+	assert.Contains(t, page, "<title>calculator</title>")
+	assert.Equal(t, 3, strings.Count(page, "<svg"))
+	assert.Contains(t, page, `<section id="rule-term">`)
+	assert.Contains(t, page, "<code>let a = 1</code>")
+}
+
+func TestNewFromConfig(t *testing.T) {
+	gen, err := NewFromConfig(dslbuilder.DSLConfig{
+		Name:   "greeting",
+		Tokens: map[string]string{"HELLO": "hello", "NAME": "[A-Z][a-z]+"},
+		Rules: []dslbuilder.RuleConfig{
+			{Name: "greeting", Pattern: []string{"HELLO", "NAME"}, Action: "greet"},
+		},
+	}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello Aa"}, gen.Examples("greeting"))
+}
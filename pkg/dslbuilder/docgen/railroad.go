@@ -0,0 +1,240 @@
+package docgen
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// Layout constants for railroad diagrams (in SVG user units).
+const (
+	charWidth  = 8.0  // Approximate width of one monospace character
+	boxPadding = 10.0 // Horizontal padding inside a box
+	boxHalf    = 11.0 // Half the height of a box
+	itemGap    = 16.0 // Line length between consecutive items
+	rowGap     = 10.0 // Vertical gap between alternatives
+	arcRadius  = 10.0 // Radius of the rails joining alternatives
+	margin     = 20.0 // Margin around the whole diagram
+)
+
+// diagram is one element of a railroad diagram.
+// Every element is drawn on a horizontal baseline: up is the extent above
+// the baseline and down the extent below it.
+type diagram interface {
+	size() (width, up, down float64)
+	render(b *strings.Builder, x, y float64)
+}
+
+// terminal is a token drawn as a rounded box.
+type terminal struct {
+	label string
+}
+
+// nonTerminal is a rule reference drawn as a square box linking to the rule.
+type nonTerminal struct {
+	name string
+}
+
+// sequence draws its items one after another.
+type sequence struct {
+	items []diagram
+}
+
+// choice stacks its alternatives vertically between two rails.
+type choice struct {
+	rows []diagram
+}
+
+// skip is an empty alternative drawn as a plain line.
+type skip struct{}
+
+func boxWidth(label string) float64 {
+	return float64(len([]rune(label)))*charWidth + 2*boxPadding
+}
+
+func (t terminal) size() (float64, float64, float64) {
+	return boxWidth(t.label), boxHalf, boxHalf
+}
+
+func (t terminal) render(b *strings.Builder, x, y float64) {
+	w := boxWidth(t.label)
+	fmt.Fprintf(b, `<rect class="terminal" x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="%.1f"/>`,
+		x, y-boxHalf, w, 2*boxHalf, boxHalf)
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f">%s</text>`, x+w/2, y+4, html.EscapeString(t.label))
+}
+
+func (n nonTerminal) size() (float64, float64, float64) {
+	return boxWidth(n.name), boxHalf, boxHalf
+}
+
+func (n nonTerminal) render(b *strings.Builder, x, y float64) {
+	w := boxWidth(n.name)
+	fmt.Fprintf(b, `<a href="#rule-%s">`, html.EscapeString(n.name))
+	fmt.Fprintf(b, `<rect class="nonterminal" x="%.1f" y="%.1f" width="%.1f" height="%.1f"/>`,
+		x, y-boxHalf, w, 2*boxHalf)
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f">%s</text>`, x+w/2, y+4, html.EscapeString(n.name))
+	b.WriteString(`</a>`)
+}
+
+func (skip) size() (float64, float64, float64) {
+	return itemGap, 0, 0
+}
+
+func (skip) render(b *strings.Builder, x, y float64) {
+	line(b, x, y, x+itemGap, y)
+}
+
+func (s sequence) size() (float64, float64, float64) {
+	var width, up, down float64
+	for i, item := range s.items {
+		w, u, d := item.size()
+		width += w
+		if i > 0 {
+			width += itemGap
+		}
+		up = max(up, u)
+		down = max(down, d)
+	}
+	return width, up, down
+}
+
+func (s sequence) render(b *strings.Builder, x, y float64) {
+	for i, item := range s.items {
+		if i > 0 {
+			line(b, x, y, x+itemGap, y)
+			x += itemGap
+		}
+		item.render(b, x, y)
+		w, _, _ := item.size()
+		x += w
+	}
+}
+
+func (c choice) size() (float64, float64, float64) {
+	var inner float64
+	for _, row := range c.rows {
+		w, _, _ := row.size()
+		inner = max(inner, w)
+	}
+	_, up, down := c.rows[0].size()
+	for _, row := range c.rows[1:] {
+		_, u, d := row.size()
+		down += rowGap + u + d
+	}
+	return inner + 4*arcRadius, up, down
+}
+
+func (c choice) render(b *strings.Builder, x, y float64) {
+	width, _, _ := c.size()
+	inner := width - 4*arcRadius
+	left := x + 2*arcRadius
+	right := left + inner
+
+	// First alternative sits on the main line
+	w, _, down := c.rows[0].size()
+	line(b, x, y, left, y)
+	c.rows[0].render(b, left, y)
+	line(b, left+w, y, x+width, y)
+
+	rowY := y + down
+	for _, row := range c.rows[1:] {
+		w, u, d := row.size()
+		rowY += rowGap + u
+
+		// Rail down from the main line into this alternative
+		fmt.Fprintf(b, `<path d="M%.1f %.1f Q%.1f %.1f %.1f %.1f L%.1f %.1f Q%.1f %.1f %.1f %.1f"/>`,
+			x, y, x+arcRadius, y, x+arcRadius, y+arcRadius,
+			x+arcRadius, rowY-arcRadius, x+arcRadius, rowY, left, rowY)
+		row.render(b, left, rowY)
+		line(b, left+w, rowY, right, rowY)
+
+		// Rail back up to the main line
+		fmt.Fprintf(b, `<path d="M%.1f %.1f Q%.1f %.1f %.1f %.1f L%.1f %.1f Q%.1f %.1f %.1f %.1f"/>`,
+			right, rowY, right+arcRadius, rowY, right+arcRadius, rowY-arcRadius,
+			right+arcRadius, y+arcRadius, right+arcRadius, y, x+width, y)
+
+		rowY += d
+	}
+}
+
+func line(b *strings.Builder, x1, y1, x2, y2 float64) {
+	fmt.Fprintf(b, `<path d="M%.1f %.1f L%.1f %.1f"/>`, x1, y1, x2, y2)
+}
+
+// buildDiagram converts a rule into a railroad diagram.
+// Each alternative becomes a row of the choice; a rule with a single
+// alternative is drawn as a plain sequence.
+func buildDiagram(grammar *dslbuilder.Grammar, rule dslbuilder.RuleInfo) diagram {
+	rows := make([]diagram, 0, len(rule.Alternatives))
+	for _, alt := range rule.Alternatives {
+		if len(alt.Sequence) == 0 {
+			rows = append(rows, skip{})
+			continue
+		}
+		items := make([]diagram, 0, len(alt.Sequence))
+		for _, symbol := range alt.Sequence {
+			items = append(items, symbolDiagram(grammar, symbol))
+		}
+		rows = append(rows, sequence{items: items})
+	}
+
+	if len(rows) == 0 {
+		return skip{}
+	}
+	if len(rows) == 1 {
+		return rows[0]
+	}
+	return choice{rows: rows}
+}
+
+// symbolDiagram draws keyword tokens with their literal text, other tokens
+// and lookahead or cut operators with their name and rule references as
+// links.
+func symbolDiagram(grammar *dslbuilder.Grammar, symbol string) diagram {
+	if dslbuilder.IsOperator(symbol) {
+		return terminal{label: symbol}
+	}
+	if token, ok := grammar.Token(symbol); ok {
+		if token.IsKeyword() {
+			return terminal{label: `"` + token.Keyword + `"`}
+		}
+		return terminal{label: symbol}
+	}
+	return nonTerminal{name: symbol}
+}
+
+// renderSVG renders a complete, self-contained SVG document for a diagram.
+func renderSVG(d diagram) string {
+	w, up, down := d.size()
+	const endWidth = 10.0
+
+	width := w + 2*margin + 2*endWidth
+	height := up + down + 2*margin
+	y := margin + up
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" class="railroad" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`,
+		width, height, width, height)
+	b.WriteString(`<style>` + svgStyle + `</style>`)
+	b.WriteString(`<g>`)
+
+	// Start and end markers
+	fmt.Fprintf(&b, `<path d="M%.1f %.1f v20 M%.1f %.1f v20"/>`, margin, y-10, margin+4, y-10)
+	line(&b, margin+4, y, margin+endWidth, y)
+	d.render(&b, margin+endWidth, y)
+	end := margin + endWidth + w
+	line(&b, end, y, end+endWidth-4, y)
+	fmt.Fprintf(&b, `<path d="M%.1f %.1f v20 M%.1f %.1f v20"/>`, end+endWidth-4, y-10, end+endWidth, y-10)
+
+	b.WriteString(`</g></svg>`)
+	return b.String()
+}
+
+// svgStyle keeps every SVG self-contained so it can be embedded or saved alone.
+const svgStyle = `path{stroke:#333;stroke-width:2;fill:none}` +
+	`rect{stroke:#333;stroke-width:2}` +
+	`rect.terminal{fill:#e8f4e8}` +
+	`rect.nonterminal{fill:#e8eef8}` +
+	`text{font:13px monospace;text-anchor:middle;fill:#000}`
@@ -140,11 +140,16 @@ func createParseError(message string, position int, token string, input string)
 //   - Functions: Go functions exposed to the DSL
 //   - Context: Runtime variables accessible during parsing
 type DSL struct {
-	name      string                 // Name of the DSL for identification
-	grammar   *Grammar               // Grammar rules and tokens
-	actions   map[string]ActionFunc  // Semantic actions for rules
-	functions map[string]interface{} // Go functions available to DSL code
-	context   map[string]interface{} // Runtime context variables
+	name       string                 // Name of the DSL for identification
+	grammar    *Grammar               // Grammar rules and tokens
+	actions    map[string]ActionFunc  // Semantic actions for rules
+	functions  map[string]interface{} // Go functions available to DSL code
+	context    map[string]interface{} // Runtime context variables
+	strict     bool                   // Fail on actions referenced but not registered
+	coverage   *Coverage              // Grammar coverage recorder (nil when disabled)
+	tracer     Tracer                 // Parse event receiver (nil when disabled)
+	backend    Backend                // Parsing algorithm used by Parse and ParseTree
+	prediction PredictionTable        // LL(1) table used by BackendPredictive
 }
 
 // ActionFunc is a function that processes parsed tokens and returns a result.
@@ -242,6 +247,36 @@ func (d *DSL) TokenWithLookaround(name, pattern string, lookahead, lookbehind st
 	return d.grammar.AddTokenWithLookaround(name, pattern, lookahead, lookbehind)
 }
 
+// SkipToken marks a defined token as skipped. Skipped tokens are matched
+// by the tokenizer like any other token, but they are never passed to the
+// parser, so rules cannot (and need not) mention them. ParseTree keeps them
+// in Tree.Trivia, which lets tools such as formatters preserve comments.
+//
+// Example:
+//
+//	dsl.Token("COMMENT", "#[^\n]*")
+//	dsl.SkipToken("COMMENT")
+func (d *DSL) SkipToken(name string) error {
+	return d.grammar.SkipToken(name)
+}
+
+// CommentToken defines a skipped token in the comment category.
+// It is a shorthand for Token, SkipToken and SetTokenCategory.
+//
+// Example:
+//
+//	dsl.CommentToken("COMMENT", "//[^\n]*")
+//	dsl.CommentToken("BLOCK_COMMENT", "/\\*(?s:.*?)\\*/")
+func (d *DSL) CommentToken(name, pattern string) error {
+	if err := d.grammar.AddToken(name, pattern); err != nil {
+		return err
+	}
+	if err := d.grammar.SkipToken(name); err != nil {
+		return err
+	}
+	return d.grammar.SetTokenCategory(name, CategoryComment)
+}
+
 // Rule defines a grammar rule that describes how to parse a language construct.
 // Rules can reference tokens and other rules to build complex grammars.
 //
@@ -522,6 +557,8 @@ func (d *DSL) Get(name string) (interface{}, bool) {
 
 // Debug returns debug information about the DSL configuration.
 // Useful for understanding the grammar structure and troubleshooting.
+// Tools should prefer the typed, ordered views returned by Tokens, Rules
+// and StartRule.
 //
 // Returns a map containing:
 //   - "name": DSL name
@@ -588,13 +625,15 @@ func (d *DSL) Debug() map[string]interface{} {
 //	//   {TokenType: "ASSIGN", Value: "=", Start: 2, End: 3},
 //	//   {TokenType: "NUMBER", Value: "42", Start: 4, End: 6}
 //	// ]
+//
+// Skipped tokens are left out. With SetLexer, the tokens come from the
+// custom lexer.
 func (d *DSL) DebugTokens(code string) ([]TokenMatch, error) {
-	parser := NewParser(d.grammar)
-	err := parser.tokenize(code)
+	tokens, _, err := d.grammar.tokenize(code)
 	if err != nil {
 		return nil, err
 	}
-	return parser.tokens, nil
+	return tokens, nil
 }
 
 // Use evaluates DSL code with an optional context override.
@@ -638,6 +677,14 @@ func (d *DSL) Use(code string, ctx map[string]interface{}) (*Result, error) {
 //   - *Result: Contains AST and output value
 //   - error: ParseError with detailed position info if parsing fails
 //
+// In strict mode, Parse first checks that every action referenced by the
+// grammar is registered (see Strict and Validate).
+//
+// With SetBackend(BackendEarley), Parse uses the Earley parser instead and
+// evaluates the preferred derivation of the parse forest (see ParseForest).
+// With BackendPredictive it runs a table-driven LL(1) parser that never
+// backtracks (see SetPredictionTable).
+//
 // Example:
 //
 //	result, err := dsl.Parse("2 + 3 * 4")
@@ -649,6 +696,19 @@ func (d *DSL) Use(code string, ctx map[string]interface{}) (*Result, error) {
 //	}
 //	fmt.Println(result.GetOutput()) // Prints: 14
 func (d *DSL) Parse(code string) (*Result, error) {
+	if d.strict {
+		if err := d.Validate(); err != nil {
+			return nil, fmt.Errorf("strict mode: %w", err)
+		}
+	}
+
+	switch d.backend {
+	case BackendEarley:
+		return d.parseEarley(code)
+	case BackendPredictive:
+		return d.parsePredictive(code)
+	}
+
 	parser := NewImprovedParser(d.grammar)
 	parser.dsl = d // Give parser access to DSL functions
 	ast, err := parser.Parse(code)
@@ -678,10 +738,16 @@ func (d *DSL) Parse(code string) (*Result, error) {
 //   - startRule: The root rule to begin parsing
 //   - actions: Functions that process matched patterns
 type Grammar struct {
-	rules     map[string]*Rule      // Named grammar rules
-	tokens    map[string]*Token     // Named token definitions
-	startRule string                // Entry point for parsing
-	actions   map[string]ActionFunc // Semantic actions
+	rules      map[string]*Rule         // Named grammar rules
+	tokens     map[string]*Token        // Named token definitions
+	startRule  string                   // Entry point for parsing
+	actions    map[string]ActionFunc    // Semantic actions
+	ruleOrder  []string                 // Rule names in definition order
+	tokenOrder []string                 // Token names in definition order
+	predicates map[string]PredicateFunc // Semantic predicates
+	derived    map[string][]string      // Actions generated by Optimize -> actions they call
+	lexer      Lexer                    // Custom lexer (SetLexer), nil for the RegexLexer
+	builtins   *builtinActions          // Built-in action library (UseBuiltinActions), nil when disabled
 }
 
 // Rule represents a grammar rule (non-terminal symbol).
@@ -709,6 +775,7 @@ type Alternative struct {
 	action        string   // Action function name
 	precedence    int      // Operator precedence (higher = higher priority)
 	associativity string   // "left", "right", or "none"
+	predicate     string   // Predicate that must accept the match (may be empty)
 }
 
 // Token represents a token (terminal symbol) in the grammar.
@@ -728,15 +795,19 @@ type Token struct {
 	priority   int            // Matching priority
 	lookahead  string         // Positive lookahead pattern
 	lookbehind string         // Positive lookbehind pattern
+	keyword    string         // Literal keyword text (keyword tokens only)
+	category   TokenCategory  // Highlighting category set by SetTokenCategory
+	skip       bool           // Matched but not passed to the parser (SkipToken)
 }
 
 // NewGrammar creates a new empty grammar.
 // The grammar can be populated with tokens and rules.
 func NewGrammar() *Grammar {
 	return &Grammar{
-		rules:   make(map[string]*Rule),
-		tokens:  make(map[string]*Token),
-		actions: make(map[string]ActionFunc),
+		rules:      make(map[string]*Rule),
+		tokens:     make(map[string]*Token),
+		actions:    make(map[string]ActionFunc),
+		predicates: make(map[string]PredicateFunc),
 	}
 }
 
@@ -760,12 +831,12 @@ func (g *Grammar) AddToken(name, pattern string) error {
 		return fmt.Errorf("invalid regex pattern: %w", err)
 	}
 
-	g.tokens[name] = &Token{
+	g.addToken(&Token{
 		name:     name,
 		pattern:  pattern,
 		regex:    regex,
 		priority: 0,
-	}
+	})
 	return nil
 }
 
@@ -793,12 +864,13 @@ func (g *Grammar) AddKeywordToken(name, keyword string) error {
 		return fmt.Errorf("invalid keyword pattern: %w", err)
 	}
 
-	g.tokens[name] = &Token{
+	g.addToken(&Token{
 		name:     name,
 		pattern:  pattern,
 		regex:    regex,
 		priority: 90, // High priority for keywords
-	}
+		keyword:  keyword,
+	})
 	return nil
 }
 
@@ -833,17 +905,37 @@ func (g *Grammar) AddTokenWithLookaround(name, pattern, lookahead, lookbehind st
 		return fmt.Errorf("invalid regex pattern: %w", err)
 	}
 
-	g.tokens[name] = &Token{
+	g.addToken(&Token{
 		name:       name,
 		pattern:    pattern,
 		regex:      regex,
 		priority:   50, // Medium priority for lookaround tokens
 		lookahead:  lookahead,
 		lookbehind: lookbehind,
+	})
+	return nil
+}
+
+// SkipToken marks a defined token as skipped: the tokenizer matches it
+// but does not pass it to the parser.
+func (g *Grammar) SkipToken(name string) error {
+	token, exists := g.tokens[name]
+	if !exists {
+		return fmt.Errorf("token %s not defined", name)
 	}
+	token.skip = true
 	return nil
 }
 
+// addToken stores a token definition, remembering the order in which
+// token names were first defined. Redefining a token keeps its position.
+func (g *Grammar) addToken(token *Token) {
+	if _, exists := g.tokens[token.name]; !exists {
+		g.tokenOrder = append(g.tokenOrder, token.name)
+	}
+	g.tokens[token.name] = token
+}
+
 // AddRule adds a rule alternative to the grammar.
 // Multiple calls with the same rule name create alternatives (like BNF |).
 //
@@ -870,6 +962,7 @@ func (g *Grammar) AddRule(name string, sequence []string, action string) {
 			alternatives: []*Alternative{},
 		}
 		g.rules[name] = rule
+		g.ruleOrder = append(g.ruleOrder, name)
 		if g.startRule == "" {
 			g.startRule = name
 		}
@@ -912,6 +1005,7 @@ func (g *Grammar) AddRuleWithPrecedence(name string, sequence []string, action s
 			alternatives: []*Alternative{},
 		}
 		g.rules[name] = rule
+		g.ruleOrder = append(g.ruleOrder, name)
 		if g.startRule == "" {
 			g.startRule = name
 		}
@@ -1026,7 +1120,7 @@ func (p *Parser) Parse(code string) (interface{}, error) {
 // based on the grammar's token definitions.
 //
 // The tokenizer:
-//   - Skips whitespace automatically
+//   - Skips whitespace and tokens marked with SkipToken
 //   - Uses token priority (keywords > regular tokens)
 //   - For same priority, longest match wins
 //   - Returns detailed error with position on failure
@@ -1041,7 +1135,6 @@ func (p *Parser) Parse(code string) (interface{}, error) {
 //	Input: "if x > 10"
 //	Output: [IF, ID("x"), GT, NUMBER("10")]
 func (p *Parser) tokenize(code string) error {
-	code = strings.TrimSpace(code)
 	pos := 0
 
 	for pos < len(code) {
@@ -1055,9 +1148,13 @@ func (p *Parser) tokenize(code string) error {
 		bestMatch := TokenMatch{}
 		bestLength := 0
 		bestPriority := -1
+		bestSkip := false
 
 		// Find best matching token
 		for _, token := range p.grammar.tokens {
+			if token.regex == nil {
+				continue // ExternalToken
+			}
 			if matches := token.regex.FindStringIndex(code[pos:]); matches != nil && matches[0] == 0 {
 				matchLength := matches[1]
 
@@ -1072,6 +1169,7 @@ func (p *Parser) tokenize(code string) error {
 				if shouldReplace {
 					bestLength = matchLength
 					bestPriority = token.priority
+					bestSkip = token.skip
 					bestMatch = TokenMatch{
 						TokenType: token.name,
 						Value:     code[pos : pos+matchLength],
@@ -1084,7 +1182,9 @@ func (p *Parser) tokenize(code string) error {
 		}
 
 		if matched {
-			p.tokens = append(p.tokens, bestMatch)
+			if !bestSkip {
+				p.tokens = append(p.tokens, bestMatch)
+			}
 			pos += bestLength
 		} else {
 			message := fmt.Sprintf("unexpected character: %c", code[pos])
@@ -1196,7 +1296,7 @@ func (p *Parser) parseAlternative(alt *Alternative) (interface{}, error) {
 
 	// Apply action if available
 	if alt.action != "" {
-		if action, exists := p.grammar.actions[alt.action]; exists {
+		if action, exists := p.grammar.action(alt.action); exists {
 			result, err := action(results)
 			if err != nil {
 				return nil, err
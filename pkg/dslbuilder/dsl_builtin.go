@@ -0,0 +1,545 @@
+// Package dslbuilder - Built-in action library
+package dslbuilder
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// UseBuiltinActions enables the built-in action library, so rules can name
+// actions as expressions and grammars loaded from YAML or JSON run without
+// Go code. Set builtin_actions: true in a configuration file for the same
+// effect. Actions registered with Action take precedence over built-ins of
+// the same name.
+//
+// An action expression is a call, $n for the n-th matched value (from 1),
+// or one of the bare names pass, list and concat, which apply to all
+// values. Call arguments are $n, numbers, 'quoted' or "quoted" strings and
+// nested calls:
+//
+//	pass($n)              the n-th value (bare pass: the first)
+//	list(a, ...)          a list of the arguments (bare list: all values)
+//	concat(a, ...)        the arguments joined into one list, splicing lists
+//	int(a), float(a)      a number parsed from text
+//	unquote(a)            text without its quotes and escapes
+//	map(key=a, ...)       a map from names to values
+//	add, sub, mul, div, mod, neg
+//	                      arithmetic on numbers or numeric text; integers
+//	                      stay integers unless a float is involved
+//	eq, ne, lt, le, gt, ge
+//	                      comparisons of numbers, or of text
+//	ctx.get(a)            a context value (SetContext, Use)
+//
+// Example:
+//
+//	rules:
+//	  - name: expr
+//	    pattern: [expr, PLUS, term]
+//	    action: add($1, $3)
+//	  - name: term
+//	    pattern: [NUMBER]
+//	    action: int($1)
+//	  - name: pair
+//	    pattern: [KEY, COLON, value]
+//	    action: map(key=unquote($1), value=$3)
+func (d *DSL) UseBuiltinActions(enabled bool) {
+	if !enabled {
+		d.grammar.builtins = nil
+		return
+	}
+	if d.grammar.builtins == nil {
+		d.grammar.builtins = &builtinActions{
+			context: func() map[string]interface{} { return d.context },
+			actions: make(map[string]ActionFunc),
+			errors:  make(map[string]error),
+		}
+	}
+}
+
+// UsesBuiltinActions reports whether the built-in action library is enabled.
+func (d *DSL) UsesBuiltinActions() bool {
+	return d.grammar.builtins != nil
+}
+
+// builtinActions caches the action expressions compiled for a grammar.
+// Expressions compile on first use, so the cache is shared by concurrent
+// Parse calls.
+type builtinActions struct {
+	mu      sync.Mutex
+	context func() map[string]interface{} // Context of the DSL, read when ctx.get runs
+	actions map[string]ActionFunc         // Compiled expressions
+	errors  map[string]error              // Expressions that do not compile
+}
+
+// action returns the action for a name: the registered one, or the
+// compiled built-in expression when the library is enabled.
+func (g *Grammar) action(name string) (ActionFunc, bool) {
+	if action, exists := g.actions[name]; exists {
+		return action, true
+	}
+	if g.builtins == nil || name == "" {
+		return nil, false
+	}
+	action, err := g.builtins.compile(name)
+	return action, err == nil
+}
+
+// compile compiles an action expression once.
+func (b *builtinActions) compile(name string) (ActionFunc, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if action, exists := b.actions[name]; exists {
+		return action, nil
+	}
+	if err, exists := b.errors[name]; exists {
+		return nil, err
+	}
+
+	c := &builtinCompiler{source: name, context: b.context}
+	expr, err := c.action()
+	if err != nil {
+		err = fmt.Errorf("invalid built-in action %s: %w", name, err)
+		b.errors[name] = err
+		return nil, err
+	}
+	action := func(args []interface{}) (interface{}, error) {
+		value, err := expr(args)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return value, nil
+	}
+	b.actions[name] = action
+	return action, nil
+}
+
+// invalidBuiltins separates the unbound actions written as expressions
+// from the others, returning the other names and the compile errors of
+// the expressions, so Validate can report them.
+func (d *DSL) invalidBuiltins(unbound []string) (names, problems []string) {
+	if d.grammar.builtins == nil {
+		return unbound, nil
+	}
+	names = []string{}
+	for _, name := range unbound {
+		if !strings.ContainsAny(name, "($") {
+			names = append(names, name)
+			continue
+		}
+		_, err := d.grammar.builtins.compile(name)
+		problems = append(problems, err.Error())
+	}
+	return names, problems
+}
+
+// builtinExpr evaluates part of an action expression.
+type builtinExpr func(args []interface{}) (interface{}, error)
+
+// builtinCompiler parses an action expression.
+type builtinCompiler struct {
+	source  string
+	pos     int
+	context func() map[string]interface{}
+}
+
+// action parses a whole action expression.
+func (c *builtinCompiler) action() (builtinExpr, error) {
+	name := strings.TrimSpace(c.source)
+	switch name {
+	case "pass":
+		return func(args []interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return nil, nil
+			}
+			return args[0], nil
+		}, nil
+	case "list":
+		return func(args []interface{}) (interface{}, error) {
+			return append([]interface{}{}, args...), nil
+		}, nil
+	case "concat":
+		return func(args []interface{}) (interface{}, error) {
+			return concatValues(args), nil
+		}, nil
+	}
+
+	expr, err := c.expr()
+	if err != nil {
+		return nil, err
+	}
+	if c.skipSpace(); c.pos < len(c.source) {
+		return nil, fmt.Errorf("unexpected %q", c.source[c.pos:])
+	}
+	return expr, nil
+}
+
+func (c *builtinCompiler) skipSpace() {
+	for c.pos < len(c.source) && (c.source[c.pos] == ' ' || c.source[c.pos] == '\t') {
+		c.pos++
+	}
+}
+
+// scan returns the run of bytes at the current position accepted by ok.
+func (c *builtinCompiler) scan(ok func(b byte) bool) string {
+	start := c.pos
+	for c.pos < len(c.source) && ok(c.source[c.pos]) {
+		c.pos++
+	}
+	return c.source[start:c.pos]
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b == '.' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+func isNumberByte(b byte) bool {
+	return b == '.' || b == '-' || b >= '0' && b <= '9'
+}
+
+// expr parses a value: $n, a number, a string or a call.
+func (c *builtinCompiler) expr() (builtinExpr, error) {
+	c.skipSpace()
+	if c.pos >= len(c.source) {
+		return nil, fmt.Errorf("unexpected end")
+	}
+
+	switch b := c.source[c.pos]; {
+	case b == '$':
+		c.pos++
+		digits := c.scan(func(b byte) bool { return b >= '0' && b <= '9' })
+		n, err := strconv.Atoi(digits)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid argument reference $%s", digits)
+		}
+		return func(args []interface{}) (interface{}, error) {
+			if n > len(args) {
+				return nil, fmt.Errorf("$%d out of range (%d values)", n, len(args))
+			}
+			return args[n-1], nil
+		}, nil
+	case b == '\'' || b == '"':
+		return c.str(b)
+	case b == '-' || b >= '0' && b <= '9':
+		text := c.scan(isNumberByte)
+		value, err := parseNumber(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", text)
+		}
+		return func([]interface{}) (interface{}, error) { return value, nil }, nil
+	case isIdentByte(b):
+		return c.call(c.scan(isIdentByte))
+	default:
+		return nil, fmt.Errorf("unexpected %q", c.source[c.pos:])
+	}
+}
+
+// str parses a string literal; a backslash escapes the next character.
+func (c *builtinCompiler) str(quote byte) (builtinExpr, error) {
+	start := c.pos
+	var b strings.Builder
+	for c.pos++; c.pos < len(c.source); c.pos++ {
+		switch ch := c.source[c.pos]; {
+		case ch == '\\' && c.pos+1 < len(c.source):
+			c.pos++
+			b.WriteByte(c.source[c.pos])
+		case ch == quote:
+			c.pos++
+			value := b.String()
+			return func([]interface{}) (interface{}, error) { return value, nil }, nil
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return nil, fmt.Errorf("unterminated string %s", c.source[start:])
+}
+
+// call parses the arguments of a call and binds the function.
+func (c *builtinCompiler) call(name string) (builtinExpr, error) {
+	c.skipSpace()
+	if c.pos >= len(c.source) || c.source[c.pos] != '(' {
+		return nil, fmt.Errorf("expected ( after %s", name)
+	}
+	c.pos++
+
+	var keys []string
+	var params []builtinExpr
+	for c.skipSpace(); c.pos < len(c.source) && c.source[c.pos] != ')'; {
+		if len(params) > 0 {
+			if c.source[c.pos] != ',' {
+				return nil, fmt.Errorf("expected , or ) in %s", name)
+			}
+			c.pos++
+			c.skipSpace()
+		}
+		key := ""
+		if name == "map" {
+			key = c.scan(isIdentByte)
+			if c.skipSpace(); key == "" || c.pos >= len(c.source) || c.source[c.pos] != '=' {
+				return nil, fmt.Errorf("map arguments are written key=value")
+			}
+			c.pos++
+		}
+		param, err := c.expr()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		params = append(params, param)
+		c.skipSpace()
+	}
+	if c.pos >= len(c.source) {
+		return nil, fmt.Errorf("missing ) after %s arguments", name)
+	}
+	c.pos++
+
+	if name == "map" {
+		return func(args []interface{}) (interface{}, error) {
+			values, err := evaluate(params, args)
+			if err != nil {
+				return nil, err
+			}
+			result := make(map[string]interface{}, len(values))
+			for i, value := range values {
+				result[keys[i]] = value
+			}
+			return result, nil
+		}, nil
+	}
+	fn, arity, exists := c.function(name)
+	if !exists {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	if arity >= 0 && len(params) != arity {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", name, arity, len(params))
+	}
+	return func(args []interface{}) (interface{}, error) {
+		values, err := evaluate(params, args)
+		if err != nil {
+			return nil, err
+		}
+		return fn(values)
+	}, nil
+}
+
+// function returns a built-in function and its number of arguments, -1
+// for any number.
+func (c *builtinCompiler) function(name string) (func(values []interface{}) (interface{}, error), int, bool) {
+	switch name {
+	case "pass":
+		return func(v []interface{}) (interface{}, error) { return v[0], nil }, 1, true
+	case "list":
+		return func(v []interface{}) (interface{}, error) { return v, nil }, -1, true
+	case "concat":
+		return func(v []interface{}) (interface{}, error) { return concatValues(v), nil }, -1, true
+	case "int":
+		return func(v []interface{}) (interface{}, error) {
+			n, err := toNumber(v[0])
+			if err != nil {
+				return nil, err
+			}
+			if f, isFloat := n.(float64); isFloat {
+				return int(f), nil
+			}
+			return n, nil
+		}, 1, true
+	case "float":
+		return func(v []interface{}) (interface{}, error) {
+			n, err := toNumber(v[0])
+			if err != nil {
+				return nil, err
+			}
+			if i, isInt := n.(int); isInt {
+				return float64(i), nil
+			}
+			return n, nil
+		}, 1, true
+	case "unquote":
+		return func(v []interface{}) (interface{}, error) { return unquote(fmt.Sprint(v[0])) }, 1, true
+	case "neg":
+		return func(v []interface{}) (interface{}, error) { return arithmetic("sub", 0, v[0]) }, 1, true
+	case "add", "sub", "mul", "div", "mod":
+		return func(v []interface{}) (interface{}, error) { return arithmetic(name, v[0], v[1]) }, 2, true
+	case "eq", "ne", "lt", "le", "gt", "ge":
+		return func(v []interface{}) (interface{}, error) { return compare(name, v[0], v[1]) }, 2, true
+	case "ctx.get":
+		return func(v []interface{}) (interface{}, error) {
+			return c.context()[fmt.Sprint(v[0])], nil
+		}, 1, true
+	}
+	return nil, 0, false
+}
+
+// evaluate evaluates call arguments in order.
+func evaluate(params []builtinExpr, args []interface{}) ([]interface{}, error) {
+	values := make([]interface{}, len(params))
+	for i, param := range params {
+		value, err := param(args)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// concatValues joins values into one list, splicing the values that are
+// lists themselves.
+func concatValues(values []interface{}) []interface{} {
+	result := []interface{}{}
+	for _, value := range values {
+		if list, isList := value.([]interface{}); isList {
+			result = append(result, list...)
+		} else {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// parseNumber parses text as an int, or as a float64 when it is not an
+// integer.
+func parseNumber(text string) (interface{}, error) {
+	if i, err := strconv.Atoi(text); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a number", text)
+	}
+	return f, nil
+}
+
+// toNumber converts a value to an int or a float64.
+func toNumber(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case float64:
+		return v, nil
+	case string:
+		return parseNumber(strings.TrimSpace(v))
+	default:
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return int(rv.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int(rv.Uint()), nil
+		case reflect.Float32:
+			return rv.Float(), nil
+		}
+		return nil, fmt.Errorf("%v (%T) is not a number", value, value)
+	}
+}
+
+// arithmetic applies an operator to two numbers.
+func arithmetic(op string, left, right interface{}) (interface{}, error) {
+	l, err := toNumber(left)
+	if err != nil {
+		return nil, err
+	}
+	r, err := toNumber(right)
+	if err != nil {
+		return nil, err
+	}
+
+	li, lInt := l.(int)
+	ri, rInt := r.(int)
+	if lInt && rInt {
+		switch op {
+		case "add":
+			return li + ri, nil
+		case "sub":
+			return li - ri, nil
+		case "mul":
+			return li * ri, nil
+		}
+		if ri == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if op == "div" {
+			return li / ri, nil
+		}
+		return li % ri, nil
+	}
+
+	lf, rf := asFloat(l), asFloat(r)
+	switch op {
+	case "add":
+		return lf + rf, nil
+	case "sub":
+		return lf - rf, nil
+	case "mul":
+		return lf * rf, nil
+	case "div":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return lf / rf, nil
+	}
+	return nil, fmt.Errorf("mod needs integers")
+}
+
+func asFloat(n interface{}) float64 {
+	if i, isInt := n.(int); isInt {
+		return float64(i)
+	}
+	return n.(float64)
+}
+
+// compare compares two numbers, or two values as text when either is not
+// a number.
+func compare(op string, left, right interface{}) (interface{}, error) {
+	var cmp int
+	l, lErr := toNumber(left)
+	r, rErr := toNumber(right)
+	switch {
+	case lErr == nil && rErr == nil:
+		lf, rf := asFloat(l), asFloat(r)
+		if lf < rf {
+			cmp = -1
+		} else if lf > rf {
+			cmp = 1
+		}
+	case op == "eq" || op == "ne":
+		if !reflect.DeepEqual(left, right) {
+			cmp = 1
+		}
+	default:
+		cmp = strings.Compare(fmt.Sprint(left), fmt.Sprint(right))
+	}
+
+	switch op {
+	case "eq":
+		return cmp == 0, nil
+	case "ne":
+		return cmp != 0, nil
+	case "lt":
+		return cmp < 0, nil
+	case "le":
+		return cmp <= 0, nil
+	case "gt":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+// unquote removes the quotes of a "double", 'single' or `raw` quoted
+// string and resolves its escapes. Text without quotes is returned as is.
+func unquote(text string) (interface{}, error) {
+	if len(text) < 2 || text[0] != text[len(text)-1] || !strings.ContainsRune("\"'`", rune(text[0])) {
+		return text, nil
+	}
+	if text[0] == '\'' {
+		inner := strings.ReplaceAll(text[1:len(text)-1], `\'`, `'`)
+		text = `"` + strings.ReplaceAll(inner, `"`, `\"`) + `"`
+	}
+	value, err := strconv.Unquote(text)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unquote %s", text)
+	}
+	return value, nil
+}
@@ -0,0 +1,195 @@
+package dslbuilder
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const builtinCalculator = `
+name: calculator
+builtin_actions: true
+tokens:
+  NUMBER: "[0-9]+"
+  NAME: "[a-z]+"
+  PLUS: "\\+"
+  MINUS: "-"
+  TIMES: "\\*"
+  DIVIDE: "/"
+  LESS: "<"
+rules:
+  - name: cmp
+    pattern: [expr, LESS, expr]
+    action: lt($1, $3)
+  - name: cmp
+    pattern: [expr]
+    action: pass
+  - name: expr
+    pattern: [expr, PLUS, term]
+    action: add($1, $3)
+  - name: expr
+    pattern: [expr, MINUS, term]
+    action: sub($1, $3)
+  - name: expr
+    pattern: [term]
+    action: $1
+  - name: term
+    pattern: [term, TIMES, factor]
+    action: mul($1, $3)
+  - name: term
+    pattern: [term, DIVIDE, factor]
+    action: div($1, $3)
+  - name: term
+    pattern: [factor]
+    action: pass($1)
+  - name: factor
+    pattern: [MINUS, factor]
+    action: neg($2)
+  - name: factor
+    pattern: [NUMBER]
+    action: int($1)
+  - name: factor
+    pattern: [NAME]
+    action: ctx.get($1)
+context:
+  ten: 10
+`
+
+func TestBuiltinActions(t *testing.T) {
+	dsl, err := LoadFromYAML([]byte(builtinCalculator))
+	require.NoError(t, err)
+	assert.True(t, dsl.UsesBuiltinActions())
+	assert.Empty(t, dsl.UnboundActions())
+	require.NoError(t, dsl.Validate())
+
+	cases := map[string]interface{}{
+		"1 + 2 * 3":    7,
+		"7 / 2":        3,
+		"-4 - 1":       -5,
+		"ten * 3":      30,
+		"1 + 1 < 3":    true,
+		"10 - 2 < ten": true,
+	}
+	for input, want := range cases {
+		result, err := dsl.Parse(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, result.GetOutput(), input)
+	}
+
+	// A failing action fails its alternative
+	_, err = dsl.Parse("1 / 0")
+	assert.EqualError(t, err, "unexpected token: /")
+
+	// Registered actions win over built-ins
+	dsl.Action("int($1)", func(args []interface{}) (interface{}, error) {
+		return 100, nil
+	})
+	result, err := dsl.Parse("1 + 2")
+	require.NoError(t, err)
+	assert.Equal(t, 200, result.GetOutput())
+
+	// Other backends resolve built-ins too
+	dsl, err = LoadFromYAML([]byte(builtinCalculator))
+	require.NoError(t, err)
+	dsl.SetBackend(BackendEarley)
+	result, err = dsl.Parse("ten - 2 * 3")
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.GetOutput())
+}
+
+func TestBuiltinConcurrentParse(t *testing.T) {
+	dsl, err := LoadFromYAML([]byte(builtinCalculator))
+	require.NoError(t, err)
+	dsl.Strict(true)
+
+	// Expressions compile on first use, from every goroutine at once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			result, err := dsl.Parse(fmt.Sprintf("%d * 2 - ten", n))
+			assert.NoError(t, err)
+			assert.Equal(t, n*2-10, result.GetOutput())
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestBuiltinValues(t *testing.T) {
+	dsl := New("values")
+	require.NoError(t, dsl.Token("STRING", `"[^"]*"|'[^']*'`))
+	require.NoError(t, dsl.Token("COLON", ":"))
+	require.NoError(t, dsl.Token("COMMA", ","))
+	dsl.Rule("object", []string{"pairs"}, "pass")
+	dsl.Rule("pairs", []string{"pair", "COMMA", "pairs"}, "concat($1, $3)")
+	dsl.Rule("pairs", []string{"pair"}, "list")
+	dsl.Rule("pair", []string{"STRING", "COLON", "STRING"}, "map(key=unquote($1), value=unquote($3), kind='pair')")
+	dsl.UseBuiltinActions(true)
+
+	result, err := dsl.Parse(`"a": 'x', 'b': 'y " z'`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"key": "a", "value": "x", "kind": "pair"},
+		map[string]interface{}{"key": "b", "value": `y " z`, "kind": "pair"},
+	}, result.GetOutput())
+
+	// Disabling the library leaves the actions unbound
+	dsl.UseBuiltinActions(false)
+	assert.False(t, dsl.UsesBuiltinActions())
+	assert.Equal(t, []string{"concat($1, $3)", "list", "map(key=unquote($1), value=unquote($3), kind='pair')", "pass"}, dsl.UnboundActions())
+	assert.False(t, dsl.toConfig().BuiltinActions)
+}
+
+func TestBuiltinErrors(t *testing.T) {
+	dsl := New("errors")
+	require.NoError(t, dsl.Token("WORD", "[a-z]+"))
+	dsl.Rule("start", []string{"WORD"}, "int($1")
+	dsl.Rule("start", []string{"WORD", "WORD"}, "add($1)")
+	dsl.Rule("start", []string{"WORD", "WORD", "WORD"}, "custom")
+	dsl.UseBuiltinActions(true)
+	assert.EqualError(t, dsl.Validate(), "unbound actions: custom; "+
+		"invalid built-in action add($1): add takes 2 arguments, got 1; "+
+		"invalid built-in action int($1: missing ) after int arguments")
+
+	dsl = New("errors")
+	require.NoError(t, dsl.Token("WORD", "[a-z]+"))
+	dsl.Rule("start", []string{"WORD"}, "int($1)")
+	dsl.UseBuiltinActions(true)
+	_, err := dsl.Parse("abc")
+	assert.EqualError(t, err, "no alternative matched for rule start")
+	action, exists := dsl.Grammar().action("int($2)")
+	require.True(t, exists)
+	_, err = action([]interface{}{"1"})
+	assert.EqualError(t, err, "int($2): $2 out of range (1 values)")
+	action, _ = dsl.Grammar().action("int($1)")
+	_, err = action([]interface{}{"abc"})
+	assert.EqualError(t, err, `int($1): "abc" is not a number`)
+
+	for expr, want := range map[string]interface{}{
+		"float($1)":            2.5,
+		"add(1, float('2.5'))": 3.5,
+		"mod(7, 4)":            3,
+		"eq($1, 2.5)":          true,
+		"ge('b', 'a')":         true,
+		"ne('a', 1)":           true,
+		"list($1, 'x')":        []interface{}{"2.5", "x"},
+	} {
+		action, exists := dsl.Grammar().action(expr)
+		require.True(t, exists, expr)
+		value, err := action([]interface{}{"2.5"})
+		require.NoError(t, err, expr)
+		assert.Equal(t, want, value, expr)
+	}
+	action, _ = dsl.Grammar().action("div($1, 0)")
+	_, err = action([]interface{}{"2.5"})
+	assert.EqualError(t, err, "div($1, 0): division by zero")
+
+	for _, expr := range []string{"$0", "unknown($1)", "map($1)", "add($1 $2)", "'open", "pass($1) extra"} {
+		_, exists := dsl.Grammar().action(expr)
+		assert.False(t, exists, expr)
+	}
+}
@@ -0,0 +1,75 @@
+// Package dslbuilder - Token categories
+package dslbuilder
+
+import "fmt"
+
+// TokenCategory classifies a token for editors and highlighters, such as
+// the dslbuilder/export grammars and the language server.
+type TokenCategory string
+
+// Token categories understood by the tooling.
+const (
+	CategoryNone       TokenCategory = ""           // Not highlighted
+	CategoryKeyword    TokenCategory = "keyword"    // Reserved words
+	CategoryNumber     TokenCategory = "number"     // Numeric literals
+	CategoryString     TokenCategory = "string"     // String literals
+	CategoryOperator   TokenCategory = "operator"   // Operators and punctuation
+	CategoryComment    TokenCategory = "comment"    // Comments
+	CategoryIdentifier TokenCategory = "identifier" // Names and variables
+)
+
+// TokenCategories lists the valid non-empty categories.
+var TokenCategories = []TokenCategory{
+	CategoryKeyword,
+	CategoryNumber,
+	CategoryString,
+	CategoryOperator,
+	CategoryComment,
+	CategoryIdentifier,
+}
+
+// valid reports whether c is CategoryNone or one of TokenCategories.
+func (c TokenCategory) valid() bool {
+	if c == CategoryNone {
+		return true
+	}
+	for _, known := range TokenCategories {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTokenCategory sets the category of a defined token. Tokens defined
+// with KeywordToken are CategoryKeyword unless set otherwise; all other
+// tokens have no category until one is set.
+//
+// Example:
+//
+//	dsl.Token("NUMBER", "[0-9]+")
+//	dsl.SetTokenCategory("NUMBER", dslbuilder.CategoryNumber)
+func (d *DSL) SetTokenCategory(name string, category TokenCategory) error {
+	return d.grammar.SetTokenCategory(name, category)
+}
+
+// SetTokenCategory sets the category of a defined token.
+func (g *Grammar) SetTokenCategory(name string, category TokenCategory) error {
+	token, exists := g.tokens[name]
+	if !exists {
+		return fmt.Errorf("token %s not defined", name)
+	}
+	if !category.valid() {
+		return fmt.Errorf("unknown token category %q for token %s", category, name)
+	}
+	token.category = category
+	return nil
+}
+
+// effectiveCategory returns the category reported by TokenInfo.
+func (t *Token) effectiveCategory() TokenCategory {
+	if t.category == CategoryNone && t.keyword != "" {
+		return CategoryKeyword
+	}
+	return t.category
+}
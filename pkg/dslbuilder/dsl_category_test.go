@@ -0,0 +1,66 @@
+package dslbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetTokenCategory(t *testing.T) {
+	dsl := New("Categories")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+
+	require.NoError(t, dsl.SetTokenCategory("NUMBER", CategoryNumber))
+
+	info, _ := dsl.Grammar().Token("LET")
+	assert.Equal(t, CategoryKeyword, info.Category, "keyword tokens default to keyword")
+	info, _ = dsl.Grammar().Token("NUMBER")
+	assert.Equal(t, CategoryNumber, info.Category)
+	info, _ = dsl.Grammar().Token("ID")
+	assert.Equal(t, CategoryNone, info.Category)
+
+	assert.Error(t, dsl.SetTokenCategory("MISSING", CategoryNumber))
+	assert.Error(t, dsl.SetTokenCategory("ID", TokenCategory("color")))
+}
+
+func TestTokenMetadataConfig(t *testing.T) {
+	yamlData := []byte(`
+name: calc
+tokens:
+  LET: let
+  NUMBER: "[0-9]+"
+  PLUS: "\\+"
+  COMMENT: "#[^\\n]*"
+skip_tokens: [COMMENT]
+token_categories:
+  NUMBER: number
+  PLUS: operator
+rules:
+  - name: expr
+    pattern: [NUMBER, PLUS, NUMBER]
+`)
+	dsl, err := LoadFromYAML(yamlData)
+	require.NoError(t, err)
+
+	info, _ := dsl.Grammar().Token("PLUS")
+	assert.Equal(t, CategoryOperator, info.Category)
+	info, _ = dsl.Grammar().Token("COMMENT")
+	assert.True(t, info.Skip)
+	_, err = dsl.Parse("1 + 2 # three")
+	assert.NoError(t, err)
+
+	// Only explicit categories are saved, so defaults stay implicit
+	config := dsl.toConfig()
+	assert.Equal(t, map[string]TokenCategory{"NUMBER": CategoryNumber, "PLUS": CategoryOperator}, config.TokenCategories)
+	assert.Equal(t, []string{"COMMENT"}, config.SkipTokens)
+
+	_, err = LoadFromConfig(DSLConfig{
+		Name:            "bad",
+		Tokens:          map[string]string{"NUMBER": "[0-9]+"},
+		TokenCategories: map[string]TokenCategory{"NUMBER": "digits"},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,150 @@
+// Package dslbuilder - Auto-completion
+package dslbuilder
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CompletionKind tells what a completion inserts.
+type CompletionKind int
+
+const (
+	CompletionKeyword CompletionKind = iota // Text of a keyword token
+	CompletionLiteral                       // Fixed text of a regex token, such as an operator
+	CompletionToken                         // Placeholder for a token with varying text, such as NUMBER
+	CompletionContext                       // Context key (SetContext) accepted by a token
+)
+
+// String returns the name of the kind.
+func (k CompletionKind) String() string {
+	switch k {
+	case CompletionKeyword:
+		return "keyword"
+	case CompletionLiteral:
+		return "literal"
+	case CompletionToken:
+		return "token"
+	case CompletionContext:
+		return "context"
+	default:
+		return "unknown"
+	}
+}
+
+// Completion is one suggestion for the text at a cursor position.
+type Completion struct {
+	Text    string         // Text to insert; the token name for CompletionToken
+	Kind    CompletionKind // What the text is
+	Token   string         // Token that would match the text
+	Pattern string         // Regex pattern of the token
+	Rules   []string       // Rules being parsed when the token was expected, outermost first
+	Start   int            // Byte offset where the partial word under the cursor starts
+}
+
+// Complete returns the terminals that could legally come at offset in code.
+// It parses the code up to the cursor and collects the tokens the grammar
+// expects next, with the rules that expected them. The partial word right
+// before the cursor is not parsed; it filters the suggestions instead, and
+// Completion.Start tells where it begins so editors can replace it.
+//
+// Keywords and tokens with fixed text become CompletionKeyword and
+// CompletionLiteral suggestions. Tokens with varying text become
+// CompletionToken placeholders, plus a CompletionContext suggestion for
+// every context key the token would match. Complete returns nil when the
+// code before the cursor does not parse.
+//
+// Complete always uses the packrat parser, whatever backend SetBackend
+// selects, so with BackendEarley or BackendPredictive it can suggest
+// tokens that Parse would reject, such as those of alternatives a
+// prediction table never chooses.
+//
+// Example:
+//
+//	for _, c := range dsl.Complete("let x = ", 8) {
+//	    fmt.Println(c.Kind, c.Text) // token NUMBER, context rate, ...
+//	}
+func (d *DSL) Complete(code string, offset int) []Completion {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(code) {
+		offset = len(code)
+	}
+	prefix := code[:offset]
+	start := len(prefix)
+	for start > 0 && isWordByte(prefix[start-1]) {
+		start--
+	}
+	word := prefix[start:]
+	prefix = prefix[:start]
+
+	parser := NewImprovedParser(d.grammar)
+	parser.dsl = d
+	parser.buildTree = true
+	parser.Parse(prefix)
+
+	expectedOffset := len(prefix)
+	if parser.farthest < len(parser.tokens) {
+		expectedOffset = parser.tokens[parser.farthest].Start
+	}
+	if strings.TrimSpace(prefix[expectedOffset:]) != "" {
+		// The code is broken before the cursor
+		return nil
+	}
+
+	var completions []Completion
+	seen := make(map[string]bool)
+	for i, name := range parser.expected {
+		info, ok := d.grammar.Token(name)
+		if !ok {
+			continue
+		}
+		c := Completion{Token: name, Pattern: info.Pattern, Rules: parser.expectedRules[i], Start: start}
+
+		if text, ok := info.Literal(); ok {
+			c.Text, c.Kind = text, CompletionLiteral
+			matches := strings.HasPrefix(text, word)
+			if info.IsKeyword() {
+				c.Kind = CompletionKeyword
+				matches = strings.HasPrefix(strings.ToLower(text), strings.ToLower(word))
+			}
+			if matches {
+				completions = append(completions, c)
+			}
+			continue
+		}
+
+		re, err := regexp.Compile("^(?:" + info.Pattern + ")$")
+		if err != nil {
+			continue
+		}
+		if word == "" || re.MatchString(word) {
+			c.Text, c.Kind = name, CompletionToken
+			completions = append(completions, c)
+		}
+		for _, key := range d.contextKeys() {
+			if !seen[key] && strings.HasPrefix(key, word) && re.MatchString(key) {
+				seen[key] = true
+				c.Text, c.Kind = key, CompletionContext
+				completions = append(completions, c)
+			}
+		}
+	}
+	return completions
+}
+
+// contextKeys returns the context keys in sorted order.
+func (d *DSL) contextKeys() []string {
+	keys := make([]string, 0, len(d.context))
+	for key := range d.context {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
@@ -0,0 +1,111 @@
+package dslbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func completionTexts(completions []Completion) []string {
+	texts := []string{}
+	for _, c := range completions {
+		texts = append(texts, c.Kind.String()+":"+c.Text)
+	}
+	return texts
+}
+
+func TestComplete(t *testing.T) {
+	dsl := New("Completion")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.KeywordToken("PRINT", "print"))
+	require.NoError(t, dsl.Token("EQUALS", "="))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	dsl.Rule("stmt", []string{"LET", "ID", "EQUALS", "expr"}, "")
+	dsl.Rule("stmt", []string{"PRINT", "expr"}, "")
+	dsl.Rule("expr", []string{"expr", "PLUS", "value"}, "")
+	dsl.Rule("expr", []string{"value"}, "")
+	dsl.Rule("value", []string{"NUMBER"}, "")
+	dsl.Rule("value", []string{"ID"}, "")
+	dsl.SetContext("rate", 0.2)
+	dsl.SetContext("Total", 10)
+
+	t.Run("start of input", func(t *testing.T) {
+		completions := dsl.Complete("", 0)
+		assert.Equal(t, []string{"keyword:let", "keyword:print"}, completionTexts(completions))
+		assert.Equal(t, []string{"stmt"}, completions[0].Rules)
+	})
+
+	t.Run("partial keyword", func(t *testing.T) {
+		completions := dsl.Complete("pr", 2)
+		assert.Equal(t, []string{"keyword:print"}, completionTexts(completions))
+		assert.Equal(t, 0, completions[0].Start)
+	})
+
+	t.Run("token and context keys", func(t *testing.T) {
+		completions := dsl.Complete("let x = ", 8)
+		assert.Equal(t, []string{"token:NUMBER", "token:ID", "context:rate"}, completionTexts(completions))
+		assert.Equal(t, []string{"stmt", "expr", "value"}, completions[0].Rules)
+		assert.Equal(t, "[0-9]+", completions[0].Pattern)
+	})
+
+	t.Run("partial context key", func(t *testing.T) {
+		completions := dsl.Complete("print 1 + ra", 12)
+		assert.Equal(t, []string{"token:ID", "context:rate"}, completionTexts(completions))
+		assert.Equal(t, 10, completions[1].Start)
+	})
+
+	t.Run("after a complete statement", func(t *testing.T) {
+		completions := dsl.Complete("print 1 ", 8)
+		assert.Equal(t, []string{"literal:+"}, completionTexts(completions))
+		assert.Equal(t, "PLUS", completions[0].Token)
+	})
+
+	t.Run("cursor inside the code", func(t *testing.T) {
+		assert.Equal(t, []string{"literal:="}, completionTexts(dsl.Complete("let x  1", 6)))
+	})
+
+	t.Run("broken before the cursor", func(t *testing.T) {
+		assert.Nil(t, dsl.Complete("let = 1 ", 8))
+	})
+}
+
+func TestCompleteIgnoresBackend(t *testing.T) {
+	dsl := New("Backends")
+	require.NoError(t, dsl.Token("A", "a"))
+	require.NoError(t, dsl.Token("B", "b"))
+	require.NoError(t, dsl.Token("C", "c"))
+	dsl.Rule("start", []string{"A", "B"}, "")
+	dsl.Rule("start", []string{"A", "C"}, "")
+
+	// The prediction table always picks the first alternative, so Parse
+	// rejects "a c", but completion still offers C from the packrat parser
+	dsl.SetBackend(BackendPredictive)
+	dsl.SetPredictionTable(PredictionTable{"start": {"A": 0}})
+	_, err := dsl.Parse("a c")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"literal:b", "literal:c"}, completionTexts(dsl.Complete("a ", 2)))
+}
+
+func TestTokenInfoLiteral(t *testing.T) {
+	dsl := New("Literals")
+	require.NoError(t, dsl.KeywordToken("IF", "if"))
+	require.NoError(t, dsl.Token("ARROW", "->"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+
+	info, _ := dsl.Grammar().Token("IF")
+	text, ok := info.Literal()
+	assert.True(t, ok)
+	assert.Equal(t, "if", text)
+
+	info, _ = dsl.Grammar().Token("ARROW")
+	text, ok = info.Literal()
+	assert.True(t, ok)
+	assert.Equal(t, "->", text)
+
+	info, _ = dsl.Grammar().Token("NUMBER")
+	_, ok = info.Literal()
+	assert.False(t, ok)
+}
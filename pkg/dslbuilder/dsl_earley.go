@@ -0,0 +1,493 @@
+// Package dslbuilder - Earley parser backend for ambiguous grammars
+package dslbuilder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Backend selects the parsing algorithm used by Parse and ParseTree.
+type Backend int
+
+const (
+	// BackendPEG is the default packrat parser. Alternatives are ordered
+	// choices: the first alternative that matches wins, even if a later one
+	// would also match.
+	BackendPEG Backend = iota
+	// BackendEarley is an Earley parser. It accepts any context-free
+	// grammar, including ambiguous and left-recursive ones, by building a
+	// parse forest of every derivation (see ParseForest).
+	BackendEarley
+	// BackendPredictive is a table-driven LL(1) parser. It never
+	// backtracks and needs a table from SetPredictionTable, which the
+	// analysis package builds for grammars that qualify.
+	BackendPredictive
+)
+
+// String returns the backend name, such as "peg" or "earley".
+func (b Backend) String() string {
+	switch b {
+	case BackendPEG:
+		return "peg"
+	case BackendEarley:
+		return "earley"
+	case BackendPredictive:
+		return "predictive"
+	}
+	return fmt.Sprintf("Backend(%d)", int(b))
+}
+
+// SetBackend selects the parsing algorithm for every following Parse and
+// ParseTree call.
+//
+// With BackendEarley, Parse evaluates the preferred derivation of the parse
+// forest: earlier alternatives win, then longer leftmost children, which
+// makes ambiguous binary operators left-associative. Action errors are
+// returned instead of making the alternative fail, and tracers are not
+// called. Use ParseForest and EvalTree to pick another derivation.
+//
+// Example:
+//
+//	dsl.SetBackend(dslbuilder.BackendEarley)
+//	result, err := dsl.Parse("show orders from clients with debt")
+func (d *DSL) SetBackend(backend Backend) {
+	d.backend = backend
+}
+
+// Backend returns the selected parsing algorithm.
+func (d *DSL) Backend() Backend {
+	return d.backend
+}
+
+// earleyItem is a dotted alternative: symbols before dot have matched
+// tokens origin up to the set holding the item.
+type earleyItem struct {
+	rule   string
+	alt    int
+	dot    int
+	origin int
+}
+
+// earleyParser recognizes the token stream and keeps the chart needed to
+// build the parse forest.
+//
+// Fields:
+//   - sets: Items of each Earley set, in insertion order
+//   - index: Item lookup for each set
+//   - completed: For each set, the origins of the rules completed there
+//   - nullable: Rules that can match the empty input
+//   - farthest/expected: Last non-empty set and the tokens it could scan
+type earleyParser struct {
+	grammar   *Grammar
+	input     string
+	tokens    []TokenMatch
+	trivia    []TokenMatch
+	sets      [][]earleyItem
+	index     []map[earleyItem]bool
+	completed []map[string][]int
+	nullable  map[string]bool
+	farthest  int
+	expected  []string
+	nodes     map[forestKey]*ForestNode
+	splits    map[splitKey][][]*ForestNode
+	context   map[string]interface{} // Context passed to predicates
+	peg       *ImprovedParser        // Runs rule lookaheads over the same tokens
+}
+
+// forestKey identifies a forest node by symbol and token span.
+type forestKey struct {
+	symbol   string
+	from, to int
+}
+
+// splitKey identifies the prefix of an alternative matched over a token span.
+type splitKey struct {
+	item earleyItem
+	end  int
+}
+
+// newEarleyParser tokenizes code with the same lexer as the PEG parser.
+func newEarleyParser(grammar *Grammar, code string) (*earleyParser, error) {
+	lexer := NewImprovedParser(grammar)
+	lexer.input = code
+	err := lexer.tokenize(code)
+	p := &earleyParser{
+		grammar: grammar,
+		input:   code,
+		tokens:  lexer.tokens,
+		trivia:  lexer.trivia,
+		nodes:   make(map[forestKey]*ForestNode),
+		splits:  make(map[splitKey][][]*ForestNode),
+	}
+	return p, err
+}
+
+// recognize fills the chart and reports whether the start rule matches
+// the whole token stream.
+func (p *earleyParser) recognize() error {
+	p.nullable = p.grammar.nullableRules()
+	n := len(p.tokens)
+	p.sets = make([][]earleyItem, n+1)
+	p.index = make([]map[earleyItem]bool, n+1)
+	p.completed = make([]map[string][]int, n+1)
+	for k := range p.sets {
+		p.index[k] = make(map[earleyItem]bool)
+		p.completed[k] = make(map[string][]int)
+	}
+
+	if err := p.predict(p.grammar.startRule, 0); err != nil {
+		return err
+	}
+	for k := 0; k <= n; k++ {
+		if len(p.sets[k]) == 0 {
+			break
+		}
+		p.farthest = k
+		for i := 0; i < len(p.sets[k]); i++ {
+			item := p.sets[k][i]
+			sequence := p.grammar.rules[item.rule].alternatives[item.alt].sequence
+			if item.dot == len(sequence) {
+				p.complete(item, k)
+				continue
+			}
+			symbol := sequence[item.dot]
+			if IsOperator(symbol) {
+				if p.operator(symbol, k) {
+					p.add(k, advance(item))
+				}
+				continue
+			}
+			if _, isToken := p.grammar.tokens[symbol]; isToken {
+				if k < n && p.tokens[k].TokenType == symbol {
+					p.add(k+1, advance(item))
+				}
+				continue
+			}
+			if err := p.predict(symbol, k); err != nil {
+				return err
+			}
+			if p.nullable[symbol] || p.completedFrom(symbol, k, k) {
+				p.add(k, advance(item))
+			}
+		}
+	}
+
+	for _, origin := range p.completed[n][p.grammar.startRule] {
+		if origin == 0 {
+			return nil
+		}
+	}
+	return p.failure()
+}
+
+// operator tests a lookahead at set k. Rule lookaheads run the default
+// parser over the same tokens, without actions. The cut always passes:
+// Earley parsing never backtracks.
+func (p *earleyParser) operator(symbol string, k int) bool {
+	target, negative, ok := LookaheadOf(symbol)
+	if !ok {
+		return true
+	}
+	if _, isToken := p.grammar.tokens[target]; isToken {
+		return (k < len(p.tokens) && p.tokens[k].TokenType == target) != negative
+	}
+	if p.peg == nil {
+		p.peg = NewImprovedParser(p.grammar)
+		p.peg.tokens = p.tokens
+		p.peg.input = p.input
+		p.peg.buildTree = true
+	}
+	p.peg.pos = k
+	return p.peg.lookahead(target) != negative
+}
+
+// completedFrom reports whether a rule spanning set origin to set k has
+// been completed. Rules that match the empty input only when a lookahead
+// passes are not in nullable, and may complete before items wait for them.
+func (p *earleyParser) completedFrom(ruleName string, origin, k int) bool {
+	for _, o := range p.completed[k][ruleName] {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// predict adds the alternatives of a rule starting at set k.
+func (p *earleyParser) predict(ruleName string, k int) error {
+	rule, exists := p.grammar.rules[ruleName]
+	if !exists {
+		return fmt.Errorf("rule %s not found", ruleName)
+	}
+	for i := range rule.alternatives {
+		p.add(k, earleyItem{rule: ruleName, alt: i, origin: k})
+	}
+	return nil
+}
+
+// complete records a finished item and advances the items waiting for it.
+func (p *earleyParser) complete(item earleyItem, k int) {
+	origins := p.completed[k][item.rule]
+	for _, origin := range origins {
+		if origin == item.origin {
+			return
+		}
+	}
+	p.completed[k][item.rule] = append(origins, item.origin)
+
+	for i := 0; i < len(p.sets[item.origin]); i++ {
+		waiting := p.sets[item.origin][i]
+		sequence := p.grammar.rules[waiting.rule].alternatives[waiting.alt].sequence
+		if waiting.dot < len(sequence) && sequence[waiting.dot] == item.rule {
+			p.add(k, advance(waiting))
+		}
+	}
+}
+
+// add appends an item to set k unless it is already there.
+func (p *earleyParser) add(k int, item earleyItem) {
+	if p.index[k][item] {
+		return
+	}
+	p.index[k][item] = true
+	p.sets[k] = append(p.sets[k], item)
+}
+
+// advance moves the dot of an item over one symbol.
+func advance(item earleyItem) earleyItem {
+	item.dot++
+	return item
+}
+
+// failure builds the ParseError for the farthest set the parser reached,
+// recording the tokens that set could scan.
+func (p *earleyParser) failure() error {
+	seen := make(map[string]bool)
+	for _, item := range p.sets[p.farthest] {
+		sequence := p.grammar.rules[item.rule].alternatives[item.alt].sequence
+		if item.dot == len(sequence) {
+			continue
+		}
+		symbol := sequence[item.dot]
+		if _, isToken := p.grammar.tokens[symbol]; isToken && !seen[symbol] {
+			seen[symbol] = true
+			p.expected = append(p.expected, symbol)
+		}
+	}
+
+	if p.farthest < len(p.tokens) {
+		token := p.tokens[p.farthest]
+		message := fmt.Sprintf("unexpected token: %s", token.Value)
+		if len(p.expected) > 0 {
+			message += fmt.Sprintf(" (expected %s)", strings.Join(p.expected, ", "))
+		}
+		return createParseError(message, token.Start, token.Value, p.input)
+	}
+	return createParseError("unexpected end of input", len(p.input), "<end of input>", p.input)
+}
+
+// nullableRules returns the rules that can match the empty input.
+func (g *Grammar) nullableRules() map[string]bool {
+	nullable := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for name, rule := range g.rules {
+			if nullable[name] {
+				continue
+			}
+			for _, alt := range rule.alternatives {
+				empty := true
+				for _, symbol := range alt.sequence {
+					if !nullable[symbol] {
+						empty = false
+						break
+					}
+				}
+				if empty {
+					nullable[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return nullable
+}
+
+// node returns the shared forest node for a symbol spanning tokens from..to.
+// Nodes are registered before their derivations are built, so cyclic
+// grammars such as a -> a produce a cyclic forest instead of recursing.
+func (p *earleyParser) node(symbol string, from, to int) *ForestNode {
+	key := forestKey{symbol, from, to}
+	if node, exists := p.nodes[key]; exists {
+		return node
+	}
+
+	node := &ForestNode{Start: len(p.input), End: len(p.input)}
+	if from < len(p.tokens) {
+		node.Start = p.tokens[from].Start
+		node.End = node.Start
+	}
+	if to > from {
+		node.End = p.tokens[to-1].End
+	}
+	p.nodes[key] = node
+
+	if _, isToken := p.grammar.tokens[symbol]; isToken {
+		token := p.tokens[from]
+		node.Token = &token
+		return node
+	}
+
+	node.Rule = symbol
+	for i, alt := range p.grammar.rules[symbol].alternatives {
+		item := earleyItem{rule: symbol, alt: i, dot: len(alt.sequence), origin: from}
+		if !p.index[to][item] {
+			continue
+		}
+		ctx := PredicateContext{Rule: symbol, Alternative: i, Tokens: p.tokens[from:to:to], Context: p.context}
+		if !p.grammar.accepts(alt, ctx) {
+			continue // Rejected derivations are left out of the forest
+		}
+		for _, children := range p.split(item, to) {
+			node.Packed = append(node.Packed, &PackedNode{Alternative: i, Children: children})
+		}
+	}
+	sortPacked(node.Packed)
+	return node
+}
+
+// split returns every way the symbols before the dot of item, which is in
+// set end, divide the tokens between the item's origin and end.
+func (p *earleyParser) split(item earleyItem, end int) [][]*ForestNode {
+	if item.dot == 0 {
+		return [][]*ForestNode{nil}
+	}
+	key := splitKey{item, end}
+	if children, exists := p.splits[key]; exists {
+		return children
+	}
+
+	var result [][]*ForestNode
+	symbol := p.grammar.rules[item.rule].alternatives[item.alt].sequence[item.dot-1]
+	previous := item
+	previous.dot--
+	if IsOperator(symbol) {
+		// Operators match no input and have no forest node
+		if p.index[end][previous] {
+			result = p.split(previous, end)
+		}
+		p.splits[key] = result
+		return result
+	}
+	extend := func(mid int) {
+		if !p.index[mid][previous] {
+			return
+		}
+		last := p.node(symbol, mid, end)
+		for _, prefix := range p.split(previous, mid) {
+			children := make([]*ForestNode, len(prefix), len(prefix)+1)
+			copy(children, prefix)
+			result = append(result, append(children, last))
+		}
+	}
+
+	if _, isToken := p.grammar.tokens[symbol]; isToken {
+		if end > item.origin && p.tokens[end-1].TokenType == symbol {
+			extend(end - 1)
+		}
+	} else {
+		for _, mid := range p.completed[end][symbol] {
+			if mid >= item.origin {
+				extend(mid)
+			}
+		}
+	}
+	p.splits[key] = result
+	return result
+}
+
+// earley parses code into a parse forest.
+func (d *DSL) earley(code string) (*earleyParser, *Forest, error) {
+	p, err := newEarleyParser(d.grammar, code)
+	p.context = d.context
+	forest := &Forest{Source: code, Tokens: p.tokens, Trivia: p.trivia}
+	if err != nil {
+		return p, forest, err
+	}
+	if err := p.recognize(); err != nil {
+		return p, forest, err
+	}
+	forest.Root = p.node(d.grammar.startRule, 0, len(p.tokens))
+	if d.grammar.hasPredicates() && forest.Count() == 0 {
+		forest.Root = nil
+		return p, forest, fmt.Errorf("predicates rejected every derivation of rule %s", d.grammar.startRule)
+	}
+	return p, forest, nil
+}
+
+// ParseForest parses code with the Earley parser, whatever backend is
+// selected, and returns a shared packed parse forest holding every
+// derivation of the input. No actions run; derivations rejected by
+// predicates are left out.
+//
+// Example:
+//
+//	forest, err := dsl.ParseForest("1 + 2 * 3")
+//	if err == nil && forest.Ambiguous() {
+//	    for _, tree := range forest.Trees(10) {
+//	        fmt.Println(tree)
+//	    }
+//	}
+func (d *DSL) ParseForest(code string) (*Forest, error) {
+	_, forest, err := d.earley(code)
+	if err != nil {
+		if IsParseError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("parsing error: %w", err)
+	}
+	return forest, nil
+}
+
+// parseEarley implements Parse for BackendEarley.
+func (d *DSL) parseEarley(code string) (*Result, error) {
+	forest, err := d.ParseForest(code)
+	if err != nil {
+		return nil, err
+	}
+	output, err := d.EvalTree(forest.Tree())
+	if err != nil {
+		return nil, fmt.Errorf("parsing error: %w", err)
+	}
+	return &Result{
+		AST:    output,
+		Code:   code,
+		Output: output,
+		DSL:    d,
+	}, nil
+}
+
+// parseTreeEarley implements ParseTree for BackendEarley.
+func (d *DSL) parseTreeEarley(code string) (*Tree, error) {
+	p, forest, err := d.earley(code)
+	tree := &Tree{
+		Source:         code,
+		Tokens:         p.tokens,
+		Trivia:         p.trivia,
+		Expected:       p.expected,
+		ExpectedOffset: len(code),
+	}
+	if p.farthest < len(p.tokens) {
+		tree.ExpectedOffset = p.tokens[p.farthest].Start
+	}
+
+	if err != nil {
+		if IsParseError(err) {
+			return tree, err
+		}
+		return tree, fmt.Errorf("parsing error: %w", err)
+	}
+	tree.Root = forest.Tree()
+	return tree, nil
+}
@@ -0,0 +1,232 @@
+package dslbuilder
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAmbiguousCalc builds e -> e + e | e * e | NUMBER, which has no
+// precedence and is ambiguous for any input with two operators.
+func newAmbiguousCalc(t *testing.T) *DSL {
+	dsl := New("ambiguous")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("TIMES", "\\*"))
+	dsl.Rule("e", []string{"e", "PLUS", "e"}, "add")
+	dsl.Rule("e", []string{"e", "TIMES", "e"}, "mul")
+	dsl.Rule("e", []string{"NUMBER"}, "number")
+	dsl.Action("add", func(args []interface{}) (interface{}, error) {
+		return args[0].(int) + args[2].(int), nil
+	})
+	dsl.Action("mul", func(args []interface{}) (interface{}, error) {
+		return args[0].(int) * args[2].(int), nil
+	})
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		return strconv.Atoi(args[0].(string))
+	})
+	return dsl
+}
+
+func TestParseForest(t *testing.T) {
+	dsl := newAmbiguousCalc(t)
+	forest, err := dsl.ParseForest("1 + 2 * 3")
+	require.NoError(t, err)
+
+	assert.True(t, forest.Ambiguous())
+	assert.Equal(t, 2, forest.Count())
+	require.Len(t, forest.Ambiguities(), 1)
+	assert.Same(t, forest.Root, forest.Ambiguities()[0])
+
+	var trees []string
+	var values []interface{}
+	for _, tree := range forest.Trees(10) {
+		trees = append(trees, tree.String())
+		value, err := dsl.EvalTree(tree)
+		require.NoError(t, err)
+		values = append(values, value)
+	}
+	assert.Equal(t, []string{
+		`(e (e NUMBER:"1") PLUS:"+" (e (e NUMBER:"2") TIMES:"*" (e NUMBER:"3")))`,
+		`(e (e (e NUMBER:"1") PLUS:"+" (e NUMBER:"2")) TIMES:"*" (e NUMBER:"3"))`,
+	}, trees)
+	assert.Equal(t, []interface{}{7, 9}, values)
+	assert.Len(t, forest.Trees(1), 1)
+}
+
+func TestParseForestSharesNodes(t *testing.T) {
+	dsl := newAmbiguousCalc(t)
+	forest, err := dsl.ParseForest("1 + 2 + 3 + 4 + 5 + 6 + 7 + 8 + 9 + 10")
+	require.NoError(t, err)
+
+	// Catalan(9) derivations from a forest of polynomial size
+	assert.Equal(t, 4862, forest.Count())
+	assert.Len(t, forest.Trees(100), 100)
+	assert.Equal(t, `(e (e (e NUMBER:"1") PLUS:"+" (e NUMBER:"2")) PLUS:"+" (e NUMBER:"3"))`,
+		mustForest(t, dsl, "1 + 2 + 3").Tree().String(), "preferred derivation is left-associative")
+}
+
+func mustForest(t *testing.T, dsl *DSL, code string) *Forest {
+	forest, err := dsl.ParseForest(code)
+	require.NoError(t, err, code)
+	return forest
+}
+
+func TestEarleyBackend(t *testing.T) {
+	dsl := newAmbiguousCalc(t)
+	assert.Equal(t, BackendPEG, dsl.Backend())
+	dsl.SetBackend(BackendEarley)
+	assert.Equal(t, "earley", dsl.Backend().String())
+
+	result, err := dsl.Parse("2 * 3 + 4")
+	require.NoError(t, err)
+	assert.Equal(t, 10, result.GetOutput())
+
+	tree, err := dsl.ParseTree("2 * 3")
+	require.NoError(t, err)
+	assert.Equal(t, `(e (e NUMBER:"2") TIMES:"*" (e NUMBER:"3"))`, tree.Root.String())
+	assert.Equal(t, "2 * 3", tree.Root.Text(tree.Source))
+}
+
+func TestForestChoose(t *testing.T) {
+	// PP attachment: "with debt" can qualify the clients or the orders
+	dsl := New("query")
+	require.NoError(t, dsl.KeywordToken("SHOW", "show"))
+	require.NoError(t, dsl.KeywordToken("FROM", "from"))
+	require.NoError(t, dsl.KeywordToken("WITH", "with"))
+	require.NoError(t, dsl.Token("NOUN", "[a-z]+"))
+	dsl.Rule("command", []string{"SHOW", "np"}, "")
+	dsl.Rule("np", []string{"np", "pp"}, "")
+	dsl.Rule("np", []string{"NOUN"}, "")
+	dsl.Rule("pp", []string{"FROM", "np"}, "")
+	dsl.Rule("pp", []string{"WITH", "np"}, "")
+
+	forest := mustForest(t, dsl, "show orders from clients with debt")
+	assert.Equal(t, 2, forest.Count())
+	ambiguities := forest.Ambiguities()
+	require.Len(t, ambiguities, 1)
+	assert.Equal(t, "np", ambiguities[0].Name())
+	assert.Equal(t, "orders from clients with debt", forest.Source[ambiguities[0].Start:ambiguities[0].End])
+
+	assert.Equal(t,
+		`(command SHOW:"show" (np (np (np NOUN:"orders") (pp FROM:"from" (np NOUN:"clients"))) (pp WITH:"with" (np NOUN:"debt"))))`,
+		forest.Tree().String())
+
+	var seen []*ForestNode
+	tree := forest.Choose(func(node *ForestNode, choices []*PackedNode) int {
+		seen = append(seen, node)
+		return len(choices) - 1
+	})
+	assert.Equal(t, ambiguities, seen)
+	assert.Equal(t,
+		`(command SHOW:"show" (np (np NOUN:"orders") (pp FROM:"from" (np (np NOUN:"clients") (pp WITH:"with" (np NOUN:"debt"))))))`,
+		tree.String())
+}
+
+func TestEarleyParsesWhatPEGRejects(t *testing.T) {
+	// Ordered choice commits to "a -> X" and never retries "a -> X X"
+	dsl := New("choice")
+	require.NoError(t, dsl.Token("X", "x"))
+	require.NoError(t, dsl.Token("B", "b"))
+	dsl.Rule("s", []string{"a", "B"}, "")
+	dsl.Rule("a", []string{"X"}, "")
+	dsl.Rule("a", []string{"X", "X"}, "")
+
+	_, err := dsl.Parse("x x b")
+	assert.Error(t, err)
+
+	dsl.SetBackend(BackendEarley)
+	result, err := dsl.Parse("x x b")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{"x", "x"}, "b"}, result.GetOutput())
+}
+
+func TestEarleyGrammars(t *testing.T) {
+	dsl := New("grammars")
+	require.NoError(t, dsl.Token("X", "x"))
+	require.NoError(t, dsl.Token("Y", "y"))
+	require.NoError(t, dsl.Token("COMMA", ","))
+	dsl.Rule("s", []string{"list", "opt", "indirect"}, "")
+	// Nullable, left-recursive list
+	dsl.Rule("list", []string{"list", "X", "COMMA"}, "")
+	dsl.Rule("list", []string{}, "")
+	dsl.Rule("opt", []string{"Y"}, "")
+	dsl.Rule("opt", []string{}, "")
+	// Indirect left recursion through a cycle: indirect -> loop -> indirect
+	dsl.Rule("indirect", []string{"loop", "X"}, "")
+	dsl.Rule("indirect", []string{"Y"}, "")
+	dsl.Rule("loop", []string{"indirect"}, "")
+	dsl.Rule("loop", []string{"loop"}, "")
+
+	for input, count := range map[string]int{
+		"y":            1,
+		"x, x, y":      1,
+		"y y x x":      1,
+		"x, y y x":     1,
+		"x, x, x, y x": 1,
+	} {
+		forest := mustForest(t, dsl, input)
+		assert.Equal(t, count, forest.Count(), input)
+		assert.NotNil(t, forest.Tree(), input)
+		assert.Len(t, forest.Trees(5), count, input)
+	}
+	assert.Equal(t, 0, mustForest(t, dsl, "y").Root.Packed[0].Children[0].End, "empty list")
+}
+
+func TestEarleyErrors(t *testing.T) {
+	dsl := newAmbiguousCalc(t)
+	dsl.SetBackend(BackendEarley)
+
+	_, err := dsl.Parse("1 + * 2")
+	require.True(t, IsParseError(err))
+	assert.Equal(t, "unexpected token: * (expected NUMBER)", err.Error())
+	assert.Equal(t, 4, err.(*ParseError).Position)
+
+	_, err = dsl.Parse("1 +")
+	assert.EqualError(t, err, "unexpected end of input")
+
+	_, err = dsl.Parse("1 ? 2")
+	assert.True(t, IsParseError(err))
+
+	tree, err := dsl.ParseTree("1 + 2 *")
+	assert.Error(t, err)
+	assert.Nil(t, tree.Root)
+	assert.Equal(t, []string{"NUMBER"}, tree.Expected)
+	assert.Equal(t, 7, tree.ExpectedOffset)
+
+	dsl.Action("mul", func(args []interface{}) (interface{}, error) {
+		return nil, assert.AnError
+	})
+	_, err = dsl.Parse("2 * 3")
+	assert.ErrorIs(t, err, assert.AnError)
+
+	undefined := New("undefined")
+	require.NoError(t, undefined.Token("X", "x"))
+	undefined.Rule("s", []string{"X", "missing"}, "")
+	_, err = undefined.ParseForest("x")
+	assert.EqualError(t, err, "parsing error: rule missing not found")
+}
+
+func TestEvalTreeMatchesParse(t *testing.T) {
+	dsl := newTreeDSL(t)
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		return strconv.Atoi(args[0].(string))
+	})
+	dsl.Action("add", func(args []interface{}) (interface{}, error) {
+		return args[0].(int) + args[2].(int), nil
+	})
+	dsl.Action("pass", func(args []interface{}) (interface{}, error) {
+		return args[0], nil
+	})
+
+	result, err := dsl.Parse("let x = 1 + 2 + 3")
+	require.NoError(t, err)
+	tree, err := dsl.ParseTree("let x = 1 + 2 + 3")
+	require.NoError(t, err)
+	value, err := dsl.EvalTree(tree.Root)
+	require.NoError(t, err)
+	assert.Equal(t, result.GetOutput(), value)
+	assert.Equal(t, []interface{}{"let", "x", "=", 6}, value)
+}
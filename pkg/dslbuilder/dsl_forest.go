@@ -0,0 +1,321 @@
+// Package dslbuilder - Shared packed parse forests
+package dslbuilder
+
+import (
+	"math"
+	"sort"
+)
+
+// ForestNode is a node of a shared packed parse forest built by ParseForest.
+// A rule node stands for every derivation of the rule over its span; each
+// derivation is a PackedNode. Token leaves carry the matched token. Nodes
+// are shared between derivations, and Start and End are byte offsets into
+// the source.
+type ForestNode struct {
+	Rule   string        // Rule name; empty for token leaves
+	Token  *TokenMatch   // Matched token for leaves, nil for rule nodes
+	Packed []*PackedNode // Derivations of a rule node, preferred first
+	Start  int           // Byte offset of the first character
+	End    int           // Byte offset after the last character
+}
+
+// PackedNode is one derivation of a rule node: the alternative that matched
+// and the forest nodes of its symbols.
+type PackedNode struct {
+	Alternative int           // Index of the matched alternative
+	Children    []*ForestNode // Nodes of the alternative's symbols in order
+}
+
+// IsToken reports whether the node is a token leaf.
+func (n *ForestNode) IsToken() bool {
+	return n.Token != nil
+}
+
+// Name returns the rule name, or the token type for leaves.
+func (n *ForestNode) Name() string {
+	if n.Token != nil {
+		return n.Token.TokenType
+	}
+	return n.Rule
+}
+
+// Ambiguous reports whether the rule node has more than one derivation.
+func (n *ForestNode) Ambiguous() bool {
+	return len(n.Packed) > 1
+}
+
+// Forest is the result of ParseForest.
+type Forest struct {
+	Root   *ForestNode  // Node of the start rule over the whole input
+	Source string       // Parsed source
+	Tokens []TokenMatch // Tokens of the source
+	Trivia []TokenMatch // Skipped tokens such as comments, in source order
+}
+
+// Chooser picks the derivation of an ambiguous forest node by returning an
+// index into choices. Choices are ordered by preference, as Tree picks them,
+// and exclude derivations that would repeat a node already being expanded.
+// An index out of range picks the first choice.
+type Chooser func(node *ForestNode, choices []*PackedNode) int
+
+// sortPacked orders derivations by preference: earlier alternatives first,
+// then longer leftmost children.
+func sortPacked(packed []*PackedNode) {
+	sort.SliceStable(packed, func(i, j int) bool {
+		a, b := packed[i], packed[j]
+		if a.Alternative != b.Alternative {
+			return a.Alternative < b.Alternative
+		}
+		for k := range a.Children {
+			if a.Children[k].End != b.Children[k].End {
+				return a.Children[k].End > b.Children[k].End
+			}
+		}
+		return false
+	})
+}
+
+// Ambiguous reports whether the input has more than one derivation.
+func (f *Forest) Ambiguous() bool {
+	return len(f.Ambiguities()) > 0
+}
+
+// Ambiguities returns the ambiguous nodes of the forest ordered by start
+// offset, longer spans first.
+func (f *Forest) Ambiguities() []*ForestNode {
+	var nodes []*ForestNode
+	visited := make(map[*ForestNode]bool)
+	var visit func(n *ForestNode)
+	visit = func(n *ForestNode) {
+		if n == nil || visited[n] {
+			return
+		}
+		visited[n] = true
+		if n.Ambiguous() {
+			nodes = append(nodes, n)
+		}
+		for _, packed := range n.Packed {
+			for _, child := range packed.Children {
+				visit(child)
+			}
+		}
+	}
+	visit(f.Root)
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Start != nodes[j].Start {
+			return nodes[i].Start < nodes[j].Start
+		}
+		return nodes[i].End > nodes[j].End
+	})
+	return nodes
+}
+
+// Count returns the number of derivations of the input, saturating at
+// math.MaxInt. Derivations that go around a cycle of the grammar, such as
+// a -> a, are not counted.
+func (f *Forest) Count() int {
+	counts := make(map[*ForestNode]int)
+	var count func(n *ForestNode) int
+	count = func(n *ForestNode) int {
+		if n.Token != nil {
+			return 1
+		}
+		if c, exists := counts[n]; exists {
+			return max(c, 0)
+		}
+		counts[n] = -1 // In progress: a cycle contributes nothing
+		total := 0
+		for _, packed := range n.Packed {
+			product := 1
+			for _, child := range packed.Children {
+				product = saturatingMul(product, count(child))
+			}
+			total = saturatingAdd(total, product)
+		}
+		counts[n] = total
+		return total
+	}
+	if f.Root == nil {
+		return 0
+	}
+	return count(f.Root)
+}
+
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+func saturatingMul(a, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
+}
+
+// Tree returns the preferred derivation: earlier alternatives first, then
+// longer leftmost children, which makes ambiguous binary operators
+// left-associative. This is the derivation Parse evaluates with
+// BackendEarley.
+func (f *Forest) Tree() *Node {
+	return f.Choose(nil)
+}
+
+// Choose returns the derivation selected by choose at every ambiguous node.
+// A nil chooser takes the preferred derivation, as Tree does.
+//
+// Example:
+//
+//	// Prefer the last alternative, e.g. to attach "else" to the outer "if"
+//	tree := forest.Choose(func(node *dslbuilder.ForestNode, choices []*dslbuilder.PackedNode) int {
+//	    return len(choices) - 1
+//	})
+func (f *Forest) Choose(choose Chooser) *Node {
+	if f.Root == nil {
+		return nil
+	}
+	return f.extract(f.Root, choose, make(map[*ForestNode]bool))
+}
+
+// extract builds the tree of a node, skipping derivations that revisit a
+// node on the current path. It returns nil if none is left.
+func (f *Forest) extract(n *ForestNode, choose Chooser, path map[*ForestNode]bool) *Node {
+	if n.Token != nil {
+		return &Node{Alternative: -1, Token: n.Token, Start: n.Start, End: n.End}
+	}
+
+	path[n] = true
+	defer delete(path, n)
+
+	choices := acyclic(n, path)
+	if choose != nil && len(choices) > 1 {
+		if i := choose(n, choices); i > 0 && i < len(choices) {
+			choices = append([]*PackedNode{choices[i]}, append(choices[:i:i], choices[i+1:]...)...)
+		}
+	}
+
+next:
+	for _, packed := range choices {
+		node := &Node{Rule: n.Rule, Alternative: packed.Alternative, Start: n.Start, End: n.End}
+		for _, child := range packed.Children {
+			tree := f.extract(child, choose, path)
+			if tree == nil {
+				continue next
+			}
+			node.Children = append(node.Children, tree)
+		}
+		return node
+	}
+	return nil
+}
+
+// acyclic returns the derivations of n whose children are not on path.
+func acyclic(n *ForestNode, path map[*ForestNode]bool) []*PackedNode {
+	choices := make([]*PackedNode, 0, len(n.Packed))
+next:
+	for _, packed := range n.Packed {
+		for _, child := range packed.Children {
+			if path[child] {
+				continue next
+			}
+		}
+		choices = append(choices, packed)
+	}
+	return choices
+}
+
+// Trees enumerates up to limit derivations of the input, preferred first.
+// Subtrees may be shared between the returned trees.
+func (f *Forest) Trees(limit int) []*Node {
+	if f.Root == nil || limit <= 0 {
+		return nil
+	}
+	return f.trees(f.Root, limit, make(map[*ForestNode]bool))
+}
+
+// trees enumerates up to limit derivations of a node.
+func (f *Forest) trees(n *ForestNode, limit int, path map[*ForestNode]bool) []*Node {
+	if n.Token != nil {
+		return []*Node{{Alternative: -1, Token: n.Token, Start: n.Start, End: n.End}}
+	}
+
+	path[n] = true
+	defer delete(path, n)
+
+	var result []*Node
+	for _, packed := range acyclic(n, path) {
+		combinations := [][]*Node{nil}
+		for _, child := range packed.Children {
+			subtrees := f.trees(child, limit, path)
+			var extended [][]*Node
+			for _, prefix := range combinations {
+				for _, subtree := range subtrees {
+					if len(extended) == limit {
+						break
+					}
+					children := make([]*Node, len(prefix), len(prefix)+1)
+					copy(children, prefix)
+					extended = append(extended, append(children, subtree))
+				}
+			}
+			combinations = extended
+		}
+		for _, children := range combinations {
+			if len(result) == limit {
+				return result
+			}
+			result = append(result, &Node{Rule: n.Rule, Alternative: packed.Alternative, Children: children, Start: n.Start, End: n.End})
+		}
+	}
+	return result
+}
+
+// EvalTree runs the grammar's actions over a syntax tree, such as one
+// returned by ParseTree or picked from a parse forest, and returns the
+// value of the root. Actions receive the same arguments as during Parse:
+// token text for token leaves and the values of child rules. An action
+// error stops evaluation and is returned.
+//
+// Example:
+//
+//	for _, tree := range forest.Trees(10) {
+//	    value, err := dsl.EvalTree(tree)
+//	    fmt.Println(value, err)
+//	}
+func (d *DSL) EvalTree(root *Node) (interface{}, error) {
+	if root == nil {
+		return nil, nil
+	}
+	if root.Token != nil {
+		return root.Token.Value, nil
+	}
+
+	var args []interface{}
+	var consumed []string
+	for _, child := range root.Children {
+		value, err := d.EvalTree(child)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+		if child.Token != nil {
+			consumed = append(consumed, child.Token.TokenType)
+		}
+	}
+
+	if d.coverage != nil {
+		d.coverage.recordAlternative(root.Rule, root.Alternative, consumed)
+	}
+	rule, exists := d.grammar.rules[root.Rule]
+	if !exists || root.Alternative < 0 || root.Alternative >= len(rule.alternatives) {
+		return args, nil
+	}
+	alt := rule.alternatives[root.Alternative]
+	if action, exists := d.grammar.action(alt.action); exists && alt.action != "" {
+		return action(args)
+	}
+	return args, nil
+}
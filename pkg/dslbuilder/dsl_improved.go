@@ -5,7 +5,6 @@ package dslbuilder
 
 import (
 	"fmt"
-	"strings"
 )
 
 // ImprovedParser represents an improved DSL parser that handles left recursion.
@@ -25,17 +24,35 @@ import (
 //   - dsl: Parent DSL for function/context access
 //   - memo: Memoization table for Packrat parsing
 //   - input: Original input for error messages
+//   - trivia: Skipped tokens such as comments
 //   - leftRecStack: Stack for detecting left recursion cycles
 //   - growing: Track rules currently being grown
+//   - depth: Rule nesting depth reported to tracers
+//   - buildTree: Build syntax tree nodes instead of running actions
+//   - farthest/expected: Tokens expected at the farthest failure
+//   - rules/expectedRules: Rule stack, and the stack where each expected token was tried
+//   - committed: Failure after a cut, which ends the parse
+//   - lookaheads/memoAt/pruned: Lookahead nesting, and the memo index cuts prune
 type ImprovedParser struct {
-	grammar      *Grammar
-	tokens       []TokenMatch
-	pos          int
-	dsl          *DSL
-	memo         map[string]map[int]memoEntry // Memoization for packrat parsing
-	input        string                       // Original input for error reporting
-	leftRecStack []string                     // Stack to detect left recursion
-	growing      map[string]bool              // Rules currently being grown
+	grammar       *Grammar
+	tokens        []TokenMatch
+	pos           int
+	dsl           *DSL
+	memo          map[string]map[int]memoEntry // Memoization for packrat parsing
+	input         string                       // Original input for error reporting
+	trivia        []TokenMatch                 // Skipped tokens (SkipToken), in source order
+	leftRecStack  []string                     // Stack to detect left recursion
+	growing       map[string]bool              // Rules currently being grown
+	depth         int                          // Rule nesting depth for tracing
+	buildTree     bool                         // Build *Node results, skip actions
+	farthest      int                          // Farthest token position where a token was expected
+	expected      []string                     // Tokens expected at farthest
+	rules         []string                     // Rules being parsed, outermost first
+	expectedRules [][]string                   // Rule stack for each expected token
+	committed     error                        // Failure after a cut (Cut)
+	lookaheads    int                          // Depth of lookahead tests in progress
+	memoAt        map[int][]string             // Rules memoized at each position, when the grammar has cuts
+	pruned        int                          // Positions below are dropped from the memo
 }
 
 // memoEntry stores the cached result of parsing a rule at a specific position.
@@ -95,11 +112,24 @@ func NewImprovedParser(grammar *Grammar) *ImprovedParser {
 func (p *ImprovedParser) Parse(code string) (interface{}, error) {
 	// Reset parser state
 	p.tokens = []TokenMatch{}
+	p.trivia = nil
 	p.pos = 0
 	p.memo = make(map[string]map[int]memoEntry)
 	p.input = code // Store input for error reporting
 	p.leftRecStack = []string{}
 	p.growing = make(map[string]bool)
+	p.depth = 0
+	p.farthest = 0
+	p.expected = nil
+	p.rules = nil
+	p.expectedRules = nil
+	p.committed = nil
+	p.lookaheads = 0
+	p.memoAt = nil
+	p.pruned = 0
+	if p.grammar.hasCuts() {
+		p.memoAt = make(map[int][]string)
+	}
 
 	// Tokenize
 	err := p.tokenize(code)
@@ -110,6 +140,9 @@ func (p *ImprovedParser) Parse(code string) (interface{}, error) {
 	// Parse from start rule
 	p.pos = 0
 	result, err := p.parseRuleWithMemo(p.grammar.startRule)
+	if p.committed != nil {
+		return nil, p.committed
+	}
 
 	// Check if we consumed all tokens
 	if err == nil && p.pos < len(p.tokens) {
@@ -120,66 +153,16 @@ func (p *ImprovedParser) Parse(code string) (interface{}, error) {
 	return result, err
 }
 
-// tokenize converts code into tokens (lexical analysis).
-// Uses the same algorithm as the basic parser but with
-// input tracking for better error messages.
-//
-// Token matching priority:
-//  1. Higher priority value wins (keywords > regular)
-//  2. For same priority, longest match wins
-//  3. Whitespace is automatically skipped
+// tokenize converts code into tokens (lexical analysis) with the lexer of
+// the grammar, the RegexLexer unless SetLexer installed another one.
+// Skipped tokens are collected as trivia.
 func (p *ImprovedParser) tokenize(code string) error {
-	code = strings.TrimSpace(code)
-	pos := 0
-
-	for pos < len(code) {
-		// Skip whitespace
-		if code[pos] == ' ' || code[pos] == '\t' || code[pos] == '\n' || code[pos] == '\r' {
-			pos++
-			continue
-		}
-
-		matched := false
-		bestMatch := TokenMatch{}
-		bestLength := 0
-		bestPriority := -1
-
-		// Find best matching token
-		for _, token := range p.grammar.tokens {
-			if matches := token.regex.FindStringIndex(code[pos:]); matches != nil && matches[0] == 0 {
-				matchLength := matches[1]
-
-				// Higher priority or longer match wins
-				shouldReplace := false
-				if token.priority > bestPriority {
-					shouldReplace = true
-				} else if token.priority == bestPriority && matchLength > bestLength {
-					shouldReplace = true
-				}
-
-				if shouldReplace {
-					bestLength = matchLength
-					bestPriority = token.priority
-					bestMatch = TokenMatch{
-						TokenType: token.name,
-						Value:     code[pos : pos+matchLength],
-						Start:     pos,
-						End:       pos + matchLength,
-					}
-					matched = true
-				}
-			}
-		}
-
-		if matched {
-			p.tokens = append(p.tokens, bestMatch)
-			pos += bestLength
-		} else {
-			message := fmt.Sprintf("unexpected character: %c", code[pos])
-			return createParseError(message, pos, string(code[pos]), p.input)
-		}
+	tokens, trivia, err := p.grammar.tokenize(code)
+	if err != nil {
+		return err
 	}
-
+	p.tokens = tokens
+	p.trivia = trivia
 	return nil
 }
 
@@ -203,6 +186,9 @@ func (p *ImprovedParser) parseRuleWithMemo(ruleName string) (interface{}, error)
 	// Check memo table
 	if ruleMemo, exists := p.memo[ruleName]; exists {
 		if entry, exists := ruleMemo[p.pos]; exists {
+			if p.tracing() {
+				p.trace(TraceEvent{Kind: TraceMemoHit, Rule: ruleName, Alternative: -1, Pos: p.pos, End: entry.endPos, Err: entry.err})
+			}
 			p.pos = entry.endPos
 			return entry.result, entry.err
 		}
@@ -211,17 +197,29 @@ func (p *ImprovedParser) parseRuleWithMemo(ruleName string) (interface{}, error)
 	}
 
 	startPos := p.pos
+	if p.tracing() {
+		p.trace(TraceEvent{Kind: TraceMemoMiss, Rule: ruleName, Alternative: -1, Pos: startPos, End: startPos})
+		p.trace(TraceEvent{Kind: TraceRuleEnter, Rule: ruleName, Alternative: -1, Pos: startPos, End: startPos})
+		p.depth++
+	}
+	p.rules = append(p.rules, ruleName)
 
-	// Use iterative approach for left-recursive rules
+	var result interface{}
+	var err error
 	if p.isLeftRecursive(ruleName) {
-		result, err := p.parseLeftRecursive(ruleName)
-		p.memo[ruleName][startPos] = memoEntry{result: result, endPos: p.pos, err: err}
-		return result, err
+		// Use iterative approach for left-recursive rules
+		result, err = p.parseLeftRecursive(ruleName)
+	} else {
+		// Regular recursive parsing for non-left-recursive rules
+		result, err = p.parseRuleRegular(ruleName)
 	}
+	p.remember(ruleName, startPos, memoEntry{result: result, endPos: p.pos, err: err})
+	p.rules = p.rules[:len(p.rules)-1]
 
-	// Regular recursive parsing for non-left-recursive rules
-	result, err := p.parseRuleRegular(ruleName)
-	p.memo[ruleName][startPos] = memoEntry{result: result, endPos: p.pos, err: err}
+	if p.tracing() {
+		p.depth--
+		p.trace(TraceEvent{Kind: TraceRuleExit, Rule: ruleName, Alternative: -1, Pos: startPos, End: p.pos, Err: err})
+	}
 	return result, err
 }
 
@@ -292,20 +290,23 @@ func (p *ImprovedParser) parseLeftRecursive(ruleName string) (interface{}, error
 	foundSeed := false
 
 	// First pass: try non-recursive alternatives
-	for _, alt := range rule.alternatives {
+	for i, alt := range rule.alternatives {
 		// Skip left-recursive alternatives in first pass
 		if len(alt.sequence) > 0 && alt.sequence[0] == ruleName {
 			continue
 		}
 
 		p.pos = startPos
-		result, err := p.parseAlternative(alt)
+		result, err := p.parseAlternative(ruleName, i, alt)
 		if err == nil {
 			seed = result
 			seedPos = p.pos
 			foundSeed = true
 			break
 		}
+		if p.committed != nil {
+			return nil, p.committed
+		}
 	}
 
 	// If no non-recursive alternative matched, try recursive ones with nil seed
@@ -322,7 +323,7 @@ func (p *ImprovedParser) parseLeftRecursive(ruleName string) (interface{}, error
 		bestPos := seedPos
 
 		// Try each left-recursive alternative with current seed
-		for _, alt := range rule.alternatives {
+		for i, alt := range rule.alternatives {
 			// Only process left-recursive alternatives
 			if len(alt.sequence) == 0 || alt.sequence[0] != ruleName {
 				continue
@@ -330,7 +331,7 @@ func (p *ImprovedParser) parseLeftRecursive(ruleName string) (interface{}, error
 
 			// Reset position for this attempt
 			p.pos = startPos
-			
+
 			// Temporarily install the seed in memo for this rule
 			if p.memo[ruleName] == nil {
 				p.memo[ruleName] = make(map[int]memoEntry)
@@ -341,59 +342,49 @@ func (p *ImprovedParser) parseLeftRecursive(ruleName string) (interface{}, error
 				err:    nil,
 			}
 
-			// Try to parse this alternative
-			var results []interface{}
-			results = append(results, seed) // Use seed as first element
-
-			// Parse remaining symbols after the recursive call
-			p.pos = seedPos // Start after the seed
-			success := true
-			
-			for i := 1; i < len(alt.sequence); i++ {
-				symbol := alt.sequence[i]
-
-				if p.pos >= len(p.tokens) {
-					success = false
-					break
-				}
-
-				// Check if symbol is a token
-				if _, isToken := p.grammar.tokens[symbol]; isToken {
-					if p.tokens[p.pos].TokenType == symbol {
-						results = append(results, p.tokens[p.pos].Value)
-						p.pos++
-					} else {
-						success = false
-						break
-					}
-				} else {
-					// Symbol is a rule - parse it
-					result, err := p.parseRuleWithMemo(symbol)
-					if err != nil {
-						success = false
-						break
-					}
-					results = append(results, result)
-				}
+			// Parse remaining symbols after the recursive call,
+			// using the seed as the first element
+			p.pos = seedPos
+			if p.tracing() {
+				p.trace(TraceEvent{Kind: TraceAlternativeTry, Rule: ruleName, Alternative: i, Sequence: alt.sequence, Pos: startPos, End: seedPos})
+			}
+			results, consumed, err := p.matchSymbols(ruleName, i, alt, 1, []interface{}{seed})
+			if err == nil {
+				err = p.checkPredicate(ruleName, i, alt, startPos)
 			}
+			success := err == nil
 
 			if success && p.pos > bestPos {
 				// We found a longer match - apply action
-				if alt.action != "" {
-					if action, exists := p.grammar.actions[alt.action]; exists {
-						actionResult, err := action(results)
-						if err == nil {
+				if p.buildTree {
+					bestResult = p.newNode(ruleName, i, results)
+					bestPos = p.pos
+					improved = true
+				} else if alt.action != "" {
+					if action, exists := p.grammar.action(alt.action); exists {
+						actionResult, actionErr := p.invokeAction(ruleName, i, alt.action, action, results)
+						if actionErr == nil {
 							bestResult = actionResult
 							bestPos = p.pos
 							improved = true
+							p.recordCoverage(ruleName, i, consumed)
 						}
+						err = actionErr
 					}
 				} else {
 					bestResult = results
 					bestPos = p.pos
 					improved = true
+					p.recordCoverage(ruleName, i, consumed)
 				}
 			}
+
+			if err != nil && p.tracing() {
+				p.trace(TraceEvent{Kind: TraceAlternativeFail, Rule: ruleName, Alternative: i, Sequence: alt.sequence, Pos: startPos, End: p.pos, Err: err})
+			}
+			if p.committed != nil {
+				return nil, p.committed
+			}
 		}
 
 		if !improved {
@@ -430,12 +421,15 @@ func (p *ImprovedParser) parseRuleRegular(ruleName string) (interface{}, error)
 	}
 
 	// Try each alternative
-	for _, alt := range rule.alternatives {
+	for i, alt := range rule.alternatives {
 		savedPos := p.pos
-		result, err := p.parseAlternative(alt)
+		result, err := p.parseAlternative(ruleName, i, alt)
 		if err == nil {
 			return result, nil
 		}
+		if p.committed != nil {
+			return nil, p.committed
+		}
 		// Restore position if failed
 		p.pos = savedPos
 	}
@@ -473,47 +467,144 @@ func (p *ImprovedParser) parseRuleRegular(ruleName string) (interface{}, error)
 //
 //	Alternative: ["IF", "expr", "THEN", "stmt"]
 //	Results: ["if", exprValue, "then", stmtValue]
-func (p *ImprovedParser) parseAlternative(alt *Alternative) (interface{}, error) {
-	var results []interface{}
-
-	for _, symbol := range alt.sequence {
-		if p.pos >= len(p.tokens) {
-			message := "unexpected end of input"
-			position := len(p.input)
-			return nil, createParseError(message, position, "<end of input>", p.input)
+func (p *ImprovedParser) parseAlternative(ruleName string, index int, alt *Alternative) (interface{}, error) {
+	startPos := p.pos
+	if p.tracing() {
+		p.trace(TraceEvent{Kind: TraceAlternativeTry, Rule: ruleName, Alternative: index, Sequence: alt.sequence, Pos: startPos, End: startPos})
+	}
+
+	results, consumed, err := p.matchSymbols(ruleName, index, alt, 0, nil)
+	if err == nil {
+		err = p.checkPredicate(ruleName, index, alt, startPos)
+	}
+	if err != nil {
+		p.traceAlternativeFail(ruleName, index, alt, startPos, err)
+		return nil, err
+	}
+
+	if p.buildTree {
+		return p.newNode(ruleName, index, results), nil
+	}
+
+	// Apply action if available
+	if alt.action != "" {
+		if action, exists := p.grammar.action(alt.action); exists {
+			result, err := p.invokeAction(ruleName, index, alt.action, action, results)
+			if err != nil {
+				p.traceAlternativeFail(ruleName, index, alt, startPos, err)
+				return nil, err
+			}
+			p.recordCoverage(ruleName, index, consumed)
+			return result, nil
+		}
+	}
+
+	p.recordCoverage(ruleName, index, consumed)
+	return results, nil
+}
+
+// invokeAction runs an action and reports it to the tracer.
+func (p *ImprovedParser) invokeAction(ruleName string, index int, name string, action ActionFunc, args []interface{}) (interface{}, error) {
+	result, err := action(args)
+	if p.tracing() {
+		p.trace(TraceEvent{Kind: TraceActionInvoked, Rule: ruleName, Alternative: index, Pos: p.pos, End: p.pos, Action: name, Err: err})
+	}
+	return result, err
+}
+
+// traceAlternativeFail reports a failed alternative to the tracer.
+func (p *ImprovedParser) traceAlternativeFail(ruleName string, index int, alt *Alternative, startPos int, err error) {
+	if p.tracing() {
+		p.trace(TraceEvent{Kind: TraceAlternativeFail, Rule: ruleName, Alternative: index, Sequence: alt.sequence, Pos: startPos, End: p.pos, Err: err})
+	}
+}
+
+// matchSymbols matches the symbols of an alternative starting at index from,
+// appending matched values to results. It returns the collected values and
+// the token types consumed directly by this alternative.
+func (p *ImprovedParser) matchSymbols(ruleName string, index int, alt *Alternative, from int, results []interface{}) ([]interface{}, []string, error) {
+	var consumed []string
+	cut := false
+
+	for _, symbol := range alt.sequence[from:] {
+		// Operators match no input
+		if symbol == Cut {
+			cut = true
+			p.cut()
+			continue
+		}
+		if _, _, ok := LookaheadOf(symbol); ok {
+			if err := p.testLookahead(symbol); err != nil {
+				return nil, nil, p.commit(cut, err)
+			}
+			continue
 		}
 
 		// Check if symbol is a token
 		if _, isToken := p.grammar.tokens[symbol]; isToken {
+			if p.pos >= len(p.tokens) {
+				p.expect(symbol)
+				message := "unexpected end of input"
+				position := len(p.input)
+				return nil, nil, p.commit(cut, createParseError(message, position, "<end of input>", p.input))
+			}
 			if p.tokens[p.pos].TokenType == symbol {
-				results = append(results, p.tokens[p.pos].Value)
+				if p.tracing() {
+					token := p.tokens[p.pos]
+					p.trace(TraceEvent{Kind: TraceTokenConsumed, Rule: ruleName, Alternative: index, Pos: p.pos, End: p.pos + 1, Token: &token})
+				}
+				if p.buildTree {
+					token := p.tokens[p.pos]
+					results = append(results, &Node{Alternative: -1, Token: &token, Start: token.Start, End: token.End})
+				} else {
+					results = append(results, p.tokens[p.pos].Value)
+				}
+				consumed = append(consumed, symbol)
 				p.pos++
 			} else {
+				p.expect(symbol)
 				message := fmt.Sprintf("expected token %s, got %s", symbol, p.tokens[p.pos].TokenType)
-				return nil, createParseError(message, p.tokens[p.pos].Start, p.tokens[p.pos].Value, p.input)
+				return nil, nil, p.commit(cut, createParseError(message, p.tokens[p.pos].Start, p.tokens[p.pos].Value, p.input))
 			}
 		} else {
 			// Symbol is a rule
 			result, err := p.parseRuleWithMemo(symbol)
 			if err != nil {
-				return nil, err
+				return nil, nil, p.commit(cut, err)
 			}
 			results = append(results, result)
 		}
 	}
 
-	// Apply action if available
-	if alt.action != "" {
-		if action, exists := p.grammar.actions[alt.action]; exists {
-			result, err := action(results)
-			if err != nil {
-				return nil, err
-			}
-			return result, nil
+	return results, consumed, nil
+}
+
+// expect records that a token was expected at the current position.
+// Only the farthest position is kept, which is where the input most
+// likely went wrong or, for a prefix, what may come next.
+func (p *ImprovedParser) expect(symbol string) {
+	if p.pos > p.farthest {
+		p.farthest = p.pos
+		p.expected = nil
+		p.expectedRules = nil
+	}
+	if p.pos < p.farthest {
+		return
+	}
+	for _, e := range p.expected {
+		if e == symbol {
+			return
 		}
 	}
+	p.expected = append(p.expected, symbol)
+	p.expectedRules = append(p.expectedRules, append([]string(nil), p.rules...))
+}
 
-	return results, nil
+// recordCoverage counts a successful alternative when coverage is enabled.
+func (p *ImprovedParser) recordCoverage(ruleName string, index int, consumed []string) {
+	if p.dsl != nil && p.dsl.coverage != nil {
+		p.dsl.coverage.recordAlternative(ruleName, index, consumed)
+	}
 }
 
 // ParseWithContext enables parsing with additional context that can be accessed
@@ -180,7 +180,7 @@ func (p *ImprovedParserV2) parseRuleWithLeftRec(ruleName string, seed interface{
 			if success && p.pos > bestEndPos {
 				// Apply action if available
 				if alt.action != "" {
-					if action, exists := p.grammar.actions[alt.action]; exists {
+					if action, exists := p.grammar.action(alt.action); exists {
 						actionResult, err := action(results)
 						if err == nil {
 							bestResult = actionResult
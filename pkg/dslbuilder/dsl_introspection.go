@@ -0,0 +1,181 @@
+// Package dslbuilder - Read-only grammar introspection
+package dslbuilder
+
+import "regexp"
+
+// TokenInfo is a read-only view of a token definition.
+// It is returned by DSL.Tokens and Grammar.Tokens for tooling such as
+// REPLs, documentation generators and validators.
+type TokenInfo struct {
+	Name       string        // Token identifier used in rules
+	Pattern    string        // Regex pattern string
+	Priority   int           // Matching priority (keywords=90, lookaround=50, regular=0)
+	Keyword    string        // Literal keyword text for keyword tokens, empty otherwise
+	Lookahead  string        // Positive lookahead pattern, if any
+	Lookbehind string        // Positive lookbehind pattern, if any
+	Category   TokenCategory // Highlighting category (keyword tokens default to keyword)
+	Skip       bool          // Matched but not passed to the parser (comments)
+	External   bool          // Produced only by a custom lexer (ExternalToken), no pattern
+}
+
+// IsKeyword reports whether the token was defined with KeywordToken.
+func (ti TokenInfo) IsKeyword() bool {
+	return ti.Keyword != ""
+}
+
+// Literal returns the fixed text of the token: the keyword of a keyword
+// token, or the text of a pattern that matches only one string, such as
+// "\\+" or "print". It reports false for tokens that match varying text.
+func (ti TokenInfo) Literal() (string, bool) {
+	if ti.IsKeyword() {
+		return ti.Keyword, true
+	}
+	if ti.External {
+		return "", false
+	}
+	re, err := regexp.Compile(ti.Pattern)
+	if err != nil {
+		return "", false
+	}
+	prefix, complete := re.LiteralPrefix()
+	return prefix, complete && prefix != ""
+}
+
+// AlternativeInfo is a read-only view of one alternative of a rule.
+type AlternativeInfo struct {
+	Sequence      []string // Symbol sequence to match
+	Action        string   // Action function name (may be empty)
+	Precedence    int      // Operator precedence
+	Associativity string   // "left", "right", or "none"
+	Predicate     string   // Predicate guarding the alternative (may be empty)
+}
+
+// RuleInfo is a read-only view of a rule and all its alternatives.
+type RuleInfo struct {
+	Name         string            // Rule identifier
+	Alternatives []AlternativeInfo // Alternatives in definition order
+}
+
+// Grammar returns the grammar of the DSL.
+// The returned grammar is shared with the DSL, so it reflects later
+// Token and Rule calls.
+func (d *DSL) Grammar() *Grammar {
+	return d.grammar
+}
+
+// Name returns the name the DSL was created with.
+func (d *DSL) Name() string {
+	return d.name
+}
+
+// Tokens returns all token definitions in the order they were defined.
+//
+// Example:
+//
+//	for _, tok := range dsl.Tokens() {
+//	    fmt.Printf("%s: %s\n", tok.Name, tok.Pattern)
+//	}
+func (d *DSL) Tokens() []TokenInfo {
+	return d.grammar.Tokens()
+}
+
+// Rules returns all rules with their alternatives in the order they were
+// first defined. The start rule is always first unless it was changed.
+func (d *DSL) Rules() []RuleInfo {
+	return d.grammar.Rules()
+}
+
+// StartRule returns the name of the rule parsing begins with.
+func (d *DSL) StartRule() string {
+	return d.grammar.startRule
+}
+
+// StartRule returns the name of the rule parsing begins with.
+func (g *Grammar) StartRule() string {
+	return g.startRule
+}
+
+// Tokens returns all token definitions in the order they were defined.
+func (g *Grammar) Tokens() []TokenInfo {
+	infos := make([]TokenInfo, 0, len(g.tokenOrder))
+	for _, name := range g.tokenOrder {
+		infos = append(infos, g.tokens[name].info())
+	}
+	return infos
+}
+
+// Token returns the definition of a token by name.
+func (g *Grammar) Token(name string) (TokenInfo, bool) {
+	token, exists := g.tokens[name]
+	if !exists {
+		return TokenInfo{}, false
+	}
+	return token.info(), true
+}
+
+// IsToken reports whether symbol names a token (as opposed to a rule).
+func (g *Grammar) IsToken(symbol string) bool {
+	_, exists := g.tokens[symbol]
+	return exists
+}
+
+// Rules returns all rules in the order they were first defined.
+func (g *Grammar) Rules() []RuleInfo {
+	infos := make([]RuleInfo, 0, len(g.ruleOrder))
+	for _, name := range g.ruleOrder {
+		rule := g.rules[name]
+		infos = append(infos, RuleInfo{
+			Name:         rule.name,
+			Alternatives: rule.Alternatives(),
+		})
+	}
+	return infos
+}
+
+// Rule returns a rule by name.
+func (g *Grammar) Rule(name string) (*Rule, bool) {
+	rule, exists := g.rules[name]
+	return rule, exists
+}
+
+// Name returns the rule identifier.
+func (r *Rule) Name() string {
+	return r.name
+}
+
+// Alternatives returns a copy of the rule alternatives in definition order.
+func (r *Rule) Alternatives() []AlternativeInfo {
+	infos := make([]AlternativeInfo, 0, len(r.alternatives))
+	for _, alt := range r.alternatives {
+		infos = append(infos, alt.info())
+	}
+	return infos
+}
+
+// info returns a read-only copy of the token definition.
+func (t *Token) info() TokenInfo {
+	return TokenInfo{
+		Name:       t.name,
+		Pattern:    t.pattern,
+		Priority:   t.priority,
+		Keyword:    t.keyword,
+		Lookahead:  t.lookahead,
+		Lookbehind: t.lookbehind,
+		Category:   t.effectiveCategory(),
+		Skip:       t.skip,
+		External:   t.regex == nil,
+	}
+}
+
+// info returns a read-only copy of the alternative.
+func (a *Alternative) info() AlternativeInfo {
+	sequence := make([]string, len(a.sequence))
+	copy(sequence, a.sequence)
+	return AlternativeInfo{
+		Sequence:      sequence,
+		Action:        a.action,
+		Precedence:    a.precedence,
+		Associativity: a.associativity,
+		Predicate:     a.predicate,
+	}
+}
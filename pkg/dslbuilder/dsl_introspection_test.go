@@ -0,0 +1,92 @@
+package dslbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokensIntrospection(t *testing.T) {
+	dsl := New("Introspection")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.TokenWithLookaround("EQ", "=", "\\s", ""))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	// Redefining a token keeps its original position
+	require.NoError(t, dsl.Token("ID", "[a-zA-Z]+"))
+
+	tokens := dsl.Tokens()
+	require.Len(t, tokens, 4)
+
+	names := []string{}
+	for _, tok := range tokens {
+		names = append(names, tok.Name)
+	}
+	assert.Equal(t, []string{"LET", "ID", "EQ", "NUMBER"}, names)
+
+	assert.True(t, tokens[0].IsKeyword())
+	assert.Equal(t, "let", tokens[0].Keyword)
+	assert.Equal(t, 90, tokens[0].Priority)
+	assert.Equal(t, "[a-zA-Z]+", tokens[1].Pattern)
+	assert.False(t, tokens[1].IsKeyword())
+	assert.Equal(t, 50, tokens[2].Priority)
+	assert.Equal(t, "\\s", tokens[2].Lookahead)
+
+	info, ok := dsl.Grammar().Token("NUMBER")
+	require.True(t, ok)
+	assert.Equal(t, "[0-9]+", info.Pattern)
+	_, ok = dsl.Grammar().Token("MISSING")
+	assert.False(t, ok)
+	assert.True(t, dsl.Grammar().IsToken("ID"))
+	assert.False(t, dsl.Grammar().IsToken("stmt"))
+}
+
+func TestRulesIntrospection(t *testing.T) {
+	dsl := New("Introspection")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	dsl.Rule("program", []string{"expr"}, "")
+	dsl.RuleWithPrecedence("expr", []string{"expr", "PLUS", "expr"}, "add", 10, "left")
+	dsl.Rule("expr", []string{"NUMBER"}, "number")
+
+	assert.Equal(t, "program", dsl.StartRule())
+	assert.Equal(t, "program", dsl.Grammar().StartRule())
+	assert.Equal(t, "Introspection", dsl.Name())
+
+	rules := dsl.Rules()
+	require.Len(t, rules, 2)
+	assert.Equal(t, "program", rules[0].Name)
+	assert.Equal(t, "expr", rules[1].Name)
+	require.Len(t, rules[1].Alternatives, 2)
+	assert.Equal(t, AlternativeInfo{
+		Sequence:      []string{"expr", "PLUS", "expr"},
+		Action:        "add",
+		Precedence:    10,
+		Associativity: "left",
+	}, rules[1].Alternatives[0])
+
+	rule, ok := dsl.Grammar().Rule("expr")
+	require.True(t, ok)
+	assert.Equal(t, "expr", rule.Name())
+
+	// Returned views are copies and cannot change the grammar
+	alts := rule.Alternatives()
+	alts[1].Sequence[0] = "CHANGED"
+	assert.Equal(t, []string{"NUMBER"}, rule.Alternatives()[1].Sequence)
+}
+
+func TestToConfigKeepsRuleOrder(t *testing.T) {
+	dsl := New("Order")
+	require.NoError(t, dsl.Token("A", "a"))
+	for _, name := range []string{"start", "b", "c", "d", "e"} {
+		dsl.Rule(name, []string{"A"}, "")
+	}
+
+	config := dsl.toConfig()
+	names := []string{}
+	for _, rule := range config.Rules {
+		names = append(names, rule.Name)
+	}
+	assert.Equal(t, []string{"start", "b", "c", "d", "e"}, names)
+}
@@ -0,0 +1,179 @@
+// Package dslbuilder - Pluggable lexers
+package dslbuilder
+
+import "fmt"
+
+// Lexer turns input into tokens for the parser. The built-in RegexLexer
+// matches the token patterns of the grammar; SetLexer installs another
+// implementation, such as a hand-written scanner for nested comments or
+// raw strings with custom delimiters.
+//
+// Tokenize returns every token in source order, including tokens of
+// skipped types (SkipToken), which the parser keeps as trivia. TokenType
+// must name a token of the grammar for rules to match it, and Start and
+// End are byte offsets into the input, used for error positions.
+type Lexer interface {
+	Tokenize(input string) ([]TokenMatch, error)
+}
+
+// LexerFunc adapts a function to the Lexer interface.
+//
+// Example:
+//
+//	dsl.SetLexer(dslbuilder.LexerFunc(func(input string) ([]dslbuilder.TokenMatch, error) {
+//	    return scanBlobs(input)
+//	}))
+type LexerFunc func(input string) ([]TokenMatch, error)
+
+// Tokenize calls f(input).
+func (f LexerFunc) Tokenize(input string) ([]TokenMatch, error) {
+	return f(input)
+}
+
+// RegexLexer is the default lexer: it matches the token patterns of a
+// grammar, skipping whitespace between tokens.
+//
+// Token matching priority:
+//  1. Higher priority value wins (keywords > regular)
+//  2. For same priority, longest match wins
+//  3. For same priority and length, the token defined first wins
+//  4. Tokens defined with ExternalToken are never matched
+type RegexLexer struct {
+	grammar *Grammar
+}
+
+// NewRegexLexer creates a lexer for the token patterns of a grammar. It
+// sees tokens added to the grammar later.
+func NewRegexLexer(grammar *Grammar) *RegexLexer {
+	return &RegexLexer{grammar: grammar}
+}
+
+// Tokenize converts input into tokens. It fails with a ParseError at the
+// first character no token matches.
+func (l *RegexLexer) Tokenize(input string) ([]TokenMatch, error) {
+	tokens := []TokenMatch{}
+	pos := 0
+
+	for pos < len(input) {
+		// Skip whitespace
+		if input[pos] == ' ' || input[pos] == '\t' || input[pos] == '\n' || input[pos] == '\r' {
+			pos++
+			continue
+		}
+
+		matched := false
+		bestMatch := TokenMatch{}
+		bestLength := 0
+		bestPriority := -1
+
+		// Find best matching token
+		for _, name := range l.grammar.tokenOrder {
+			token := l.grammar.tokens[name]
+			if token.regex == nil {
+				continue
+			}
+			if matches := token.regex.FindStringIndex(input[pos:]); matches != nil && matches[0] == 0 {
+				matchLength := matches[1]
+
+				// Higher priority or longer match wins
+				shouldReplace := false
+				if token.priority > bestPriority {
+					shouldReplace = true
+				} else if token.priority == bestPriority && matchLength > bestLength {
+					shouldReplace = true
+				}
+
+				if shouldReplace {
+					bestLength = matchLength
+					bestPriority = token.priority
+					bestMatch = TokenMatch{
+						TokenType: token.name,
+						Value:     input[pos : pos+matchLength],
+						Start:     pos,
+						End:       pos + matchLength,
+					}
+					matched = true
+				}
+			}
+		}
+
+		if !matched {
+			message := fmt.Sprintf("unexpected character: %c", input[pos])
+			return nil, createParseError(message, pos, string(input[pos]), input)
+		}
+		tokens = append(tokens, bestMatch)
+		pos += bestLength
+	}
+
+	return tokens, nil
+}
+
+// SetLexer replaces the regex tokenizer with a custom lexer for every
+// backend. Pass nil to go back to the token patterns.
+//
+// Token types the lexer produces must still be declared so rules can use
+// them: with ExternalToken when no pattern describes them, or with Token
+// when the pattern is kept for tools such as completion and highlighting.
+//
+// Example:
+//
+//	dsl.ExternalToken("BLOB")
+//	dsl.Token("ID", "[a-z]+")
+//	dsl.SetLexer(blobLexer{})
+func (d *DSL) SetLexer(lexer Lexer) {
+	d.grammar.SetLexer(lexer)
+}
+
+// ExternalToken declares a token that only a custom lexer produces. It has
+// no pattern, so the RegexLexer never matches it, and tools that work
+// from token patterns, such as codegen and syntax highlighting exports,
+// skip it or reject the grammar.
+func (d *DSL) ExternalToken(name string) {
+	d.grammar.AddExternalToken(name)
+}
+
+// SetLexer sets the lexer of the grammar; nil restores the RegexLexer.
+func (g *Grammar) SetLexer(lexer Lexer) {
+	g.lexer = lexer
+}
+
+// Lexer returns the custom lexer set with SetLexer, or nil when the
+// grammar is tokenized with its patterns.
+func (g *Grammar) Lexer() Lexer {
+	return g.lexer
+}
+
+// AddExternalToken declares a token without a pattern (see
+// DSL.ExternalToken).
+func (g *Grammar) AddExternalToken(name string) {
+	g.addToken(&Token{name: name})
+}
+
+// tokenize runs the lexer of the grammar, separating skipped tokens into
+// trivia. Tokens from custom lexers are checked against the input so
+// errors can point at them.
+func (g *Grammar) tokenize(input string) (tokens, trivia []TokenMatch, err error) {
+	lexer := g.lexer
+	if lexer == nil {
+		lexer = NewRegexLexer(g)
+	}
+	matches, err := lexer.Tokenize(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokens = []TokenMatch{}
+	end := 0
+	for _, match := range matches {
+		if match.Start < end || match.End < match.Start || match.End > len(input) {
+			return nil, nil, fmt.Errorf("lexer returned token %s at invalid span %d-%d", match.TokenType, match.Start, match.End)
+		}
+		end = match.End
+		if token, exists := g.tokens[match.TokenType]; exists && token.skip {
+			trivia = append(trivia, match)
+		} else {
+			tokens = append(tokens, match)
+		}
+	}
+	return tokens, trivia, nil
+}
@@ -0,0 +1,185 @@
+package dslbuilder
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scanMessages is a hand-written lexer for "send x'0A1B' to host" with
+// nested (* comments *), which regular expressions cannot match.
+func scanMessages(input string) ([]TokenMatch, error) {
+	var tokens []TokenMatch
+	for pos := 0; pos < len(input); {
+		start := pos
+		switch c := input[pos]; {
+		case c == ' ' || c == '\n':
+			pos++
+			continue
+		case strings.HasPrefix(input[pos:], "(*"):
+			for depth := 0; ; {
+				switch {
+				case pos >= len(input):
+					return nil, createParseError("unterminated comment", start, "(*", input)
+				case strings.HasPrefix(input[pos:], "(*"):
+					depth++
+					pos += 2
+				case strings.HasPrefix(input[pos:], "*)"):
+					depth--
+					pos += 2
+				default:
+					pos++
+				}
+				if depth == 0 {
+					break
+				}
+			}
+			tokens = append(tokens, TokenMatch{TokenType: "COMMENT", Value: input[start:pos], Start: start, End: pos})
+		case strings.HasPrefix(input[pos:], "x'"):
+			end := strings.IndexByte(input[pos+2:], '\'')
+			if end < 0 {
+				return nil, createParseError("unterminated blob", start, "x'", input)
+			}
+			pos += end + 3
+			tokens = append(tokens, TokenMatch{TokenType: "BLOB", Value: input[start+2 : pos-1], Start: start, End: pos})
+		case c >= 'a' && c <= 'z':
+			for pos < len(input) && input[pos] >= 'a' && input[pos] <= 'z' {
+				pos++
+			}
+			kind := "WORD"
+			if word := input[start:pos]; word == "send" || word == "to" {
+				kind = strings.ToUpper(word)
+			}
+			tokens = append(tokens, TokenMatch{TokenType: kind, Value: input[start:pos], Start: start, End: pos})
+		default:
+			return nil, createParseError(fmt.Sprintf("unexpected character: %c", c), pos, string(c), input)
+		}
+	}
+	return tokens, nil
+}
+
+func newMessagesDSL(t *testing.T) *DSL {
+	dsl := New("messages")
+	require.NoError(t, dsl.KeywordToken("SEND", "send"))
+	require.NoError(t, dsl.KeywordToken("TO", "to"))
+	require.NoError(t, dsl.Token("WORD", "[a-z]+"))
+	dsl.ExternalToken("BLOB")
+	dsl.ExternalToken("COMMENT")
+	require.NoError(t, dsl.SkipToken("COMMENT"))
+	dsl.Rule("message", []string{"SEND", "BLOB", "TO", "WORD"}, "send")
+	dsl.Action("send", func(args []interface{}) (interface{}, error) {
+		return fmt.Sprintf("%s -> %s", args[1], args[3]), nil
+	})
+	dsl.SetLexer(LexerFunc(scanMessages))
+	return dsl
+}
+
+func TestSetLexer(t *testing.T) {
+	dsl := newMessagesDSL(t)
+	input := "send (* to (* nested *) *) x'0A1B' to host"
+	result, err := dsl.Parse(input)
+	require.NoError(t, err)
+	assert.Equal(t, "0A1B -> host", result.GetOutput())
+
+	tree, err := dsl.ParseTree(input)
+	require.NoError(t, err)
+	require.Len(t, tree.Trivia, 1)
+	assert.Equal(t, "(* to (* nested *) *)", tree.Trivia[0].Value)
+
+	tokens, err := dsl.DebugTokens(input)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 4)
+
+	_, err = dsl.Parse("send x'0A to host")
+	assert.EqualError(t, err, "unterminated blob")
+	_, err = dsl.Parse("send x'0A' host")
+	assert.EqualError(t, err, "no alternative matched for rule message")
+
+	// Without the custom lexer, external tokens never match
+	dsl.SetLexer(nil)
+	assert.Nil(t, dsl.Grammar().Lexer())
+	_, err = dsl.Parse("send x'0A' to host")
+	assert.EqualError(t, err, "unexpected character: '")
+}
+
+func TestLexerBackends(t *testing.T) {
+	dsl := newMessagesDSL(t)
+	dsl.SetBackend(BackendEarley)
+	result, err := dsl.Parse("send x'FF' (* c *) to host")
+	require.NoError(t, err)
+	assert.Equal(t, "FF -> host", result.GetOutput())
+
+	dsl.SetBackend(BackendPredictive)
+	dsl.SetPredictionTable(PredictionTable{"message": {"SEND": 0}})
+	result, err = dsl.Parse("send x'FF' to host")
+	require.NoError(t, err)
+	assert.Equal(t, "FF -> host", result.GetOutput())
+}
+
+func TestRegexLexer(t *testing.T) {
+	dsl := New("comments")
+	require.NoError(t, dsl.Token("WORD", "[a-z]+"))
+	require.NoError(t, dsl.CommentToken("COMMENT", "#[^\n]*"))
+	dsl.ExternalToken("BLOB")
+
+	var lexer Lexer = NewRegexLexer(dsl.Grammar())
+	tokens, err := lexer.Tokenize("a # b\nc")
+	require.NoError(t, err)
+	assert.Equal(t, []TokenMatch{
+		{TokenType: "WORD", Value: "a", Start: 0, End: 1},
+		{TokenType: "COMMENT", Value: "# b", Start: 2, End: 5},
+		{TokenType: "WORD", Value: "c", Start: 6, End: 7},
+	}, tokens)
+
+	_, err = lexer.Tokenize("a $")
+	assert.EqualError(t, err, "unexpected character: $")
+
+	info, ok := dsl.Grammar().Token("BLOB")
+	require.True(t, ok)
+	assert.True(t, info.External)
+	assert.Equal(t, "", info.Pattern)
+	_, literal := info.Literal()
+	assert.False(t, literal)
+}
+
+func TestLexerSpans(t *testing.T) {
+	dsl := New("spans")
+	dsl.ExternalToken("A")
+	dsl.Rule("start", []string{"A", "A"}, "")
+	dsl.SetLexer(LexerFunc(func(input string) ([]TokenMatch, error) {
+		return []TokenMatch{
+			{TokenType: "A", Value: "a", Start: 0, End: 1},
+			{TokenType: "A", Value: "a", Start: 0, End: 1},
+		}, nil
+	}))
+	_, err := dsl.Parse("a")
+	assert.EqualError(t, err, "parsing error: lexer returned token A at invalid span 0-1")
+}
+
+func TestExternalTokenConfig(t *testing.T) {
+	dsl := newMessagesDSL(t)
+	config := dsl.toConfig()
+	assert.Equal(t, []string{"BLOB", "COMMENT"}, config.ExternalTokens)
+	assert.NotContains(t, config.Tokens, "BLOB")
+
+	data, err := dsl.SaveToYAML()
+	require.NoError(t, err)
+	loaded, err := LoadFromYAML(data)
+	require.NoError(t, err)
+	info, ok := loaded.Grammar().Token("COMMENT")
+	require.True(t, ok)
+	assert.True(t, info.External)
+	assert.True(t, info.Skip)
+
+	// The lexer is code, so it is installed after loading
+	loaded.SetLexer(LexerFunc(scanMessages))
+	loaded.Action("send", func(args []interface{}) (interface{}, error) {
+		return args[1], nil
+	})
+	result, err := loaded.Parse("send x'00' to host")
+	require.NoError(t, err)
+	assert.Equal(t, "00", result.GetOutput())
+}
@@ -0,0 +1,164 @@
+// Package dslbuilder - Syntactic lookahead and cut operators in patterns
+package dslbuilder
+
+import "fmt"
+
+// Cut is the pattern symbol that commits an alternative: once the symbols
+// before it have matched, a failure of a later symbol of the alternative
+// ends the parse with that error instead of backtracking into other
+// alternatives. It matches no input and produces no action argument.
+//
+// Place it after the keyword that identifies a construct, so errors point
+// into the construct:
+//
+//	dsl.Rule("stmt", []string{"IF", "^", "expr", "THEN", "stmt"}, "if")
+//	dsl.Rule("stmt", []string{"ID", "ASSIGN", "expr"}, "assign")
+//	// "if x y" fails with "expected token THEN, got ID"
+//	// rather than "no alternative matched for rule stmt"
+//
+// A cut also tells the default parser that no alternative in progress
+// will backtrack behind it, so packrat memo entries for earlier positions
+// are dropped, which bounds memory on long inputs. Backends that never
+// backtrack ignore it.
+const Cut = "^"
+
+// LookaheadOf splits a lookahead pattern symbol. "&X" succeeds where the
+// token or rule X matches, "!X" where it does not; neither consumes input
+// or produces an action argument. It returns X and whether the lookahead
+// is negative; ok is false for other symbols.
+//
+// Example:
+//
+//	// An identifier that is not a keyword
+//	dsl.Rule("name", []string{"!KEYWORD", "IDENT"}, "name")
+func LookaheadOf(symbol string) (target string, negative, ok bool) {
+	if len(symbol) > 1 && (symbol[0] == '&' || symbol[0] == '!') {
+		return symbol[1:], symbol[0] == '!', true
+	}
+	return "", false, false
+}
+
+// IsOperator reports whether a pattern symbol is a lookahead or the cut,
+// which match no input.
+func IsOperator(symbol string) bool {
+	_, _, lookahead := LookaheadOf(symbol)
+	return lookahead || symbol == Cut
+}
+
+// Symbols returns the alternative's sequence without lookahead and cut
+// operators: the symbols that match input, one per action argument.
+func (ai AlternativeInfo) Symbols() []string {
+	symbols := make([]string, 0, len(ai.Sequence))
+	for _, symbol := range ai.Sequence {
+		if !IsOperator(symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// hasOperators reports whether the alternative uses lookahead or cut
+// operators.
+func (a *Alternative) hasOperators() bool {
+	for _, symbol := range a.sequence {
+		if IsOperator(symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// referenced returns the token or rule a pattern symbol refers to: the
+// target of a lookahead, or the symbol itself.
+func referenced(symbol string) string {
+	if target, _, ok := LookaheadOf(symbol); ok {
+		return target
+	}
+	return symbol
+}
+
+// hasCuts reports whether any alternative contains a cut.
+func (g *Grammar) hasCuts() bool {
+	for _, rule := range g.rules {
+		for _, alt := range rule.alternatives {
+			for _, symbol := range alt.sequence {
+				if symbol == Cut {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// lookahead reports whether a token or rule matches at the current
+// position, without consuming input. Failures inside the test do not
+// commit the parser, and cuts inside it keep the memo.
+func (p *ImprovedParser) lookahead(symbol string) bool {
+	if _, isToken := p.grammar.tokens[symbol]; isToken {
+		return p.pos < len(p.tokens) && p.tokens[p.pos].TokenType == symbol
+	}
+	pos, committed := p.pos, p.committed
+	p.lookaheads++
+	_, err := p.parseRuleWithMemo(symbol)
+	p.lookaheads--
+	p.pos, p.committed = pos, committed
+	return err == nil
+}
+
+// testLookahead runs a lookahead symbol and returns the parse error when
+// it fails.
+func (p *ImprovedParser) testLookahead(symbol string) error {
+	target, negative, _ := LookaheadOf(symbol)
+	if p.lookahead(target) != negative {
+		return nil
+	}
+
+	message := fmt.Sprintf("expected %s", target)
+	if negative {
+		message = fmt.Sprintf("unexpected %s", target)
+	} else if _, isToken := p.grammar.tokens[target]; isToken {
+		p.expect(target)
+	}
+	if p.pos < len(p.tokens) {
+		return createParseError(message, p.tokens[p.pos].Start, p.tokens[p.pos].Value, p.input)
+	}
+	return createParseError(message, len(p.input), "<end of input>", p.input)
+}
+
+// cut drops the memo entries before the current position. Alternatives
+// still in progress cannot fail back behind a cut, and completed ones are
+// only re-parsed, never wrong, if an outer rule backtracks.
+func (p *ImprovedParser) cut() {
+	if p.memoAt == nil || p.lookaheads > 0 {
+		return
+	}
+	for ; p.pruned < p.pos; p.pruned++ {
+		for _, rule := range p.memoAt[p.pruned] {
+			delete(p.memo[rule], p.pruned)
+		}
+		delete(p.memoAt, p.pruned)
+	}
+}
+
+// commit records the failure of a symbol after a cut, which ends the
+// parse with err.
+func (p *ImprovedParser) commit(cut bool, err error) error {
+	if cut && p.committed == nil {
+		p.committed = err
+	}
+	return err
+}
+
+// remember stores a memo entry, indexing it by position for cut. Rules
+// that started before a cut and complete after it are not stored.
+func (p *ImprovedParser) remember(ruleName string, pos int, entry memoEntry) {
+	if p.memoAt == nil {
+		p.memo[ruleName][pos] = entry
+		return
+	}
+	if pos >= p.pruned {
+		p.memo[ruleName][pos] = entry
+		p.memoAt[pos] = append(p.memoAt[pos], ruleName)
+	}
+}
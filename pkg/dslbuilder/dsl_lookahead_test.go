@@ -0,0 +1,174 @@
+package dslbuilder
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEntriesDSL builds "key: value value ... key: value ..." where a value
+// is an identifier not followed by a colon.
+func newEntriesDSL(t *testing.T, value []string) *DSL {
+	dsl := New("entries")
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("COLON", ":"))
+	dsl.Rule("entries", []string{"entry", "entries"}, "append")
+	dsl.Rule("entries", []string{"entry"}, "list")
+	dsl.Rule("entry", []string{"ID", "COLON", "values"}, "entry")
+	dsl.Rule("values", []string{"value", "values"}, "append")
+	dsl.Rule("values", []string{"value"}, "list")
+	dsl.Rule("value", value, "value")
+	dsl.Action("list", func(args []interface{}) (interface{}, error) {
+		return []interface{}{args[0]}, nil
+	})
+	dsl.Action("append", func(args []interface{}) (interface{}, error) {
+		return append([]interface{}{args[0]}, args[1].([]interface{})...), nil
+	})
+	dsl.Action("entry", func(args []interface{}) (interface{}, error) {
+		return fmt.Sprintf("%s=%v", args[0], args[2]), nil
+	})
+	dsl.Action("value", func(args []interface{}) (interface{}, error) {
+		require.Len(t, args, 1)
+		return args[0], nil
+	})
+	return dsl
+}
+
+func TestNegativeLookahead(t *testing.T) {
+	// Without the lookahead, values swallow the next key
+	dsl := newEntriesDSL(t, []string{"ID"})
+	_, err := dsl.Parse("a: b c d: e")
+	assert.Error(t, err)
+
+	dsl = newEntriesDSL(t, []string{"ID", "!COLON"})
+	result, err := dsl.Parse("a: b c d: e")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a=[b c]", "d=[e]"}, result.GetOutput())
+
+	_, err = dsl.Parse("a: b:")
+	assert.Error(t, err)
+}
+
+func TestPositiveLookahead(t *testing.T) {
+	dsl := New("calls")
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("LPAREN", "\\("))
+	require.NoError(t, dsl.Token("RPAREN", "\\)"))
+	dsl.Rule("expr", []string{"&call", "ID", "LPAREN", "RPAREN"}, "call")
+	dsl.Rule("expr", []string{"ID"}, "name")
+	dsl.Rule("call", []string{"ID", "LPAREN"}, "")
+	dsl.Action("call", func(args []interface{}) (interface{}, error) {
+		return "call " + args[0].(string), nil
+	})
+	dsl.Action("name", func(args []interface{}) (interface{}, error) {
+		return "name " + args[0].(string), nil
+	})
+
+	result, err := dsl.Parse("f()")
+	require.NoError(t, err)
+	assert.Equal(t, "call f", result.GetOutput())
+	result, err = dsl.Parse("x")
+	require.NoError(t, err)
+	assert.Equal(t, "name x", result.GetOutput())
+
+	// Lookaheads are not part of the tree
+	tree, err := dsl.ParseTree("f()")
+	require.NoError(t, err)
+	assert.Len(t, tree.Root.Children, 3)
+
+	assert.Equal(t, []string{"ID", "LPAREN", "RPAREN"}, dsl.Rules()[0].Alternatives[0].Symbols())
+	assert.True(t, IsOperator("&call"))
+	assert.True(t, IsOperator(Cut))
+	assert.False(t, IsOperator("!"))
+	target, negative, ok := LookaheadOf("!COLON")
+	assert.Equal(t, "COLON", target)
+	assert.True(t, negative)
+	assert.True(t, ok)
+}
+
+// newIfDSL builds statements "if ID then stmt" and "ID = ID".
+func newIfDSL(t *testing.T, cut bool) *DSL {
+	dsl := New("if")
+	require.NoError(t, dsl.KeywordToken("IF", "if"))
+	require.NoError(t, dsl.KeywordToken("THEN", "then"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+	if cut {
+		dsl.Rule("stmt", []string{"IF", Cut, "ID", "THEN", "stmt"}, "if")
+	} else {
+		dsl.Rule("stmt", []string{"IF", "ID", "THEN", "stmt"}, "if")
+	}
+	dsl.Rule("stmt", []string{"ID", "ASSIGN", "ID"}, "assign")
+	dsl.Action("if", func(args []interface{}) (interface{}, error) {
+		return fmt.Sprintf("if %s { %v }", args[1], args[3]), nil
+	})
+	dsl.Action("assign", func(args []interface{}) (interface{}, error) {
+		return fmt.Sprintf("%s = %s", args[0], args[2]), nil
+	})
+	return dsl
+}
+
+func TestCut(t *testing.T) {
+	_, err := newIfDSL(t, false).Parse("if x y = z")
+	assert.EqualError(t, err, "no alternative matched for rule stmt")
+
+	dsl := newIfDSL(t, true)
+	_, err = dsl.Parse("if x y = z")
+	assert.EqualError(t, err, "expected token THEN, got ID")
+	_, err = dsl.Parse("if x then if y")
+	assert.EqualError(t, err, "unexpected end of input")
+
+	result, err := dsl.Parse("if x then if y then a = b")
+	require.NoError(t, err)
+	assert.Equal(t, "if x { if y { a = b } }", result.GetOutput())
+
+	// The cut produces no argument and other backends accept it
+	dsl.SetBackend(BackendEarley)
+	result, err = dsl.Parse("if x then a = b")
+	require.NoError(t, err)
+	assert.Equal(t, "if x { a = b }", result.GetOutput())
+}
+
+func TestCutPrunesMemo(t *testing.T) {
+	dsl := newIfDSL(t, true)
+	parser := NewImprovedParser(dsl.grammar)
+	input := strings.Repeat("if x then ", 50) + "a = b"
+	_, err := parser.Parse(input)
+	require.NoError(t, err)
+	assert.Less(t, len(parser.memoAt), 10)
+	assert.Less(t, len(parser.memo["stmt"]), 10)
+
+	// Without cuts the memo keeps every position
+	parser = NewImprovedParser(newIfDSL(t, false).grammar)
+	_, err = parser.Parse(input)
+	require.NoError(t, err)
+	assert.Nil(t, parser.memoAt)
+	assert.Greater(t, len(parser.memo["stmt"]), 50)
+}
+
+func TestLookaheadBackends(t *testing.T) {
+	dsl := newEntriesDSL(t, []string{"ID", "!COLON"})
+
+	dsl.SetBackend(BackendEarley)
+	result, err := dsl.Parse("a: b c d: e")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a=[b c]", "d=[e]"}, result.GetOutput())
+	forest, err := dsl.ParseForest("a: b c d: e")
+	require.NoError(t, err)
+	assert.False(t, forest.Ambiguous())
+
+	dsl = New("values")
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("COLON", ":"))
+	dsl.Rule("value", []string{"!COLON", "ID"}, "")
+	dsl.SetBackend(BackendPredictive)
+	dsl.SetPredictionTable(PredictionTable{"value": {"ID": 0, "COLON": 0}})
+	result, err = dsl.Parse("a")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a"}, result.GetOutput())
+	_, err = dsl.Parse(":")
+	assert.EqualError(t, err, "unexpected COLON")
+}
@@ -0,0 +1,468 @@
+package dslbuilder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OptimizeOptions selects the transformations applied by Grammar.Optimize.
+// The zero value left-factors alternatives, inlines single-use rules and
+// removes unreachable rules; rewriting left recursion must be requested.
+type OptimizeOptions struct {
+	NoLeftFactoring        bool // Keep alternatives that share a common prefix
+	NoInlining             bool // Keep single-use rules with one alternative
+	KeepUnreachable        bool // Keep rules the start rule cannot reach
+	EliminateLeftRecursion bool // Rewrite direct left recursion into iteration
+}
+
+// OptimizeChange describes one transformation applied by Optimize.
+type OptimizeChange struct {
+	Kind   string // "unreachable", "left-recursion", "left-factor" or "inline"
+	Rule   string // Rule that was rewritten or removed
+	Detail string // What changed
+}
+
+// String returns the change in the form "left-factor query: ...".
+func (c OptimizeChange) String() string {
+	return fmt.Sprintf("%s %s: %s", c.Kind, c.Rule, c.Detail)
+}
+
+// OptimizeReport lists the changes made by Optimize, in the order they
+// were applied.
+type OptimizeReport struct {
+	Changes []OptimizeChange
+}
+
+// Changed reports whether Optimize modified the grammar.
+func (r *OptimizeReport) Changed() bool {
+	return len(r.Changes) > 0
+}
+
+// String returns one change per line.
+func (r *OptimizeReport) String() string {
+	lines := make([]string, len(r.Changes))
+	for i, change := range r.Changes {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Optimize rewrites the grammar in place so the parser does less work, and
+// reports what changed. The passes run in this order:
+//
+//   - unreachable rules are removed;
+//   - with EliminateLeftRecursion, directly left-recursive rules become a
+//     base alternative followed by a right-recursive helper rule
+//     (expr -> expr PLUS term | term becomes expr -> term expr_tail), so
+//     every parsing backend handles them without seed growing;
+//   - consecutive alternatives sharing a prefix are left-factored into a
+//     helper rule (cmd -> GET ID | GET ID ID becomes cmd -> GET ID
+//     cmd_factor), so the prefix is matched once;
+//   - rules with one alternative that are referenced once are inlined
+//     into the referencing alternative;
+//   - rules left unused by the previous passes are removed.
+//
+// Action semantics are preserved: the rewritten alternatives use generated
+// actions that rebuild the original argument lists and call the original
+// actions, looked up when they run, so actions may be registered before or
+// after Optimize. RequiredActions keeps reporting the original names.
+// Alternatives guarded by predicates or using lookahead and cut operators
+// are not rewritten.
+//
+// The rewritten grammar differs from the original in a few observable ways:
+// trees from ParseTree, tracer events and coverage follow the new rules; an
+// action error fails the whole rewritten alternative instead of letting a
+// later factored alternative match; and a left-recursive rule repeats the
+// first of its left-recursive alternatives that matches, where seed growing
+// prefers the longest one. Exporters and code generators see the generated
+// actions, so optimize only the grammar that is parsed.
+func (g *Grammar) Optimize(opts OptimizeOptions) *OptimizeReport {
+	o := &optimizer{g: g, report: &OptimizeReport{}}
+	if !opts.KeepUnreachable {
+		o.removeUnreachable()
+	}
+	if opts.EliminateLeftRecursion {
+		for _, name := range append([]string(nil), g.ruleOrder...) {
+			o.leftRecursion(name)
+		}
+	}
+	if !opts.NoLeftFactoring {
+		for _, name := range append([]string(nil), g.ruleOrder...) {
+			o.leftFactor(name)
+		}
+	}
+	if !opts.NoInlining {
+		o.inline()
+	}
+	if !opts.KeepUnreachable {
+		o.removeUnreachable()
+	}
+	return o.report
+}
+
+// optimizer holds the state of one Optimize call.
+type optimizer struct {
+	g      *Grammar
+	report *OptimizeReport
+}
+
+// leftStep is one left-recursive alternative matched by a helper rule
+// created by leftRecursion: the values of its symbols after the recursive
+// reference.
+type leftStep struct {
+	alternative string // Original action
+	values      []interface{}
+}
+
+// factorTail is the value of a helper rule created by leftFactor: which
+// alternative of the factored run matched and the values of its suffix.
+type factorTail struct {
+	index  int
+	values []interface{}
+}
+
+func (o *optimizer) record(kind, rule, format string, args ...interface{}) {
+	o.report.Changes = append(o.report.Changes, OptimizeChange{Kind: kind, Rule: rule, Detail: fmt.Sprintf(format, args...)})
+}
+
+// helperRule adds an empty rule named after base that clashes with no rule
+// or token.
+func (o *optimizer) helperRule(base, suffix string) *Rule {
+	name := base + "_" + suffix
+	for n := 2; o.g.rules[name] != nil || o.g.tokens[name] != nil; n++ {
+		name = fmt.Sprintf("%s_%s%d", base, suffix, n)
+	}
+	rule := &Rule{name: name}
+	o.g.rules[name] = rule
+	o.g.ruleOrder = append(o.g.ruleOrder, name)
+	return rule
+}
+
+// action registers a generated action for rule and returns its name.
+// originals are the actions it calls, for RequiredActions.
+func (o *optimizer) action(kind, rule string, originals []string, fn ActionFunc) string {
+	var name string
+	for n := 0; ; n++ {
+		name = fmt.Sprintf("%s:%s:%d", kind, rule, n)
+		if _, exists := o.g.actions[name]; !exists {
+			break
+		}
+	}
+	o.g.actions[name] = fn
+	if o.g.derived == nil {
+		o.g.derived = make(map[string][]string)
+	}
+	o.g.derived[name] = originals
+	return name
+}
+
+// apply runs an action the way the parser does: bound actions are called,
+// and without one the matched values themselves are the result.
+func (g *Grammar) apply(name string, args []interface{}) (interface{}, error) {
+	if action, exists := g.action(name); exists && name != "" {
+		return action(args)
+	}
+	return args, nil
+}
+
+// originalActions expands a generated action into the user actions it
+// calls.
+func (g *Grammar) originalActions(name string) []string {
+	originals, derived := g.derived[name]
+	if !derived {
+		return []string{name}
+	}
+	var names []string
+	for _, original := range originals {
+		if original != "" {
+			names = append(names, g.originalActions(original)...)
+		}
+	}
+	return names
+}
+
+// values copies matched values so actions may append to their arguments.
+// Like the parser, it passes nil for an empty sequence.
+func values(args []interface{}) []interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	return append([]interface{}(nil), args...)
+}
+
+// removeUnreachable deletes the rules the start rule cannot reach.
+func (o *optimizer) removeUnreachable() {
+	g := o.g
+	if g.rules[g.startRule] == nil {
+		return
+	}
+	reached := map[string]bool{g.startRule: true}
+	queue := []string{g.startRule}
+	for len(queue) > 0 {
+		rule := g.rules[queue[0]]
+		queue = queue[1:]
+		for _, alt := range rule.alternatives {
+			for _, symbol := range alt.sequence {
+				if symbol = referenced(symbol); g.rules[symbol] != nil && !reached[symbol] {
+					reached[symbol] = true
+					queue = append(queue, symbol)
+				}
+			}
+		}
+	}
+
+	order := g.ruleOrder[:0]
+	for _, name := range g.ruleOrder {
+		if reached[name] {
+			order = append(order, name)
+			continue
+		}
+		delete(g.rules, name)
+		o.record("unreachable", name, "removed, the start rule %s never uses it", g.startRule)
+	}
+	g.ruleOrder = order
+}
+
+// leftRecursion rewrites a directly left-recursive rule
+//
+//	A -> A a1 | ... | A an | b1 | ... | bm
+//
+// into
+//
+//	A      -> b1 A_tail | ... | bm A_tail
+//	A_tail -> a1 A_tail | ... | an A_tail | ε
+//
+// The helper collects the matched ai, and the action of each bj folds them
+// over its own value with the original actions, left to right.
+func (o *optimizer) leftRecursion(name string) {
+	g := o.g
+	rule := g.rules[name]
+	var recursive, base []*Alternative
+	for _, alt := range rule.alternatives {
+		if alt.predicate != "" || alt.hasOperators() {
+			return // Predicates see the tokens of the original alternatives
+		}
+		if len(alt.sequence) > 0 && alt.sequence[0] == name {
+			if len(alt.sequence) == 1 {
+				return // A -> A derives nothing new and cannot be iterated
+			}
+			recursive = append(recursive, alt)
+		} else {
+			base = append(base, alt)
+		}
+	}
+	if len(recursive) == 0 || len(base) == 0 {
+		return
+	}
+
+	tail := o.helperRule(name, "tail")
+	originals := []string{}
+	for _, alt := range recursive {
+		original := alt.action
+		originals = append(originals, original)
+		n := len(alt.sequence) - 1
+		act := o.action("repeat", tail.name, nil, func(args []interface{}) (interface{}, error) {
+			steps := append([]leftStep{{alternative: original, values: values(args[:n])}}, args[n].([]leftStep)...)
+			return steps, nil
+		})
+		tail.alternatives = append(tail.alternatives, &Alternative{
+			sequence:      append(append([]string(nil), alt.sequence[1:]...), tail.name),
+			action:        act,
+			precedence:    alt.precedence,
+			associativity: alt.associativity,
+		})
+	}
+	end := o.action("repeat", tail.name, nil, func(args []interface{}) (interface{}, error) {
+		return []leftStep{}, nil
+	})
+	tail.alternatives = append(tail.alternatives, &Alternative{sequence: []string{}, action: end, associativity: "left"})
+
+	alternatives := make([]*Alternative, 0, len(base))
+	for _, alt := range base {
+		original := alt.action
+		n := len(alt.sequence)
+		act := o.action("fold", name, append([]string{original}, originals...), func(args []interface{}) (interface{}, error) {
+			value, err := g.apply(original, values(args[:n]))
+			if err != nil {
+				return nil, err
+			}
+			for _, step := range args[n].([]leftStep) {
+				value, err = g.apply(step.alternative, append([]interface{}{value}, step.values...))
+				if err != nil {
+					return nil, err
+				}
+			}
+			return value, nil
+		})
+		alternatives = append(alternatives, &Alternative{
+			sequence:      append(append([]string(nil), alt.sequence...), tail.name),
+			action:        act,
+			precedence:    alt.precedence,
+			associativity: alt.associativity,
+		})
+	}
+	rule.alternatives = alternatives
+	o.record("left-recursion", name, "rewrote %d left-recursive alternatives into repetition of %s", len(recursive), tail.name)
+}
+
+// leftFactor replaces every run of consecutive alternatives of a rule that
+// start with the same symbol by one alternative matching their longest
+// common prefix followed by a helper rule with the remaining suffixes.
+// Left-recursive alternatives, alternatives guarded by predicates and
+// alternatives with operators are left alone.
+func (o *optimizer) leftFactor(name string) {
+	g := o.g
+	rule := g.rules[name]
+	first := func(alt *Alternative) string {
+		if len(alt.sequence) == 0 || alt.sequence[0] == name || alt.predicate != "" || alt.hasOperators() {
+			return ""
+		}
+		return alt.sequence[0]
+	}
+
+	var alternatives []*Alternative
+	var helpers []*Rule
+	alts := rule.alternatives
+	for i := 0; i < len(alts); {
+		j := i + 1
+		for first(alts[i]) != "" && j < len(alts) && first(alts[j]) == first(alts[i]) {
+			j++
+		}
+		if j-i < 2 {
+			alternatives = append(alternatives, alts[i])
+			i++
+			continue
+		}
+
+		run := alts[i:j]
+		k := commonPrefix(run)
+		helper := o.helperRule(name, "factor")
+		originals := make([]string, len(run))
+		for m, alt := range run {
+			originals[m] = alt.action
+			index := m
+			act := o.action("suffix", helper.name, nil, func(args []interface{}) (interface{}, error) {
+				return factorTail{index: index, values: values(args)}, nil
+			})
+			helper.alternatives = append(helper.alternatives, &Alternative{
+				sequence:      append([]string(nil), alt.sequence[k:]...),
+				action:        act,
+				precedence:    alt.precedence,
+				associativity: alt.associativity,
+			})
+		}
+		act := o.action("factor", name, originals, func(args []interface{}) (interface{}, error) {
+			tail := args[k].(factorTail)
+			return g.apply(originals[tail.index], append(values(args[:k]), tail.values...))
+		})
+		alternatives = append(alternatives, &Alternative{
+			sequence:      append(append([]string(nil), run[0].sequence[:k]...), helper.name),
+			action:        act,
+			precedence:    run[0].precedence,
+			associativity: run[0].associativity,
+		})
+		helpers = append(helpers, helper)
+		o.record("left-factor", name, "factored prefix %q of alternatives %d-%d into %s", strings.Join(run[0].sequence[:k], " "), i, j-1, helper.name)
+		i = j
+	}
+	rule.alternatives = alternatives
+
+	// The suffixes may share prefixes of their own
+	for _, helper := range helpers {
+		o.leftFactor(helper.name)
+	}
+}
+
+// commonPrefix returns the length of the longest symbol prefix shared by
+// all alternatives.
+func commonPrefix(alts []*Alternative) int {
+	k := len(alts[0].sequence)
+	for _, alt := range alts[1:] {
+		n := 0
+		for n < k && n < len(alt.sequence) && alt.sequence[n] == alts[0].sequence[n] {
+			n++
+		}
+		k = n
+	}
+	return k
+}
+
+// inline replaces references to rules that have one alternative and are
+// referenced once by that alternative's symbols, until none is left. The
+// start rule, recursive rules, alternatives guarded by predicates or with
+// operators, and inlinings that would create left recursion are skipped.
+func (o *optimizer) inline() {
+	g := o.g
+	for inlined := true; inlined; {
+		inlined = false
+		uses := make(map[string]int)
+		type site struct {
+			rule     string
+			index    int
+			position int
+		}
+		sites := make(map[string]site)
+		for _, name := range g.ruleOrder {
+			for i, alt := range g.rules[name].alternatives {
+				for position, symbol := range alt.sequence {
+					if symbol = referenced(symbol); g.rules[symbol] != nil {
+						uses[symbol]++
+						sites[symbol] = site{name, i, position}
+					}
+				}
+			}
+		}
+
+		for _, name := range g.ruleOrder {
+			rule := g.rules[name]
+			at := sites[name]
+			if name == g.startRule || uses[name] != 1 || len(rule.alternatives) != 1 || at.rule == name {
+				continue
+			}
+			inner := rule.alternatives[0]
+			if at.position == 0 && len(inner.sequence) > 0 && inner.sequence[0] == at.rule {
+				continue
+			}
+			parent := g.rules[at.rule]
+			outer := parent.alternatives[at.index]
+			if inner.predicate != "" || outer.predicate != "" || inner.hasOperators() || outer.hasOperators() {
+				continue
+			}
+
+			innerAction, outerAction := inner.action, outer.action
+			position, n := at.position, len(inner.sequence)
+			act := o.action("inline", at.rule, []string{outerAction, innerAction}, func(args []interface{}) (interface{}, error) {
+				value, err := g.apply(innerAction, values(args[position:position+n]))
+				if err != nil {
+					return nil, err
+				}
+				regrouped := make([]interface{}, 0, len(args)-n+1)
+				regrouped = append(regrouped, args[:position]...)
+				regrouped = append(regrouped, value)
+				regrouped = append(regrouped, args[position+n:]...)
+				return g.apply(outerAction, regrouped)
+			})
+
+			sequence := append([]string(nil), outer.sequence[:position]...)
+			sequence = append(sequence, inner.sequence...)
+			sequence = append(sequence, outer.sequence[position+1:]...)
+			parent.alternatives[at.index] = &Alternative{
+				sequence:      sequence,
+				action:        act,
+				precedence:    outer.precedence,
+				associativity: outer.associativity,
+			}
+			delete(g.rules, name)
+			order := g.ruleOrder[:0]
+			for _, other := range g.ruleOrder {
+				if other != name {
+					order = append(order, other)
+				}
+			}
+			g.ruleOrder = order
+			o.record("inline", name, "inlined into alternative %d of %s", at.index, at.rule)
+			inlined = true
+			break
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package dslbuilder
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseAll parses every input and returns the outputs, or the errors as
+// strings.
+func parseAll(dsl *DSL, inputs []string) []interface{} {
+	outputs := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		result, err := dsl.Parse(input)
+		if err != nil {
+			outputs[i] = err.Error()
+			continue
+		}
+		outputs[i] = result.GetOutput()
+	}
+	return outputs
+}
+
+func sequences(dsl *DSL, rule string) [][]string {
+	var result [][]string
+	for _, info := range dsl.Rules() {
+		if info.Name == rule {
+			for _, alt := range info.Alternatives {
+				result = append(result, alt.Sequence)
+			}
+		}
+	}
+	return result
+}
+
+func newQueryDSL(t *testing.T) *DSL {
+	dsl := New("query")
+	require.NoError(t, dsl.KeywordToken("FROM", "from"))
+	require.NoError(t, dsl.KeywordToken("SELECT", "select"))
+	require.NoError(t, dsl.KeywordToken("WHERE", "where"))
+	require.NoError(t, dsl.KeywordToken("COUNT", "count"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("GT", ">"))
+	require.NoError(t, dsl.Token("WORD", "[a-z]+"))
+	dsl.Rule("query", []string{"FROM", "WORD", "SELECT", "WORD"}, "select")
+	dsl.Rule("query", []string{"FROM", "WORD", "COUNT"}, "count")
+	dsl.Rule("query", []string{"FROM", "WORD", "WHERE", "WORD", "GT", "NUMBER", "SELECT", "WORD"}, "whereSelect")
+	dsl.Rule("query", []string{"FROM", "WORD", "WHERE", "WORD", "GT", "NUMBER", "COUNT"}, "")
+	for _, name := range []string{"select", "count", "whereSelect"} {
+		name := name
+		dsl.Action(name, func(args []interface{}) (interface{}, error) {
+			return name + fmt.Sprint(args), nil
+		})
+	}
+	return dsl
+}
+
+func TestOptimizeLeftFactoring(t *testing.T) {
+	dsl := newQueryDSL(t)
+	inputs := []string{
+		"from users select name",
+		"from users count",
+		"from users where age > 18 select name",
+		"from users where age > 18 count",
+		"from users where age select name",
+	}
+	before := parseAll(dsl, inputs)
+
+	report := dsl.Grammar().Optimize(OptimizeOptions{})
+	assert.Equal(t, `left-factor query: factored prefix "FROM WORD" of alternatives 0-3 into query_factor
+left-factor query_factor: factored prefix "WHERE WORD GT NUMBER" of alternatives 2-3 into query_factor_factor`, report.String())
+	assert.True(t, report.Changed())
+
+	assert.Equal(t, [][]string{{"FROM", "WORD", "query_factor"}}, sequences(dsl, "query"))
+	assert.Equal(t, [][]string{{"SELECT", "WORD"}, {"COUNT"}, {"WHERE", "WORD", "GT", "NUMBER", "query_factor_factor"}}, sequences(dsl, "query_factor"))
+	assert.Equal(t, [][]string{{"SELECT", "WORD"}, {"COUNT"}}, sequences(dsl, "query_factor_factor"))
+
+	after := parseAll(dsl, inputs)
+	assert.Equal(t, before, after)
+	assert.Equal(t, "whereSelect[from users where age > 18 select name]", after[2])
+	assert.Equal(t, []interface{}{"from", "users", "where", "age", ">", "18", "count"}, after[3])
+
+	// Generated actions are not reported as required
+	assert.Equal(t, []string{"count", "select", "whereSelect"}, dsl.RequiredActions())
+	assert.Empty(t, dsl.UnboundActions())
+}
+
+func TestOptimizeLeftRecursion(t *testing.T) {
+	dsl := New("calc")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("MINUS", "-"))
+	require.NoError(t, dsl.Token("LPAREN", "\\("))
+	require.NoError(t, dsl.Token("RPAREN", "\\)"))
+	dsl.Rule("expr", []string{"expr", "PLUS", "term"}, "add")
+	dsl.Rule("expr", []string{"expr", "MINUS", "term"}, "sub")
+	dsl.Rule("expr", []string{"term"}, "")
+	dsl.Rule("term", []string{"NUMBER"}, "number")
+	dsl.Rule("term", []string{"LPAREN", "expr", "RPAREN"}, "paren")
+	dsl.Action("add", func(args []interface{}) (interface{}, error) {
+		return fmt.Sprintf("(%v + %v)", args[0], args[2]), nil
+	})
+	dsl.Action("sub", func(args []interface{}) (interface{}, error) {
+		return fmt.Sprintf("(%v - %v)", args[0], args[2]), nil
+	})
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		return args[0], nil
+	})
+	dsl.Action("paren", func(args []interface{}) (interface{}, error) {
+		return args[1], nil
+	})
+
+	inputs := []string{"1", "10 - 2 - 3", "1 + (2 - 3) + 4", "1 +"}
+	before := parseAll(dsl, inputs)
+
+	report := dsl.Grammar().Optimize(OptimizeOptions{EliminateLeftRecursion: true})
+	assert.Equal(t, "left-recursion expr: rewrote 2 left-recursive alternatives into repetition of expr_tail", report.String())
+	assert.Equal(t, [][]string{{"term", "expr_tail"}}, sequences(dsl, "expr"))
+	assert.Equal(t, [][]string{{"PLUS", "term", "expr_tail"}, {"MINUS", "term", "expr_tail"}, {}}, sequences(dsl, "expr_tail"))
+
+	after := parseAll(dsl, inputs)
+	assert.Equal(t, before, after)
+	assert.Equal(t, "(([10] - 2) - 3)", after[1])
+
+	// The rewritten grammar parses the same with the Earley backend
+	dsl.SetBackend(BackendEarley)
+	assert.Equal(t, before[:3], parseAll(dsl, inputs)[:3])
+	assert.Equal(t, []string{"add", "number", "paren", "sub"}, dsl.RequiredActions())
+}
+
+func TestOptimizeInlining(t *testing.T) {
+	dsl := New("let")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+	dsl.Rule("stmt", []string{"LET", "assignment"}, "let")
+	dsl.Rule("stmt", []string{"value"}, "")
+	dsl.Rule("assignment", []string{"ID", "ASSIGN", "value"}, "assign")
+	dsl.Rule("value", []string{"NUMBER"}, "number")
+	dsl.Rule("value", []string{"ID"}, "")
+	dsl.Rule("unused", []string{"ID"}, "unused")
+	dsl.Action("let", func(args []interface{}) (interface{}, error) {
+		return args[1], nil
+	})
+	dsl.Action("assign", func(args []interface{}) (interface{}, error) {
+		return map[string]interface{}{args[0].(string): args[2]}, nil
+	})
+
+	inputs := []string{"let x = 1", "let x = y", "42", "let = 1"}
+	before := parseAll(dsl, inputs)
+
+	report := dsl.Grammar().Optimize(OptimizeOptions{})
+	assert.Equal(t, []OptimizeChange{
+		{Kind: "unreachable", Rule: "unused", Detail: "removed, the start rule stmt never uses it"},
+		{Kind: "inline", Rule: "assignment", Detail: "inlined into alternative 0 of stmt"},
+	}, report.Changes)
+	assert.Equal(t, [][]string{{"LET", "ID", "ASSIGN", "value"}, {"value"}}, sequences(dsl, "stmt"))
+	assert.Equal(t, []string{"stmt", "value"}, ruleNames(dsl))
+
+	// Actions bound after Optimize are picked up
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		return "#" + args[0].(string), nil
+	})
+	after := parseAll(dsl, inputs)
+	assert.Equal(t, map[string]interface{}{"x": "#1"}, after[0])
+	assert.Equal(t, before[1], after[1])
+	assert.Equal(t, []interface{}{"#42"}, after[2])
+	assert.Equal(t, before[3], after[3])
+	assert.Equal(t, []string{"assign", "let", "number"}, dsl.RequiredActions())
+
+	// Action errors still make the alternative fail
+	dsl.Action("assign", func(args []interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("read-only variable %s", args[0])
+	})
+	_, err := dsl.Parse("let x = 1")
+	assert.EqualError(t, err, "no alternative matched for rule stmt")
+}
+
+func TestOptimizeOptions(t *testing.T) {
+	dsl := newQueryDSL(t)
+	dsl.Rule("unused", []string{"WORD"}, "")
+	report := dsl.Grammar().Optimize(OptimizeOptions{NoLeftFactoring: true, NoInlining: true, KeepUnreachable: true})
+	assert.False(t, report.Changed())
+	assert.Equal(t, "", report.String())
+	assert.Len(t, sequences(dsl, "query"), 4)
+	assert.Equal(t, []string{"query", "unused"}, ruleNames(dsl))
+}
+
+func ruleNames(dsl *DSL) []string {
+	var names []string
+	for _, info := range dsl.Rules() {
+		names = append(names, info.Name)
+	}
+	return names
+}
@@ -0,0 +1,146 @@
+// Package dslbuilder - Semantic predicates on alternatives
+package dslbuilder
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PredicateContext is what a predicate sees about a matched alternative.
+type PredicateContext struct {
+	Rule        string                 // Rule being matched
+	Alternative int                    // Index of the alternative in the rule
+	Tokens      []TokenMatch           // Tokens matched by the alternative, including nested rules
+	Context     map[string]interface{} // DSL context (SetContext, Use)
+}
+
+// Value returns the text of the i-th matched token, or "" if there is none.
+func (c PredicateContext) Value(i int) string {
+	if i < 0 || i >= len(c.Tokens) {
+		return ""
+	}
+	return c.Tokens[i].Value
+}
+
+// Get returns a context value.
+func (c PredicateContext) Get(key string) interface{} {
+	return c.Context[key]
+}
+
+// PredicateFunc decides whether a matched alternative is accepted.
+type PredicateFunc func(ctx PredicateContext) bool
+
+// Predicate registers a semantic predicate for alternatives added with
+// RuleWithPredicate. The predicate runs after the alternative's symbols
+// have matched and before its action; when it returns false the
+// alternative fails as if it had not matched, and the parser tries the
+// next one. Unregistered predicates reject every match.
+//
+// With BackendEarley, rejected derivations are removed from the parse
+// forest. BackendPredictive cannot backtrack, so a rejection is a parse
+// error.
+//
+// Example:
+//
+//	// An IDENT is an entity name only if the context knows the entity
+//	dsl.Predicate("isEntity", func(ctx dslbuilder.PredicateContext) bool {
+//	    entities, _ := ctx.Get("entities").(map[string]interface{})
+//	    _, exists := entities[ctx.Value(0)]
+//	    return exists
+//	})
+//	dsl.RuleWithPredicate("source", []string{"IDENT"}, "entity", "isEntity")
+//	dsl.Rule("source", []string{"IDENT"}, "variable")
+func (d *DSL) Predicate(name string, fn PredicateFunc) {
+	d.grammar.predicates[name] = fn
+}
+
+// RuleWithPredicate adds a rule alternative guarded by a predicate
+// registered with Predicate.
+func (d *DSL) RuleWithPredicate(name string, pattern []string, actionName, predicate string) {
+	d.grammar.AddRuleWithPredicate(name, pattern, actionName, predicate)
+}
+
+// AddRuleWithPredicate adds a rule alternative guarded by a predicate.
+func (g *Grammar) AddRuleWithPredicate(name string, sequence []string, action, predicate string) {
+	g.AddRule(name, sequence, action)
+	alts := g.rules[name].alternatives
+	alts[len(alts)-1].predicate = predicate
+}
+
+// RequiredPredicates returns the names of all predicates referenced by the
+// grammar rules, sorted alphabetically and without duplicates.
+func (d *DSL) RequiredPredicates() []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, rule := range d.grammar.rules {
+		for _, alt := range rule.alternatives {
+			if alt.predicate != "" && !seen[alt.predicate] {
+				seen[alt.predicate] = true
+				names = append(names, alt.predicate)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnboundPredicates returns the predicates referenced by the grammar that
+// have not been registered with Predicate, sorted alphabetically.
+func (d *DSL) UnboundPredicates() []string {
+	unbound := []string{}
+	for _, name := range d.RequiredPredicates() {
+		if _, exists := d.grammar.predicates[name]; !exists {
+			unbound = append(unbound, name)
+		}
+	}
+	return unbound
+}
+
+// hasPredicates reports whether any alternative is guarded by a predicate.
+func (g *Grammar) hasPredicates() bool {
+	for _, rule := range g.rules {
+		for _, alt := range rule.alternatives {
+			if alt.predicate != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// accepts runs the predicate of a matched alternative. Alternatives without
+// a predicate are always accepted.
+func (g *Grammar) accepts(alt *Alternative, ctx PredicateContext) bool {
+	if alt.predicate == "" {
+		return true
+	}
+	predicate, exists := g.predicates[alt.predicate]
+	return exists && predicate(ctx)
+}
+
+// checkPredicate runs the predicate of an alternative that matched the
+// tokens from start to the current position.
+func (p *ImprovedParser) checkPredicate(ruleName string, index int, alt *Alternative, start int) error {
+	if alt.predicate == "" {
+		return nil
+	}
+	var context map[string]interface{}
+	if p.dsl != nil {
+		context = p.dsl.context
+	}
+	ctx := PredicateContext{
+		Rule:        ruleName,
+		Alternative: index,
+		Tokens:      p.tokens[start:p.pos:p.pos],
+		Context:     context,
+	}
+	if p.grammar.accepts(alt, ctx) {
+		return nil
+	}
+
+	message := fmt.Sprintf("predicate %s rejected rule %s", alt.predicate, ruleName)
+	if start < len(p.tokens) {
+		return createParseError(message, p.tokens[start].Start, p.tokens[start].Value, p.input)
+	}
+	return createParseError(message, len(p.input), "<end of input>", p.input)
+}
@@ -0,0 +1,178 @@
+package dslbuilder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEntityDSL builds "show <source>" where a source is an entity when the
+// context lists it, and a variable otherwise.
+func newEntityDSL(t *testing.T) *DSL {
+	dsl := New("entities")
+	require.NoError(t, dsl.KeywordToken("SHOW", "show"))
+	require.NoError(t, dsl.Token("IDENT", "[a-z]+"))
+	dsl.Rule("query", []string{"SHOW", "source"}, "query")
+	dsl.RuleWithPredicate("source", []string{"IDENT"}, "entity", "isEntity")
+	dsl.Rule("source", []string{"IDENT"}, "variable")
+	dsl.Action("query", func(args []interface{}) (interface{}, error) {
+		return args[1], nil
+	})
+	dsl.Action("entity", func(args []interface{}) (interface{}, error) {
+		return "entity " + args[0].(string), nil
+	})
+	dsl.Action("variable", func(args []interface{}) (interface{}, error) {
+		return "variable " + args[0].(string), nil
+	})
+	dsl.SetContext("entities", map[string]interface{}{"users": true})
+	return dsl
+}
+
+func TestPredicate(t *testing.T) {
+	dsl := newEntityDSL(t)
+	var seen []PredicateContext
+	dsl.Predicate("isEntity", func(ctx PredicateContext) bool {
+		seen = append(seen, ctx)
+		entities, _ := ctx.Get("entities").(map[string]interface{})
+		_, exists := entities[ctx.Value(0)]
+		return exists
+	})
+
+	result, err := dsl.Parse("show users")
+	require.NoError(t, err)
+	assert.Equal(t, "entity users", result.GetOutput())
+	require.Len(t, seen, 1)
+	assert.Equal(t, "source", seen[0].Rule)
+	assert.Equal(t, 0, seen[0].Alternative)
+	assert.Len(t, seen[0].Tokens, 1)
+	assert.Equal(t, "", seen[0].Value(1))
+
+	// A rejected alternative falls through to the next one
+	result, err = dsl.Parse("show total")
+	require.NoError(t, err)
+	assert.Equal(t, "variable total", result.GetOutput())
+
+	result, err = dsl.Use("show total", map[string]interface{}{"entities": map[string]interface{}{"total": true}})
+	require.NoError(t, err)
+	assert.Equal(t, "entity total", result.GetOutput())
+
+	// Trees follow the same choice
+	tree, err := dsl.ParseTree("show orders")
+	require.NoError(t, err)
+	assert.Equal(t, 1, tree.Root.Children[1].Alternative)
+}
+
+func TestPredicateInLeftRecursion(t *testing.T) {
+	dsl := New("list")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("COMMA", ","))
+	dsl.RuleWithPredicate("list", []string{"list", "COMMA", "NUMBER"}, "append", "short")
+	dsl.Rule("list", []string{"NUMBER"}, "single")
+	dsl.Action("single", func(args []interface{}) (interface{}, error) {
+		return []interface{}{args[0]}, nil
+	})
+	dsl.Action("append", func(args []interface{}) (interface{}, error) {
+		return append(args[0].([]interface{}), args[2]), nil
+	})
+	// At most three items: the predicate sees every token of the list
+	dsl.Predicate("short", func(ctx PredicateContext) bool {
+		return len(ctx.Tokens) <= 5
+	})
+
+	result, err := dsl.Parse("1, 2, 3")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"1", "2", "3"}, result.GetOutput())
+	_, err = dsl.Parse("1, 2, 3, 4")
+	assert.EqualError(t, err, "unexpected token: ,")
+}
+
+func TestPredicateBackends(t *testing.T) {
+	dsl := newEntityDSL(t)
+	dsl.Predicate("isEntity", func(ctx PredicateContext) bool {
+		entities, _ := ctx.Get("entities").(map[string]interface{})
+		_, exists := entities[ctx.Value(0)]
+		return exists
+	})
+
+	// Earley drops the rejected derivation, so nothing is ambiguous
+	dsl.SetBackend(BackendEarley)
+	forest, err := dsl.ParseForest("show users")
+	require.NoError(t, err)
+	assert.True(t, forest.Ambiguous())
+	forest, err = dsl.ParseForest("show total")
+	require.NoError(t, err)
+	assert.False(t, forest.Ambiguous())
+	result, err := dsl.Parse("show total")
+	require.NoError(t, err)
+	assert.Equal(t, "variable total", result.GetOutput())
+
+	dsl.Predicate("never", func(ctx PredicateContext) bool { return false })
+	dsl.RuleWithPredicate("query", []string{"SHOW", "SHOW"}, "", "never")
+	_, err = dsl.Parse("show show")
+	assert.EqualError(t, err, "parsing error: predicates rejected every derivation of rule query")
+
+	// The predictive parser cannot backtrack, so a rejection is an error
+	dsl.SetBackend(BackendPredictive)
+	dsl.SetPredictionTable(PredictionTable{
+		"query":  {"SHOW": 0},
+		"source": {"IDENT": 0},
+	})
+	result, err = dsl.Parse("show users")
+	require.NoError(t, err)
+	assert.Equal(t, "entity users", result.GetOutput())
+	_, err = dsl.Parse("show total")
+	assert.EqualError(t, err, "predicate isEntity rejected rule source")
+}
+
+func TestUnboundPredicates(t *testing.T) {
+	dsl := newEntityDSL(t)
+	assert.Equal(t, []string{"isEntity"}, dsl.RequiredPredicates())
+	assert.Equal(t, []string{"isEntity"}, dsl.UnboundPredicates())
+	assert.EqualError(t, dsl.Validate(), "unbound predicates: isEntity")
+	assert.Equal(t, "isEntity", dsl.Rules()[1].Alternatives[0].Predicate)
+
+	// Unregistered predicates reject every match
+	result, err := dsl.Parse("show users")
+	require.NoError(t, err)
+	assert.Equal(t, "variable users", result.GetOutput())
+
+	dsl.Strict(true)
+	_, err = dsl.Parse("show users")
+	assert.EqualError(t, err, "strict mode: unbound predicates: isEntity")
+
+	dsl.Rule("query", []string{"IDENT"}, "missing")
+	assert.EqualError(t, dsl.Validate(), "unbound actions: missing; unbound predicates: isEntity")
+}
+
+func TestPredicateConfig(t *testing.T) {
+	dsl, err := LoadFromYAML([]byte(`
+name: entities
+tokens:
+  SHOW: show
+  IDENT: "[a-z]+"
+rules:
+  - name: query
+    pattern: [SHOW, source]
+    action: query
+  - name: source
+    pattern: [IDENT]
+    action: entity
+    predicate: isEntity
+  - name: source
+    pattern: [IDENT]
+    action: variable
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"isEntity"}, dsl.RequiredPredicates())
+
+	config := dsl.toConfig()
+	assert.Equal(t, "isEntity", config.Rules[1].Predicate)
+	assert.Equal(t, "", config.Rules[2].Predicate)
+
+	data, err := dsl.SaveToYAML()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "predicate: isEntity")
+	assert.Equal(t, 1, strings.Count(string(data), "predicate:"))
+}
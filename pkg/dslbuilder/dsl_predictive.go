@@ -0,0 +1,251 @@
+// Package dslbuilder - Table-driven predictive (LL(1)) parsing
+package dslbuilder
+
+import (
+	"fmt"
+	"sort"
+)
+
+// EndOfInput is the lookahead symbol used in a PredictionTable for the end
+// of the token stream.
+const EndOfInput = "$"
+
+// PredictionTable maps a rule name and a lookahead token name to the index
+// of the alternative to use. Missing entries are syntax errors. Tables are
+// normally built by the analysis package, which only produces them for
+// LL(1) grammars.
+type PredictionTable map[string]map[string]int
+
+// SetPredictionTable installs the table used by BackendPredictive.
+// The table must be rebuilt when the grammar changes.
+//
+// Example:
+//
+//	dsl.SetPredictionTable(table)
+//	dsl.SetBackend(dslbuilder.BackendPredictive)
+func (d *DSL) SetPredictionTable(table PredictionTable) {
+	d.prediction = table
+}
+
+// PredictionTable returns the installed prediction table, or nil.
+func (d *DSL) PredictionTable() PredictionTable {
+	return d.prediction
+}
+
+// predictiveParser parses with an explicit stack, choosing every
+// alternative from the prediction table without backtracking. It reuses
+// the ImprovedParser lexer, tree building and bookkeeping.
+type predictiveParser struct {
+	*ImprovedParser
+	table PredictionTable
+}
+
+// predictiveFrame is an alternative being matched.
+type predictiveFrame struct {
+	rule     string
+	index    int
+	alt      *Alternative
+	next     int           // Index of the next symbol to match
+	values   []interface{} // Values of the matched symbols
+	consumed []string      // Token types matched directly
+	start    int           // Token position where the alternative starts
+}
+
+// newPredictiveParser creates a predictive parser for the DSL.
+func (d *DSL) newPredictiveParser(buildTree bool) (*predictiveParser, error) {
+	if d.prediction == nil {
+		return nil, fmt.Errorf("predictive parsing needs a prediction table (see SetPredictionTable)")
+	}
+	parser := NewImprovedParser(d.grammar)
+	parser.dsl = d
+	parser.buildTree = buildTree
+	return &predictiveParser{ImprovedParser: parser, table: d.prediction}, nil
+}
+
+// Parse tokenizes code and parses it from the start rule.
+func (p *predictiveParser) Parse(code string) (interface{}, error) {
+	p.input = code
+	p.tokens = []TokenMatch{}
+	p.trivia = nil
+	p.pos = 0
+	if err := p.tokenize(code); err != nil {
+		return nil, err
+	}
+
+	frame, err := p.predict(p.grammar.startRule)
+	if err != nil {
+		return nil, err
+	}
+	stack := []*predictiveFrame{frame}
+	for {
+		top := stack[len(stack)-1]
+		if top.next == len(top.alt.sequence) {
+			value, err := p.reduce(top)
+			if err != nil {
+				return nil, err
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				if p.pos < len(p.tokens) {
+					message := fmt.Sprintf("unexpected token: %s", p.tokens[p.pos].Value)
+					return nil, createParseError(message, p.tokens[p.pos].Start, p.tokens[p.pos].Value, p.input)
+				}
+				return value, nil
+			}
+			parent := stack[len(stack)-1]
+			parent.values = append(parent.values, value)
+			parent.next++
+			continue
+		}
+
+		symbol := top.alt.sequence[top.next]
+		if IsOperator(symbol) {
+			// The parser never backtracks, so a cut changes nothing
+			if symbol != Cut {
+				if err := p.testLookahead(symbol); err != nil {
+					return nil, err
+				}
+			}
+			top.next++
+			continue
+		}
+		if _, isToken := p.grammar.tokens[symbol]; !isToken {
+			frame, err := p.predict(symbol)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, frame)
+			continue
+		}
+
+		if p.pos >= len(p.tokens) {
+			p.expect(symbol)
+			return nil, createParseError("unexpected end of input", len(p.input), "<end of input>", p.input)
+		}
+		token := p.tokens[p.pos]
+		if token.TokenType != symbol {
+			p.expect(symbol)
+			message := fmt.Sprintf("expected token %s, got %s", symbol, token.TokenType)
+			return nil, createParseError(message, token.Start, token.Value, p.input)
+		}
+		if p.tracing() {
+			p.trace(TraceEvent{Kind: TraceTokenConsumed, Rule: top.rule, Alternative: top.index, Pos: p.pos, End: p.pos + 1, Token: &token})
+		}
+		if p.buildTree {
+			top.values = append(top.values, &Node{Alternative: -1, Token: &token, Start: token.Start, End: token.End})
+		} else {
+			top.values = append(top.values, token.Value)
+		}
+		top.consumed = append(top.consumed, symbol)
+		top.next++
+		p.pos++
+	}
+}
+
+// predict looks up the alternative of a rule for the current token.
+func (p *predictiveParser) predict(ruleName string) (*predictiveFrame, error) {
+	rule, exists := p.grammar.rules[ruleName]
+	if !exists {
+		return nil, fmt.Errorf("rule %s not found", ruleName)
+	}
+
+	lookahead := EndOfInput
+	if p.pos < len(p.tokens) {
+		lookahead = p.tokens[p.pos].TokenType
+	}
+	index, ok := p.table[ruleName][lookahead]
+	if ok && index >= 0 && index < len(rule.alternatives) {
+		return &predictiveFrame{rule: ruleName, index: index, alt: rule.alternatives[index], start: p.pos}, nil
+	}
+
+	expected := make([]string, 0, len(p.table[ruleName]))
+	for symbol := range p.table[ruleName] {
+		if symbol != EndOfInput {
+			expected = append(expected, symbol)
+		}
+	}
+	sort.Strings(expected)
+	for _, symbol := range expected {
+		p.expect(symbol)
+	}
+
+	message := fmt.Sprintf("no alternative matched for rule %s", ruleName)
+	if p.pos < len(p.tokens) {
+		return nil, createParseError(message, p.tokens[p.pos].Start, p.tokens[p.pos].Value, p.input)
+	}
+	return nil, createParseError(message, len(p.input), "<end of input>", p.input)
+}
+
+// reduce computes the value of a matched alternative.
+func (p *predictiveParser) reduce(frame *predictiveFrame) (interface{}, error) {
+	if err := p.checkPredicate(frame.rule, frame.index, frame.alt, frame.start); err != nil {
+		return nil, err
+	}
+	if p.buildTree {
+		return p.newNode(frame.rule, frame.index, frame.values), nil
+	}
+	if frame.alt.action != "" {
+		if action, exists := p.grammar.action(frame.alt.action); exists {
+			result, err := p.invokeAction(frame.rule, frame.index, frame.alt.action, action, frame.values)
+			if err != nil {
+				return nil, err
+			}
+			p.recordCoverage(frame.rule, frame.index, frame.consumed)
+			return result, nil
+		}
+	}
+	p.recordCoverage(frame.rule, frame.index, frame.consumed)
+	return frame.values, nil
+}
+
+// parsePredictive implements Parse for BackendPredictive.
+func (d *DSL) parsePredictive(code string) (*Result, error) {
+	parser, err := d.newPredictiveParser(false)
+	if err != nil {
+		return nil, err
+	}
+	output, err := parser.Parse(code)
+	if err != nil {
+		if IsParseError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("parsing error: %w", err)
+	}
+	return &Result{
+		AST:    output,
+		Code:   code,
+		Output: output,
+		DSL:    d,
+	}, nil
+}
+
+// parseTreePredictive implements ParseTree for BackendPredictive.
+func (d *DSL) parseTreePredictive(code string) (*Tree, error) {
+	parser, err := d.newPredictiveParser(true)
+	if err != nil {
+		return &Tree{Source: code, ExpectedOffset: len(code)}, err
+	}
+	result, err := parser.Parse(code)
+
+	tree := &Tree{
+		Source:         code,
+		Tokens:         parser.tokens,
+		Trivia:         parser.trivia,
+		Expected:       parser.expected,
+		ExpectedOffset: len(code),
+	}
+	if parser.farthest < len(parser.tokens) {
+		tree.ExpectedOffset = parser.tokens[parser.farthest].Start
+	}
+
+	if err != nil {
+		if IsParseError(err) {
+			return tree, err
+		}
+		return tree, fmt.Errorf("parsing error: %w", err)
+	}
+	if root, ok := result.(*Node); ok {
+		tree.Root = root
+	}
+	return tree, nil
+}
@@ -0,0 +1,35 @@
+package dslbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredictiveBackend(t *testing.T) {
+	dsl := newTreeDSL(t)
+	dsl.SetBackend(BackendPredictive)
+	_, err := dsl.Parse("1")
+	assert.EqualError(t, err, "predictive parsing needs a prediction table (see SetPredictionTable)")
+
+	// Hand-written table for stmt -> LET ID ASSIGN expr | expr, expr -> term
+	dsl.SetPredictionTable(PredictionTable{
+		"stmt": {"LET": 0, "NUMBER": 1},
+		"expr": {"NUMBER": 1},
+		"term": {"NUMBER": 0},
+	})
+	dsl.Action("let", func(args []interface{}) (interface{}, error) {
+		return map[string]interface{}{args[1].(string): args[3]}, nil
+	})
+	result, err := dsl.Parse("let x = 42")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"x": []interface{}{[]interface{}{"42"}}}, result.GetOutput())
+
+	// The table never predicts expr -> expr PLUS term, so "+" is an error
+	_, err = dsl.Parse("1 + 2")
+	assert.EqualError(t, err, "unexpected token: +")
+	_, err = dsl.Parse("let = 1")
+	assert.EqualError(t, err, "expected token ID, got ASSIGN")
+	assert.Equal(t, "predictive", dsl.Backend().String())
+}
@@ -0,0 +1,103 @@
+// Package dslbuilder - Strict action binding and action introspection
+package dslbuilder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Strict enables or disables strict mode.
+// In strict mode every action referenced by the grammar must be registered
+// before parsing. Parse fails fast with an error listing all unbound actions
+// instead of silently returning the raw matched values for those alternatives.
+//
+// Example:
+//
+//	dsl.Strict(true)
+//	if err := dsl.Validate(); err != nil {
+//	    log.Fatal(err) // unbound actions: add, number
+//	}
+func (d *DSL) Strict(enabled bool) {
+	d.strict = enabled
+}
+
+// IsStrict reports whether strict mode is enabled.
+func (d *DSL) IsStrict() bool {
+	return d.strict
+}
+
+// Actions returns the names of all registered actions, sorted alphabetically.
+func (d *DSL) Actions() []string {
+	names := make([]string, 0, len(d.actions))
+	for name := range d.actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RequiredActions returns the names of all actions referenced by the grammar
+// rules, sorted alphabetically and without duplicates.
+// Alternatives without an action are not included.
+func (d *DSL) RequiredActions() []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, rule := range d.grammar.rules {
+		for _, alt := range rule.alternatives {
+			if alt.action == "" {
+				continue
+			}
+			for _, name := range d.grammar.originalActions(alt.action) {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnboundActions returns the actions referenced by the grammar that have not
+// been registered with Action, sorted alphabetically.
+//
+// Tools can use it to auto-bind passthrough actions for grammars loaded
+// from YAML or JSON:
+//
+//	for _, name := range dsl.UnboundActions() {
+//	    dsl.Action(name, func(args []interface{}) (interface{}, error) {
+//	        return args, nil
+//	    })
+//	}
+func (d *DSL) UnboundActions() []string {
+	unbound := []string{}
+	for _, name := range d.RequiredActions() {
+		if _, exists := d.grammar.action(name); !exists {
+			unbound = append(unbound, name)
+		}
+	}
+	return unbound
+}
+
+// Validate checks that the DSL is ready to parse.
+// It returns an error listing every unbound action and predicate, and
+// every built-in action expression that does not compile (see
+// UseBuiltinActions), so missing registrations are reported at once
+// instead of one parse failure at a time.
+//
+// Validate runs automatically at the start of Parse when strict mode is enabled.
+func (d *DSL) Validate() error {
+	unbound, problems := d.invalidBuiltins(d.UnboundActions())
+	if len(unbound) > 0 {
+		problems = append([]string{"unbound actions: " + strings.Join(unbound, ", ")}, problems...)
+	}
+	if unbound := d.UnboundPredicates(); len(unbound) > 0 {
+		problems = append(problems, "unbound predicates: "+strings.Join(unbound, ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package dslbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStrictTestDSL(t *testing.T) *DSL {
+	dsl := New("StrictTest")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	dsl.Rule("expr", []string{"term", "PLUS", "term"}, "add")
+	dsl.Rule("expr", []string{"term"}, "")
+	dsl.Rule("term", []string{"NUMBER"}, "number")
+	return dsl
+}
+
+func TestRequiredActions(t *testing.T) {
+	dsl := newStrictTestDSL(t)
+
+	assert.Equal(t, []string{"add", "number"}, dsl.RequiredActions())
+	assert.Equal(t, []string{}, dsl.Actions())
+	assert.Equal(t, []string{"add", "number"}, dsl.UnboundActions())
+
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		return args[0], nil
+	})
+	dsl.Action("unused", func(args []interface{}) (interface{}, error) {
+		return nil, nil
+	})
+
+	assert.Equal(t, []string{"number", "unused"}, dsl.Actions())
+	assert.Equal(t, []string{"add"}, dsl.UnboundActions())
+}
+
+func TestValidateListsAllUnboundActions(t *testing.T) {
+	dsl := newStrictTestDSL(t)
+
+	err := dsl.Validate()
+	require.Error(t, err)
+	assert.Equal(t, "unbound actions: add, number", err.Error())
+}
+
+func TestStrictModeFailsFast(t *testing.T) {
+	dsl := newStrictTestDSL(t)
+
+	// Non-strict mode keeps the historical behavior of returning raw values
+	result, err := dsl.Parse("1 + 2")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{"1"}, "+", []interface{}{"2"}}, result.GetOutput())
+
+	dsl.Strict(true)
+	assert.True(t, dsl.IsStrict())
+
+	_, err = dsl.Parse("1 + 2")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "strict mode")
+	assert.Contains(t, err.Error(), "add, number")
+
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		return args[0], nil
+	})
+	dsl.Action("add", func(args []interface{}) (interface{}, error) {
+		return args[0].(string) + "+" + args[2].(string), nil
+	})
+
+	result, err = dsl.Parse("1 + 2")
+	require.NoError(t, err)
+	assert.Equal(t, "1+2", result.GetOutput())
+}
@@ -0,0 +1,116 @@
+// Package dslbuilder - Parse tracing hooks
+package dslbuilder
+
+import "time"
+
+// TraceEventKind identifies what happened in a TraceEvent.
+type TraceEventKind int
+
+const (
+	// TraceRuleEnter is sent when the parser starts parsing a rule
+	// at a position that is not memoized yet.
+	TraceRuleEnter TraceEventKind = iota
+	// TraceRuleExit is sent when a rule finishes; Err is set on failure.
+	TraceRuleExit
+	// TraceAlternativeTry is sent before an alternative is attempted.
+	TraceAlternativeTry
+	// TraceAlternativeFail is sent when an alternative does not match
+	// and the parser backtracks.
+	TraceAlternativeFail
+	// TraceMemoHit is sent when a rule result is reused from the memo table.
+	TraceMemoHit
+	// TraceMemoMiss is sent when a rule has no memoized result yet.
+	TraceMemoMiss
+	// TraceTokenConsumed is sent when a token is matched by an alternative.
+	TraceTokenConsumed
+	// TraceActionInvoked is sent after an action ran; Err is set on failure.
+	TraceActionInvoked
+)
+
+// String returns the event kind name, such as "rule-enter".
+func (k TraceEventKind) String() string {
+	switch k {
+	case TraceRuleEnter:
+		return "rule-enter"
+	case TraceRuleExit:
+		return "rule-exit"
+	case TraceAlternativeTry:
+		return "alternative-try"
+	case TraceAlternativeFail:
+		return "alternative-fail"
+	case TraceMemoHit:
+		return "memo-hit"
+	case TraceMemoMiss:
+		return "memo-miss"
+	case TraceTokenConsumed:
+		return "token-consumed"
+	case TraceActionInvoked:
+		return "action-invoked"
+	default:
+		return "unknown"
+	}
+}
+
+// TraceEvent describes one step of a parse.
+//
+// Positions are token indexes. Pos is where the event happened and End is
+// the position after a successful rule, memo hit, or consumed token.
+type TraceEvent struct {
+	Kind        TraceEventKind
+	Time        time.Time
+	Depth       int         // Rule nesting depth, 0 for the start rule
+	Rule        string      // Rule being parsed
+	Alternative int         // Alternative index, or -1 when not applicable
+	Sequence    []string    // Symbols of the alternative, if any
+	Pos         int         // Token position where the event happened
+	End         int         // Token position after the event
+	Token       *TokenMatch // Consumed token (TraceTokenConsumed)
+	Action      string      // Action name (TraceActionInvoked)
+	Err         error       // Failure reason (exit, alternative fail, action)
+}
+
+// Tracer receives parse events. Tracers are called synchronously from the
+// parser, so they should be fast; the parser does no tracing work at all
+// when no tracer is set.
+//
+// The dslbuilder/trace package provides an indented printer, a Chrome
+// trace-event writer and a per-rule profiler.
+type Tracer interface {
+	Event(e TraceEvent)
+}
+
+// TracerFunc adapts a function to the Tracer interface.
+type TracerFunc func(e TraceEvent)
+
+// Event calls f(e).
+func (f TracerFunc) Event(e TraceEvent) {
+	f(e)
+}
+
+// SetTracer installs a tracer for every following Parse call.
+// Pass nil to disable tracing.
+//
+// Example:
+//
+//	dsl.SetTracer(trace.NewPrinter(os.Stderr))
+//	dsl.Parse("1 + 2")
+func (d *DSL) SetTracer(tracer Tracer) {
+	d.tracer = tracer
+}
+
+// Tracer returns the installed tracer, or nil.
+func (d *DSL) Tracer() Tracer {
+	return d.tracer
+}
+
+// tracing reports whether the parser has a tracer installed.
+func (p *ImprovedParser) tracing() bool {
+	return p.dsl != nil && p.dsl.tracer != nil
+}
+
+// trace fills in the common fields of an event and sends it to the tracer.
+func (p *ImprovedParser) trace(e TraceEvent) {
+	e.Time = time.Now()
+	e.Depth = p.depth
+	p.dsl.tracer.Event(e)
+}
@@ -0,0 +1,103 @@
+package dslbuilder
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordTrace collects a compact description of every event.
+func recordTrace(dsl *DSL) *[]string {
+	events := []string{}
+	dsl.SetTracer(TracerFunc(func(e TraceEvent) {
+		desc := fmt.Sprintf("%d %s %s", e.Depth, e.Kind, e.Rule)
+		switch e.Kind {
+		case TraceAlternativeTry, TraceAlternativeFail:
+			desc += fmt.Sprintf("[%d]", e.Alternative)
+		case TraceTokenConsumed:
+			desc += " " + e.Token.Value
+		case TraceActionInvoked:
+			desc += " " + e.Action
+		}
+		if e.Err != nil {
+			desc += " error"
+		}
+		events = append(events, desc)
+	}))
+	return &events
+}
+
+func TestTracerEvents(t *testing.T) {
+	dsl := New("trace")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	dsl.Rule("sum", []string{"NUMBER", "PLUS", "NUMBER"}, "add")
+	dsl.Rule("sum", []string{"NUMBER"}, "")
+	dsl.Action("add", func(args []interface{}) (interface{}, error) { return "added", nil })
+
+	events := recordTrace(dsl)
+	_, err := dsl.Parse("1")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"0 memo-miss sum",
+		"0 rule-enter sum",
+		"1 alternative-try sum[0]",
+		"1 token-consumed sum 1",
+		"1 alternative-fail sum[0] error",
+		"1 alternative-try sum[1]",
+		"1 token-consumed sum 1",
+		"0 rule-exit sum",
+	}, *events)
+
+	*events = nil
+	_, err = dsl.Parse("1 + 2")
+	require.NoError(t, err)
+	assert.Contains(t, *events, "1 action-invoked sum add")
+}
+
+func TestTracerLeftRecursion(t *testing.T) {
+	dsl := New("trace")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	dsl.Rule("expr", []string{"expr", "PLUS", "NUMBER"}, "add")
+	dsl.Rule("expr", []string{"NUMBER"}, "")
+	dsl.Action("add", func(args []interface{}) (interface{}, error) {
+		if args[2] == "0" {
+			return nil, errors.New("zero")
+		}
+		return "added", nil
+	})
+
+	events := recordTrace(dsl)
+	_, err := dsl.Parse("1 + 2")
+	require.NoError(t, err)
+	assert.Contains(t, *events, "1 alternative-try expr[0]")
+	assert.Contains(t, *events, "1 token-consumed expr 2")
+	assert.Contains(t, *events, "1 action-invoked expr add")
+	// The last growth attempt runs out of input
+	assert.Equal(t, "1 alternative-fail expr[0] error", (*events)[len(*events)-2])
+
+	*events = nil
+	_, err = dsl.Parse("1 + 0")
+	require.Error(t, err)
+	assert.Contains(t, *events, "1 action-invoked expr add error")
+}
+
+func TestTracerDisabled(t *testing.T) {
+	dsl := New("trace")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	dsl.Rule("start", []string{"NUMBER"}, "")
+
+	events := recordTrace(dsl)
+	assert.NotNil(t, dsl.Tracer())
+	dsl.SetTracer(nil)
+	assert.Nil(t, dsl.Tracer())
+
+	_, err := dsl.Parse("1")
+	require.NoError(t, err)
+	assert.Empty(t, *events)
+}
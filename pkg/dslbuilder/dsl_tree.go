@@ -0,0 +1,179 @@
+// Package dslbuilder - Concrete syntax trees
+package dslbuilder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Node is a node of a concrete syntax tree built by ParseTree.
+// Rule nodes record which alternative matched; token leaves carry the
+// matched token. Start and End are byte offsets into the source.
+type Node struct {
+	Rule        string      // Rule name; empty for token leaves
+	Alternative int         // Index of the matched alternative, -1 for token leaves
+	Token       *TokenMatch // Matched token for leaves, nil for rule nodes
+	Children    []*Node     // Matched symbols in order
+	Start       int         // Byte offset of the first character
+	End         int         // Byte offset after the last character
+}
+
+// IsToken reports whether the node is a token leaf.
+func (n *Node) IsToken() bool {
+	return n.Token != nil
+}
+
+// Name returns the rule name, or the token type for leaves.
+func (n *Node) Name() string {
+	if n.Token != nil {
+		return n.Token.TokenType
+	}
+	return n.Rule
+}
+
+// Text returns the source text covered by the node.
+func (n *Node) Text(source string) string {
+	if n.Start < 0 || n.End > len(source) || n.Start > n.End {
+		return ""
+	}
+	return source[n.Start:n.End]
+}
+
+// Walk visits the node and its descendants in source order. Returning false
+// from fn skips the children of that node.
+func (n *Node) Walk(fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, child := range n.Children {
+		child.Walk(fn)
+	}
+}
+
+// String returns the tree as an S-expression, such as
+// (expr (term NUMBER:"1") PLUS:"+" (term NUMBER:"2")).
+func (n *Node) String() string {
+	if n.Token != nil {
+		return fmt.Sprintf("%s:%q", n.Token.TokenType, n.Token.Value)
+	}
+	parts := []string{n.Rule}
+	for _, child := range n.Children {
+		parts = append(parts, child.String())
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// Tree is the result of ParseTree.
+type Tree struct {
+	Root   *Node        // Root node for the start rule; nil if parsing failed
+	Source string       // Parsed source
+	Tokens []TokenMatch // Tokens of the source, up to a lexical error if any
+	Trivia []TokenMatch // Skipped tokens such as comments, in source order
+
+	// Expected lists the tokens the parser tried at the farthest position it
+	// reached, and ExpectedOffset is the byte offset of that position. For an
+	// incomplete input ExpectedOffset is len(Source) and Expected holds the
+	// tokens that may come next.
+	Expected       []string
+	ExpectedOffset int
+}
+
+// Path returns the nodes containing the byte offset, from the root down to
+// the innermost node. It returns nil if the offset is outside the tree.
+func (t *Tree) Path(offset int) []*Node {
+	if t.Root == nil {
+		return nil
+	}
+	var path []*Node
+	node := t.Root
+	for node != nil && node.Start <= offset && offset < node.End {
+		path = append(path, node)
+		next := (*Node)(nil)
+		for _, child := range node.Children {
+			if child.Start <= offset && offset < child.End {
+				next = child
+				break
+			}
+		}
+		node = next
+	}
+	return path
+}
+
+// ParseTree parses code into a concrete syntax tree without running any
+// actions, which makes it safe for tools such as editors, formatters and
+// viewers. The returned tree is never nil: on error it still holds the
+// tokens and the expected tokens at the farthest failure. With
+// BackendEarley the root is the preferred derivation of the parse forest.
+//
+// Example:
+//
+//	tree, err := dsl.ParseTree("1 + 2")
+//	if err == nil {
+//	    fmt.Println(tree.Root) // (expr (expr (term NUMBER:"1")) PLUS:"+" (term NUMBER:"2"))
+//	}
+func (d *DSL) ParseTree(code string) (*Tree, error) {
+	switch d.backend {
+	case BackendEarley:
+		return d.parseTreeEarley(code)
+	case BackendPredictive:
+		return d.parseTreePredictive(code)
+	}
+
+	parser := NewImprovedParser(d.grammar)
+	parser.dsl = d
+	parser.buildTree = true
+	result, err := parser.Parse(code)
+
+	tree := &Tree{
+		Source:         code,
+		Tokens:         parser.tokens,
+		Trivia:         parser.trivia,
+		Expected:       parser.expected,
+		ExpectedOffset: len(code),
+	}
+	if parser.farthest < len(parser.tokens) {
+		tree.ExpectedOffset = parser.tokens[parser.farthest].Start
+	}
+
+	if err != nil {
+		if IsParseError(err) {
+			return tree, err
+		}
+		return tree, fmt.Errorf("parsing error: %w", err)
+	}
+	if root, ok := result.(*Node); ok {
+		tree.Root = root
+	}
+	return tree, nil
+}
+
+// newNode builds a rule node from the values matched by an alternative.
+func (p *ImprovedParser) newNode(ruleName string, index int, results []interface{}) *Node {
+	node := &Node{Rule: ruleName, Alternative: index}
+	for _, r := range results {
+		if child, ok := r.(*Node); ok {
+			node.Children = append(node.Children, child)
+		}
+	}
+
+	// Span the non-empty children; empty nodes sit at the current position
+	node.Start, node.End = -1, -1
+	for _, child := range node.Children {
+		if child.Start == child.End {
+			continue
+		}
+		if node.Start < 0 {
+			node.Start = child.Start
+		}
+		node.End = child.End
+	}
+	if node.Start < 0 {
+		node.Start = len(p.input)
+		if p.pos < len(p.tokens) {
+			node.Start = p.tokens[p.pos].Start
+		}
+		node.End = node.Start
+	}
+	return node
+}
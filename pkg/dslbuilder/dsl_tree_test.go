@@ -0,0 +1,152 @@
+package dslbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTreeDSL(t *testing.T) *DSL {
+	dsl := New("tree")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+
+	dsl.Rule("stmt", []string{"LET", "ID", "ASSIGN", "expr"}, "let")
+	dsl.Rule("stmt", []string{"expr"}, "pass")
+	dsl.Rule("expr", []string{"expr", "PLUS", "term"}, "add")
+	dsl.Rule("expr", []string{"term"}, "pass")
+	dsl.Rule("term", []string{"NUMBER"}, "number")
+	return dsl
+}
+
+func TestParseTree(t *testing.T) {
+	dsl := newTreeDSL(t)
+	called := false
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		called = true
+		return args[0], nil
+	})
+
+	tree, err := dsl.ParseTree("let x = 1 + 2")
+	require.NoError(t, err)
+	assert.False(t, called, "actions must not run")
+	assert.Equal(t,
+		`(stmt LET:"let" ID:"x" ASSIGN:"=" (expr (expr (term NUMBER:"1")) PLUS:"+" (term NUMBER:"2")))`,
+		tree.Root.String())
+	assert.Equal(t, 0, tree.Root.Alternative)
+	assert.Equal(t, "let x = 1 + 2", tree.Root.Text(tree.Source))
+	assert.Len(t, tree.Tokens, 6)
+
+	expr := tree.Root.Children[3]
+	assert.Equal(t, "expr", expr.Name())
+	assert.Equal(t, "1 + 2", expr.Text(tree.Source))
+	assert.Equal(t, 8, expr.Start)
+	assert.Equal(t, 13, expr.End)
+}
+
+func TestParseTreeKeepsOffsets(t *testing.T) {
+	dsl := newTreeDSL(t)
+	tree, err := dsl.ParseTree("  \n  1 + 2  ")
+	require.NoError(t, err)
+	assert.Equal(t, 5, tree.Root.Start)
+	assert.Equal(t, "1 + 2", tree.Root.Text(tree.Source))
+}
+
+func TestParseTreePath(t *testing.T) {
+	dsl := newTreeDSL(t)
+	tree, err := dsl.ParseTree("1 + 2")
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, node := range tree.Path(4) {
+		names = append(names, node.Name())
+	}
+	assert.Equal(t, []string{"stmt", "expr", "term", "NUMBER"}, names)
+	assert.Nil(t, tree.Path(99))
+}
+
+func TestParseTreeExpected(t *testing.T) {
+	dsl := newTreeDSL(t)
+
+	tree, err := dsl.ParseTree("let x")
+	require.Error(t, err)
+	assert.Nil(t, tree.Root)
+	assert.Equal(t, []string{"ASSIGN"}, tree.Expected)
+	assert.Equal(t, 5, tree.ExpectedOffset)
+
+	// A complete input may still be extended
+	tree, err = dsl.ParseTree("1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PLUS"}, tree.Expected)
+	assert.Equal(t, 1, tree.ExpectedOffset)
+
+	tree, err = dsl.ParseTree("1 + let")
+	require.Error(t, err)
+	assert.Equal(t, []string{"NUMBER"}, tree.Expected)
+	assert.Equal(t, 4, tree.ExpectedOffset)
+}
+
+func TestParseTreeWalk(t *testing.T) {
+	dsl := newTreeDSL(t)
+	tree, err := dsl.ParseTree("1 + 2")
+	require.NoError(t, err)
+
+	tokens := []string{}
+	tree.Root.Walk(func(n *Node) bool {
+		if n.IsToken() {
+			tokens = append(tokens, n.Token.Value)
+		}
+		return n.Rule != "term" || n.Start == 0
+	})
+	assert.Equal(t, []string{"1", "+"}, tokens)
+}
+
+func TestEmptyAlternativeAtEndOfInput(t *testing.T) {
+	dsl := New("optional")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("BANG", "!"))
+	dsl.Rule("start", []string{"NUMBER", "suffix"}, "")
+	dsl.Rule("suffix", []string{"BANG"}, "")
+	dsl.Rule("suffix", []string{}, "")
+
+	_, err := dsl.Parse("1")
+	require.NoError(t, err)
+
+	tree, err := dsl.ParseTree("1 ")
+	require.NoError(t, err)
+	assert.Equal(t, `(start NUMBER:"1" (suffix))`, tree.Root.String())
+	assert.Equal(t, 1, tree.Root.End)
+	assert.Equal(t, []string{"BANG"}, tree.Expected)
+}
+
+func TestSkipTokensBecomeTrivia(t *testing.T) {
+	dsl := newTreeDSL(t)
+	require.NoError(t, dsl.CommentToken("COMMENT", "#[^\n]*"))
+	dsl.Action("add", func(args []interface{}) (interface{}, error) {
+		return "sum", nil
+	})
+
+	code := "# header\nlet x = 1 # one\n+ 2"
+	tree, err := dsl.ParseTree(code)
+	require.NoError(t, err)
+	require.Len(t, tree.Trivia, 2)
+	assert.Equal(t, "# header", tree.Trivia[0].Value)
+	assert.Equal(t, "# one", code[tree.Trivia[1].Start:tree.Trivia[1].End])
+	assert.Len(t, tree.Tokens, 6, "skipped tokens are not parser tokens")
+
+	tokens, err := dsl.DebugTokens(code)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 6)
+
+	_, err = dsl.Parse(code)
+	assert.NoError(t, err)
+
+	info, _ := dsl.Grammar().Token("COMMENT")
+	assert.True(t, info.Skip)
+	assert.Equal(t, CategoryComment, info.Category)
+	assert.Error(t, dsl.SkipToken("MISSING"))
+}
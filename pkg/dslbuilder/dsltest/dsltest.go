@@ -0,0 +1,231 @@
+// Package dsltest runs declarative grammar tests: inputs listed in YAML
+// with the outcome expected from parsing them. The cases live in a tests
+// section, either in the DSL configuration file itself or in a separate
+// file, and run with the dsltest command or from go test:
+//
+//	tests:
+//	  - name: addition
+//	    input: "1 + 2"
+//	    output: 3
+//	  - name: chained operators
+//	    input: "1 + 2 + 3"
+//	  - name: missing operand
+//	    input: "1 +"
+//	    reject: true
+//	  - name: missing operator
+//	    input: "1 2"
+//	    error:
+//	      line: 1
+//	      column: 3
+//	      message: unexpected token
+//
+// A case without reject or error expects the input to parse. Outputs are
+// compared as JSON, so 3 matches an action returning int or float64.
+//
+// Example:
+//
+//	func TestGrammar(t *testing.T) {
+//	    dsl := newCalculator()
+//	    dsltest.RunFile(t, dsl, "calculator.yaml")
+//	}
+package dsltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Case is one grammar test.
+type Case struct {
+	Name   string      `yaml:"name,omitempty" json:"name,omitempty"`     // Test name; defaults to the quoted input
+	Input  string      `yaml:"input" json:"input"`                       // Source to parse
+	Reject bool        `yaml:"reject,omitempty" json:"reject,omitempty"` // Expect parsing to fail
+	Output interface{} `yaml:"output,omitempty" json:"output,omitempty"` // Expected result; nil is not checked
+	Error  *Error      `yaml:"error,omitempty" json:"error,omitempty"`   // Expected error; implies Reject
+}
+
+// Error describes an expected parse error. Zero fields are not checked.
+type Error struct {
+	Line    int    `yaml:"line,omitempty" json:"line,omitempty"`       // 1-based line
+	Column  int    `yaml:"column,omitempty" json:"column,omitempty"`   // 1-based column
+	Message string `yaml:"message,omitempty" json:"message,omitempty"` // Substring of the error message
+}
+
+// Title returns the name of the case, or its quoted input if it has none.
+func (c Case) Title() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return fmt.Sprintf("%q", c.Input)
+}
+
+// Result is the outcome of running a case.
+type Result struct {
+	Case     Case
+	Output   interface{}   // Parse output if parsing succeeded
+	Err      error         // Parse error if parsing failed
+	Failure  string        // Why the case failed; empty if it passed
+	Duration time.Duration // Time spent parsing
+}
+
+// Passed reports whether the case passed.
+func (r Result) Passed() bool {
+	return r.Failure == ""
+}
+
+// Parse reads the tests section of a YAML or JSON document.
+func Parse(data []byte) ([]Case, error) {
+	var file struct {
+		Tests []Case `yaml:"tests"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tests: %w", err)
+	}
+	return file.Tests, nil
+}
+
+// Load reads the tests section of a file, such as a DSL configuration file
+// or a separate test file.
+func Load(filename string) ([]Case, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tests: %w", err)
+	}
+	return Parse(data)
+}
+
+// Check parses the input of a case with the DSL and compares the outcome
+// with the expectation. Actions run, so they see and may change the
+// context of the DSL.
+func Check(dsl *dslbuilder.DSL, c Case) Result {
+	start := time.Now()
+	result, err := dsl.Parse(c.Input)
+	r := Result{Case: c, Err: err, Duration: time.Since(start)}
+	if err == nil {
+		r.Output = result.GetOutput()
+	}
+
+	switch {
+	case c.Error != nil:
+		if err == nil {
+			r.Failure = "parsing succeeded, want an error"
+			break
+		}
+		r.Failure = checkError(err, *c.Error)
+	case c.Reject:
+		if err == nil {
+			r.Failure = "parsing succeeded, want a rejection"
+		}
+	case err != nil:
+		r.Failure = "parsing failed: " + dslbuilder.GetDetailedError(err)
+	case c.Output != nil:
+		r.Failure = checkOutput(r.Output, c.Output)
+	}
+	return r
+}
+
+// Run checks the cases in order.
+func Run(dsl *dslbuilder.DSL, cases []Case) []Result {
+	results := make([]Result, len(cases))
+	for i, c := range cases {
+		results[i] = Check(dsl, c)
+	}
+	return results
+}
+
+// RunCases runs every case as a subtest of t.
+func RunCases(t *testing.T, dsl *dslbuilder.DSL, cases []Case) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.Title(), func(t *testing.T) {
+			if r := Check(dsl, c); !r.Passed() {
+				t.Errorf("input %q: %s", c.Input, r.Failure)
+			}
+		})
+	}
+}
+
+// RunFile loads the tests section of a file and runs every case as a
+// subtest of t.
+func RunFile(t *testing.T, dsl *dslbuilder.DSL, filename string) {
+	t.Helper()
+	cases, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatalf("%s has no tests", filename)
+	}
+	RunCases(t, dsl, cases)
+}
+
+// checkError compares a parse error with the expected one.
+func checkError(err error, want Error) string {
+	var problems []string
+	if want.Message != "" && !strings.Contains(err.Error(), want.Message) {
+		problems = append(problems, fmt.Sprintf("message %q does not contain %q", err.Error(), want.Message))
+	}
+	if want.Line != 0 || want.Column != 0 {
+		parseErr, ok := err.(*dslbuilder.ParseError)
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("error %q has no position", err.Error()))
+		case want.Line != 0 && parseErr.Line != want.Line,
+			want.Column != 0 && parseErr.Column != want.Column:
+			problems = append(problems, fmt.Sprintf("error at %d:%d, want %s",
+				parseErr.Line, parseErr.Column, position(want)))
+		}
+	}
+	return strings.Join(problems, "; ")
+}
+
+// position formats the expected line and column, with _ for an unchecked
+// field.
+func position(want Error) string {
+	line, column := "_", "_"
+	if want.Line != 0 {
+		line = fmt.Sprint(want.Line)
+	}
+	if want.Column != 0 {
+		column = fmt.Sprint(want.Column)
+	}
+	return line + ":" + column
+}
+
+// checkOutput compares an output with the expected one as JSON.
+func checkOutput(got, want interface{}) string {
+	gotJSON, err := normalize(got)
+	if err != nil {
+		return fmt.Sprintf("output %v cannot be compared as JSON: %v", got, err)
+	}
+	wantJSON, err := normalize(want)
+	if err != nil {
+		return fmt.Sprintf("expected output %v cannot be compared as JSON: %v", want, err)
+	}
+	if reflect.DeepEqual(gotJSON, wantJSON) {
+		return ""
+	}
+	gotText, _ := json.Marshal(gotJSON)
+	wantText, _ := json.Marshal(wantJSON)
+	return fmt.Sprintf("output = %s, want %s", gotText, wantText)
+}
+
+// normalize converts a value to its JSON representation decoded again, so
+// that values of different Go types with the same JSON compare equal.
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
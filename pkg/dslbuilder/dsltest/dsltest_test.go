@@ -0,0 +1,135 @@
+package dsltest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCalculator(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("calculator")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("MINUS", "-"))
+
+	dsl.Rule("expr", []string{"expr", "PLUS", "NUMBER"}, "add")
+	dsl.Rule("expr", []string{"expr", "MINUS", "NUMBER"}, "sub")
+	dsl.Rule("expr", []string{"NUMBER"}, "number")
+
+	dsl.Action("add", func(args []interface{}) (interface{}, error) {
+		return args[0].(int) + number(args[2]), nil
+	})
+	dsl.Action("sub", func(args []interface{}) (interface{}, error) {
+		return args[0].(int) - number(args[2]), nil
+	})
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		return number(args[0]), nil
+	})
+	return dsl
+}
+
+func number(v interface{}) int {
+	n, _ := strconv.Atoi(v.(string))
+	return n
+}
+
+const suite = `
+name: calculator
+tests:
+  - name: addition
+    input: "1 + 2"
+    output: 3
+  - input: "5 - 1 + 2"
+  - name: missing operand
+    input: "1 +"
+    reject: true
+  - name: missing operator
+    input: "1 2"
+    error:
+      line: 1
+      column: 3
+      message: unexpected token
+`
+
+func TestParse(t *testing.T) {
+	cases, err := Parse([]byte(suite))
+	require.NoError(t, err)
+	require.Len(t, cases, 4)
+
+	assert.Equal(t, "addition", cases[0].Title())
+	assert.Equal(t, 3, cases[0].Output)
+	assert.Equal(t, `"5 - 1 + 2"`, cases[1].Title())
+	assert.True(t, cases[2].Reject)
+	assert.Equal(t, &Error{Line: 1, Column: 3, Message: "unexpected token"}, cases[3].Error)
+
+	_, err = Parse([]byte("tests: {"))
+	assert.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	dsl := newCalculator(t)
+	cases, err := Parse([]byte(suite))
+	require.NoError(t, err)
+
+	for _, r := range Run(dsl, cases) {
+		assert.True(t, r.Passed(), "%s: %s", r.Case.Title(), r.Failure)
+	}
+
+	tests := []struct {
+		c       Case
+		failure string
+	}{
+		{Case{Input: "1 + 2", Output: 4}, "output = 3, want 4"},
+		{Case{Input: "1 + 2", Output: []int{3}}, "output = 3, want [3]"},
+		{Case{Input: "1 + 2", Reject: true}, "parsing succeeded, want a rejection"},
+		{Case{Input: "1 + 2", Error: &Error{}}, "parsing succeeded, want an error"},
+		{Case{Input: "1 2", Error: &Error{Column: 2}}, "error at 1:3, want _:2"},
+		{Case{Input: "1 2", Error: &Error{Message: "division"}}, `message "unexpected token: 2" does not contain "division"`},
+	}
+	for _, tt := range tests {
+		r := Check(dsl, tt.c)
+		assert.Equal(t, tt.failure, r.Failure, tt.c.Input)
+	}
+
+	r := Check(dsl, Case{Input: "1 +"})
+	assert.Contains(t, r.Failure, "parsing failed: ")
+	assert.Error(t, r.Err)
+	assert.Nil(t, r.Output)
+}
+
+func TestRunFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "calculator.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte(suite), 0644))
+	RunFile(t, newCalculator(t), filename)
+}
+
+func TestWriteJUnit(t *testing.T) {
+	dsl := newCalculator(t)
+	results := Run(dsl, []Case{
+		{Name: "addition", Input: "1 + 2", Output: 3},
+		{Name: "wrong", Input: "1 + 2", Output: 4},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJUnit(&buf, Suite{Name: "calculator.yaml", Results: results}))
+
+	var report junitSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+	assert.Equal(t, 2, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	require.Len(t, report.Suites, 1)
+	suite := report.Suites[0]
+	assert.Equal(t, "calculator.yaml", suite.Name)
+	require.Len(t, suite.Cases, 2)
+	assert.Nil(t, suite.Cases[0].Failure)
+	require.NotNil(t, suite.Cases[1].Failure)
+	assert.Equal(t, "output = 3, want 4", suite.Cases[1].Failure.Message)
+	assert.Contains(t, suite.Cases[1].Failure.Text, `input: "1 + 2"`)
+}
@@ -0,0 +1,89 @@
+package dsltest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Suite groups the results of one test file for WriteJUnit.
+type Suite struct {
+	Name    string
+	Results []Result
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as JUnit XML, the report format most CI
+// systems display.
+func WriteJUnit(w io.Writer, suites ...Suite) error {
+	report := junitSuites{}
+	var total float64
+	for _, suite := range suites {
+		js := junitSuite{Name: suite.Name, Tests: len(suite.Results)}
+		var elapsed float64
+		for _, r := range suite.Results {
+			jc := junitCase{
+				Name:      r.Case.Title(),
+				Classname: suite.Name,
+				Time:      seconds(r.Duration.Seconds()),
+			}
+			if !r.Passed() {
+				js.Failures++
+				jc.Failure = &junitFailure{
+					Message: r.Failure,
+					Text:    fmt.Sprintf("input: %q\n%s", r.Case.Input, r.Failure),
+				}
+			}
+			elapsed += r.Duration.Seconds()
+			js.Cases = append(js.Cases, jc)
+		}
+		js.Time = seconds(elapsed)
+		report.Tests += js.Tests
+		report.Failures += js.Failures
+		total += elapsed
+		report.Suites = append(report.Suites, js)
+	}
+	report.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
@@ -0,0 +1,260 @@
+// Package export generates syntax highlighting definitions for go-dsl
+// grammars: a TextMate grammar (.tmLanguage.json) for VS Code, Sublime Text
+// and other TextMate-compatible editors, and language definitions for
+// highlight.js and Prism for documentation sites.
+//
+// Highlighting is driven by token categories. Keyword tokens are keywords
+// by default; every other token is highlighted only once it has a category,
+// set with DSL.SetTokenCategory or the token_categories section of a
+// DSLConfig. Token patterns are translated from Go regular expressions to
+// the target engines.
+//
+// Example:
+//
+//	dsl.SetTokenCategory("NUMBER", dslbuilder.CategoryNumber)
+//	exp := export.New(dsl, export.Options{Extensions: []string{".calc"}})
+//	grammar, err := exp.TextMate()
+//	if err != nil {
+//	    return err
+//	}
+//	os.WriteFile("calc.tmLanguage.json", grammar, 0644)
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// Options controls the generated definitions.
+type Options struct {
+	Name       string   // Language identifier (defaults to the DSL name, lowercased)
+	Title      string   // Display name (defaults to the DSL name)
+	ScopeName  string   // TextMate scope (defaults to "source.<Name>")
+	Extensions []string // File extensions, such as ".calc"
+}
+
+// Exporter produces highlighting definitions for one DSL.
+type Exporter struct {
+	dsl  *dslbuilder.DSL
+	opts Options
+}
+
+// category describes how a token category is highlighted in each target.
+type category struct {
+	category dslbuilder.TokenCategory
+	textMate string // TextMate scope prefix
+	hljs     string // highlight.js scope
+	prism    string // Prism token name
+	greedy   bool   // Prism greedy matching, for tokens that may contain others
+}
+
+// categories are in matching order: comments and strings come first so
+// keywords inside them are not highlighted.
+var categories = []category{
+	{dslbuilder.CategoryComment, "comment.line", "comment", "comment", true},
+	{dslbuilder.CategoryString, "string.quoted", "string", "string", true},
+	{dslbuilder.CategoryKeyword, "keyword.control", "keyword", "keyword", false},
+	{dslbuilder.CategoryNumber, "constant.numeric", "number", "number", false},
+	{dslbuilder.CategoryOperator, "keyword.operator", "operator", "operator", false},
+	{dslbuilder.CategoryIdentifier, "variable.other", "variable", "variable", false},
+}
+
+// New creates an exporter for a DSL.
+func New(dsl *dslbuilder.DSL, opts Options) *Exporter {
+	if opts.Name == "" {
+		opts.Name = identifier(dsl.Name())
+	}
+	if opts.Title == "" {
+		opts.Title = dsl.Name()
+	}
+	if opts.ScopeName == "" {
+		opts.ScopeName = "source." + opts.Name
+	}
+	return &Exporter{dsl: dsl, opts: opts}
+}
+
+// NewFromConfig creates an exporter from a declarative configuration,
+// without registering any actions.
+func NewFromConfig(config dslbuilder.DSLConfig, opts Options) (*Exporter, error) {
+	dsl, err := dslbuilder.LoadFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return New(dsl, opts), nil
+}
+
+// identifier turns a DSL name into a language identifier, such as
+// "HTTP DSL" into "http-dsl".
+func identifier(name string) string {
+	id := strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if id == "" {
+		return "dsl"
+	}
+	return id
+}
+
+// tokensOf returns the tokens of a category, keyword tokens first and then
+// in definition order, which is the order the tokenizer prefers them.
+// External tokens have no pattern to highlight.
+func (e *Exporter) tokensOf(c dslbuilder.TokenCategory) []dslbuilder.TokenInfo {
+	var keywords, others []dslbuilder.TokenInfo
+	for _, token := range e.dsl.Tokens() {
+		switch {
+		case token.Category != c, token.External:
+		case token.IsKeyword():
+			keywords = append(keywords, token)
+		default:
+			others = append(others, token)
+		}
+	}
+	return append(keywords, others...)
+}
+
+// TextMate returns a TextMate grammar in JSON (.tmLanguage.json) with one
+// repository entry per token category.
+func (e *Exporter) TextMate() ([]byte, error) {
+	type pattern struct {
+		Name    string `json:"name,omitempty"`
+		Match   string `json:"match,omitempty"`
+		Include string `json:"include,omitempty"`
+		Comment string `json:"comment,omitempty"`
+	}
+	type repositoryEntry struct {
+		Patterns []pattern `json:"patterns"`
+	}
+	grammar := struct {
+		Schema     string                     `json:"$schema"`
+		Name       string                     `json:"name"`
+		ScopeName  string                     `json:"scopeName"`
+		FileTypes  []string                   `json:"fileTypes,omitempty"`
+		Patterns   []pattern                  `json:"patterns"`
+		Repository map[string]repositoryEntry `json:"repository"`
+	}{
+		Schema:     "https://raw.githubusercontent.com/martinring/tmlanguage/master/tmlanguage.json",
+		Name:       e.opts.Title,
+		ScopeName:  e.opts.ScopeName,
+		Patterns:   []pattern{},
+		Repository: map[string]repositoryEntry{},
+	}
+	for _, ext := range e.opts.Extensions {
+		grammar.FileTypes = append(grammar.FileTypes, strings.TrimPrefix(ext, "."))
+	}
+
+	for _, c := range categories {
+		var patterns []pattern
+		for _, token := range e.tokensOf(c.category) {
+			re, err := translate(token.Pattern, oniguruma, false)
+			if err != nil {
+				return nil, fmt.Errorf("token %s: %w", token.Name, err)
+			}
+			patterns = append(patterns, pattern{
+				Name:    c.textMate + "." + e.opts.Name,
+				Match:   re.source,
+				Comment: token.Name,
+			})
+		}
+		if len(patterns) == 0 {
+			continue
+		}
+		key := string(c.category)
+		grammar.Repository[key] = repositoryEntry{Patterns: patterns}
+		grammar.Patterns = append(grammar.Patterns, pattern{Include: "#" + key})
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(grammar); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// HighlightJS returns a highlight.js (v11) language definition as an ES
+// module whose default export is the language function:
+//
+//	import calc from "./calc.js";
+//	hljs.registerLanguage("calc", calc);
+//
+// highlight.js ignores the flags of mode regexes, so case-insensitive
+// keywords are spelled out as character classes.
+func (e *Exporter) HighlightJS() (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "/*\nLanguage: %s\nGenerated by go-dsl from the %s grammar.\n*/\n\n", e.opts.Title, e.dsl.Name())
+	b.WriteString("export default function (hljs) {\n")
+	b.WriteString("  return {\n")
+	fmt.Fprintf(&b, "    name: %s,\n", quote(e.opts.Title))
+	if aliases := e.aliases(); len(aliases) > 0 {
+		fmt.Fprintf(&b, "    aliases: [%s],\n", strings.Join(aliases, ", "))
+	}
+	b.WriteString("    contains: [\n")
+	for _, c := range categories {
+		for _, token := range e.tokensOf(c.category) {
+			re, err := translate(token.Pattern, javascript, true)
+			if err != nil {
+				return "", fmt.Errorf("token %s: %w", token.Name, err)
+			}
+			fmt.Fprintf(&b, "      { scope: %s, match: /%s/ }, // %s\n", quote(c.hljs), re.source, token.Name)
+		}
+	}
+	b.WriteString("    ]\n")
+	b.WriteString("  };\n")
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// Prism returns a Prism language definition that registers itself on the
+// global Prism object.
+func (e *Exporter) Prism() (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s language definition for Prism, generated by go-dsl.\n", e.opts.Title)
+	fmt.Fprintf(&b, "Prism.languages[%s] = {\n", quote(e.opts.Name))
+	for _, c := range categories {
+		tokens := e.tokensOf(c.category)
+		if len(tokens) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "  %s: [\n", quote(c.prism))
+		for _, token := range tokens {
+			re, err := translate(token.Pattern, javascript, false)
+			if err != nil {
+				return "", fmt.Errorf("token %s: %w", token.Name, err)
+			}
+			fmt.Fprintf(&b, "    { pattern: /%s/%s", re.source, re.flags)
+			if c.greedy {
+				b.WriteString(", greedy: true")
+			}
+			fmt.Fprintf(&b, " }, // %s\n", token.Name)
+		}
+		b.WriteString("  ],\n")
+	}
+	b.WriteString("};\n")
+	for _, alias := range e.aliases() {
+		fmt.Fprintf(&b, "Prism.languages[%s] = Prism.languages[%s];\n", alias, quote(e.opts.Name))
+	}
+	return b.String(), nil
+}
+
+// aliases returns the quoted file extensions that differ from the name.
+func (e *Exporter) aliases() []string {
+	var aliases []string
+	for _, ext := range e.opts.Extensions {
+		alias := strings.TrimPrefix(ext, ".")
+		if alias != "" && alias != e.opts.Name {
+			aliases = append(aliases, quote(alias))
+		}
+	}
+	return aliases
+}
+
+// quote returns s as a JavaScript string literal.
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
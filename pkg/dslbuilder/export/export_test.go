@@ -0,0 +1,149 @@
+package export
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCalculator(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("Calc DSL")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.Token("COMMENT", "#[^\\n]*"))
+	require.NoError(t, dsl.Token("STRING", `"[^"]*"`))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+(\\.[0-9]+)?"))
+	require.NoError(t, dsl.Token("PLUS", "\\+|-"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+
+	require.NoError(t, dsl.SetTokenCategory("COMMENT", dslbuilder.CategoryComment))
+	require.NoError(t, dsl.SetTokenCategory("STRING", dslbuilder.CategoryString))
+	require.NoError(t, dsl.SetTokenCategory("NUMBER", dslbuilder.CategoryNumber))
+	require.NoError(t, dsl.SetTokenCategory("PLUS", dslbuilder.CategoryOperator))
+
+	dsl.Rule("stmt", []string{"LET", "ID", "ASSIGN", "NUMBER"}, "")
+	return dsl
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		pattern string
+		onig    string
+		js      string
+		flags   string
+		hljs    string
+	}{
+		{`(?i)\blet\b`, `(?i)\blet\b`, `\blet\b`, "i", `\b[lL][eE][tT]\b`},
+		{`[0-9]+(\.[0-9]+)?`, `\d+(\.\d+)?`, `\d+(\.\d+)?`, "", `\d+(\.\d+)?`},
+		{`//[^\n]*`, `//[^\n]*`, `\/\/[^\n]*`, "", `\/\/[^\n]*`},
+		{`\+|-`, `[+\-]`, `[+\-]`, "", `[+\-]`},
+		{`(?P<key>\w+)=`, `(?<key>\w+)=`, `(?<key>\w+)=`, "", `(?<key>\w+)=`},
+		{`(?m)^#.*$`, `^#.*$`, `^#.*$`, "m", `^#.*$`},
+		{`(ab){2,}?`, `(ab){2,}?`, `(ab){2,}?`, "", `(ab){2,}?`},
+		{`(?i)x*y`, `(?i)x*y`, `x*y`, "i", `[xX]*[yY]`},
+	}
+	for _, tt := range tests {
+		onig, err := translate(tt.pattern, oniguruma, false)
+		require.NoError(t, err)
+		assert.Equal(t, tt.onig, onig.source, tt.pattern)
+
+		js, err := translate(tt.pattern, javascript, false)
+		require.NoError(t, err)
+		assert.Equal(t, tt.js, js.source, tt.pattern)
+		assert.Equal(t, tt.flags, js.flags, tt.pattern)
+
+		hljs, err := translate(tt.pattern, javascript, true)
+		require.NoError(t, err)
+		assert.Equal(t, tt.hljs, hljs.source, tt.pattern)
+	}
+}
+
+// The translated patterns still match what the Go pattern matches.
+func TestTranslatePreservesMatches(t *testing.T) {
+	patterns := []string{`[0-9]+(\.[0-9]+)?`, `"(?:[^"\\]|\\.)*"`, `[^a-z]+`, `\S+`, `[a-z]{2,3}`}
+	inputs := []string{"12.5", `"a\"b"`, "AB1", "x y", "abcd", ""}
+	for _, p := range patterns {
+		tr, err := translate(p, oniguruma, false)
+		require.NoError(t, err)
+		goRe, trRe := regexp.MustCompile(p), regexp.MustCompile(tr.source)
+		for _, in := range inputs {
+			assert.Equal(t, goRe.FindString(in), trRe.FindString(in), "%s on %q", p, in)
+		}
+	}
+}
+
+func TestTextMate(t *testing.T) {
+	data, err := New(newCalculator(t), Options{Extensions: []string{".calc"}}).TextMate()
+	require.NoError(t, err)
+
+	var grammar struct {
+		Name       string              `json:"name"`
+		ScopeName  string              `json:"scopeName"`
+		FileTypes  []string            `json:"fileTypes"`
+		Patterns   []map[string]string `json:"patterns"`
+		Repository map[string]struct {
+			Patterns []map[string]string `json:"patterns"`
+		} `json:"repository"`
+	}
+	require.NoError(t, json.Unmarshal(data, &grammar))
+
+	assert.Equal(t, "Calc DSL", grammar.Name)
+	assert.Equal(t, "source.calc-dsl", grammar.ScopeName)
+	assert.Equal(t, []string{"calc"}, grammar.FileTypes)
+
+	includes := []string{}
+	for _, p := range grammar.Patterns {
+		includes = append(includes, p["include"])
+	}
+	assert.Equal(t, []string{"#comment", "#string", "#keyword", "#number", "#operator"}, includes)
+
+	keyword := grammar.Repository["keyword"].Patterns
+	require.Len(t, keyword, 1)
+	assert.Equal(t, "keyword.control.calc-dsl", keyword[0]["name"])
+	assert.Equal(t, `(?i)\blet\b`, keyword[0]["match"])
+	assert.Equal(t, "LET", keyword[0]["comment"])
+	assert.NotContains(t, grammar.Repository, "identifier", "uncategorized tokens are not highlighted")
+}
+
+func TestHighlightJS(t *testing.T) {
+	js, err := New(newCalculator(t), Options{Name: "calc", Extensions: []string{".calc", ".cdsl"}}).HighlightJS()
+	require.NoError(t, err)
+
+	assert.Contains(t, js, "export default function (hljs) {")
+	assert.Contains(t, js, `name: "Calc DSL",`)
+	assert.Contains(t, js, `aliases: ["cdsl"],`)
+	assert.Contains(t, js, `{ scope: "keyword", match: /\b[lL][eE][tT]\b/ }, // LET`)
+	assert.Contains(t, js, `{ scope: "comment", match: /#[^\n]*/ }, // COMMENT`)
+	assert.Less(t, strings.Index(js, `"string"`), strings.Index(js, `"keyword"`), "strings match before keywords")
+}
+
+func TestPrism(t *testing.T) {
+	js, err := New(newCalculator(t), Options{}).Prism()
+	require.NoError(t, err)
+
+	assert.Contains(t, js, `Prism.languages["calc-dsl"] = {`)
+	assert.Contains(t, js, `{ pattern: /\blet\b/i }, // LET`)
+	assert.Contains(t, js, `{ pattern: /"[^"]*"/, greedy: true }, // STRING`)
+	assert.Contains(t, js, `{ pattern: /[+\-]/ }, // PLUS`)
+	assert.NotContains(t, js, "ASSIGN")
+}
+
+func TestNewFromConfig(t *testing.T) {
+	exp, err := NewFromConfig(dslbuilder.DSLConfig{
+		Name:            "query",
+		Tokens:          map[string]string{"SELECT": "select", "NUMBER": "[0-9]+"},
+		TokenCategories: map[string]dslbuilder.TokenCategory{"NUMBER": dslbuilder.CategoryNumber},
+		Rules:           []dslbuilder.RuleConfig{{Name: "q", Pattern: []string{"SELECT", "NUMBER"}}},
+	}, Options{})
+	require.NoError(t, err)
+
+	js, err := exp.Prism()
+	require.NoError(t, err)
+	assert.Contains(t, js, `"keyword": [`)
+	assert.Contains(t, js, `{ pattern: /\d+/ }, // NUMBER`)
+}
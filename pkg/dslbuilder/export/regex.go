@@ -0,0 +1,346 @@
+package export
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// dialect is a target regular expression syntax.
+type dialect int
+
+const (
+	oniguruma  dialect = iota // TextMate grammars
+	javascript                // highlight.js and Prism
+)
+
+// translated is a token pattern rewritten for a dialect.
+type translated struct {
+	source string // Pattern without delimiters
+	flags  string // JavaScript flags ("i", "m", "u"); Oniguruma uses inline (?i)
+}
+
+// translator rewrites Go (RE2) patterns through regexp/syntax, so every
+// construct is either translated or reported instead of silently changing
+// meaning in the target engine.
+type translator struct {
+	dialect dialect
+	// noFlags expands case-insensitive literals into character classes,
+	// for highlight.js which ignores the flags of mode regexes.
+	noFlags bool
+
+	fold      bool // Emit folded literals as-is and set the i flag
+	multiline bool
+	unicode   bool
+}
+
+// translate converts a Go pattern to the dialect.
+func translate(pattern string, d dialect, noFlags bool) (translated, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return translated{}, err
+	}
+
+	t := &translator{dialect: d, noFlags: noFlags}
+	t.fold = !noFlags && allLiteralsFold(re)
+
+	var b strings.Builder
+	if err := t.write(&b, re); err != nil {
+		return translated{}, err
+	}
+
+	out := translated{source: b.String()}
+	if t.dialect == oniguruma {
+		if t.fold {
+			out.source = "(?i)" + out.source
+		}
+		return out, nil
+	}
+	if t.fold {
+		out.flags += "i"
+	}
+	if t.multiline {
+		out.flags += "m"
+	}
+	if t.unicode {
+		out.flags += "u"
+	}
+	return out, nil
+}
+
+// allLiteralsFold reports whether the pattern has literals and all of them
+// are case-insensitive, as in KeywordToken patterns.
+func allLiteralsFold(re *syntax.Regexp) bool {
+	found, folded := false, true
+	var visit func(*syntax.Regexp)
+	visit = func(re *syntax.Regexp) {
+		if re.Op == syntax.OpLiteral {
+			found = true
+			folded = folded && re.Flags&syntax.FoldCase != 0
+		}
+		for _, sub := range re.Sub {
+			visit(sub)
+		}
+	}
+	visit(re)
+	return found && folded
+}
+
+func (t *translator) write(b *strings.Builder, re *syntax.Regexp) error {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		b.WriteString(`[^\s\S]`)
+	case syntax.OpEmptyMatch:
+		b.WriteString("(?:)")
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			switch {
+			case re.Flags&syntax.FoldCase == 0:
+				t.writeRune(b, r, false)
+			case t.fold:
+				t.writeRune(b, unicode.ToLower(r), false)
+			default:
+				t.writeFolded(b, r)
+			}
+		}
+	case syntax.OpCharClass:
+		t.writeClass(b, re.Rune)
+	case syntax.OpAnyCharNotNL:
+		b.WriteString(".")
+	case syntax.OpAnyChar:
+		b.WriteString(`[\s\S]`)
+	case syntax.OpBeginLine:
+		t.multiline = true
+		b.WriteString("^")
+	case syntax.OpEndLine:
+		t.multiline = true
+		b.WriteString("$")
+	case syntax.OpBeginText:
+		b.WriteString("^")
+	case syntax.OpEndText:
+		b.WriteString("$")
+	case syntax.OpWordBoundary:
+		b.WriteString(`\b`)
+	case syntax.OpNoWordBoundary:
+		b.WriteString(`\B`)
+	case syntax.OpCapture:
+		if re.Name != "" {
+			b.WriteString("(?<" + re.Name + ">")
+		} else {
+			b.WriteString("(")
+		}
+		if err := t.write(b, re.Sub[0]); err != nil {
+			return err
+		}
+		b.WriteString(")")
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		if err := t.writeAtom(b, re.Sub[0]); err != nil {
+			return err
+		}
+		switch re.Op {
+		case syntax.OpStar:
+			b.WriteString("*")
+		case syntax.OpPlus:
+			b.WriteString("+")
+		case syntax.OpQuest:
+			b.WriteString("?")
+		default:
+			switch {
+			case re.Max == -1:
+				fmt.Fprintf(b, "{%d,}", re.Min)
+			case re.Min == re.Max:
+				fmt.Fprintf(b, "{%d}", re.Min)
+			default:
+				fmt.Fprintf(b, "{%d,%d}", re.Min, re.Max)
+			}
+		}
+		if re.Flags&syntax.NonGreedy != 0 {
+			b.WriteString("?")
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpAlternate {
+				if err := t.writeGroup(b, sub); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := t.write(b, sub); err != nil {
+				return err
+			}
+		}
+	case syntax.OpAlternate:
+		for i, sub := range re.Sub {
+			if i > 0 {
+				b.WriteString("|")
+			}
+			if err := t.write(b, sub); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported regular expression %s", re)
+	}
+	return nil
+}
+
+// writeAtom writes the operand of a repetition, grouping it if needed.
+func (t *translator) writeAtom(b *strings.Builder, re *syntax.Regexp) error {
+	switch {
+	case re.Op == syntax.OpLiteral && len(re.Rune) == 1,
+		re.Op == syntax.OpCharClass, re.Op == syntax.OpAnyChar, re.Op == syntax.OpAnyCharNotNL,
+		re.Op == syntax.OpCapture:
+		return t.write(b, re)
+	}
+	return t.writeGroup(b, re)
+}
+
+func (t *translator) writeGroup(b *strings.Builder, re *syntax.Regexp) error {
+	b.WriteString("(?:")
+	if err := t.write(b, re); err != nil {
+		return err
+	}
+	b.WriteString(")")
+	return nil
+}
+
+// writeFolded writes a case-insensitive rune as a class such as [lL].
+func (t *translator) writeFolded(b *strings.Builder, r rune) {
+	variants := []rune{unicode.ToLower(r)}
+	for _, v := range []rune{unicode.ToUpper(r), unicode.ToTitle(r)} {
+		if !strings.ContainsRune(string(variants), v) {
+			variants = append(variants, v)
+		}
+	}
+	if len(variants) == 1 {
+		t.writeRune(b, r, false)
+		return
+	}
+	b.WriteString("[")
+	for _, v := range variants {
+		t.writeRune(b, v, true)
+	}
+	b.WriteString("]")
+}
+
+// perlClasses are the shorthand classes, as ranges after parsing.
+var perlClasses = []struct {
+	ranges []rune
+	name   string
+}{
+	{[]rune{'0', '9'}, `\d`},
+	{[]rune{'0', '9', 'A', 'Z', '_', '_', 'a', 'z'}, `\w`},
+	{[]rune{'\t', '\n', '\f', '\r', ' ', ' '}, `\s`},
+}
+
+// writeClass writes a character class from its sorted ranges, using a
+// shorthand such as \d when one matches and negating the class when that
+// is shorter.
+func (t *translator) writeClass(b *strings.Builder, ranges []rune) {
+	for _, perl := range perlClasses {
+		switch {
+		case equalRunes(ranges, perl.ranges):
+			b.WriteString(perl.name)
+			return
+		case equalRunes(ranges, complementOf(perl.ranges)):
+			b.WriteString(strings.ToUpper(perl.name))
+			return
+		}
+	}
+
+	negated := false
+	if len(ranges) > 0 && ranges[0] == 0 && ranges[len(ranges)-1] == unicode.MaxRune {
+		negated = true
+		ranges = gaps(ranges)
+	}
+	b.WriteString("[")
+	if negated {
+		b.WriteString("^")
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		t.writeRune(b, lo, true)
+		if hi > lo {
+			if hi > lo+1 {
+				b.WriteString("-")
+			}
+			t.writeRune(b, hi, true)
+		}
+	}
+	b.WriteString("]")
+}
+
+// gaps returns the ranges between those of a class starting at 0 and
+// ending at unicode.MaxRune, which is the class to negate.
+func gaps(ranges []rune) []rune {
+	var out []rune
+	for i := 1; i+1 < len(ranges); i += 2 {
+		out = append(out, ranges[i]+1, ranges[i+1]-1)
+	}
+	return out
+}
+
+// complementOf returns the ranges not covered by a class.
+func complementOf(ranges []rune) []rune {
+	out := []rune{0}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		out = append(out, ranges[i]-1, ranges[i+1]+1)
+	}
+	return append(out, unicode.MaxRune)
+}
+
+func equalRunes(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeRune writes one rune, escaped for use inside or outside a class.
+func (t *translator) writeRune(b *strings.Builder, r rune, inClass bool) {
+	special := `\^$.|?*+()[]{}`
+	if inClass {
+		special = `\^-[]`
+	}
+	if t.dialect == javascript {
+		special += "/"
+	}
+
+	switch {
+	case strings.ContainsRune(special, r):
+		b.WriteString(`\`)
+		b.WriteRune(r)
+	case r == '\n':
+		b.WriteString(`\n`)
+	case r == '\r':
+		b.WriteString(`\r`)
+	case r == '\t':
+		b.WriteString(`\t`)
+	case r < 0x20 || r == 0x7f || (r > 0x7f && !unicode.IsPrint(r)):
+		t.writeCodePoint(b, r)
+	case r > 0xffff:
+		t.writeCodePoint(b, r)
+	default:
+		b.WriteRune(r)
+	}
+}
+
+func (t *translator) writeCodePoint(b *strings.Builder, r rune) {
+	hex := strconv.FormatInt(int64(r), 16)
+	switch {
+	case t.dialect == oniguruma:
+		b.WriteString(`\x{` + hex + `}`)
+	case r > 0xffff:
+		t.unicode = true
+		b.WriteString(`\u{` + hex + `}`)
+	default:
+		fmt.Fprintf(b, `\u%04x`, r)
+	}
+}
@@ -0,0 +1,183 @@
+// Package format pretty-prints source code of any go-dsl grammar. It walks
+// the concrete syntax tree from DSL.ParseTree and lays tokens out following
+// per-rule and per-token hints: line breaks, indentation and spacing around
+// tokens. Comments (tokens marked with SkipToken or defined with
+// CommentToken) are preserved, as are single blank lines between lines.
+//
+// Formatting is parse-preserving and idempotent: Format re-parses its
+// output and returns an error instead of source that parses to a different
+// tree, or that would change when formatted again.
+//
+// Example:
+//
+//	f := format.New(dsl, format.Options{Layout: format.Layout{
+//	    Rules:  map[string]format.RuleLayout{"stmt": {Line: true}, "body": {Indent: true}},
+//	    Tokens: map[string]format.TokenLayout{"COMMA": {NoSpaceBefore: true}},
+//	}})
+//	out, err := f.Format(source)
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// Layout holds the formatting hints for a grammar. Rules and tokens
+// without hints are laid out on the current line, separated by one space.
+type Layout struct {
+	Rules  map[string]RuleLayout  `yaml:"rules,omitempty" json:"rules,omitempty"`   // Hints by rule name
+	Tokens map[string]TokenLayout `yaml:"tokens,omitempty" json:"tokens,omitempty"` // Hints by token name
+	Indent string                 `yaml:"indent,omitempty" json:"indent,omitempty"` // Indentation unit (defaults to two spaces)
+}
+
+// RuleLayout describes how the nodes of a rule are laid out.
+type RuleLayout struct {
+	// Line starts every node of the rule on a new line and ends the line
+	// after it, as for statements.
+	Line bool `yaml:"line,omitempty" json:"line,omitempty"`
+	// Indent indents the lines that start inside the node by one level,
+	// as for the statements of a block.
+	Indent bool `yaml:"indent,omitempty" json:"indent,omitempty"`
+}
+
+// TokenLayout describes the spacing around a token.
+type TokenLayout struct {
+	NoSpaceBefore bool `yaml:"no_space_before,omitempty" json:"no_space_before,omitempty"` // Attach to the previous token, as for "," and ")"
+	NoSpaceAfter  bool `yaml:"no_space_after,omitempty" json:"no_space_after,omitempty"`   // Attach the next token, as for "("
+	LineBefore    bool `yaml:"line_before,omitempty" json:"line_before,omitempty"`         // Start a new line before the token
+	LineAfter     bool `yaml:"line_after,omitempty" json:"line_after,omitempty"`           // End the line after the token, as for ";"
+}
+
+// Options controls a Formatter.
+type Options struct {
+	Layout Layout
+
+	// PerLine formats every non-empty line as a separate statement, like
+	// DSL.ParseMultiline. Lines starting with # or // are comments and are
+	// kept as they are. Line break hints are ignored in this mode.
+	PerLine bool
+}
+
+// Formatter formats source code of one DSL.
+type Formatter struct {
+	dsl  *dslbuilder.DSL
+	opts Options
+}
+
+// New creates a formatter for a DSL.
+func New(dsl *dslbuilder.DSL, opts Options) *Formatter {
+	if opts.Layout.Indent == "" {
+		opts.Layout.Indent = "  "
+	}
+	return &Formatter{dsl: dsl, opts: opts}
+}
+
+// NewFromConfig creates a formatter from a declarative configuration,
+// without registering any actions.
+func NewFromConfig(config dslbuilder.DSLConfig, opts Options) (*Formatter, error) {
+	dsl, err := dslbuilder.LoadFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return New(dsl, opts), nil
+}
+
+// Format returns the canonical form of source. It returns the parse error
+// if source does not parse, and an error if the layout hints would produce
+// source that parses differently, such as two tokens glued together.
+func (f *Formatter) Format(source string) (string, error) {
+	if f.opts.PerLine {
+		return f.formatLines(source)
+	}
+	return f.formatStatement(source, true)
+}
+
+// Tree formats a parsed tree without verifying the result.
+// The tree must have been parsed successfully.
+func (f *Formatter) Tree(tree *dslbuilder.Tree) string {
+	p := newPrinter(f, tree, !f.opts.PerLine)
+	return p.print()
+}
+
+// formatStatement formats source parsed with the start rule and checks
+// that the result is parse-preserving and stable.
+func (f *Formatter) formatStatement(source string, lines bool) (string, error) {
+	tree, err := f.dsl.ParseTree(source)
+	if err != nil {
+		return "", err
+	}
+	out := newPrinter(f, tree, lines).print()
+
+	check, err := f.dsl.ParseTree(out)
+	if err != nil {
+		return "", fmt.Errorf("formatted source does not parse (check the layout hints): %w", err)
+	}
+	if f.shape(check) != f.shape(tree) {
+		return "", fmt.Errorf("formatted source parses differently (check the layout hints)")
+	}
+	if again := newPrinter(f, check, lines).print(); again != out {
+		return "", fmt.Errorf("formatting is not stable for this input")
+	}
+	return out, nil
+}
+
+// formatLines formats a document with one statement per line.
+func (f *Formatter) formatLines(source string) (string, error) {
+	var out []string
+	blank := false
+	for i, line := range strings.Split(source, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			blank = len(out) > 0
+			continue
+		case strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//"):
+		default:
+			formatted, err := f.formatStatement(trimmed, false)
+			if err != nil {
+				return "", fmt.Errorf("line %d: %w", i+1, err)
+			}
+			trimmed = strings.TrimSuffix(formatted, "\n")
+		}
+		if blank {
+			out = append(out, "")
+			blank = false
+		}
+		out = append(out, trimmed)
+	}
+	if len(out) == 0 {
+		return "", nil
+	}
+	return strings.Join(out, "\n") + "\n", nil
+}
+
+// shape describes a tree independently of layout, for comparing the trees
+// of the input and the output.
+func (f *Formatter) shape(tree *dslbuilder.Tree) string {
+	var b strings.Builder
+	if tree.Root != nil {
+		tree.Root.Walk(func(n *dslbuilder.Node) bool {
+			if n.IsToken() {
+				fmt.Fprintf(&b, "%s:%q ", n.Token.TokenType, f.text(*n.Token))
+			} else {
+				fmt.Fprintf(&b, "%s[%d]/%d ", n.Rule, n.Alternative, len(n.Children))
+			}
+			return true
+		})
+	}
+	for _, t := range tree.Trivia {
+		fmt.Fprintf(&b, "%s:%q ", t.TokenType, t.Value)
+	}
+	return b.String()
+}
+
+// text returns the canonical text of a token: keywords are written as
+// defined, other tokens as they appear in the source.
+func (f *Formatter) text(token dslbuilder.TokenMatch) string {
+	if info, ok := f.dsl.Grammar().Token(token.TokenType); ok && info.IsKeyword() {
+		return info.Keyword
+	}
+	return token.Value
+}
@@ -0,0 +1,169 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBlocks returns a small language with statements, blocks, calls and
+// comments.
+func newBlocks(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("blocks")
+	require.NoError(t, dsl.KeywordToken("IF", "if"))
+	require.NoError(t, dsl.KeywordToken("SET", "set"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("LBRACE", "\\{"))
+	require.NoError(t, dsl.Token("RBRACE", "\\}"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+	require.NoError(t, dsl.Token("LPAREN", "\\("))
+	require.NoError(t, dsl.Token("RPAREN", "\\)"))
+	require.NoError(t, dsl.Token("COMMA", ","))
+	require.NoError(t, dsl.CommentToken("COMMENT", "#[^\n]*"))
+
+	dsl.Rule("program", []string{"stmts"}, "")
+	dsl.Rule("stmts", []string{"stmt", "stmts"}, "")
+	dsl.Rule("stmts", []string{}, "")
+	dsl.Rule("stmt", []string{"IF", "ID", "block"}, "")
+	dsl.Rule("stmt", []string{"SET", "ID", "ASSIGN", "value"}, "")
+	dsl.Rule("stmt", []string{"ID", "LPAREN", "args", "RPAREN"}, "")
+	dsl.Rule("args", []string{"value", "COMMA", "args"}, "")
+	dsl.Rule("args", []string{"value"}, "")
+	dsl.Rule("value", []string{"NUMBER"}, "")
+	dsl.Rule("value", []string{"ID"}, "")
+	dsl.Rule("block", []string{"LBRACE", "body", "RBRACE"}, "")
+	dsl.Rule("body", []string{"stmts"}, "")
+	return dsl
+}
+
+var blocksLayout = Layout{
+	Rules: map[string]RuleLayout{
+		"stmt": {Line: true},
+		"body": {Indent: true},
+	},
+	Tokens: map[string]TokenLayout{
+		"COMMA":  {NoSpaceBefore: true},
+		"LPAREN": {NoSpaceBefore: true, NoSpaceAfter: true},
+		"RPAREN": {NoSpaceBefore: true},
+	},
+}
+
+func TestFormat(t *testing.T) {
+	f := New(newBlocks(t), Options{Layout: blocksLayout})
+
+	out, err := f.Format("IF x {set a=1\n  print ( a ,b,2 ) if y { } } SET b = 2")
+	require.NoError(t, err)
+	assert.Equal(t, "if x {\n  set a = 1\n  print(a, b, 2)\n  if y { }\n}\nset b = 2\n", out)
+}
+
+func TestFormatPreservesComments(t *testing.T) {
+	f := New(newBlocks(t), Options{Layout: blocksLayout})
+
+	src := "# header\nif x { set a = 1 # one\n\n\n\n print(a)\n# last\n}\n"
+	out, err := f.Format(src)
+	require.NoError(t, err)
+	assert.Equal(t, "# header\nif x {\n  set a = 1 # one\n\n  print(a)\n  # last\n}\n", out)
+}
+
+func TestFormatIsIdempotent(t *testing.T) {
+	f := New(newBlocks(t), Options{Layout: blocksLayout})
+	inputs := []string{
+		"set a = 1",
+		"if x {if y {set a=1}} # done",
+		"f(1,2) # c\n\n\n# d\ng(3)",
+		"",
+	}
+	for _, in := range inputs {
+		once, err := f.Format(in)
+		require.NoError(t, err, in)
+		twice, err := f.Format(once)
+		require.NoError(t, err, in)
+		assert.Equal(t, once, twice, in)
+	}
+}
+
+func TestFormatDefaultLayout(t *testing.T) {
+	f := New(newBlocks(t), Options{})
+
+	out, err := f.Format("set   a=1\nset b =2")
+	require.NoError(t, err)
+	assert.Equal(t, "set a = 1 set b = 2\n", out)
+}
+
+func TestFormatSeparatesWords(t *testing.T) {
+	f := New(newBlocks(t), Options{Layout: Layout{Tokens: map[string]TokenLayout{
+		"SET": {NoSpaceAfter: true},
+	}}})
+
+	// Without a space "set" and "a" would become one identifier
+	out, err := f.Format("set a = 1")
+	require.NoError(t, err)
+	assert.Equal(t, "set a = 1\n", out)
+}
+
+func TestFormatRejectsChangedParse(t *testing.T) {
+	dsl := dslbuilder.New("eq")
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+	require.NoError(t, dsl.Token("EQ", "=="))
+	dsl.Rule("stmt", []string{"ID", "ASSIGN", "ASSIGN", "ID"}, "")
+
+	f := New(dsl, Options{Layout: Layout{Tokens: map[string]TokenLayout{"ASSIGN": {NoSpaceAfter: true}}}})
+	_, err := f.Format("a = = b")
+	assert.Error(t, err)
+}
+
+func TestFormatParseError(t *testing.T) {
+	f := New(newBlocks(t), Options{Layout: blocksLayout})
+	_, err := f.Format("set a =")
+	require.Error(t, err)
+	assert.True(t, dslbuilder.IsParseError(err))
+}
+
+func TestFormatKeywordCase(t *testing.T) {
+	f := New(newBlocks(t), Options{Layout: blocksLayout})
+	out, err := f.Format("SET a = 1")
+	require.NoError(t, err)
+	assert.Equal(t, "set a = 1\n", out)
+}
+
+func TestFormatPerLine(t *testing.T) {
+	dsl := dslbuilder.New("lines")
+	require.NoError(t, dsl.KeywordToken("GET", "get"))
+	require.NoError(t, dsl.Token("URL", "/[a-z/]*"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("TIMES", "\\*"))
+	dsl.Rule("request", []string{"GET", "URL", "TIMES", "NUMBER"}, "")
+	dsl.Rule("request", []string{"GET", "URL"}, "")
+
+	f := New(dsl, Options{PerLine: true, Layout: Layout{Tokens: map[string]TokenLayout{
+		"TIMES": {NoSpaceAfter: true},
+	}}})
+	out, err := f.Format("\n\n  GET   /users\n# list\n\n\n\nget /items  * 3\n\n")
+	require.NoError(t, err)
+	assert.Equal(t, "get /users\n# list\n\nget /items *3\n", out)
+
+	_, err = f.Format("get /a\nget")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestNewFromConfig(t *testing.T) {
+	f, err := NewFromConfig(dslbuilder.DSLConfig{
+		Name:       "sum",
+		Tokens:     map[string]string{"NUMBER": "[0-9]+", "PLUS": "\\+", "COMMENT": "//[^\\n]*"},
+		SkipTokens: []string{"COMMENT"},
+		Rules: []dslbuilder.RuleConfig{
+			{Name: "expr", Pattern: []string{"NUMBER", "PLUS", "expr"}},
+			{Name: "expr", Pattern: []string{"NUMBER"}},
+		},
+	}, Options{})
+	require.NoError(t, err)
+
+	out, err := f.Format("1+2 // three\n+3")
+	require.NoError(t, err)
+	assert.Equal(t, "1 + 2 // three\n+ 3\n", out)
+}
@@ -0,0 +1,184 @@
+package format
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// printer lays out one tree. Line breaks are requested by rules, tokens and
+// comments and written lazily before the next token, so consecutive
+// requests collapse into one break at the indentation of the next token.
+type printer struct {
+	f      *Formatter
+	root   *dslbuilder.Node
+	source string
+	tokens []dslbuilder.TokenMatch
+	trivia []dslbuilder.TokenMatch
+	lines  bool // Honor line break hints
+
+	out         strings.Builder
+	level       int
+	newline     bool // A line break is pending
+	noSpace     bool // The previous token asked for no space after it
+	lastEnd     int  // Source offset after the last written token or comment
+	wroteAny    bool
+	lastWritten string
+}
+
+func newPrinter(f *Formatter, tree *dslbuilder.Tree, lines bool) *printer {
+	trivia := append([]dslbuilder.TokenMatch{}, tree.Trivia...)
+	sort.Slice(trivia, func(i, j int) bool { return trivia[i].Start < trivia[j].Start })
+	return &printer{f: f, root: tree.Root, source: tree.Source, tokens: tree.Tokens, trivia: trivia, lines: lines}
+}
+
+// print returns the formatted source, ending with a newline unless empty.
+func (p *printer) print() string {
+	if p.root != nil {
+		p.node(p.root)
+	}
+	p.flushTrivia(len(p.source))
+	if !p.wroteAny {
+		return ""
+	}
+	if p.lines {
+		p.out.WriteString("\n")
+	}
+	return p.out.String()
+}
+
+func (p *printer) node(n *dslbuilder.Node) {
+	if n.IsToken() {
+		p.token(*n.Token)
+		return
+	}
+	if n.Start == n.End {
+		// Empty rules have no tokens to lay out
+		return
+	}
+
+	layout := p.f.opts.Layout.Rules[n.Rule]
+	if layout.Indent {
+		p.level++
+	}
+	if layout.Line {
+		p.breakLine()
+	}
+	for _, child := range n.Children {
+		p.node(child)
+	}
+	if layout.Line {
+		p.breakLine()
+	}
+	if layout.Indent {
+		// Comments before the token that closes the node belong inside it
+		p.flushTrivia(p.nextTokenStart(n.End))
+		p.level--
+	}
+}
+
+// nextTokenStart returns the source offset of the first token at or after
+// offset, or the end of the source.
+func (p *printer) nextTokenStart(offset int) int {
+	i := sort.Search(len(p.tokens), func(i int) bool { return p.tokens[i].Start >= offset })
+	if i < len(p.tokens) {
+		return p.tokens[i].Start
+	}
+	return len(p.source)
+}
+
+// breakLine requests a line break before the next token.
+func (p *printer) breakLine() {
+	if p.lines {
+		p.newline = true
+	}
+}
+
+func (p *printer) token(t dslbuilder.TokenMatch) {
+	p.flushTrivia(t.Start)
+
+	layout := p.f.opts.Layout.Tokens[t.TokenType]
+	if layout.LineBefore {
+		p.breakLine()
+	}
+	text := p.f.text(t)
+	p.separate(t.Start, text, layout.NoSpaceBefore || p.noSpace)
+	p.write(text, t.End)
+
+	p.noSpace = layout.NoSpaceAfter
+	if layout.LineAfter {
+		p.breakLine()
+	}
+}
+
+// flushTrivia writes the comments that come before the source offset.
+// A comment that followed a token on the same line stays on that line;
+// other comments get their own line. A comment that ended its line in the
+// source still ends it, since line comments extend to the end of the line.
+func (p *printer) flushTrivia(before int) {
+	for len(p.trivia) > 0 && p.trivia[0].Start < before {
+		c := p.trivia[0]
+		p.trivia = p.trivia[1:]
+
+		// A pending line break waits until after a comment on the same line
+		pending := false
+		if p.wroteAny && strings.Contains(p.source[p.lastEnd:c.Start], "\n") {
+			p.newline = true
+		} else {
+			pending, p.newline = p.newline, false
+		}
+		p.separate(c.Start, c.Value, false)
+		p.write(c.Value, c.End)
+		p.noSpace = false
+		p.newline = pending
+
+		next := len(p.source)
+		if len(p.trivia) > 0 {
+			next = p.trivia[0].Start
+		} else if before < next {
+			next = before
+		}
+		if strings.Contains(p.source[c.End:next], "\n") || next == len(p.source) {
+			p.newline = true
+		}
+	}
+}
+
+// separate writes the line break or space that goes before text, which
+// starts at the source offset start.
+func (p *printer) separate(start int, text string, noSpace bool) {
+	switch {
+	case !p.wroteAny:
+	case p.newline:
+		p.out.WriteString("\n")
+		if strings.Count(p.source[p.lastEnd:start], "\n") > 1 {
+			// Keep one blank line where the source had some
+			p.out.WriteString("\n")
+		}
+		p.out.WriteString(strings.Repeat(p.f.opts.Layout.Indent, p.level))
+	case !noSpace || glued(p.lastWritten, text):
+		p.out.WriteString(" ")
+	}
+	p.newline = false
+}
+
+func (p *printer) write(text string, end int) {
+	p.out.WriteString(text)
+	p.lastWritten = text
+	p.lastEnd = end
+	p.wroteAny = true
+}
+
+// glued reports whether two texts would run together into one word if
+// written without a space.
+func glued(before, after string) bool {
+	if before == "" || after == "" {
+		return false
+	}
+	return isWordByte(before[len(before)-1]) && isWordByte(after[0])
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
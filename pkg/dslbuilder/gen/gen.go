@@ -0,0 +1,308 @@
+// Package gen generates random sentences from a go-dsl grammar.
+// It is meant for testing DSLs without hand-writing example strings:
+// sentences are derived from the rules with a depth limit, token values are
+// sampled from the token regular expressions, and alternatives can be
+// weighted. The gentest package plugs the generator into Go testing and
+// native fuzzing.
+//
+// Example:
+//
+//	g := gen.New(dsl.Grammar(), gen.Options{Seed: 42, MaxDepth: 6})
+//	for i := 0; i < 10; i++ {
+//	    sentence, _ := g.Generate()
+//	    fmt.Println(sentence)
+//	}
+package gen
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// Options controls sentence generation.
+type Options struct {
+	Seed      int64                // Random seed; the same seed yields the same sentences
+	MaxDepth  int                  // Rule nesting depth before choosing shortest derivations (default 8)
+	MaxRepeat int                  // Extra repetitions for regex *, + and {n,} (default 3)
+	Weights   map[string][]float64 // Per-rule alternative weights; missing entries weigh 1, 0 disables
+	Separator string               // Text placed between tokens (default " ")
+}
+
+// Generator produces sentences for one grammar.
+// A Generator is not safe for concurrent use.
+type Generator struct {
+	grammar  *dslbuilder.Grammar
+	opts     Options
+	rand     *rand.Rand
+	order    []string // Rule names in definition order
+	rules    map[string]dslbuilder.RuleInfo
+	tokens   map[string]dslbuilder.TokenInfo
+	compiled map[string]*regexp.Regexp
+	cost     map[string]int // Minimal number of tokens a rule can derive
+	best     map[string]int // Index of the alternative reaching that cost
+}
+
+// infinite marks rules that cannot derive a finite sentence.
+const infinite = math.MaxInt32
+
+// New creates a generator for a grammar.
+func New(grammar *dslbuilder.Grammar, opts Options) *Generator {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 8
+	}
+	if opts.MaxRepeat <= 0 {
+		opts.MaxRepeat = 3
+	}
+	if opts.Separator == "" {
+		opts.Separator = " "
+	}
+
+	g := &Generator{
+		grammar:  grammar,
+		opts:     opts,
+		rand:     rand.New(rand.NewSource(opts.Seed)),
+		rules:    make(map[string]dslbuilder.RuleInfo),
+		tokens:   make(map[string]dslbuilder.TokenInfo),
+		compiled: make(map[string]*regexp.Regexp),
+		cost:     make(map[string]int),
+		best:     make(map[string]int),
+	}
+	for _, token := range grammar.Tokens() {
+		g.tokens[token.Name] = token
+		if re, err := regexp.Compile(token.Pattern); err == nil {
+			g.compiled[token.Name] = re
+		}
+	}
+	for _, rule := range grammar.Rules() {
+		// Lookaheads and cuts match no input; sentences may violate lookaheads
+		for i, alt := range rule.Alternatives {
+			rule.Alternatives[i].Sequence = alt.Symbols()
+		}
+		g.order = append(g.order, rule.Name)
+		g.rules[rule.Name] = rule
+		g.cost[rule.Name] = infinite
+	}
+	g.computeCosts()
+	return g
+}
+
+// computeCosts runs a fixpoint over all rules until no minimal cost improves.
+// The resulting best alternatives never form a cycle, so following them
+// always terminates. Rules are visited in definition order to keep the
+// chosen alternatives deterministic.
+func (g *Generator) computeCosts() {
+	for changed := true; changed; {
+		changed = false
+		for _, name := range g.order {
+			rule := g.rules[name]
+			for i, alt := range rule.Alternatives {
+				if c := g.sequenceCost(alt.Sequence); c < g.cost[name] {
+					g.cost[name] = c
+					g.best[name] = i
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+func (g *Generator) symbolCost(symbol string) int {
+	if _, ok := g.tokens[symbol]; ok {
+		return 1
+	}
+	if c, ok := g.cost[symbol]; ok {
+		return c
+	}
+	return infinite // Undefined symbol
+}
+
+func (g *Generator) sequenceCost(sequence []string) int {
+	total := 0
+	for _, symbol := range sequence {
+		c := g.symbolCost(symbol)
+		if c == infinite {
+			return infinite
+		}
+		total += c
+	}
+	return total
+}
+
+// Generate returns a random sentence derived from the start rule.
+func (g *Generator) Generate() (string, error) {
+	return g.GenerateRule(g.grammar.StartRule())
+}
+
+// GenerateRule returns a random sentence derived from the given rule.
+func (g *Generator) GenerateRule(rule string) (string, error) {
+	if err := g.derivable(rule); err != nil {
+		return "", err
+	}
+	words := []string{}
+	g.expand(rule, 0, &words)
+	return strings.Join(words, g.opts.Separator), nil
+}
+
+// Shortest returns the shortest sentence derived from one alternative of
+// a rule, using the smallest sample of every token. The result is
+// deterministic, which makes it suitable for documentation examples.
+// It returns false if the alternative cannot derive a finite sentence.
+func (g *Generator) Shortest(rule string, alt int) (string, bool) {
+	info, ok := g.rules[rule]
+	if !ok || alt < 0 || alt >= len(info.Alternatives) {
+		return "", false
+	}
+	sequence := info.Alternatives[alt].Sequence
+	if g.sequenceCost(sequence) == infinite {
+		return "", false
+	}
+	words := []string{}
+	for _, symbol := range sequence {
+		g.expandShortest(symbol, &words)
+	}
+	return strings.Join(words, g.opts.Separator), true
+}
+
+// SampleToken returns a random value matched by the named token.
+func (g *Generator) SampleToken(name string) (string, error) {
+	if _, ok := g.tokens[name]; !ok {
+		return "", fmt.Errorf("token %s not found", name)
+	}
+	return g.sampleToken(name, sampler{rand: g.rand, maxRepeat: g.opts.MaxRepeat}), nil
+}
+
+// ShortestToken returns the smallest value matched by the named token,
+// the one Shortest uses.
+func (g *Generator) ShortestToken(name string) (string, error) {
+	if _, ok := g.tokens[name]; !ok {
+		return "", fmt.Errorf("token %s not found", name)
+	}
+	return g.sampleToken(name, sampler{}), nil
+}
+
+func (g *Generator) derivable(rule string) error {
+	if _, ok := g.rules[rule]; !ok {
+		return fmt.Errorf("rule %s not found", rule)
+	}
+	if g.cost[rule] == infinite {
+		return fmt.Errorf("rule %s cannot derive a finite sentence", rule)
+	}
+	return nil
+}
+
+// expand appends a random derivation of symbol to words.
+// Past MaxDepth only the shortest derivation is used, which guarantees
+// termination for recursive grammars.
+func (g *Generator) expand(symbol string, depth int, words *[]string) {
+	if _, ok := g.tokens[symbol]; ok {
+		*words = append(*words, g.sampleToken(symbol, sampler{rand: g.rand, maxRepeat: g.opts.MaxRepeat}))
+		return
+	}
+	if depth >= g.opts.MaxDepth {
+		g.expandShortest(symbol, words)
+		return
+	}
+	rule := g.rules[symbol]
+	for _, s := range rule.Alternatives[g.chooseAlternative(rule)].Sequence {
+		g.expand(s, depth+1, words)
+	}
+}
+
+func (g *Generator) expandShortest(symbol string, words *[]string) {
+	if _, ok := g.tokens[symbol]; ok {
+		*words = append(*words, g.sampleToken(symbol, sampler{}))
+		return
+	}
+	for _, s := range g.rules[symbol].Alternatives[g.best[symbol]].Sequence {
+		g.expandShortest(s, words)
+	}
+}
+
+// chooseAlternative picks a weighted random alternative among those that
+// can derive a finite sentence.
+func (g *Generator) chooseAlternative(rule dslbuilder.RuleInfo) int {
+	weights := g.opts.Weights[rule.Name]
+	total := 0.0
+	candidates := make([]float64, len(rule.Alternatives))
+	for i, alt := range rule.Alternatives {
+		if g.sequenceCost(alt.Sequence) == infinite {
+			continue
+		}
+		w := 1.0
+		if i < len(weights) {
+			w = weights[i]
+		}
+		if w > 0 {
+			candidates[i] = w
+			total += w
+		}
+	}
+	if total == 0 {
+		return g.best[rule.Name]
+	}
+
+	pick := g.rand.Float64() * total
+	for i, w := range candidates {
+		if w == 0 {
+			continue
+		}
+		if pick < w {
+			return i
+		}
+		pick -= w
+	}
+	return g.best[rule.Name]
+}
+
+// sampleToken returns a value for the named token. Keywords use their
+// literal text. Regex samples are retried a few times when another token
+// would win the match, so generated sentences tokenize as intended.
+func (g *Generator) sampleToken(name string, s sampler) string {
+	token := g.tokens[name]
+	if token.IsKeyword() {
+		return token.Keyword
+	}
+
+	value := ""
+	for attempt := 0; attempt < 10; attempt++ {
+		value = s.sample(token.Pattern)
+		if value != "" && !g.shadowed(token, value) {
+			return value
+		}
+		if s.rand == nil {
+			break
+		}
+	}
+	if value == "" {
+		return token.Name
+	}
+	return value
+}
+
+// shadowed reports whether the lexer could match value as a different token:
+// a higher priority token matching a prefix, or a same priority token
+// matching the whole value.
+func (g *Generator) shadowed(token dslbuilder.TokenInfo, value string) bool {
+	for name, re := range g.compiled {
+		if name == token.Name {
+			continue
+		}
+		loc := re.FindStringIndex(value)
+		if loc == nil || loc[0] != 0 || loc[1] == 0 {
+			continue
+		}
+		other := g.tokens[name]
+		if other.Priority > token.Priority {
+			return true
+		}
+		if other.Priority == token.Priority && loc[1] == len(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package gen
+
+import (
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCalculator(t testing.TB) *dslbuilder.DSL {
+	dsl := dslbuilder.New("calculator")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("TIMES", "\\*"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+	require.NoError(t, dsl.Token("LPAREN", "\\("))
+	require.NoError(t, dsl.Token("RPAREN", "\\)"))
+
+	dsl.Rule("stmt", []string{"LET", "ID", "ASSIGN", "expr"}, "let")
+	dsl.Rule("stmt", []string{"expr"}, "pass")
+	dsl.Rule("expr", []string{"expr", "PLUS", "term"}, "add")
+	dsl.Rule("expr", []string{"term"}, "pass")
+	dsl.Rule("term", []string{"term", "TIMES", "factor"}, "mul")
+	dsl.Rule("term", []string{"factor"}, "pass")
+	dsl.Rule("factor", []string{"NUMBER"}, "number")
+	dsl.Rule("factor", []string{"LPAREN", "expr", "RPAREN"}, "paren")
+
+	dsl.Action("let", func(args []interface{}) (interface{}, error) { return args[3], nil })
+	dsl.Action("pass", func(args []interface{}) (interface{}, error) { return args[0], nil })
+	dsl.Action("add", func(args []interface{}) (interface{}, error) {
+		return args[0].(int) + args[2].(int), nil
+	})
+	dsl.Action("mul", func(args []interface{}) (interface{}, error) {
+		return args[0].(int) * args[2].(int), nil
+	})
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		return strconv.Atoi(args[0].(string))
+	})
+	dsl.Action("paren", func(args []interface{}) (interface{}, error) { return args[1], nil })
+	return dsl
+}
+
+func TestGenerateIsDeterministicPerSeed(t *testing.T) {
+	dsl := newCalculator(t)
+
+	a := New(dsl.Grammar(), Options{Seed: 7})
+	b := New(dsl.Grammar(), Options{Seed: 7})
+	for i := 0; i < 20; i++ {
+		sa, err := a.Generate()
+		require.NoError(t, err)
+		sb, err := b.Generate()
+		require.NoError(t, err)
+		assert.Equal(t, sa, sb)
+	}
+}
+
+func TestGeneratedSentencesParse(t *testing.T) {
+	dsl := newCalculator(t)
+	g := New(dsl.Grammar(), Options{Seed: 1, MaxDepth: 6})
+
+	for i := 0; i < 200; i++ {
+		sentence, err := g.Generate()
+		require.NoError(t, err)
+		_, err = dsl.Parse(sentence)
+		assert.NoError(t, err, sentence)
+	}
+}
+
+func TestMaxDepthBoundsSentences(t *testing.T) {
+	dsl := newCalculator(t)
+	g := New(dsl.Grammar(), Options{Seed: 3, MaxDepth: 1})
+
+	for i := 0; i < 50; i++ {
+		sentence, err := g.GenerateRule("factor")
+		require.NoError(t, err)
+		// factor → NUMBER | ( expr ), and past the depth limit expr
+		// collapses to its shortest derivation
+		assert.Regexp(t, `^([0-9]+|\( 1 \))$`, sentence)
+	}
+}
+
+func TestWeights(t *testing.T) {
+	dsl := newCalculator(t)
+	g := New(dsl.Grammar(), Options{
+		Seed:    5,
+		Weights: map[string][]float64{"stmt": {1, 0}},
+	})
+
+	for i := 0; i < 20; i++ {
+		sentence, err := g.Generate()
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(sentence, "let "), sentence)
+	}
+}
+
+func TestShortest(t *testing.T) {
+	dsl := newCalculator(t)
+	g := New(dsl.Grammar(), Options{})
+
+	sentence, ok := g.Shortest("stmt", 0)
+	require.True(t, ok)
+	assert.Equal(t, "let a = 1", sentence)
+
+	sentence, ok = g.Shortest("factor", 1)
+	require.True(t, ok)
+	assert.Equal(t, "( 1 )", sentence)
+
+	_, ok = g.Shortest("stmt", 5)
+	assert.False(t, ok)
+	_, ok = g.Shortest("missing", 0)
+	assert.False(t, ok)
+
+	value, err := g.ShortestToken("ID")
+	require.NoError(t, err)
+	assert.Equal(t, "a", value)
+	_, err = g.ShortestToken("MISSING")
+	assert.Error(t, err)
+}
+
+func TestGenerateErrors(t *testing.T) {
+	dsl := dslbuilder.New("loop")
+	require.NoError(t, dsl.Token("A", "a"))
+	dsl.Rule("start", []string{"start", "A"}, "")
+
+	g := New(dsl.Grammar(), Options{})
+	_, err := g.Generate()
+	assert.EqualError(t, err, "rule start cannot derive a finite sentence")
+
+	_, err = g.GenerateRule("missing")
+	assert.EqualError(t, err, "rule missing not found")
+
+	_, err = g.SampleToken("MISSING")
+	assert.Error(t, err)
+}
+
+func TestSampleMatchesPattern(t *testing.T) {
+	patterns := []string{
+		"[0-9]+",
+		"[a-zA-Z_][a-zA-Z0-9_]*",
+		`"[^"]*"`,
+		"[0-9]+\\.[0-9]+",
+		"==|!=|<=|>=",
+		"[a-f0-9]{2,4}",
+		"#[^\\n]*",
+	}
+	s := sampler{rand: rand.New(rand.NewSource(1)), maxRepeat: 3}
+	for _, pattern := range patterns {
+		re := regexp.MustCompile("^(?:" + pattern + ")$")
+		for i := 0; i < 50; i++ {
+			value := s.sample(pattern)
+			assert.Regexp(t, re, value, pattern)
+		}
+	}
+}
+
+func TestMinimalSample(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"[0-9]+", "1"},
+		{"[a-zA-Z_][a-zA-Z0-9_]*", "a"},
+		{`"[^"]*"`, `""`},
+		{"\\+", "+"},
+		{"==|!=", "=="},
+		{"[0-9]+\\.[0-9]+", "1.1"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, sampler{}.sample(tt.pattern), tt.pattern)
+	}
+}
+
+func TestSampleAvoidsShadowedTokens(t *testing.T) {
+	dsl := dslbuilder.New("keywords")
+	require.NoError(t, dsl.KeywordToken("IF", "if"))
+	require.NoError(t, dsl.Token("ID", "[a-z]{1,2}"))
+	dsl.Rule("start", []string{"ID"}, "")
+
+	g := New(dsl.Grammar(), Options{Seed: 11})
+	for i := 0; i < 100; i++ {
+		value, err := g.SampleToken("ID")
+		require.NoError(t, err)
+		assert.NotEqual(t, "if", value)
+	}
+}
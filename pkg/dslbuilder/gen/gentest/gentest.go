@@ -0,0 +1,137 @@
+// Package gentest plugs the gen sentence generator into Go testing and
+// native fuzzing. It is kept apart from gen so tools that generate
+// sentences do not link the testing package.
+//
+// Example:
+//
+//	func TestCalculator(t *testing.T) {
+//	    gentest.Check(t, newCalculator(), gentest.FuzzOptions{RequireAccept: true})
+//	}
+package gentest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/gen"
+)
+
+// FuzzOptions controls Fuzz and Check.
+type FuzzOptions struct {
+	gen.Options
+
+	// Sentences is the number of generated sentences used as seed corpus
+	// (Fuzz) or checked (Check). Defaults to 100.
+	Sentences int
+
+	// RequireAccept fails when a generated sentence is rejected by Parse.
+	// Leave it off for grammars whose ordered choice or token priorities
+	// reject some sentences the rules can derive.
+	RequireAccept bool
+
+	// Format is an optional pretty-printer. When set, every accepted input
+	// must still parse after formatting and produce the same output.
+	Format func(code string) (string, error)
+}
+
+// Fuzz seeds a native fuzz test with generated sentences and fuzzes Parse.
+// Parse must never panic; with Format set, accepted inputs must round-trip.
+//
+// Example:
+//
+//	func FuzzCalculator(f *testing.F) {
+//	    gentest.Fuzz(f, newCalculator(), gentest.FuzzOptions{})
+//	}
+func Fuzz(f *testing.F, dsl *dslbuilder.DSL, opts FuzzOptions) {
+	f.Helper()
+	for _, sentence := range sentences(dsl, opts) {
+		f.Add(sentence)
+	}
+	f.Fuzz(func(t *testing.T, code string) {
+		if err := checkInput(dsl, code, opts, false); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// Check generates sentences and verifies each one against Parse inside a
+// regular test: no panics, acceptance when RequireAccept is set, and
+// round-tripping when Format is set.
+func Check(t testing.TB, dsl *dslbuilder.DSL, opts FuzzOptions) {
+	t.Helper()
+	for _, sentence := range sentences(dsl, opts) {
+		if err := checkInput(dsl, sentence, opts, opts.RequireAccept); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// sentences generates the corpus for Fuzz and Check.
+func sentences(dsl *dslbuilder.DSL, opts FuzzOptions) []string {
+	count := opts.Sentences
+	if count <= 0 {
+		count = 100
+	}
+	g := gen.New(dsl.Grammar(), opts.Options)
+	result := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		sentence, err := g.Generate()
+		if err != nil {
+			break
+		}
+		result = append(result, sentence)
+	}
+	return result
+}
+
+// checkInput parses code, converting panics into errors.
+func checkInput(dsl *dslbuilder.DSL, code string, opts FuzzOptions, requireAccept bool) error {
+	result, err := safeParse(dsl, code)
+	if perr, ok := err.(panicError); ok {
+		return perr
+	}
+	if err != nil {
+		if requireAccept {
+			return fmt.Errorf("generated sentence rejected: %q: %v", code, err)
+		}
+		return nil
+	}
+	if opts.Format == nil {
+		return nil
+	}
+
+	formatted, err := opts.Format(code)
+	if err != nil {
+		return fmt.Errorf("format failed for %q: %v", code, err)
+	}
+	again, err := safeParse(dsl, formatted)
+	if err != nil {
+		return fmt.Errorf("formatted input does not parse: %q -> %q: %v", code, formatted, err)
+	}
+	if !reflect.DeepEqual(result.GetOutput(), again.GetOutput()) {
+		return fmt.Errorf("formatting changed the result: %q -> %q: %v != %v",
+			code, formatted, result.GetOutput(), again.GetOutput())
+	}
+	return nil
+}
+
+// panicError reports a panic raised while parsing.
+type panicError struct {
+	code  string
+	value interface{}
+}
+
+func (e panicError) Error() string {
+	return fmt.Sprintf("parse panicked on %q: %v", e.code, e.value)
+}
+
+func safeParse(dsl *dslbuilder.DSL, code string) (result *dslbuilder.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, panicError{code: code, value: r}
+		}
+	}()
+	return dsl.Parse(code)
+}
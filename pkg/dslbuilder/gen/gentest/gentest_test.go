@@ -0,0 +1,77 @@
+package gentest
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCalculator(t testing.TB) *dslbuilder.DSL {
+	dsl := dslbuilder.New("calculator")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("TIMES", "\\*"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+	require.NoError(t, dsl.Token("LPAREN", "\\("))
+	require.NoError(t, dsl.Token("RPAREN", "\\)"))
+
+	dsl.Rule("stmt", []string{"LET", "ID", "ASSIGN", "expr"}, "let")
+	dsl.Rule("stmt", []string{"expr"}, "pass")
+	dsl.Rule("expr", []string{"expr", "PLUS", "term"}, "add")
+	dsl.Rule("expr", []string{"term"}, "pass")
+	dsl.Rule("term", []string{"term", "TIMES", "factor"}, "mul")
+	dsl.Rule("term", []string{"factor"}, "pass")
+	dsl.Rule("factor", []string{"NUMBER"}, "number")
+	dsl.Rule("factor", []string{"LPAREN", "expr", "RPAREN"}, "paren")
+
+	dsl.Action("let", func(args []interface{}) (interface{}, error) { return args[3], nil })
+	dsl.Action("pass", func(args []interface{}) (interface{}, error) { return args[0], nil })
+	dsl.Action("add", func(args []interface{}) (interface{}, error) {
+		return args[0].(int) + args[2].(int), nil
+	})
+	dsl.Action("mul", func(args []interface{}) (interface{}, error) {
+		return args[0].(int) * args[2].(int), nil
+	})
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		return strconv.Atoi(args[0].(string))
+	})
+	dsl.Action("paren", func(args []interface{}) (interface{}, error) { return args[1], nil })
+	return dsl
+}
+
+func TestCheck(t *testing.T) {
+	dsl := newCalculator(t)
+	Check(t, dsl, FuzzOptions{
+		Options:       gen.Options{Seed: 9},
+		Sentences:     50,
+		RequireAccept: true,
+		Format: func(code string) (string, error) {
+			// Collapsing spaces must not change the result
+			return strings.Join(strings.Fields(code), "  "), nil
+		},
+	})
+}
+
+func TestCheckReportsPanics(t *testing.T) {
+	dsl := dslbuilder.New("panics")
+	require.NoError(t, dsl.Token("A", "a"))
+	dsl.Rule("start", []string{"A"}, "boom")
+	dsl.Action("boom", func(args []interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	err := checkInput(dsl, "a", FuzzOptions{}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "parse panicked")
+}
+
+func FuzzCalculator(f *testing.F) {
+	Fuzz(f, newCalculator(f), FuzzOptions{Options: gen.Options{Seed: 1}, Sentences: 20})
+}
@@ -0,0 +1,122 @@
+package gen
+
+import (
+	"math/rand"
+	"regexp/syntax"
+	"strings"
+)
+
+// sampler produces strings matched by regular expressions.
+// With a nil rand it is deterministic and returns the shortest readable match;
+// otherwise it picks random runes and repetition counts.
+type sampler struct {
+	rand      *rand.Rand
+	maxRepeat int
+}
+
+// sample returns a string matched by pattern, or "" if the pattern is invalid.
+func (s sampler) sample(pattern string) string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	s.write(re.Simplify(), &b)
+	return b.String()
+}
+
+func (s sampler) write(re *syntax.Regexp, b *strings.Builder) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		b.WriteRune(s.pickRune(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		b.WriteRune(s.pickRune([]rune{'a', 'z'}))
+	case syntax.OpCapture:
+		s.write(re.Sub[0], b)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			s.write(sub, b)
+		}
+	case syntax.OpAlternate:
+		s.write(re.Sub[s.intn(len(re.Sub))], b)
+	case syntax.OpStar:
+		s.repeat(re.Sub[0], 0, -1, b)
+	case syntax.OpPlus:
+		s.repeat(re.Sub[0], 1, -1, b)
+	case syntax.OpQuest:
+		s.repeat(re.Sub[0], 0, 1, b)
+	case syntax.OpRepeat:
+		s.repeat(re.Sub[0], re.Min, re.Max, b)
+	}
+	// Empty-width assertions (\b, ^, $) contribute nothing
+}
+
+// repeat writes sub between min and max times (max < 0 means unbounded).
+// Deterministic sampling always uses the minimum.
+func (s sampler) repeat(sub *syntax.Regexp, min, max int, b *strings.Builder) {
+	count := min
+	if s.rand != nil {
+		upper := min + s.maxRepeat
+		if max >= 0 && max < upper {
+			upper = max
+		}
+		count = min + s.rand.Intn(upper-min+1)
+	}
+	for i := 0; i < count; i++ {
+		s.write(sub, b)
+	}
+}
+
+func (s sampler) intn(n int) int {
+	if s.rand == nil {
+		return 0
+	}
+	return s.rand.Intn(n)
+}
+
+// pickRune chooses a rune from a character class given as [lo, hi] pairs.
+// Printable ASCII is preferred so samples look like real input. The
+// deterministic sampler prefers a lowercase letter or digit.
+func (s sampler) pickRune(ranges []rune) rune {
+	if len(ranges) == 0 {
+		return 'x'
+	}
+
+	if s.rand == nil {
+		for _, preferred := range []rune{'a', 'x', '1', '0', 'A'} {
+			if inRanges(ranges, preferred) {
+				return preferred
+			}
+		}
+		for r := rune('!'); r <= '~'; r++ {
+			if inRanges(ranges, r) {
+				return r
+			}
+		}
+		return ranges[0]
+	}
+
+	printable := []rune{}
+	for r := rune('!'); r <= '~'; r++ {
+		if inRanges(ranges, r) {
+			printable = append(printable, r)
+		}
+	}
+	if len(printable) > 0 {
+		return printable[s.rand.Intn(len(printable))]
+	}
+	pair := s.rand.Intn(len(ranges)/2) * 2
+	lo, hi := ranges[pair], ranges[pair+1]
+	return lo + rune(s.rand.Intn(int(hi-lo)+1))
+}
+
+func inRanges(ranges []rune, r rune) bool {
+	for i := 0; i+1 < len(ranges); i += 2 {
+		if ranges[i] <= r && r <= ranges[i+1] {
+			return true
+		}
+	}
+	return false
+}
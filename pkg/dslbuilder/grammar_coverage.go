@@ -0,0 +1,99 @@
+// Package dslbuilder - Grammar coverage recording
+package dslbuilder
+
+import "sync"
+
+// Coverage records which grammar elements matched while parsing.
+// Enable it with DSL.EnableCoverage and render it with the coverage package.
+//
+// Only successful matches are counted: an alternative is hit when all of its
+// symbols matched and its action succeeded, and a token is hit when it was
+// consumed by such an alternative. Memoized results are counted once.
+type Coverage struct {
+	mu           sync.Mutex
+	rules        map[string]int   // Successful matches per rule
+	alternatives map[string][]int // Successful matches per rule alternative
+	tokens       map[string]int   // Consumed tokens per token type
+}
+
+// NewCoverage creates an empty coverage recorder.
+func NewCoverage() *Coverage {
+	c := &Coverage{}
+	c.Reset()
+	return c
+}
+
+// EnableCoverage starts recording coverage for every following Parse call
+// and returns the recorder. Calling it again keeps the existing counts.
+//
+// Example:
+//
+//	cov := dsl.EnableCoverage()
+//	dsl.Parse("1 + 2")
+//	fmt.Println(cov.AlternativeHits("expr"))
+func (d *DSL) EnableCoverage() *Coverage {
+	if d.coverage == nil {
+		d.coverage = NewCoverage()
+	}
+	return d.coverage
+}
+
+// DisableCoverage stops recording coverage and discards the counts.
+func (d *DSL) DisableCoverage() {
+	d.coverage = nil
+}
+
+// Coverage returns the active coverage recorder, or nil if coverage is off.
+func (d *DSL) Coverage() *Coverage {
+	return d.coverage
+}
+
+// Reset clears all recorded counts.
+func (c *Coverage) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules = make(map[string]int)
+	c.alternatives = make(map[string][]int)
+	c.tokens = make(map[string]int)
+}
+
+// RuleHits returns how many times a rule matched.
+func (c *Coverage) RuleHits(rule string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rules[rule]
+}
+
+// AlternativeHits returns how many times each alternative of a rule matched,
+// indexed like the rule alternatives. Trailing alternatives that never
+// matched may be missing from the slice.
+func (c *Coverage) AlternativeHits(rule string) []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hits := make([]int, len(c.alternatives[rule]))
+	copy(hits, c.alternatives[rule])
+	return hits
+}
+
+// TokenHits returns how many times a token was consumed.
+func (c *Coverage) TokenHits(token string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens[token]
+}
+
+// recordAlternative counts a successful alternative and the tokens it consumed.
+func (c *Coverage) recordAlternative(rule string, index int, tokens []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules[rule]++
+	hits := c.alternatives[rule]
+	for len(hits) <= index {
+		hits = append(hits, 0)
+	}
+	hits[index]++
+	c.alternatives[rule] = hits
+	for _, token := range tokens {
+		c.tokens[token]++
+	}
+}
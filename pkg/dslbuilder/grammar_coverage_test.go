@@ -0,0 +1,80 @@
+package dslbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCoverageDSL(t *testing.T) *DSL {
+	dsl := New("coverage")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("MINUS", "-"))
+
+	dsl.Rule("expr", []string{"expr", "PLUS", "NUMBER"}, "add")
+	dsl.Rule("expr", []string{"expr", "MINUS", "NUMBER"}, "sub")
+	dsl.Rule("expr", []string{"NUMBER"}, "number")
+
+	dsl.Action("add", func(args []interface{}) (interface{}, error) { return "add", nil })
+	dsl.Action("sub", func(args []interface{}) (interface{}, error) { return "sub", nil })
+	dsl.Action("number", func(args []interface{}) (interface{}, error) { return args[0], nil })
+	return dsl
+}
+
+func TestCoverageDisabledByDefault(t *testing.T) {
+	dsl := newCoverageDSL(t)
+	_, err := dsl.Parse("1 + 2")
+	require.NoError(t, err)
+	assert.Nil(t, dsl.Coverage())
+}
+
+func TestCoverageRecordsHits(t *testing.T) {
+	dsl := newCoverageDSL(t)
+	cov := dsl.EnableCoverage()
+	assert.Same(t, cov, dsl.EnableCoverage())
+
+	_, err := dsl.Parse("1 + 2 + 3")
+	require.NoError(t, err)
+
+	// The seed matches NUMBER once, then the left-recursive growth
+	// matches the PLUS alternative twice
+	assert.Equal(t, []int{2, 0, 1}, cov.AlternativeHits("expr"))
+	assert.Equal(t, 3, cov.RuleHits("expr"))
+	assert.Equal(t, 3, cov.TokenHits("NUMBER"))
+	assert.Equal(t, 2, cov.TokenHits("PLUS"))
+	assert.Equal(t, 0, cov.TokenHits("MINUS"))
+
+	_, err = dsl.Parse("4 - 5")
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1, 2}, cov.AlternativeHits("expr"))
+	assert.Equal(t, 1, cov.TokenHits("MINUS"))
+}
+
+func TestCoverageIgnoresFailedParses(t *testing.T) {
+	dsl := newCoverageDSL(t)
+	cov := dsl.EnableCoverage()
+
+	_, err := dsl.Parse("+")
+	require.Error(t, err)
+	assert.Equal(t, 0, cov.RuleHits("expr"))
+	assert.Empty(t, cov.AlternativeHits("expr"))
+}
+
+func TestCoverageResetAndDisable(t *testing.T) {
+	dsl := newCoverageDSL(t)
+	cov := dsl.EnableCoverage()
+	_, err := dsl.Parse("1")
+	require.NoError(t, err)
+
+	cov.Reset()
+	assert.Equal(t, 0, cov.RuleHits("expr"))
+	assert.Equal(t, 0, cov.TokenHits("NUMBER"))
+
+	dsl.DisableCoverage()
+	assert.Nil(t, dsl.Coverage())
+	_, err = dsl.Parse("1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, cov.RuleHits("expr"))
+}
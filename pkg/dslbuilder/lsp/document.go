@@ -0,0 +1,394 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// document is an open text document and its parse results.
+type document struct {
+	uri        string
+	version    int
+	text       string
+	lineStarts []int
+	statements []statement
+}
+
+// statement is an independently parsed part of a document: the whole text,
+// or one line in per-line mode.
+type statement struct {
+	offset int // Byte offset of the statement in the document
+	text   string
+	tree   *dslbuilder.Tree
+	err    error
+}
+
+// newDocument parses text with the server DSL.
+func (s *Server) newDocument(uri string, version int, text string) *document {
+	doc := &document{uri: uri, version: version, text: text, lineStarts: []int{0}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			doc.lineStarts = append(doc.lineStarts, i+1)
+		}
+	}
+
+	for _, st := range s.split(text) {
+		st.tree, st.err = s.dsl.ParseTree(st.text)
+		doc.statements = append(doc.statements, st)
+	}
+	return doc
+}
+
+// split returns the statements of a document. In per-line mode empty lines
+// and lines starting with # or // are skipped, like DSL.ParseMultiline.
+func (s *Server) split(text string) []statement {
+	if !s.opts.PerLine {
+		return []statement{{offset: 0, text: text}}
+	}
+	var statements []statement
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "//") {
+			statements = append(statements, statement{offset: offset, text: strings.TrimRight(line, "\r\n")})
+		}
+		offset += len(line)
+	}
+	return statements
+}
+
+// position converts a byte offset into an LSP position.
+func (d *document) position(offset int) Position {
+	if offset > len(d.text) {
+		offset = len(d.text)
+	}
+	line := sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > offset }) - 1
+	start := d.lineStarts[line]
+	return Position{Line: line, Character: utf16Len(d.text[start:offset])}
+}
+
+// offset converts an LSP position into a byte offset.
+func (d *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+	offset := d.lineStarts[pos.Line]
+	units := 0
+	for offset < len(d.text) && d.text[offset] != '\n' && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
+
+func (d *document) rangeOf(start, end int) Range {
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
+
+// statementAt returns the statement containing the byte offset. In per-line
+// mode a blank line yields an empty statement at the cursor.
+func (d *document) statementAt(offset int) statement {
+	for _, st := range d.statements {
+		if st.offset <= offset && offset <= st.offset+len(st.text) {
+			return st
+		}
+	}
+	if len(d.statements) == 1 && d.statements[0].offset == 0 {
+		return d.statements[0]
+	}
+	line := d.position(offset).Line
+	return statement{offset: d.lineStarts[line], text: d.text[d.lineStarts[line]:offset]}
+}
+
+// diagnostics reports one error per statement that does not parse. When the
+// parser got further than the reported error, the farthest failure is used
+// since it is usually where the input went wrong.
+func (s *Server) diagnostics(d *document) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, st := range d.statements {
+		if st.err == nil {
+			continue
+		}
+		start, end := st.offset, st.offset+len(st.text)
+		message := st.err.Error()
+		if pe, ok := st.err.(*dslbuilder.ParseError); ok {
+			start = st.offset + pe.Position
+			end = start
+			if pe.Token != "<end of input>" {
+				end = start + len(pe.Token)
+			}
+		}
+
+		if tree := st.tree; tree != nil && len(tree.Expected) > 0 && st.offset+tree.ExpectedOffset >= start {
+			start = st.offset + tree.ExpectedOffset
+			end = start
+			found := "end of input"
+			for _, token := range tree.Tokens {
+				if token.Start == tree.ExpectedOffset {
+					found = fmt.Sprintf("%q", token.Value)
+					end = st.offset + token.End
+					break
+				}
+			}
+			message = fmt.Sprintf("unexpected %s, expected %s", found, s.describeTokens(tree.Expected))
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.rangeOf(start, end),
+			Severity: SeverityError,
+			Source:   s.dsl.Name(),
+			Message:  message,
+		})
+	}
+	return diagnostics
+}
+
+// describeTokens lists token names for messages, using the keyword or
+// literal text when the token has one.
+func (s *Server) describeTokens(names []string) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		if text, ok := s.literal(name); ok {
+			parts[i] = fmt.Sprintf("%q", text)
+		} else {
+			parts[i] = name
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " or " + parts[len(parts)-1]
+}
+
+// literal returns the fixed text of a keyword or literal regex token.
+func (s *Server) literal(name string) (string, bool) {
+	info, ok := s.dsl.Grammar().Token(name)
+	if !ok {
+		return "", false
+	}
+	return info.Literal()
+}
+
+// completion returns the tokens the grammar accepts at the cursor, and the
+// context keys those tokens would match.
+func (s *Server) completion(d *document, pos Position) []CompletionItem {
+	offset := d.offset(pos)
+	st := d.statementAt(offset)
+
+	items := []CompletionItem{}
+	for _, c := range s.dsl.Complete(st.text, offset-st.offset) {
+		switch c.Kind {
+		case dslbuilder.CompletionKeyword:
+			items = append(items, CompletionItem{Label: c.Text, Kind: CompletionKindKeyword, Detail: c.Token})
+		case dslbuilder.CompletionLiteral:
+			kind := CompletionKindOperator
+			if isWord(c.Text) {
+				kind = CompletionKindKeyword
+			}
+			items = append(items, CompletionItem{Label: c.Text, Kind: kind, Detail: c.Token})
+		case dslbuilder.CompletionToken:
+			items = append(items, CompletionItem{
+				Label:            c.Text,
+				Kind:             CompletionKindVariable,
+				Detail:           c.Pattern,
+				InsertText:       "${1:" + c.Text + "}",
+				InsertTextFormat: 2,
+			})
+		case dslbuilder.CompletionContext:
+			items = append(items, CompletionItem{Label: c.Text, Kind: CompletionKindVariable, Detail: "context " + c.Token})
+		}
+	}
+	return items
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func isWord(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isWordByte(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// hover describes the token under the cursor and the rules containing it.
+func (s *Server) hover(d *document, pos Position) *Hover {
+	offset := d.offset(pos)
+	st := d.statementAt(offset)
+	if st.tree == nil {
+		return nil
+	}
+	local := offset - st.offset
+
+	if path := st.tree.Path(local); len(path) > 0 {
+		leaf := path[len(path)-1]
+		rules := []string{}
+		for _, node := range path {
+			if !node.IsToken() {
+				rules = append(rules, fmt.Sprintf("%s[%d]", node.Rule, node.Alternative))
+			}
+		}
+		value := fmt.Sprintf("**%s**", leaf.Name())
+		if leaf.IsToken() {
+			value += fmt.Sprintf(" `%s`", leaf.Token.Value)
+		}
+		value += "\n\nRule: " + strings.Join(rules, " › ")
+		r := d.rangeOf(st.offset+leaf.Start, st.offset+leaf.End)
+		return &Hover{Contents: markupContent{Kind: "markdown", Value: value}, Range: &r}
+	}
+
+	// Without a tree, fall back to the tokens
+	for _, token := range st.tree.Tokens {
+		if token.Start <= local && local < token.End {
+			r := d.rangeOf(st.offset+token.Start, st.offset+token.End)
+			value := fmt.Sprintf("**%s** `%s`", token.TokenType, token.Value)
+			return &Hover{Contents: markupContent{Kind: "markdown", Value: value}, Range: &r}
+		}
+	}
+	return nil
+}
+
+// symbols returns one outline entry per statement. In document mode the
+// statements are the rule children of the root, flattening list rules such
+// as program → program stmt.
+func (s *Server) symbols(d *document) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, st := range d.statements {
+		if st.tree == nil || st.tree.Root == nil {
+			continue
+		}
+		if s.opts.PerLine {
+			symbols = append(symbols, s.symbol(d, st, st.tree.Root))
+			continue
+		}
+		var collect func(node *dslbuilder.Node)
+		collect = func(node *dslbuilder.Node) {
+			for _, child := range node.Children {
+				switch {
+				case child.IsToken() || child.Start == child.End:
+				case child.Rule == node.Rule:
+					collect(child)
+				default:
+					symbols = append(symbols, s.symbol(d, st, child))
+				}
+			}
+		}
+		collect(st.tree.Root)
+	}
+	return symbols
+}
+
+func (s *Server) symbol(d *document, st statement, node *dslbuilder.Node) DocumentSymbol {
+	name := strings.TrimSpace(node.Text(st.text))
+	if i := strings.IndexByte(name, '\n'); i >= 0 {
+		name = name[:i] + " …"
+	}
+	if len(name) > 60 {
+		name = name[:57] + "…"
+	}
+	r := d.rangeOf(st.offset+node.Start, st.offset+node.End)
+	return DocumentSymbol{Name: name, Detail: node.Rule, Kind: SymbolKindFunction, Range: r, SelectionRange: r}
+}
+
+// semanticTokenTypes is the legend advertised to clients.
+var semanticTokenTypes = []string{"keyword", "number", "string", "operator", "comment", "variable"}
+
+// semanticTokens encodes every token of the document, including skipped
+// tokens such as comments. Tokens spanning several lines are skipped since
+// not all clients support them.
+func (s *Server) semanticTokens(d *document) SemanticTokens {
+	data := []int{}
+	prevLine, prevChar := 0, 0
+	for _, st := range d.statements {
+		if st.tree == nil {
+			continue
+		}
+		tokens := append(append([]dslbuilder.TokenMatch{}, st.tree.Tokens...), st.tree.Trivia...)
+		sort.Slice(tokens, func(i, j int) bool { return tokens[i].Start < tokens[j].Start })
+		for _, token := range tokens {
+			if strings.Contains(token.Value, "\n") {
+				continue
+			}
+			start := d.position(st.offset + token.Start)
+			deltaChar := start.Character
+			if start.Line == prevLine {
+				deltaChar -= prevChar
+			}
+			data = append(data, start.Line-prevLine, deltaChar, utf16Len(token.Value), s.tokenType(token.TokenType), 0)
+			prevLine, prevChar = start.Line, start.Character
+		}
+	}
+	return SemanticTokens{Data: data}
+}
+
+// tokenType classifies a token into the semantic token legend. Tokens
+// without a category are classified from their name and pattern.
+func (s *Server) tokenType(name string) int {
+	info, _ := s.dsl.Grammar().Token(name)
+	switch info.Category {
+	case dslbuilder.CategoryNone:
+	case dslbuilder.CategoryIdentifier:
+		return tokenTypeIndex("variable")
+	default:
+		return tokenTypeIndex(string(info.Category))
+	}
+
+	upper := strings.ToUpper(name)
+	pattern := info.Pattern
+
+	category := "variable"
+	switch {
+	case info.IsKeyword():
+		category = "keyword"
+	case strings.Contains(upper, "COMMENT"):
+		category = "comment"
+	case strings.HasPrefix(pattern, `"`) || strings.HasPrefix(pattern, `'`) || strings.Contains(upper, "STRING"):
+		category = "string"
+	case strings.Contains(upper, "NUMBER") || strings.Contains(upper, "INT") || strings.Contains(upper, "FLOAT") ||
+		matchesWhole(pattern, "1"):
+		category = "number"
+	default:
+		if text, ok := s.literal(name); ok && !isWord(text) {
+			category = "operator"
+		} else if ok {
+			category = "keyword"
+		}
+	}
+	return tokenTypeIndex(category)
+}
+
+func tokenTypeIndex(category string) int {
+	for i, t := range semanticTokenTypes {
+		if t == category {
+			return i
+		}
+	}
+	return len(semanticTokenTypes) - 1
+}
+
+// matchesWhole reports whether pattern matches all of s.
+func matchesWhole(pattern, s string) bool {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	return err == nil && re.MatchString(s)
+}
@@ -0,0 +1,264 @@
+// Package lsp implements a Language Server Protocol server for any go-dsl
+// grammar. It speaks JSON-RPC over a reader/writer pair (usually stdio) and
+// provides diagnostics from parse errors, completion of the tokens valid at
+// the cursor, hover with the rules containing a token, document symbols and
+// semantic tokens.
+//
+// Documents are parsed with DSL.ParseTree, so actions never run while
+// editing. The cmd/dsl-lsp binary serves YAML/JSON grammars; DSLs defined in
+// Go can be served from a small custom main with Run:
+//
+//	func main() {
+//	    if err := lsp.Run(mydsl.New(), lsp.Options{PerLine: true}); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// Options controls the server.
+type Options struct {
+	// PerLine parses every non-empty line as a separate statement, like
+	// DSL.ParseMultiline. Lines starting with # or // are comments.
+	// Otherwise the whole document is parsed with the start rule.
+	PerLine bool
+
+	// Log receives protocol errors; nil discards them.
+	Log *log.Logger
+}
+
+// Server is a language server for one DSL. A Server handles one client
+// connection at a time and processes messages sequentially.
+type Server struct {
+	dsl      *dslbuilder.DSL
+	opts     Options
+	conn     *conn
+	docs     map[string]*document
+	shutdown bool
+}
+
+// NewServer creates a language server for a DSL.
+func NewServer(dsl *dslbuilder.DSL, opts Options) *Server {
+	return &Server{dsl: dsl, opts: opts, docs: make(map[string]*document)}
+}
+
+// Run serves a DSL over the standard input and output, the transport
+// editors use to start a language server. Custom builds call it with a DSL
+// written in Go, or one registered with the registry package:
+//
+//	func main() {
+//	    dsl, err := registry.New("http")
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    if err := lsp.Run(dsl, lsp.Options{PerLine: true}); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+func Run(dsl *dslbuilder.DSL, opts Options) error {
+	if dsl == nil {
+		return errors.New("lsp: nil DSL")
+	}
+	return NewServer(dsl, opts).Serve(os.Stdin, os.Stdout)
+}
+
+// Serve reads requests from r and writes responses and notifications to w
+// until the client sends exit or r is closed.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+	for {
+		body, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()})
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit received before shutdown")
+			}
+			return nil
+		}
+		if msg.Method == "" {
+			// Responses to server requests are not used
+			continue
+		}
+
+		result, rerr := s.handle(msg.Method, msg.Params)
+		if msg.ID != nil {
+			s.reply(msg.ID, result, rerr)
+		} else if rerr != nil {
+			s.logf("%s: %v", msg.Method, rerr)
+		}
+	}
+}
+
+// handle dispatches one request or notification.
+func (s *Server) handle(method string, params json.RawMessage) (interface{}, *responseError) {
+	switch method {
+	case "initialize":
+		return s.initialize(), nil
+	case "initialized", "$/cancelRequest", "$/setTrace", "workspace/didChangeConfiguration":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		s.update(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			// Full synchronization: the last change holds the whole text
+			s.update(p.TextDocument.URI, p.TextDocument.Version, p.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+		delete(s.docs, p.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		return nil, nil
+
+	case "textDocument/completion":
+		var p textDocumentPositionParams
+		doc, err := s.documentFor(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return s.completion(doc, p.Position), nil
+	case "textDocument/hover":
+		var p textDocumentPositionParams
+		doc, err := s.documentFor(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		if h := s.hover(doc, p.Position); h != nil {
+			return h, nil
+		}
+		return nil, nil
+	case "textDocument/documentSymbol":
+		var p documentParams
+		doc, err := s.documentFor(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return s.symbols(doc), nil
+	case "textDocument/semanticTokens/full":
+		var p documentParams
+		doc, err := s.documentFor(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return s.semanticTokens(doc), nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
+
+func (s *Server) initialize() interface{} {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":       1, // Full
+			"completionProvider":     map[string]interface{}{"triggerCharacters": []string{" "}},
+			"hoverProvider":          true,
+			"documentSymbolProvider": true,
+			"semanticTokensProvider": map[string]interface{}{
+				"legend": semanticTokensLegend{TokenTypes: semanticTokenTypes, TokenModifiers: []string{}},
+				"full":   true,
+			},
+		},
+		"serverInfo": map[string]interface{}{"name": "dsl-lsp"},
+	}
+}
+
+// update parses a new document version and publishes its diagnostics.
+func (s *Server) update(uri string, version int, text string) {
+	doc := s.newDocument(uri, version, text)
+	s.docs[uri] = doc
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Version:     version,
+		Diagnostics: s.diagnostics(doc),
+	})
+}
+
+// documentFor decodes params and returns the open document they refer to.
+func (s *Server) documentFor(params json.RawMessage, v interface{}, id *textDocumentIdentifier) (*document, *responseError) {
+	if err := decode(params, v); err != nil {
+		return nil, err
+	}
+	doc, ok := s.docs[id.URI]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: "document not open: " + id.URI}
+	}
+	return doc, nil
+}
+
+func decode(params json.RawMessage, v interface{}) *responseError {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, rerr *responseError) {
+	msg := &message{ID: id, Error: rerr}
+	if rerr == nil {
+		// A successful response must carry a result, even if null
+		msg.Result = json.RawMessage("null")
+		if result != nil {
+			msg.Result = result
+		}
+	}
+	if id == nil {
+		null := json.RawMessage("null")
+		msg.ID = &null
+	}
+	if err := s.conn.write(msg); err != nil {
+		s.logf("write response: %v", err)
+	}
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		s.logf("encode %s: %v", method, err)
+		return
+	}
+	if err := s.conn.write(&message{Method: method, Params: data}); err != nil {
+		s.logf("write notification: %v", err)
+	}
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.opts.Log != nil {
+		s.opts.Log.Output(2, fmt.Sprintf(format, args...))
+	}
+}
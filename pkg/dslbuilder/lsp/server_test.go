@@ -0,0 +1,275 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCalculator(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("calc")
+	require.NoError(t, dsl.KeywordToken("LET", "let"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("ASSIGN", "="))
+
+	dsl.Rule("stmt", []string{"LET", "ID", "ASSIGN", "expr"}, "let")
+	dsl.Rule("stmt", []string{"expr"}, "pass")
+	dsl.Rule("expr", []string{"expr", "PLUS", "term"}, "add")
+	dsl.Rule("expr", []string{"term"}, "pass")
+	dsl.Rule("term", []string{"NUMBER"}, "pass")
+	dsl.Rule("term", []string{"ID"}, "pass")
+	return dsl
+}
+
+// client is a scripted JSON-RPC client connected to a running server.
+type client struct {
+	t      *testing.T
+	conn   *conn
+	nextID int
+	inbox  chan map[string]json.RawMessage
+	done   chan error
+	input  *io.PipeWriter
+}
+
+func startServer(t *testing.T, opts Options) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &client{
+		t:     t,
+		conn:  newConn(clientIn, clientOut),
+		inbox: make(chan map[string]json.RawMessage, 100),
+		done:  make(chan error, 1),
+		input: clientOut,
+	}
+	go func() {
+		c.done <- NewServer(newCalculator(t), opts).Serve(serverIn, serverOut)
+		serverOut.Close()
+	}()
+	go func() {
+		for {
+			body, err := c.conn.read()
+			if err != nil {
+				close(c.inbox)
+				return
+			}
+			var msg map[string]json.RawMessage
+			if json.Unmarshal(body, &msg) == nil {
+				c.inbox <- msg
+			}
+		}
+	}()
+	return c
+}
+
+func (c *client) send(method string, params interface{}, withID bool) {
+	data, err := json.Marshal(params)
+	require.NoError(c.t, err)
+	msg := &message{Method: method, Params: data}
+	if withID {
+		c.nextID++
+		id := json.RawMessage(fmt.Sprint(c.nextID))
+		msg.ID = &id
+	}
+	require.NoError(c.t, c.conn.write(msg))
+}
+
+// receive waits for the next message matching the predicate.
+func (c *client) receive(match func(map[string]json.RawMessage) bool) map[string]json.RawMessage {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg, ok := <-c.inbox:
+			require.True(c.t, ok, "server closed the connection")
+			if match(msg) {
+				return msg
+			}
+		case <-timeout:
+			c.t.Fatal("timed out waiting for server message")
+		}
+	}
+}
+
+// request sends a request and decodes the result into v.
+func (c *client) request(method string, params interface{}, v interface{}) {
+	c.send(method, params, true)
+	id := fmt.Sprint(c.nextID)
+	msg := c.receive(func(m map[string]json.RawMessage) bool { return string(m["id"]) == id })
+	require.Nil(c.t, msg["error"], "error response: %s", msg["error"])
+	if v != nil {
+		require.NoError(c.t, json.Unmarshal(msg["result"], v))
+	}
+}
+
+// diagnostics waits for the next published diagnostics.
+func (c *client) diagnostics() []Diagnostic {
+	msg := c.receive(func(m map[string]json.RawMessage) bool {
+		return string(m["method"]) == `"textDocument/publishDiagnostics"`
+	})
+	var p publishDiagnosticsParams
+	require.NoError(c.t, json.Unmarshal(msg["params"], &p))
+	return p.Diagnostics
+}
+
+func (c *client) open(text string) {
+	c.send("textDocument/didOpen", didOpenParams{TextDocument: textDocumentItem{URI: "file:///a.calc", Version: 1, Text: text}}, false)
+}
+
+func (c *client) stop() {
+	c.request("shutdown", nil, nil)
+	c.send("exit", nil, false)
+	select {
+	case err := <-c.done:
+		assert.NoError(c.t, err)
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("server did not exit")
+	}
+}
+
+func at(line, character int) textDocumentPositionParams {
+	return textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: "file:///a.calc"},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	c := startServer(t, Options{})
+	var result struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	c.request("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}, &result)
+	assert.Equal(t, true, result.Capabilities["hoverProvider"])
+	assert.Contains(t, result.Capabilities, "semanticTokensProvider")
+	c.send("initialized", map[string]interface{}{}, false)
+	c.stop()
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := startServer(t, Options{PerLine: true})
+	c.open("let x = 1\n# comment\nlet y 2\n")
+
+	diagnostics := c.diagnostics()
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, `unexpected "2", expected "="`, diagnostics[0].Message)
+	assert.Equal(t, Range{Start: Position{2, 6}, End: Position{2, 7}}, diagnostics[0].Range)
+	assert.Equal(t, "calc", diagnostics[0].Source)
+
+	c.send("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": "file:///a.calc", "version": 2},
+		"contentChanges": []map[string]string{{"text": "let x = 1\nlet y = 2\n"}},
+	}, false)
+	assert.Empty(t, c.diagnostics())
+	c.stop()
+}
+
+func TestCompletion(t *testing.T) {
+	c := startServer(t, Options{PerLine: true})
+	c.open("let x \n1 + \nle")
+	c.diagnostics()
+
+	labels := func(items []CompletionItem) []string {
+		result := []string{}
+		for _, item := range items {
+			result = append(result, item.Label)
+		}
+		return result
+	}
+
+	var items []CompletionItem
+	c.request("textDocument/completion", at(0, 6), &items)
+	assert.Equal(t, []string{"="}, labels(items))
+
+	c.request("textDocument/completion", at(1, 4), &items)
+	assert.Equal(t, []string{"NUMBER", "ID"}, labels(items))
+	assert.Equal(t, "${1:NUMBER}", items[0].InsertText)
+
+	// The word being typed is left to the client
+	c.request("textDocument/completion", at(2, 2), &items)
+	assert.Contains(t, labels(items), "let")
+	c.stop()
+}
+
+func TestHover(t *testing.T) {
+	c := startServer(t, Options{})
+	c.open("let x = 1 + 2")
+	c.diagnostics()
+
+	var hover Hover
+	c.request("textDocument/hover", at(0, 12), &hover)
+	assert.Equal(t, "**NUMBER** `2`\n\nRule: stmt[0] › expr[0] › term[0]", hover.Contents.Value)
+	assert.Equal(t, Range{Start: Position{0, 12}, End: Position{0, 13}}, *hover.Range)
+	c.stop()
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	c := startServer(t, Options{PerLine: true})
+	c.open("let x = 1\n\nlet y = x + 1\n")
+	c.diagnostics()
+
+	var symbols []DocumentSymbol
+	c.request("textDocument/documentSymbol", documentParams{TextDocument: textDocumentIdentifier{URI: "file:///a.calc"}}, &symbols)
+	require.Len(t, symbols, 2)
+	assert.Equal(t, "let y = x + 1", symbols[1].Name)
+	assert.Equal(t, "stmt", symbols[1].Detail)
+	assert.Equal(t, 2, symbols[1].Range.Start.Line)
+	c.stop()
+}
+
+func TestSemanticTokens(t *testing.T) {
+	c := startServer(t, Options{})
+	c.open("let x = 1\n+ 2")
+	c.diagnostics()
+
+	var tokens SemanticTokens
+	c.request("textDocument/semanticTokens/full", documentParams{TextDocument: textDocumentIdentifier{URI: "file:///a.calc"}}, &tokens)
+	keyword, number, operator, variable := tokenTypeIndex("keyword"), tokenTypeIndex("number"), tokenTypeIndex("operator"), tokenTypeIndex("variable")
+	assert.Equal(t, []int{
+		0, 0, 3, keyword, 0,
+		0, 4, 1, variable, 0,
+		0, 2, 1, operator, 0,
+		0, 2, 1, number, 0,
+		1, 0, 1, operator, 0,
+		0, 2, 1, number, 0,
+	}, tokens.Data)
+	c.stop()
+}
+
+func TestSemanticTokenCategories(t *testing.T) {
+	dsl := newCalculator(t)
+	require.NoError(t, dsl.SetTokenCategory("ASSIGN", dslbuilder.CategoryKeyword))
+	require.NoError(t, dsl.SetTokenCategory("ID", dslbuilder.CategoryIdentifier))
+	s := NewServer(dsl, Options{})
+
+	assert.Equal(t, tokenTypeIndex("keyword"), s.tokenType("ASSIGN"), "category overrides the heuristic")
+	assert.Equal(t, tokenTypeIndex("variable"), s.tokenType("ID"))
+	assert.Equal(t, tokenTypeIndex("operator"), s.tokenType("PLUS"), "uncategorized tokens use the heuristic")
+}
+
+func TestRunNilDSL(t *testing.T) {
+	assert.EqualError(t, Run(nil, Options{}), "lsp: nil DSL")
+}
+
+func TestUnknownMethod(t *testing.T) {
+	c := startServer(t, Options{})
+	c.send("workspace/unknown", map[string]interface{}{}, true)
+	id := fmt.Sprint(c.nextID)
+	msg := c.receive(func(m map[string]json.RawMessage) bool { return string(m["id"]) == id })
+	assert.Contains(t, string(msg["error"]), "-32601")
+	c.stop()
+}
+
+func TestPositionsUseUTF16(t *testing.T) {
+	doc := &document{text: "a😀b\nc", lineStarts: []int{0, 7}}
+	assert.Equal(t, Position{0, 3}, doc.position(5))
+	assert.Equal(t, 5, doc.offset(Position{0, 3}))
+	assert.Equal(t, Position{1, 1}, doc.position(8))
+}
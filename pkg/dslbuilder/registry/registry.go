@@ -0,0 +1,84 @@
+// Package registry keeps a process-wide list of DSLs built in Go, by name,
+// so that tools like the REPL can offer any DSL compiled into the binary.
+// Register a DSL from main or from an init function of the package that
+// defines it, the way database/sql drivers register themselves:
+//
+//	func init() {
+//	    registry.Register("http", func() *dslbuilder.DSL { return NewHTTPDSLv3().GetDSL() })
+//	}
+//
+// The factory runs every time the DSL is requested, so each user gets a
+// fresh instance with its own context.
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// Factory creates a new instance of a DSL.
+type Factory func() *dslbuilder.DSL
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a DSL available by name. It panics if the name is empty,
+// the factory is nil, or the name is already registered.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if name == "" {
+		panic("registry: Register with empty name")
+	}
+	if factory == nil {
+		panic("registry: Register factory is nil for " + name)
+	}
+	if _, dup := factories[name]; dup {
+		panic("registry: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// New creates an instance of the DSL registered under name.
+func New(name string) (*dslbuilder.DSL, error) {
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		names := Names()
+		if len(names) == 0 {
+			return nil, fmt.Errorf("unknown DSL %q (no DSLs are registered in this build)", name)
+		}
+		return nil, fmt.Errorf("unknown DSL %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	dsl := factory()
+	if dsl == nil {
+		return nil, fmt.Errorf("factory for DSL %q returned nil", name)
+	}
+	return dsl, nil
+}
+
+// Names returns the registered names in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unregisterAll clears the registry, for tests.
+func unregisterAll() {
+	mu.Lock()
+	defer mu.Unlock()
+	factories = make(map[string]Factory)
+}
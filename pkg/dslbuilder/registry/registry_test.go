@@ -0,0 +1,41 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	defer unregisterAll()
+
+	_, err := New("calc")
+	assert.ErrorContains(t, err, "no DSLs are registered")
+
+	created := 0
+	Register("calc", func() *dslbuilder.DSL {
+		created++
+		return dslbuilder.New("calc")
+	})
+	Register("broken", func() *dslbuilder.DSL { return nil })
+	assert.Equal(t, []string{"broken", "calc"}, Names())
+
+	first, err := New("calc")
+	require.NoError(t, err)
+	second, err := New("calc")
+	require.NoError(t, err)
+	assert.Equal(t, "calc", first.Name())
+	assert.NotSame(t, first, second, "every call creates a new instance")
+	assert.Equal(t, 2, created)
+
+	_, err = New("http")
+	assert.ErrorContains(t, err, "available: broken, calc")
+	_, err = New("broken")
+	assert.Error(t, err)
+
+	assert.Panics(t, func() { Register("calc", func() *dslbuilder.DSL { return nil }) })
+	assert.Panics(t, func() { Register("", func() *dslbuilder.DSL { return nil }) })
+	assert.Panics(t, func() { Register("nil", nil) })
+}
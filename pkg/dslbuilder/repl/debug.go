@@ -0,0 +1,326 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// debugMode controls where the debugger stops next.
+type debugMode int
+
+const (
+	modeStep     debugMode = iota // Stop at every event
+	modeNext                      // Stop at the next event outside nested rules
+	modeContinue                  // Stop only at breakpoints
+	modeQuit                      // Finish the parse without stopping
+)
+
+// debugger steps through a parse using trace events. The parse runs in its
+// own goroutine; the tracer hands every event to the REPL goroutine and
+// waits until the user resumes.
+//
+// The parse cannot be aborted: quit, like the end of the input, only stops
+// prompting, and the parse still runs to completion through DSL.Use, with
+// its actions and their side effects on the context.
+type debugger struct {
+	dsl         *dslbuilder.DSL
+	in          *lineEditor
+	out         io.Writer
+	breakpoints map[string]bool
+
+	tokens    []dslbuilder.TokenMatch
+	stack     []string
+	mode      debugMode
+	nextDepth int
+	last      string
+}
+
+// debugOutcome is the result of the parse goroutine.
+type debugOutcome struct {
+	result *dslbuilder.Result
+	err    error
+}
+
+// debug parses input under the step-through debugger.
+func (r *REPL) debug(input string) {
+	if strings.TrimSpace(input) == "" {
+		fmt.Fprintln(r.out, "Usage: .debug <input>")
+		return
+	}
+
+	d := &debugger{
+		dsl:         r.dsl,
+		in:          r.editor,
+		out:         r.out,
+		breakpoints: r.breakpoints,
+	}
+	outcome := d.run(input, r.context)
+
+	if outcome.err != nil {
+		fmt.Fprintf(r.out, "\033[31mError: %v\033[0m\n", outcome.err)
+		return
+	}
+	r.displayOutput(outcome.result.GetOutput())
+}
+
+// run parses input, stopping according to the current mode and breakpoints.
+func (d *debugger) run(input string, ctx map[string]interface{}) debugOutcome {
+	tokens, err := d.dsl.DebugTokens(input)
+	if err != nil {
+		return debugOutcome{err: err}
+	}
+	d.tokens = tokens
+	d.stack = nil
+	d.mode = modeStep
+	d.last = "step"
+
+	events := make(chan dslbuilder.TraceEvent)
+	resume := make(chan struct{})
+	done := make(chan debugOutcome, 1)
+
+	previous := d.dsl.Tracer()
+	d.dsl.SetTracer(dslbuilder.TracerFunc(func(e dslbuilder.TraceEvent) {
+		events <- e
+		<-resume
+	}))
+	defer d.dsl.SetTracer(previous)
+
+	go func() {
+		var outcome debugOutcome
+		defer func() {
+			if v := recover(); v != nil {
+				outcome = debugOutcome{err: fmt.Errorf("parse panicked: %v", v)}
+			}
+			done <- outcome
+		}()
+		outcome.result, outcome.err = d.dsl.Use(input, ctx)
+	}()
+
+	fmt.Fprintf(d.out, "Debugging %q (%d tokens). Type 'help' for debugger commands.\n", input, len(tokens))
+	for {
+		select {
+		case e := <-events:
+			d.observe(e)
+			if d.shouldStop(e) {
+				d.show(e)
+				d.prompt(e)
+			}
+			resume <- struct{}{}
+		case outcome := <-done:
+			fmt.Fprintln(d.out, "Parse finished.")
+			return outcome
+		}
+	}
+}
+
+// observe keeps the rule stack in sync with the parser.
+func (d *debugger) observe(e dslbuilder.TraceEvent) {
+	switch e.Kind {
+	case dslbuilder.TraceRuleEnter:
+		d.stack = append(d.stack, e.Rule)
+	case dslbuilder.TraceRuleExit:
+		if len(d.stack) > 0 {
+			d.stack = d.stack[:len(d.stack)-1]
+		}
+	}
+}
+
+func (d *debugger) shouldStop(e dslbuilder.TraceEvent) bool {
+	if e.Kind == dslbuilder.TraceMemoMiss {
+		// Always followed by a rule-enter event
+		return false
+	}
+	if d.mode != modeQuit && e.Kind == dslbuilder.TraceRuleEnter && d.breakpoints[e.Rule] {
+		return true
+	}
+	switch d.mode {
+	case modeStep:
+		return true
+	case modeNext:
+		return e.Depth <= d.nextDepth
+	default:
+		return false
+	}
+}
+
+// show prints the current event, the rule stack and the token at the cursor.
+func (d *debugger) show(e dslbuilder.TraceEvent) {
+	var what string
+	switch e.Kind {
+	case dslbuilder.TraceRuleEnter:
+		what = "enter " + e.Rule
+		if d.breakpoints[e.Rule] {
+			what += " \033[33m(breakpoint)\033[0m"
+		}
+	case dslbuilder.TraceRuleExit:
+		if e.Err != nil {
+			what = fmt.Sprintf("exit %s \033[31mfailed: %v\033[0m", e.Rule, e.Err)
+		} else {
+			what = fmt.Sprintf("exit %s matched tokens %d..%d", e.Rule, e.Pos, e.End)
+		}
+	case dslbuilder.TraceAlternativeTry:
+		what = fmt.Sprintf("try %s[%d]: %s", e.Rule, e.Alternative, strings.Join(e.Sequence, " "))
+	case dslbuilder.TraceAlternativeFail:
+		what = fmt.Sprintf("\033[31mfail %s[%d]: %s\033[0m — %v", e.Rule, e.Alternative, strings.Join(e.Sequence, " "), e.Err)
+	case dslbuilder.TraceMemoHit:
+		what = fmt.Sprintf("memo hit %s (tokens %d..%d)", e.Rule, e.Pos, e.End)
+		if e.Err != nil {
+			what = fmt.Sprintf("memo hit %s \033[31m(failed before)\033[0m", e.Rule)
+		}
+	case dslbuilder.TraceTokenConsumed:
+		what = fmt.Sprintf("consume %s %q", e.Token.TokenType, e.Token.Value)
+	case dslbuilder.TraceActionInvoked:
+		what = "action " + e.Action
+		if e.Err != nil {
+			what += fmt.Sprintf(" \033[31mfailed: %v\033[0m", e.Err)
+		}
+	}
+
+	fmt.Fprintf(d.out, "\033[1m%s\033[0m\n", what)
+	fmt.Fprintf(d.out, "  stack: %s\n", d.stackString())
+	fmt.Fprintf(d.out, "  token: %s\n", d.tokenAt(d.cursor(e)))
+}
+
+// cursor returns the token position the parser is looking at after e.
+func (d *debugger) cursor(e dslbuilder.TraceEvent) int {
+	switch e.Kind {
+	case dslbuilder.TraceRuleEnter, dslbuilder.TraceAlternativeTry, dslbuilder.TraceTokenConsumed:
+		return e.Pos
+	default:
+		return e.End
+	}
+}
+
+func (d *debugger) tokenAt(pos int) string {
+	if pos < 0 || pos >= len(d.tokens) {
+		return "<end of input>"
+	}
+	t := d.tokens[pos]
+	return fmt.Sprintf("#%d %s %q", pos, t.TokenType, t.Value)
+}
+
+func (d *debugger) stackString() string {
+	if len(d.stack) == 0 {
+		return "<empty>"
+	}
+	return strings.Join(d.stack, " > ")
+}
+
+// prompt reads debugger commands until one resumes the parse.
+func (d *debugger) prompt(e dslbuilder.TraceEvent) {
+	for {
+		line, err := d.in.readLine("(debug) ")
+		if err != nil {
+			d.mode = modeQuit
+			return
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = d.last
+		}
+		parts := strings.Fields(line)
+
+		switch parts[0] {
+		case "step", "s":
+			d.mode = modeStep
+		case "next", "n":
+			d.mode = modeNext
+			d.nextDepth = e.Depth
+		case "continue", "c":
+			d.mode = modeContinue
+		case "quit", "q":
+			d.mode = modeQuit
+		case "break", "b":
+			d.setBreakpoints(parts[1:], true)
+			continue
+		case "clear":
+			d.setBreakpoints(parts[1:], false)
+			continue
+		case "stack", "bt":
+			fmt.Fprintf(d.out, "  stack: %s\n", d.stackString())
+			continue
+		case "tokens":
+			d.showTokens(d.cursor(e))
+			continue
+		case "help", "h":
+			d.help()
+			continue
+		default:
+			fmt.Fprintf(d.out, "Unknown debugger command: %s (type 'help')\n", parts[0])
+			continue
+		}
+		d.last = parts[0]
+		return
+	}
+}
+
+// setBreakpoints adds or removes rule breakpoints given as "rule=expr" or
+// "expr". Without arguments, break lists the breakpoints and clear removes
+// all of them.
+func (d *debugger) setBreakpoints(args []string, enable bool) {
+	if len(args) == 0 {
+		if !enable {
+			for rule := range d.breakpoints {
+				delete(d.breakpoints, rule)
+			}
+			fmt.Fprintln(d.out, "All breakpoints cleared")
+			return
+		}
+		if len(d.breakpoints) == 0 {
+			fmt.Fprintln(d.out, "No breakpoints")
+			return
+		}
+		rules := make([]string, 0, len(d.breakpoints))
+		for rule := range d.breakpoints {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		fmt.Fprintf(d.out, "Breakpoints: %s\n", strings.Join(rules, ", "))
+		return
+	}
+
+	for _, arg := range args {
+		rule := strings.TrimPrefix(arg, "rule=")
+		if _, ok := d.dsl.Grammar().Rule(rule); !ok {
+			fmt.Fprintf(d.out, "Unknown rule: %s\n", rule)
+			continue
+		}
+		if enable {
+			d.breakpoints[rule] = true
+			fmt.Fprintf(d.out, "Breakpoint set on rule %s\n", rule)
+		} else {
+			delete(d.breakpoints, rule)
+			fmt.Fprintf(d.out, "Breakpoint cleared on rule %s\n", rule)
+		}
+	}
+}
+
+func (d *debugger) showTokens(cursor int) {
+	for i, t := range d.tokens {
+		marker := "  "
+		if i == cursor {
+			marker = "→ "
+		}
+		fmt.Fprintf(d.out, "  %s#%d %-12s %q\n", marker, i, t.TokenType, t.Value)
+	}
+	if cursor >= len(d.tokens) {
+		fmt.Fprintln(d.out, "  → <end of input>")
+	}
+}
+
+func (d *debugger) help() {
+	fmt.Fprintln(d.out, "Debugger commands:")
+	fmt.Fprintln(d.out, "  step, s             Stop at the next parse event")
+	fmt.Fprintln(d.out, "  next, n             Step over nested rules")
+	fmt.Fprintln(d.out, "  continue, c         Run until a breakpoint or the end")
+	fmt.Fprintln(d.out, "  break rule=<name>   Stop when a rule is entered (no argument lists breakpoints)")
+	fmt.Fprintln(d.out, "  clear [rule=<name>] Remove one or all breakpoints")
+	fmt.Fprintln(d.out, "  stack, bt           Show the rule stack")
+	fmt.Fprintln(d.out, "  tokens              Show all tokens and the cursor")
+	fmt.Fprintln(d.out, "  quit, q             Finish the parse without stopping (actions still run)")
+	fmt.Fprintln(d.out, "  (empty line repeats the last command)")
+}
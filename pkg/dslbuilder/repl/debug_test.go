@@ -0,0 +1,143 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedEditor returns a line editor that reads script from a pipe, as
+// when the REPL input is not a terminal.
+func scriptedEditor(t *testing.T, script string, out *bytes.Buffer) *lineEditor {
+	in, w, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = w.WriteString(script)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	t.Cleanup(func() { in.Close() })
+	return newLineEditor(in, out)
+}
+
+// debugScript runs input under the debugger with the commands of script,
+// failing the test if the parse does not finish.
+func debugScript(t *testing.T, dsl *dslbuilder.DSL, script, input string) (string, debugOutcome) {
+	var out bytes.Buffer
+	d := &debugger{dsl: dsl, in: scriptedEditor(t, script, &out), out: &out, breakpoints: make(map[string]bool)}
+
+	done := make(chan debugOutcome, 1)
+	go func() { done <- d.run(input, nil) }()
+	select {
+	case outcome := <-done:
+		return out.String(), outcome
+	case <-time.After(5 * time.Second):
+		t.Fatalf("debugger deadlocked; output so far:\n%s", out.String())
+		return "", debugOutcome{}
+	}
+}
+
+var ansi = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// stops lists the events the debugger stopped at, with the rule stack.
+func stops(out string) []string {
+	var result []string
+	lines := strings.Split(ansi.ReplaceAllString(out, ""), "\n")
+	for i, line := range lines {
+		line = strings.TrimPrefix(line, "(debug) ")
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "  stack: ") && !strings.HasPrefix(line, " ") {
+			result = append(result, line+" | "+strings.TrimPrefix(lines[i+1], "  stack: "))
+		}
+	}
+	return result
+}
+
+func TestDebugStepNextBreak(t *testing.T) {
+	r := newTestREPL(t)
+	script := "step\n\nnext\nbreak\nbreak rule=nope\nbreak rule=value\ncontinue\nbt\ncontinue\n"
+	out, outcome := debugScript(t, r.dsl, script, "print 1 + 2")
+	require.NoError(t, outcome.err)
+
+	assert.Equal(t, []string{
+		"enter stmt | stmt",
+		"try stmt[0]: PRINT expr | stmt",
+		"consume PRINT \"print\" | stmt",
+		"enter expr | stmt > expr",
+		"enter value (breakpoint) | stmt > expr > value",
+		"enter value (breakpoint) | stmt > expr > value",
+	}, stops(out))
+	assert.Contains(t, out, "No breakpoints")
+	assert.Contains(t, out, "Unknown rule: nope")
+	assert.Contains(t, out, "Breakpoint set on rule value")
+	assert.Contains(t, out, "  token: #3 NUMBER \"2\"")
+	assert.True(t, strings.HasSuffix(out, "Parse finished.\n"))
+}
+
+func TestDebugQuitRunsActions(t *testing.T) {
+	dsl := dslbuilder.New("numbers")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	dsl.Rule("start", []string{"NUMBER"}, "number")
+	calls := 0
+	dsl.Action("number", func(args []interface{}) (interface{}, error) {
+		calls++
+		return args[0], nil
+	})
+
+	// quit only stops prompting: the parse and its actions still finish
+	out, outcome := debugScript(t, dsl, "quit\n", "42")
+	require.NoError(t, outcome.err)
+	assert.Equal(t, "42", outcome.result.GetOutput())
+	assert.Equal(t, 1, calls)
+	assert.Len(t, stops(out), 1)
+
+	// So does the end of the input
+	out, outcome = debugScript(t, dsl, "", "7")
+	require.NoError(t, outcome.err)
+	assert.Equal(t, "7", outcome.result.GetOutput())
+	assert.Equal(t, 2, calls)
+	assert.Len(t, stops(out), 1)
+
+	// Tokenizing errors are reported without starting the parse
+	_, outcome = debugScript(t, dsl, "", "x")
+	assert.Error(t, outcome.err)
+}
+
+func TestDebugRestoresTracer(t *testing.T) {
+	r := newTestREPL(t)
+	events := 0
+	r.dsl.SetTracer(dslbuilder.TracerFunc(func(e dslbuilder.TraceEvent) {
+		events++
+	}))
+
+	_, outcome := debugScript(t, r.dsl, "continue\n", "print 1")
+	require.NoError(t, outcome.err)
+	assert.Zero(t, events, "the debugger replaces the tracer while it runs")
+
+	_, err := r.dsl.Parse("print 1")
+	require.NoError(t, err)
+	assert.NotZero(t, events, "the previous tracer is restored")
+
+	r.dsl.SetTracer(nil)
+	debugScript(t, r.dsl, "quit\n", "print 1")
+	assert.Nil(t, r.dsl.Tracer())
+}
+
+func TestDebugCommand(t *testing.T) {
+	r := newTestREPL(t)
+	var out bytes.Buffer
+	r.out = &out
+	r.editor = scriptedEditor(t, "continue\n", &out)
+
+	r.handleCommand(".debug print ( 1")
+	assert.Contains(t, out.String(), "Parse finished.")
+	assert.Contains(t, out.String(), "Error: ")
+
+	out.Reset()
+	r.handleCommand(".debug")
+	assert.Equal(t, "Usage: .debug <input>\n", out.String())
+}
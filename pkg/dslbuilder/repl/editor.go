@@ -0,0 +1,461 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// errInterrupted is returned by readLine when the user presses Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// historyLimit is the number of history lines kept by the editor.
+const historyLimit = 1000
+
+// completer returns the candidates that can replace the word before the
+// cursor, and where that word starts. Hints are listed with the candidates
+// but never inserted, as for token placeholders like <NUMBER>.
+type completer func(line string, pos int) (start int, candidates, hints []string)
+
+// lineEditor reads lines from a terminal with Emacs-style editing keys,
+// history navigation and search, and Tab completion. When the input is not
+// a terminal it reads plain lines, so piped input works as before.
+type lineEditor struct {
+	in       *os.File
+	out      io.Writer
+	reader   *bufio.Reader
+	scanner  *bufio.Scanner
+	complete completer
+	history  []string
+
+	// State of the line being edited
+	prompt string
+	line   []rune
+	pos    int
+	width  int
+}
+
+func newLineEditor(in *os.File, out io.Writer) *lineEditor {
+	return &lineEditor{
+		in:      in,
+		out:     out,
+		reader:  bufio.NewReader(in),
+		scanner: bufio.NewScanner(in),
+	}
+}
+
+// addHistory appends a line to the history, skipping blank lines and
+// repeats of the previous line.
+func (e *lineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > historyLimit {
+		e.history = e.history[len(e.history)-historyLimit:]
+	}
+}
+
+// readLine shows the prompt and returns the line entered, without the
+// newline. It returns io.EOF at the end of the input or on Ctrl-D in an
+// empty line, and errInterrupted on Ctrl-C.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	fd := int(e.in.Fd())
+	if !isTerminal(fd) {
+		return e.readPlain(prompt)
+	}
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return e.readPlain(prompt)
+	}
+	defer restore()
+
+	e.prompt, e.line, e.pos, e.width = prompt, nil, 0, terminalWidth(fd)
+	return e.edit()
+}
+
+func (e *lineEditor) readPlain(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+	if !e.scanner.Scan() {
+		if err := e.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return e.scanner.Text(), nil
+}
+
+// Control keys
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// Keys decoded from escape sequences, outside the Unicode range
+const (
+	keyUp = unicode.MaxRune + 1 + iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDeleteForward
+	keyWordLeft
+	keyWordRight
+	keyUnknown
+)
+
+// edit runs the editing loop until the line is entered.
+func (e *lineEditor) edit() (string, error) {
+	e.refresh()
+
+	// historyPos indexes the history while browsing it; the line being
+	// edited is saved in draft while an older line is shown
+	historyPos := len(e.history)
+	draft := ""
+
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		switch key {
+		case keyEnter, '\n':
+			e.moveToEnd()
+			return string(e.line), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteForward()
+		case keyTab:
+			e.completeWord()
+		case keyBackspace, keyDelete:
+			if e.pos > 0 {
+				e.line = append(e.line[:e.pos-1], e.line[e.pos:]...)
+				e.pos--
+			}
+		case keyDeleteForward:
+			e.deleteForward()
+		case keyLeft, keyCtrlB:
+			if e.pos > 0 {
+				e.pos--
+			}
+		case keyRight, keyCtrlF:
+			if e.pos < len(e.line) {
+				e.pos++
+			}
+		case keyHome, keyCtrlA:
+			e.pos = 0
+		case keyEnd, keyCtrlE:
+			e.pos = len(e.line)
+		case keyWordLeft:
+			e.pos = e.wordStart(e.pos)
+		case keyWordRight:
+			for e.pos < len(e.line) && !isWordRune(e.line[e.pos]) {
+				e.pos++
+			}
+			for e.pos < len(e.line) && isWordRune(e.line[e.pos]) {
+				e.pos++
+			}
+		case keyCtrlK:
+			e.line = e.line[:e.pos]
+		case keyCtrlU:
+			e.line = append([]rune{}, e.line[e.pos:]...)
+			e.pos = 0
+		case keyCtrlW:
+			start := e.wordStart(e.pos)
+			e.line = append(e.line[:start], e.line[e.pos:]...)
+			e.pos = start
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\033[H\033[2J")
+		case keyUp, keyCtrlP:
+			if historyPos > 0 {
+				if historyPos == len(e.history) {
+					draft = string(e.line)
+				}
+				historyPos--
+				e.setLine(e.history[historyPos])
+			}
+		case keyDown, keyCtrlN:
+			if historyPos < len(e.history) {
+				historyPos++
+				if historyPos == len(e.history) {
+					e.setLine(draft)
+				} else {
+					e.setLine(e.history[historyPos])
+				}
+			}
+		case keyCtrlR:
+			line, accepted := e.search()
+			e.setLine(line)
+			if accepted {
+				e.moveToEnd()
+				return line, nil
+			}
+		default:
+			if key >= ' ' && key <= unicode.MaxRune {
+				e.line = append(e.line[:e.pos], append([]rune{key}, e.line[e.pos:]...)...)
+				e.pos++
+			}
+		}
+		e.refresh()
+	}
+}
+
+// readKey reads one key, decoding the escape sequences of arrow, Home,
+// End and Delete keys, and Alt-b/Alt-f for word movement.
+func (e *lineEditor) readKey() (rune, error) {
+	r, _, err := e.reader.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+
+	next, _, err := e.reader.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	switch next {
+	case 'b':
+		return keyWordLeft, nil
+	case 'f':
+		return keyWordRight, nil
+	case '[', 'O':
+	default:
+		return keyUnknown, nil
+	}
+
+	// CSI sequence: parameters, then a final byte in @..~
+	var params []rune
+	for {
+		c, _, err := e.reader.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if c >= '@' && c <= '~' {
+			return decodeSequence(string(params), c), nil
+		}
+		params = append(params, c)
+	}
+}
+
+func decodeSequence(params string, final rune) rune {
+	switch final {
+	case 'A':
+		return keyUp
+	case 'B':
+		return keyDown
+	case 'C':
+		if strings.HasSuffix(params, ";5") || strings.HasSuffix(params, ";3") {
+			return keyWordRight
+		}
+		return keyRight
+	case 'D':
+		if strings.HasSuffix(params, ";5") || strings.HasSuffix(params, ";3") {
+			return keyWordLeft
+		}
+		return keyLeft
+	case 'H':
+		return keyHome
+	case 'F':
+		return keyEnd
+	case '~':
+		switch params {
+		case "1", "7":
+			return keyHome
+		case "4", "8":
+			return keyEnd
+		case "3":
+			return keyDeleteForward
+		}
+	}
+	return keyUnknown
+}
+
+func (e *lineEditor) deleteForward() {
+	if e.pos < len(e.line) {
+		e.line = append(e.line[:e.pos], e.line[e.pos+1:]...)
+	}
+}
+
+// wordStart returns the start of the word before pos, skipping spaces.
+func (e *lineEditor) wordStart(pos int) int {
+	for pos > 0 && !isWordRune(e.line[pos-1]) {
+		pos--
+	}
+	for pos > 0 && isWordRune(e.line[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (e *lineEditor) setLine(line string) {
+	e.line = []rune(line)
+	e.pos = len(e.line)
+}
+
+// refresh redraws the prompt and the line. Lines wider than the terminal
+// scroll horizontally to keep the cursor visible.
+func (e *lineEditor) refresh() {
+	promptWidth := len([]rune(e.prompt))
+	start, end := 0, len(e.line)
+	for start < e.pos && promptWidth+e.pos-start >= e.width {
+		start++
+	}
+	for end > e.pos && promptWidth+end-start >= e.width {
+		end--
+	}
+
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(e.prompt)
+	b.WriteString(string(e.line[start:end]))
+	b.WriteString("\033[K\r")
+	if col := promptWidth + e.pos - start; col > 0 {
+		fmt.Fprintf(&b, "\033[%dC", col)
+	}
+	fmt.Fprint(e.out, b.String())
+}
+
+// moveToEnd ends the edited line so that output starts on a new line.
+func (e *lineEditor) moveToEnd() {
+	e.pos = len(e.line)
+	e.refresh()
+	fmt.Fprint(e.out, "\r\n")
+}
+
+// completeWord completes the word before the cursor. A single candidate
+// replaces the word and several extend it to their common prefix; when
+// there is nothing to insert, the candidates and hints are listed.
+func (e *lineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	prefix := string(e.line[:e.pos])
+	start, candidates, hints := e.complete(prefix, len(prefix))
+	if start < 0 || start > len(prefix) {
+		return
+	}
+	word := prefix[start:]
+
+	if len(candidates) == 1 && len(hints) == 0 {
+		e.replaceWord(len([]rune(word)), candidates[0]+" ")
+		return
+	}
+	if common := commonPrefix(candidates); len(common) > len(word) && len(hints) == 0 {
+		e.replaceWord(len([]rune(word)), common)
+		return
+	}
+	if len(candidates)+len(hints) == 0 {
+		return
+	}
+
+	list := append(append([]string{}, candidates...), hints...)
+	fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(list, "  "))
+}
+
+// replaceWord replaces the n runes before the cursor with text.
+func (e *lineEditor) replaceWord(n int, text string) {
+	rest := append([]rune(text), e.line[e.pos:]...)
+	e.line = append(e.line[:e.pos-n], rest...)
+	e.pos += len([]rune(text)) - n
+}
+
+func commonPrefix(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
+
+// search runs a reverse incremental history search (Ctrl-R). Typing
+// narrows the search, Ctrl-R finds an older match, Enter runs the match and
+// any editing key leaves the search with the match in the line. Ctrl-G and
+// Ctrl-C cancel it.
+func (e *lineEditor) search() (string, bool) {
+	original := string(e.line)
+	query := []rune{}
+	match := ""
+	index := len(e.history)
+
+	find := func(from int) {
+		for i := from; i >= 0; i-- {
+			if i < len(e.history) && strings.Contains(e.history[i], string(query)) {
+				index, match = i, e.history[i]
+				return
+			}
+		}
+	}
+
+	for {
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\033[K", string(query), match)
+
+		key, err := e.readKey()
+		if err != nil {
+			return original, false
+		}
+		switch key {
+		case keyCtrlR:
+			find(index - 1)
+		case keyBackspace, keyDelete:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(e.history) - 1)
+			}
+		case keyEnter, '\n':
+			return match, true
+		case keyCtrlG, keyCtrlC:
+			return original, false
+		default:
+			if key >= ' ' && key <= unicode.MaxRune {
+				query = append(query, key)
+				find(index)
+				continue
+			}
+			return match, false
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Key sequences sent by terminals
+const (
+	up    = "\033[A"
+	down  = "\033[B"
+	right = "\033[C"
+	left  = "\033[D"
+	home  = "\033[H"
+	end   = "\033[F"
+	del   = "\033[3~"
+)
+
+// keyEditor returns an editor in raw mode that reads keys and renders to out.
+func keyEditor(keys string, out *bytes.Buffer, history ...string) *lineEditor {
+	e := newLineEditor(nil, out)
+	e.reader = bufio.NewReader(strings.NewReader(keys))
+	e.history = history
+	e.prompt, e.width = "> ", 80
+	return e
+}
+
+// edit sends keys to a new editor and returns the entered line and what
+// the editor rendered.
+func edit(t *testing.T, keys string, history ...string) (string, string) {
+	var out bytes.Buffer
+	line, err := keyEditor(keys, &out, history...).edit()
+	require.NoError(t, err)
+	return line, out.String()
+}
+
+func TestEditorCursorMovement(t *testing.T) {
+	tests := map[string]struct {
+		keys string
+		want string
+	}{
+		"insert in the middle":   {"print 12" + left + left + "+\r", "print +12"},
+		"home and end":           {"rint 1" + home + "p" + end + "2\r", "print 12"},
+		"ctrl-a and ctrl-e":      {"rint" + "\x01p\x05 3\r", "print 3"},
+		"ctrl-b and ctrl-f":      {"ab\x02\x02\x06-\r", "a-b"},
+		"backspace":              {"print 12\x7f3\r", "print 13"},
+		"delete forward":         {"print 12" + home + del + "P\r", "Print 12"},
+		"ctrl-d deletes forward": {"xprint" + home + "\x04\r", "print"},
+		"ctrl-k kills to end":    {"print 1 + 2" + left + left + left + left + "\x0b\r", "print 1"},
+		"ctrl-u kills to start":  {"junk print" + left + left + left + left + left + "\x15\r", "print"},
+		"ctrl-w kills a word":    {"print one two\x17\r", "print one "},
+		"alt-b and alt-f":        {"print 1" + "\033b\033b" + "x\033fy\r", "xprinty 1"},
+		"ctrl-arrows":            {"a b" + "\033[1;5D" + "c" + "\033[1;5C" + "d\r", "a cbd"},
+		"unknown keys":           {"a\033[Z\033xb\r", "ab"},
+		"right stops at the end": {"ab" + right + right + "c\r", "abc"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			line, _ := edit(t, tt.keys)
+			assert.Equal(t, tt.want, line)
+		})
+	}
+}
+
+func TestEditorRendering(t *testing.T) {
+	_, out := edit(t, "print 12"+left+left+"\r")
+
+	// Every key redraws the line and puts the cursor back in its column
+	assert.True(t, strings.HasPrefix(out, "\r> \033[K\r\033[2C"))
+	assert.Contains(t, out, "\r> print 12\033[K\r\033[8C")
+	assert.True(t, strings.HasSuffix(out, "\r> print 12\033[K\r\033[10C\r\n"), "enter moves to the end of the line")
+
+	// Lines wider than the terminal scroll to keep the cursor visible
+	var buf bytes.Buffer
+	e := keyEditor(strings.Repeat("x", 20)+"\r", &buf)
+	e.width = 10
+	_, err := e.edit()
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "\r> xxxxxxx\033[K\r\033[9C")
+}
+
+func TestEditorHistory(t *testing.T) {
+	history := []string{"print 1", "print 2"}
+
+	line, _ := edit(t, up+up+"\r", history...)
+	assert.Equal(t, "print 1", line)
+
+	line, _ = edit(t, "\x10\x10\x10\x0e\r", history...)
+	assert.Equal(t, "print 2", line, "ctrl-p stops at the oldest line")
+
+	line, out := edit(t, "draft"+up+down+"!\r", history...)
+	assert.Equal(t, "draft!", line, "going past the newest line restores the draft")
+	assert.Contains(t, out, "\r> print 2\033[K")
+
+	line, _ = edit(t, up+" + 1\r", history...)
+	assert.Equal(t, "print 2 + 1", line)
+}
+
+func TestEditorHistorySearch(t *testing.T) {
+	history := []string{"print 1", "let x", "print 22"}
+
+	line, out := edit(t, "\x12pr\r", history...)
+	assert.Equal(t, "print 22", line)
+	assert.Contains(t, out, "(reverse-i-search)`pr': print 22")
+
+	line, _ = edit(t, "\x12pr\x12\r", history...)
+	assert.Equal(t, "print 1", line, "ctrl-r again finds an older match")
+
+	line, _ = edit(t, "\x12print 2\x7f1\r", history...)
+	assert.Equal(t, "print 1", line, "backspace searches again from the newest line")
+
+	line, _ = edit(t, "\x12let"+right+"!\r", history...)
+	assert.Equal(t, "let x!", line, "an editing key leaves the search with the match")
+
+	line, _ = edit(t, "ab\x12zz\x07\r", history...)
+	assert.Equal(t, "ab", line, "ctrl-g cancels the search")
+}
+
+func TestEditorTabCompletion(t *testing.T) {
+	r := newTestREPL(t)
+	complete := func(keys string) (string, string) {
+		var out bytes.Buffer
+		e := keyEditor(keys, &out)
+		e.complete = r.complete
+		line, err := e.edit()
+		require.NoError(t, err)
+		return line, out.String()
+	}
+
+	line, _ := complete("pr\t1\r")
+	assert.Equal(t, "print 1", line, "a single candidate is inserted with a space")
+
+	line, _ = complete(".hi\t\r")
+	assert.Equal(t, ".history ", line)
+
+	line, _ = complete(".s\t\r")
+	assert.Equal(t, ".s", line)
+
+	// With hints, candidates are listed instead of inserted
+	line, out := complete("print 1 + ra\t\r")
+	assert.Equal(t, "print 1 + ra", line)
+	assert.Contains(t, out, "\r\nrate  <ID>\r\n")
+
+	// Completion replaces only the word before the cursor
+	line, _ = complete("pr 1" + left + left + "\t\r")
+	assert.Equal(t, "print  1", line)
+}
+
+func TestEditorEndOfInput(t *testing.T) {
+	var out bytes.Buffer
+	_, err := keyEditor("\x04", &out).edit()
+	assert.Equal(t, io.EOF, err, "ctrl-d on an empty line")
+
+	_, err = keyEditor("print", &out).edit()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = keyEditor("print\x03", &out).edit()
+	assert.Equal(t, errInterrupted, err)
+	assert.True(t, strings.HasSuffix(out.String(), "^C\r\n"))
+}
+
+func TestContinuation(t *testing.T) {
+	r := newTestREPL(t)
+	var out bytes.Buffer
+	r.out = &out
+	r.editor = scriptedEditor(t, "print (1 +\n2\n)\nprint (\n\nprint 3\n", &out)
+	r.run()
+
+	inputs := []string{}
+	for _, entry := range r.history {
+		inputs = append(inputs, entry.Input)
+	}
+	assert.Equal(t, []string{"print (1 +\n2\n)", "print (", "print 3"}, inputs,
+		"incomplete statements continue; an empty line runs them anyway")
+	assert.Contains(t, out.String(), continuationPrompt)
+	assert.Error(t, r.history[1].Error)
+}
@@ -0,0 +1,673 @@
+// Package repl provides an interactive Read-Eval-Print Loop for any DSL,
+// with line editing, grammar-aware Tab completion, persistent history and
+// a step-through parse debugger. Recorded sessions (transcripts) can be
+// replayed with Replay as golden-output tests.
+//
+// Run starts a session for a DSL built in Go, with its real actions, so a
+// custom REPL build is a few lines; it can offer several DSLs by name with
+// the registry package:
+//
+//	func main() {
+//	    registry.Register("http", func() *dslbuilder.DSL { return universal.NewHTTPDSLv3().GetDSL() })
+//	    dsl, err := registry.New("http")
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    if err := repl.Run(dsl, repl.Options{}); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+//
+// The cmd/repl command is the front end for grammars in YAML or JSON.
+package repl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// REPL is an interactive session for one DSL. Sessions are started with Run.
+type REPL struct {
+	dsl         *dslbuilder.DSL
+	history     []HistoryEntry
+	context     map[string]interface{}
+	multiline   bool
+	buffer      []string
+	showAST     bool
+	showTime    bool
+	historyFile string
+	editor      *lineEditor
+	out         io.Writer
+	breakpoints map[string]bool // Rule breakpoints for .debug
+	done        bool            // .exit was entered
+}
+
+// HistoryEntry records one executed command and its result.
+type HistoryEntry struct {
+	Index     int
+	Input     string
+	Output    interface{}
+	Error     error
+	Timestamp time.Time
+	Duration  time.Duration
+}
+
+// Prompts shown for new input and for the continuation lines of
+// incomplete input. Transcripts use them to mark inputs.
+const (
+	inputPrompt        = "DSL> "
+	continuationPrompt = "... "
+)
+
+// Options configures a REPL session.
+type Options struct {
+	History   string                 // History file: loaded for line editing and appended on exit
+	Context   map[string]interface{} // Initial context variables
+	ShowAST   bool                   // Show the AST of every result
+	ShowTime  bool                   // Show the execution time of every command
+	Multiline bool                   // Start in multiline mode (an empty line runs the input)
+	Exec      []string               // Commands and DSL input to run before the interactive session
+}
+
+// Run starts a REPL for a DSL on the standard input and output. It runs
+// the Exec commands first, then reads commands interactively unless Exec
+// was given and the input is not a terminal. It returns when the input
+// ends or on .exit, after appending the session to the history file.
+//
+// Example:
+//
+//	func main() {
+//	    if err := repl.Run(mydsl.New(), repl.Options{History: ".mydsl_history"}); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+func Run(dsl *dslbuilder.DSL, opts Options) error {
+	if dsl == nil {
+		return fmt.Errorf("repl: nil DSL")
+	}
+	r := newSession(dsl, opts)
+
+	if err := r.loadHistory(); err != nil {
+		return err
+	}
+
+	// Execute commands if provided
+	for _, cmd := range opts.Exec {
+		if r.done {
+			break
+		}
+		r.processInput(cmd)
+	}
+
+	// Start interactive mode if no commands or after executing commands
+	if !r.done && (len(opts.Exec) == 0 || isInteractive()) {
+		r.run()
+	}
+
+	return r.saveHistory()
+}
+
+// newSession creates a session for dsl that reads the standard input and
+// writes the standard output.
+func newSession(dsl *dslbuilder.DSL, opts Options) *REPL {
+	r := &REPL{
+		dsl:         dsl,
+		history:     []HistoryEntry{},
+		context:     make(map[string]interface{}),
+		multiline:   opts.Multiline,
+		buffer:      []string{},
+		showAST:     opts.ShowAST,
+		showTime:    opts.ShowTime,
+		historyFile: opts.History,
+		editor:      newLineEditor(os.Stdin, os.Stdout),
+		out:         os.Stdout,
+		breakpoints: make(map[string]bool),
+	}
+	r.editor.complete = r.complete
+	for k, v := range opts.Context {
+		r.context[k] = v
+	}
+	return r
+}
+
+func (r *REPL) run() {
+	fmt.Fprintf(r.out, "DSL REPL\n")
+	fmt.Fprintln(r.out, "Type '.help' for help, '.exit' to quit")
+	fmt.Fprintln(r.out)
+
+	for !r.done {
+		// Show prompt
+		prompt := inputPrompt
+		if len(r.buffer) > 0 {
+			prompt = continuationPrompt
+		}
+
+		// Read input
+		input, err := r.editor.readLine(prompt)
+		if err == errInterrupted {
+			// Ctrl-C discards the statement being entered
+			r.buffer = []string{}
+			continue
+		}
+		if err != nil {
+			break
+		}
+		r.editor.addHistory(input)
+
+		// Handle multiline mode
+		if r.multiline {
+			if input == "" && len(r.buffer) > 0 {
+				// Empty line ends multiline input
+				fullInput := strings.Join(r.buffer, "\n")
+				r.buffer = []string{}
+				r.execute(fullInput)
+			} else if input != "" {
+				r.buffer = append(r.buffer, input)
+			}
+			continue
+		}
+
+		// Continue statements that do not parse yet because they are
+		// incomplete; an empty line runs them anyway
+		if len(r.buffer) > 0 || !strings.HasPrefix(input, ".") && r.incomplete(input) {
+			if strings.TrimSpace(input) != "" {
+				r.buffer = append(r.buffer, input)
+			}
+			fullInput := strings.Join(r.buffer, "\n")
+			if strings.TrimSpace(input) == "" || !r.incomplete(fullInput) {
+				r.buffer = []string{}
+				r.execute(fullInput)
+			}
+			continue
+		}
+
+		// Process single line input
+		r.processInput(input)
+	}
+
+	fmt.Fprintln(r.out, "\nGoodbye!")
+}
+
+// incomplete reports whether input fails to parse only because it ends
+// too early: every token was consumed and the grammar expects more.
+func (r *REPL) incomplete(input string) bool {
+	if strings.TrimSpace(input) == "" {
+		return false
+	}
+	tree, err := r.dsl.ParseTree(input)
+	return err != nil && tree != nil && len(tree.Expected) > 0 &&
+		strings.TrimSpace(input[tree.ExpectedOffset:]) == ""
+}
+
+// complete offers REPL commands at the start of a line, and otherwise the
+// keywords, literals and context keys the grammar accepts at the cursor.
+// Tokens with varying text, such as numbers, are shown as hints.
+func (r *REPL) complete(line string, pos int) (int, []string, []string) {
+	if strings.HasPrefix(line, ".") && !strings.Contains(line[:pos], " ") {
+		commands := []string{}
+		for _, command := range replCommands {
+			if strings.HasPrefix(command, line[:pos]) {
+				commands = append(commands, command)
+			}
+		}
+		return 0, commands, nil
+	}
+
+	// Complete within the whole statement when continuing one
+	previous := ""
+	if len(r.buffer) > 0 {
+		previous = strings.Join(r.buffer, "\n") + "\n"
+	}
+	for k, v := range r.context {
+		r.dsl.SetContext(k, v)
+	}
+
+	start := pos
+	var candidates, hints []string
+	for _, c := range r.dsl.Complete(previous+line, len(previous)+pos) {
+		start = c.Start - len(previous)
+		if c.Kind == dslbuilder.CompletionToken {
+			hints = append(hints, "<"+c.Text+">")
+		} else {
+			candidates = append(candidates, c.Text)
+		}
+	}
+	return start, candidates, hints
+}
+
+func (r *REPL) processInput(input string) {
+	// Handle REPL commands
+	if strings.HasPrefix(input, ".") {
+		r.handleCommand(input)
+		return
+	}
+
+	// Execute DSL input
+	r.execute(input)
+}
+
+// replCommands lists the commands for Tab completion.
+var replCommands = []string{
+	".help", ".exit", ".quit", ".history", ".clear", ".context", ".set", ".load", ".save",
+	".ast", ".time", ".multiline", ".tokens", ".rules", ".debug", ".reset", ".last",
+}
+
+func (r *REPL) handleCommand(input string) {
+	parts := strings.Fields(input)
+	if len(parts) == 0 {
+		return
+	}
+
+	switch parts[0] {
+	case ".help":
+		r.showHelp()
+	case ".exit", ".quit":
+		r.done = true
+	case ".history":
+		r.showHistory()
+	case ".clear":
+		fmt.Fprint(r.out, "\033[H\033[2J")
+	case ".context":
+		r.showContext()
+	case ".set":
+		if len(parts) >= 3 {
+			r.setContext(parts[1], strings.Join(parts[2:], " "))
+		} else {
+			fmt.Fprintln(r.out, "Usage: .set <key> <value>")
+		}
+	case ".load":
+		if len(parts) >= 2 {
+			r.loadFile(parts[1])
+		} else {
+			fmt.Fprintln(r.out, "Usage: .load <filename>")
+		}
+	case ".save":
+		if len(parts) >= 2 {
+			r.saveHistoryToFile(parts[1])
+		} else {
+			fmt.Fprintln(r.out, "Usage: .save <filename>")
+		}
+	case ".ast":
+		if len(parts) >= 2 {
+			r.showAST = parts[1] == "on"
+			fmt.Fprintf(r.out, "AST display: %v\n", r.showAST)
+		} else {
+			fmt.Fprintf(r.out, "AST display: %v\n", r.showAST)
+		}
+	case ".time":
+		if len(parts) >= 2 {
+			r.showTime = parts[1] == "on"
+			fmt.Fprintf(r.out, "Time display: %v\n", r.showTime)
+		} else {
+			fmt.Fprintf(r.out, "Time display: %v\n", r.showTime)
+		}
+	case ".multiline":
+		r.multiline = !r.multiline
+		fmt.Fprintf(r.out, "Multiline mode: %v\n", r.multiline)
+		if r.multiline {
+			fmt.Fprintln(r.out, "Enter empty line to execute")
+		}
+	case ".tokens":
+		r.showTokens()
+	case ".rules":
+		r.showRules()
+	case ".debug":
+		r.debug(strings.TrimSpace(strings.TrimPrefix(input, ".debug")))
+	case ".reset":
+		r.context = make(map[string]interface{})
+		r.buffer = []string{}
+		fmt.Fprintln(r.out, "Context and buffer reset")
+	case ".last":
+		if len(r.history) > 0 {
+			last := r.history[len(r.history)-1]
+			fmt.Fprintf(r.out, "Last command: %s\n", last.Input)
+			if last.Error == nil && last.Output != nil {
+				fmt.Fprintf(r.out, "Result: %v\n", last.Output)
+			}
+		} else {
+			fmt.Fprintln(r.out, "No history available")
+		}
+	default:
+		fmt.Fprintf(r.out, "Unknown command: %s\n", parts[0])
+		fmt.Fprintln(r.out, "Type .help for available commands")
+	}
+}
+
+func (r *REPL) execute(input string) {
+	if strings.TrimSpace(input) == "" {
+		return
+	}
+
+	start := time.Now()
+
+	// Parse with context
+	result, err := r.dsl.Use(input, r.context)
+
+	duration := time.Since(start)
+
+	// Record in history
+	entry := HistoryEntry{
+		Index:     len(r.history) + 1,
+		Input:     input,
+		Output:    nil,
+		Error:     err,
+		Timestamp: start,
+		Duration:  duration,
+	}
+
+	if err != nil {
+		fmt.Fprintf(r.out, "\033[31mError: %v\033[0m\n", err) // Red color for errors
+
+		// Try to provide helpful suggestions
+		if dslbuilder.IsParseError(err) || strings.Contains(err.Error(), "unexpected token") {
+			r.suggestTokens(input)
+		} else if strings.Contains(err.Error(), "no matching rule") {
+			fmt.Fprintln(r.out, "\033[33mHint: Check available rules with .rules command\033[0m")
+		}
+	} else {
+		output := result.GetOutput()
+		entry.Output = output
+
+		// Display result with better formatting
+		r.displayOutput(output)
+
+		// Show AST if enabled
+		if r.showAST {
+			r.displayAST(result)
+		}
+	}
+
+	// Show execution time if enabled
+	if r.showTime {
+		fmt.Fprintf(r.out, "⏱  %v\n", duration)
+	}
+
+	r.history = append(r.history, entry)
+}
+
+func (r *REPL) displayAST(result interface{}) {
+	fmt.Fprintln(r.out, "\n--- AST ---")
+	// Simplified AST display
+	data, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Fprintln(r.out, string(data))
+	fmt.Fprintln(r.out, "--- End AST ---")
+	fmt.Fprintln(r.out)
+}
+
+func (r *REPL) showHistory() {
+	if len(r.history) == 0 {
+		fmt.Fprintln(r.out, "No history")
+		return
+	}
+
+	for _, entry := range r.history {
+		fmt.Fprintf(r.out, "[%d] %s", entry.Index, entry.Input)
+		if entry.Error != nil {
+			fmt.Fprintf(r.out, " => Error: %v", entry.Error)
+		} else if entry.Output != nil {
+			fmt.Fprintf(r.out, " => %v", entry.Output)
+		}
+		fmt.Fprintln(r.out)
+	}
+}
+
+func (r *REPL) showContext() {
+	if len(r.context) == 0 {
+		fmt.Fprintln(r.out, "Context is empty")
+		return
+	}
+
+	keys := make([]string, 0, len(r.context))
+	for k := range r.context {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintln(r.out, "Current context:")
+	for _, k := range keys {
+		fmt.Fprintf(r.out, "  %s: %v\n", k, r.context[k])
+	}
+}
+
+func (r *REPL) setContext(key, value string) {
+	// Try to parse value as JSON first
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(value), &parsed); err == nil {
+		r.context[key] = parsed
+	} else {
+		// Otherwise store as string
+		r.context[key] = value
+	}
+	fmt.Fprintf(r.out, "Set %s = %v\n", key, r.context[key])
+}
+
+func (r *REPL) loadFile(filename string) {
+	file, err := os.Open(filename)
+	if err != nil {
+		fmt.Fprintf(r.out, "Error loading file: %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fmt.Fprintf(r.out, "[%s:%d] %s\n", filename, lineNum, line)
+		r.execute(line)
+	}
+}
+
+func (r *REPL) saveHistoryToFile(filename string) {
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Fprintf(r.out, "Error saving history: %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	r.writeHistory(file)
+	fmt.Fprintf(r.out, "History saved to %s\n", filename)
+}
+
+// writeHistory writes the history entries with their results as comments,
+// in the format read by loadHistory and .load.
+func (r *REPL) writeHistory(w io.Writer) {
+	for _, entry := range r.history {
+		fmt.Fprintf(w, "# %s\n", entry.Timestamp.Format(time.RFC3339))
+		fmt.Fprintf(w, "%s\n", entry.Input)
+		if entry.Error != nil {
+			fmt.Fprintf(w, "# Error: %v\n", entry.Error)
+		} else if entry.Output != nil {
+			fmt.Fprintf(w, "# => %v\n", entry.Output)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// loadHistory fills the line editor history from the history file, so
+// the up arrow and Ctrl-R reach the commands of earlier sessions.
+func (r *REPL) loadHistory() error {
+	if r.historyFile == "" {
+		return nil
+	}
+	file, err := os.Open(r.historyFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("loading history: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		r.editor.addHistory(line)
+	}
+	return scanner.Err()
+}
+
+// saveHistory appends the commands of this session to the history file.
+func (r *REPL) saveHistory() error {
+	if r.historyFile == "" || len(r.history) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(r.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("saving history: %w", err)
+	}
+	defer file.Close()
+
+	r.writeHistory(file)
+	return nil
+}
+
+func isInteractive() bool {
+	fi, _ := os.Stdin.Stat()
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (r *REPL) displayOutput(output interface{}) {
+	switch v := output.(type) {
+	case string:
+		fmt.Fprintln(r.out, v)
+	case int, int64, float64:
+		fmt.Fprintf(r.out, "\033[36m%v\033[0m\n", v) // Cyan for numbers
+	case bool:
+		fmt.Fprintf(r.out, "\033[35m%v\033[0m\n", v) // Magenta for booleans
+	case []interface{}:
+		if len(v) == 0 {
+			fmt.Fprintln(r.out, "[]")
+		} else {
+			fmt.Fprintln(r.out, "[")
+			for i, item := range v {
+				fmt.Fprintf(r.out, "  [%d] %v\n", i, item)
+			}
+			fmt.Fprintln(r.out, "]")
+		}
+	case map[string]interface{}:
+		data, _ := json.MarshalIndent(v, "", "  ")
+		fmt.Fprintln(r.out, string(data))
+	case nil:
+		fmt.Fprintln(r.out, "\033[90mnil\033[0m") // Gray for nil
+	default:
+		// Try JSON for complex types
+		if data, err := json.MarshalIndent(output, "", "  "); err == nil {
+			fmt.Fprintln(r.out, string(data))
+		} else {
+			fmt.Fprintf(r.out, "%v\n", output)
+		}
+	}
+}
+
+// suggestTokens lists what the grammar expected where parsing got
+// farthest, or all tokens when that is unknown.
+func (r *REPL) suggestTokens(input string) {
+	offset := len(input)
+	if tree, _ := r.dsl.ParseTree(input); tree != nil {
+		offset = tree.ExpectedOffset
+	}
+
+	suggestions := []string{}
+	for _, c := range r.dsl.Complete(input, offset) {
+		if c.Kind == dslbuilder.CompletionToken {
+			suggestions = append(suggestions, c.Text)
+		} else {
+			suggestions = append(suggestions, fmt.Sprintf("%q", c.Text))
+		}
+	}
+	if len(suggestions) > 0 {
+		fmt.Fprintf(r.out, "\033[33mHint: Expected %s\033[0m\n", strings.Join(suggestions, ", "))
+		return
+	}
+
+	names := []string{}
+	for _, token := range r.dsl.Tokens() {
+		names = append(names, token.Name)
+	}
+	fmt.Fprintf(r.out, "\033[33mHint: Available tokens: %s (use .tokens for patterns)\033[0m\n", strings.Join(names, ", "))
+}
+
+func (r *REPL) showTokens() {
+	fmt.Fprintln(r.out, "\033[1mAvailable Tokens:\033[0m")
+	tokens := r.dsl.Tokens()
+	if len(tokens) == 0 {
+		fmt.Fprintln(r.out, "  No tokens defined")
+		return
+	}
+	for _, token := range tokens {
+		if token.IsKeyword() {
+			fmt.Fprintf(r.out, "  \033[32m%-16s\033[0m keyword %q\n", token.Name, token.Keyword)
+		} else {
+			fmt.Fprintf(r.out, "  \033[32m%-16s\033[0m %s\n", token.Name, token.Pattern)
+		}
+	}
+}
+
+func (r *REPL) showRules() {
+	fmt.Fprintln(r.out, "\033[1mAvailable Rules:\033[0m")
+	rules := r.dsl.Rules()
+	if len(rules) == 0 {
+		fmt.Fprintln(r.out, "  No rules defined")
+		return
+	}
+	for _, rule := range rules {
+		marker := ""
+		if rule.Name == r.dsl.StartRule() {
+			marker = " (start)"
+		}
+		fmt.Fprintf(r.out, "  \033[32m%s\033[0m%s\n", rule.Name, marker)
+		for _, alt := range rule.Alternatives {
+			sequence := strings.Join(alt.Sequence, " ")
+			if sequence == "" {
+				sequence = "ε"
+			}
+			if alt.Action != "" {
+				fmt.Fprintf(r.out, "    → %s \033[35m{%s}\033[0m\n", sequence, alt.Action)
+			} else {
+				fmt.Fprintf(r.out, "    → %s\n", sequence)
+			}
+		}
+	}
+}
+
+func (r *REPL) showHelp() {
+	fmt.Fprintln(r.out, "\033[1mREPL Commands:\033[0m")
+	fmt.Fprintln(r.out, "  \033[32m.help\033[0m         Show this help message")
+	fmt.Fprintln(r.out, "  \033[32m.exit\033[0m         Exit the REPL")
+	fmt.Fprintln(r.out, "  \033[32m.history\033[0m      Show command history")
+	fmt.Fprintln(r.out, "  \033[32m.clear\033[0m        Clear the screen")
+	fmt.Fprintln(r.out, "  \033[32m.context\033[0m      Show current context variables")
+	fmt.Fprintln(r.out, "  \033[32m.set k v\033[0m      Set context variable k to value v")
+	fmt.Fprintln(r.out, "  \033[32m.load file\033[0m    Load and execute commands from file")
+	fmt.Fprintln(r.out, "  \033[32m.save file\033[0m    Save history to file")
+	fmt.Fprintln(r.out, "  \033[32m.ast on/off\033[0m   Toggle AST display")
+	fmt.Fprintln(r.out, "  \033[32m.time on/off\033[0m  Toggle execution time display")
+	fmt.Fprintln(r.out, "  \033[32m.multiline\033[0m    Toggle multiline input mode")
+	fmt.Fprintln(r.out, "  \033[32m.tokens\033[0m       Show available tokens")
+	fmt.Fprintln(r.out, "  \033[32m.rules\033[0m        Show available rules")
+	fmt.Fprintln(r.out, "  \033[32m.reset\033[0m        Reset context and buffer")
+	fmt.Fprintln(r.out, "  \033[32m.last\033[0m         Show last command and result")
+	fmt.Fprintln(r.out, "  \033[32m.debug input\033[0m  Step through the parse of input (step/next/continue/break rule=x)")
+	fmt.Fprintln(r.out)
+	fmt.Fprintln(r.out, "\033[1mDSL Syntax:\033[0m")
+	fmt.Fprintln(r.out, "  Enter DSL commands directly")
+	fmt.Fprintln(r.out, "  Use context variables with .set command")
+	fmt.Fprintln(r.out, "  Check your DSL configuration for available syntax")
+}
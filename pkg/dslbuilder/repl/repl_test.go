@@ -0,0 +1,135 @@
+package repl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestREPL(t *testing.T) *REPL {
+	dsl := dslbuilder.New("calc")
+	require.NoError(t, dsl.KeywordToken("PRINT", "print"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("ID", "[a-z]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("LP", "\\("))
+	require.NoError(t, dsl.Token("RP", "\\)"))
+	dsl.Rule("stmt", []string{"PRINT", "expr"}, "")
+	dsl.Rule("expr", []string{"expr", "PLUS", "value"}, "")
+	dsl.Rule("expr", []string{"value"}, "")
+	dsl.Rule("value", []string{"NUMBER"}, "")
+	dsl.Rule("value", []string{"ID"}, "")
+	dsl.Rule("value", []string{"LP", "expr", "RP"}, "")
+
+	return &REPL{
+		dsl:         dsl,
+		context:     map[string]interface{}{"rate": 2},
+		editor:      newLineEditor(os.Stdin, os.Stdout),
+		breakpoints: make(map[string]bool),
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	r := newTestREPL(t)
+	assert.True(t, r.incomplete("print (1 +"))
+	assert.True(t, r.incomplete("print"))
+	assert.False(t, r.incomplete("print 1"))
+	assert.False(t, r.incomplete("print ) 1"), "errors before the end are not continued")
+	assert.False(t, r.incomplete(""))
+}
+
+func TestComplete(t *testing.T) {
+	r := newTestREPL(t)
+
+	start, candidates, hints := r.complete("pr", 2)
+	assert.Equal(t, 0, start)
+	assert.Equal(t, []string{"print"}, candidates)
+	assert.Empty(t, hints)
+
+	start, candidates, hints = r.complete("print 1 + ra", 12)
+	assert.Equal(t, 10, start)
+	assert.Equal(t, []string{"rate"}, candidates)
+	assert.Equal(t, []string{"<ID>"}, hints)
+
+	start, candidates, _ = r.complete(".hi", 3)
+	assert.Equal(t, 0, start)
+	assert.Equal(t, []string{".history"}, candidates)
+
+	// Continuation lines complete within the whole statement
+	r.buffer = []string{"print (1"}
+	_, candidates, _ = r.complete("", 0)
+	assert.Equal(t, []string{"+", ")"}, candidates)
+}
+
+func TestHistoryFile(t *testing.T) {
+	r := newTestREPL(t)
+	r.historyFile = filepath.Join(t.TempDir(), "history")
+
+	require.NoError(t, r.loadHistory(), "a missing history file is not an error")
+	r.history = []HistoryEntry{{Index: 1, Input: "print 1", Output: 1.0}, {Index: 2, Input: "print (1 +\n2)"}}
+	require.NoError(t, r.saveHistory())
+	require.NoError(t, r.saveHistory())
+
+	next := newTestREPL(t)
+	next.historyFile = r.historyFile
+	require.NoError(t, next.loadHistory())
+	assert.Equal(t, []string{"print 1", "print (1 +", "2)", "print 1", "print (1 +", "2)"}, next.editor.history)
+}
+
+func TestCommonPrefix(t *testing.T) {
+	assert.Equal(t, "sel", commonPrefix([]string{"select", "selection", "sell"}))
+	assert.Equal(t, "", commonPrefix([]string{"é", "è"}), "never splits a UTF-8 sequence")
+	assert.Equal(t, "", commonPrefix(nil))
+}
+
+func TestReplay(t *testing.T) {
+	dsl := dslbuilder.New("printer")
+	require.NoError(t, dsl.KeywordToken("PRINT", "print"))
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	require.NoError(t, dsl.Token("LP", "\\("))
+	require.NoError(t, dsl.Token("RP", "\\)"))
+	dsl.Rule("stmt", []string{"PRINT", "expr"}, "show")
+	dsl.Rule("expr", []string{"LP", "NUMBER", "PLUS", "NUMBER", "RP"}, "")
+	dsl.Rule("expr", []string{"NUMBER"}, "")
+	dsl.Action("show", func(args []interface{}) (interface{}, error) {
+		return "printed", nil
+	})
+
+	transcript := `# A session
+DSL> print 1
+
+DSL> print (1 +
+... 2)
+stale output
+# Not a comment: more output follows
+stale
+DSL> print )
+DSL> .exit
+DSL> print 2
+unused
+`
+	got, err := Replay(dsl, Options{}, transcript)
+	require.NoError(t, err)
+	assert.Equal(t, `# A session
+DSL> print 1
+printed
+
+DSL> print (1 +
+... 2)
+printed
+DSL> print )
+Error: no alternative matched for rule stmt
+Hint: Expected "(", NUMBER
+DSL> .exit
+DSL> print 2
+`, got)
+
+	again, err := Replay(dsl, Options{}, got)
+	require.NoError(t, err)
+	assert.Equal(t, got, again, "a replayed transcript passes")
+}
@@ -0,0 +1,19 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package repl
+
+import "errors"
+
+// isTerminal reports false: line editing is only supported on Unix
+// terminals, so input is read line by line instead.
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw terminal mode not supported on this platform")
+}
+
+func terminalWidth(fd int) int {
+	return 80
+}
@@ -0,0 +1,156 @@
+package repl
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// A transcript is a recorded REPL session that doubles as a golden test:
+//
+//	# Comments and blank lines between entries are kept as they are
+//	DSL> .set rate 2
+//	Set rate = 2
+//	DSL> print (1 +
+//	... rate)
+//	3
+//
+// Lines starting with the prompt "DSL> " are inputs, and lines starting
+// with "... " continue them. The lines up to the next input are the output
+// expected from it, without colors. Comment and blank lines right before
+// an input belong to it rather than to the previous output.
+
+// transcriptEntry is one input of a transcript and the lines around it.
+type transcriptEntry struct {
+	comments []string // Comment and blank lines before the input
+	input    []string // Input lines, without prompts
+	output   []string // Output lines
+}
+
+type transcript struct {
+	header  []string // Lines before the first input
+	entries []transcriptEntry
+	trailer []string // Comment and blank lines after the last output
+}
+
+// parseTranscript splits a transcript into entries.
+func parseTranscript(text string) *transcript {
+	t := &transcript{}
+	var pending []string
+	var lines []string
+	if text != "" {
+		lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if input, ok := cutPrompt(line, inputPrompt); ok {
+			t.entries = append(t.entries, transcriptEntry{comments: pending, input: []string{input}})
+			pending = nil
+			continue
+		}
+		if len(t.entries) == 0 {
+			t.header = append(t.header, line)
+			continue
+		}
+
+		e := &t.entries[len(t.entries)-1]
+		input, ok := cutPrompt(line, continuationPrompt)
+		switch {
+		case ok && len(e.output) == 0 && len(pending) == 0:
+			e.input = append(e.input, input)
+		case line == "" || strings.HasPrefix(line, "#"):
+			pending = append(pending, line)
+		default:
+			// Blank and comment lines followed by more output are output
+			e.output = append(e.output, pending...)
+			e.output = append(e.output, line)
+			pending = nil
+		}
+	}
+	t.trailer = pending
+	return t
+}
+
+// cutPrompt returns the input after a prompt. The trailing space of the
+// prompt is optional, since editors strip it from lines without input.
+func cutPrompt(line, prompt string) (string, bool) {
+	if input, ok := strings.CutPrefix(line, prompt); ok {
+		return input, true
+	}
+	return "", line == strings.TrimRight(prompt, " ")
+}
+
+// String renders the transcript in its canonical form.
+func (t *transcript) String() string {
+	var lines []string
+	lines = append(lines, t.header...)
+	for _, e := range t.entries {
+		lines = append(lines, e.comments...)
+		for i, input := range e.input {
+			prompt := inputPrompt
+			if i > 0 {
+				prompt = continuationPrompt
+			}
+			lines = append(lines, strings.TrimRight(prompt+input, " "))
+		}
+		lines = append(lines, e.output...)
+	}
+	lines = append(lines, t.trailer...)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// ansiEscape matches the color codes of the REPL output.
+var ansiEscape = regexp.MustCompile("\033\\[[0-9;]*[A-Za-z]")
+
+// Replay runs the inputs of a transcript in a new session and returns the
+// transcript with the outputs it actually produced. A transcript passes
+// when Replay returns it unchanged; writing the result back updates its
+// golden outputs. The session starts with the context of opts and does not
+// use its history file or Exec commands.
+//
+// Example:
+//
+//	want, _ := os.ReadFile("testdata/calc.transcript")
+//	got, err := repl.Replay(calc.New(), repl.Options{}, string(want))
+//	if got != string(want) { ... }
+func Replay(dsl *dslbuilder.DSL, opts Options, text string) (string, error) {
+	if dsl == nil {
+		return "", fmt.Errorf("repl: nil DSL")
+	}
+	t := parseTranscript(text)
+
+	r := newSession(dsl, opts)
+	var out bytes.Buffer
+	r.out = &out
+	r.editor = newLineEditor(nil, &out)
+	for i := range t.entries {
+		e := &t.entries[i]
+		out.Reset()
+		if !r.done {
+			r.processInput(strings.Join(e.input, "\n"))
+		}
+		e.output = outputLines(out.String())
+	}
+	return t.String(), nil
+}
+
+// outputLines splits output into lines without colors and trailing
+// blank lines.
+func outputLines(output string) []string {
+	output = ansiEscape.ReplaceAllString(output, "")
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,123 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// Chrome writes events in the Chrome trace-event format. Rules become
+// duration slices; tokens, actions, failed alternatives and memo hits become
+// instant events. Call Close after parsing to terminate the JSON array.
+//
+// Example:
+//
+//	f, _ := os.Create("parse.json")
+//	tracer := trace.NewChrome(f)
+//	dsl.SetTracer(tracer)
+//	dsl.Parse(input)
+//	tracer.Close()
+type Chrome struct {
+	w      io.Writer
+	start  time.Time
+	events int
+	err    error
+}
+
+// chromeEvent is one entry of the trace-event JSON array.
+type chromeEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat"`
+	Phase     string                 `json:"ph"`
+	Timestamp float64                `json:"ts"` // Microseconds since the first event
+	PID       int                    `json:"pid"`
+	TID       int                    `json:"tid"`
+	Scope     string                 `json:"s,omitempty"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+// NewChrome creates a Chrome trace writer.
+func NewChrome(w io.Writer) *Chrome {
+	return &Chrome{w: w}
+}
+
+// Event implements dslbuilder.Tracer.
+func (c *Chrome) Event(e dslbuilder.TraceEvent) {
+	if c.start.IsZero() {
+		c.start = e.Time
+	}
+	ce := chromeEvent{
+		PID:       1,
+		TID:       1,
+		Timestamp: float64(e.Time.Sub(c.start).Nanoseconds()) / 1000,
+		Args:      map[string]interface{}{"pos": e.Pos},
+	}
+
+	switch e.Kind {
+	case dslbuilder.TraceRuleEnter:
+		ce.Name, ce.Category, ce.Phase = e.Rule, "rule", "B"
+	case dslbuilder.TraceRuleExit:
+		ce.Name, ce.Category, ce.Phase = e.Rule, "rule", "E"
+		ce.Args["end"] = e.End
+		if e.Err != nil {
+			ce.Args["error"] = e.Err.Error()
+		}
+	case dslbuilder.TraceAlternativeFail:
+		ce.Name, ce.Category = fmt.Sprintf("fail %s[%d]", e.Rule, e.Alternative), "backtrack"
+		ce.Args["error"] = e.Err.Error()
+	case dslbuilder.TraceMemoHit:
+		ce.Name, ce.Category = "memo "+e.Rule, "memo"
+		ce.Args["end"] = e.End
+	case dslbuilder.TraceTokenConsumed:
+		ce.Name, ce.Category = e.Token.TokenType, "token"
+		ce.Args["value"] = e.Token.Value
+	case dslbuilder.TraceActionInvoked:
+		ce.Name, ce.Category = e.Action, "action"
+		if e.Err != nil {
+			ce.Args["error"] = e.Err.Error()
+		}
+	default:
+		// Alternative attempts and memo misses would only add noise
+		// next to the rule slices
+		return
+	}
+	if ce.Phase == "" {
+		ce.Phase, ce.Scope = "i", "t"
+	}
+	c.write(ce)
+}
+
+func (c *Chrome) write(ce chromeEvent) {
+	if c.err != nil {
+		return
+	}
+	data, err := json.Marshal(ce)
+	if err != nil {
+		c.err = err
+		return
+	}
+	prefix := ",\n"
+	if c.events == 0 {
+		prefix = "[\n"
+	}
+	c.events++
+	_, c.err = fmt.Fprintf(c.w, "%s%s", prefix, data)
+}
+
+// Close terminates the JSON array and returns the first write error.
+// The Chrome writer must not be used after Close.
+func (c *Chrome) Close() error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.events == 0 {
+		_, c.err = io.WriteString(c.w, "[")
+	}
+	if c.err == nil {
+		_, c.err = io.WriteString(c.w, "\n]\n")
+	}
+	return c.err
+}
@@ -0,0 +1,82 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// Printer writes one line per event, indented by rule depth:
+//
+//	enter expr @0
+//	  try expr[1]: term
+//	  enter term @0
+//	    try term[0]: NUMBER
+//	    token NUMBER "1" @0
+//	    action number
+//	  exit term @0..1
+//	exit expr @0..1
+//
+// Positions are token indexes.
+type Printer struct {
+	w    io.Writer
+	Memo bool // Also print memo hits and misses
+}
+
+// NewPrinter creates a printer writing to w.
+func NewPrinter(w io.Writer) *Printer {
+	return &Printer{w: w}
+}
+
+// Event implements dslbuilder.Tracer.
+func (p *Printer) Event(e dslbuilder.TraceEvent) {
+	line := p.format(e)
+	if line == "" {
+		return
+	}
+	fmt.Fprintf(p.w, "%s%s\n", strings.Repeat("  ", e.Depth), line)
+}
+
+func (p *Printer) format(e dslbuilder.TraceEvent) string {
+	switch e.Kind {
+	case dslbuilder.TraceRuleEnter:
+		return fmt.Sprintf("enter %s @%d", e.Rule, e.Pos)
+	case dslbuilder.TraceRuleExit:
+		if e.Err != nil {
+			return fmt.Sprintf("exit %s @%d failed: %v", e.Rule, e.Pos, firstLine(e.Err))
+		}
+		return fmt.Sprintf("exit %s @%d..%d", e.Rule, e.Pos, e.End)
+	case dslbuilder.TraceAlternativeTry:
+		return fmt.Sprintf("try %s[%d]: %s", e.Rule, e.Alternative, strings.Join(e.Sequence, " "))
+	case dslbuilder.TraceAlternativeFail:
+		return fmt.Sprintf("fail %s[%d]: %v", e.Rule, e.Alternative, firstLine(e.Err))
+	case dslbuilder.TraceMemoHit:
+		if p.Memo {
+			return fmt.Sprintf("memo hit %s @%d..%d", e.Rule, e.Pos, e.End)
+		}
+	case dslbuilder.TraceMemoMiss:
+		if p.Memo {
+			return fmt.Sprintf("memo miss %s @%d", e.Rule, e.Pos)
+		}
+	case dslbuilder.TraceTokenConsumed:
+		return fmt.Sprintf("token %s %q @%d", e.Token.TokenType, e.Token.Value, e.Pos)
+	case dslbuilder.TraceActionInvoked:
+		if e.Err != nil {
+			return fmt.Sprintf("action %s failed: %v", e.Action, firstLine(e.Err))
+		}
+		return "action " + e.Action
+	}
+	return ""
+}
+
+// firstLine keeps traces on one line per event; action errors may span
+// several lines.
+func firstLine(err error) string {
+	msg := err.Error()
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		return msg[:i]
+	}
+	return msg
+}
@@ -0,0 +1,130 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+)
+
+// RuleProfile aggregates the cost of one rule over all traced parses.
+type RuleProfile struct {
+	Rule       string
+	Calls      int           // Rule entries (memo misses)
+	Failures   int           // Entries that did not match
+	MemoHits   int           // Results reused from the memo table
+	Backtracks int           // Alternatives that failed
+	Tokens     int           // Tokens consumed directly by the rule
+	Total      time.Duration // Time inside the rule, including nested rules
+	Self       time.Duration // Time inside the rule, excluding nested rules
+}
+
+// Profiler aggregates per-rule time and backtrack counts. It can be reused
+// across parses; call Reset to start over.
+type Profiler struct {
+	rules map[string]*RuleProfile
+	stack []profileFrame
+}
+
+type profileFrame struct {
+	rule     string
+	start    time.Time
+	children time.Duration
+}
+
+// NewProfiler creates an empty profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{rules: make(map[string]*RuleProfile)}
+}
+
+// Reset discards all collected data.
+func (p *Profiler) Reset() {
+	p.rules = make(map[string]*RuleProfile)
+	p.stack = nil
+}
+
+// Event implements dslbuilder.Tracer.
+func (p *Profiler) Event(e dslbuilder.TraceEvent) {
+	switch e.Kind {
+	case dslbuilder.TraceRuleEnter:
+		p.rule(e.Rule).Calls++
+		p.stack = append(p.stack, profileFrame{rule: e.Rule, start: e.Time})
+	case dslbuilder.TraceRuleExit:
+		if len(p.stack) == 0 {
+			return
+		}
+		frame := p.stack[len(p.stack)-1]
+		p.stack = p.stack[:len(p.stack)-1]
+		elapsed := e.Time.Sub(frame.start)
+
+		profile := p.rule(frame.rule)
+		// Recursive calls are already counted by the outermost frame
+		if !p.onStack(frame.rule) {
+			profile.Total += elapsed
+		}
+		profile.Self += elapsed - frame.children
+		if e.Err != nil {
+			profile.Failures++
+		}
+		if len(p.stack) > 0 {
+			p.stack[len(p.stack)-1].children += elapsed
+		}
+	case dslbuilder.TraceMemoHit:
+		p.rule(e.Rule).MemoHits++
+	case dslbuilder.TraceAlternativeFail:
+		p.rule(e.Rule).Backtracks++
+	case dslbuilder.TraceTokenConsumed:
+		p.rule(e.Rule).Tokens++
+	}
+}
+
+func (p *Profiler) rule(name string) *RuleProfile {
+	profile, ok := p.rules[name]
+	if !ok {
+		profile = &RuleProfile{Rule: name}
+		p.rules[name] = profile
+	}
+	return profile
+}
+
+func (p *Profiler) onStack(rule string) bool {
+	for _, frame := range p.stack {
+		if frame.rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// Profile returns the collected rule profiles, most expensive first by
+// self time, then by name.
+func (p *Profiler) Profile() []RuleProfile {
+	profiles := make([]RuleProfile, 0, len(p.rules))
+	for _, profile := range p.rules {
+		profiles = append(profiles, *profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].Self != profiles[j].Self {
+			return profiles[i].Self > profiles[j].Self
+		}
+		return profiles[i].Rule < profiles[j].Rule
+	})
+	return profiles
+}
+
+// WriteText writes the profile as an aligned table.
+func (p *Profiler) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-20s %8s %8s %8s %10s %8s %12s %12s\n",
+		"RULE", "CALLS", "FAILS", "MEMO", "BACKTRACK", "TOKENS", "TOTAL", "SELF")
+	for _, profile := range p.Profile() {
+		fmt.Fprintf(&b, "%-20s %8d %8d %8d %10d %8d %12s %12s\n",
+			profile.Rule, profile.Calls, profile.Failures, profile.MemoHits,
+			profile.Backtracks, profile.Tokens, profile.Total, profile.Self)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
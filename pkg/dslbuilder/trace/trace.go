@@ -0,0 +1,26 @@
+// Package trace provides built-in tracers for dslbuilder parsers.
+//
+//   - Printer writes an indented, human readable trace of a parse.
+//   - Chrome writes Chrome trace-event JSON, viewable in chrome://tracing
+//     or https://ui.perfetto.dev.
+//   - Profiler aggregates time, calls and backtracks per rule to find
+//     expensive rules.
+//
+// Example:
+//
+//	profiler := trace.NewProfiler()
+//	dsl.SetTracer(trace.Multi(trace.NewPrinter(os.Stderr), profiler))
+//	dsl.Parse("1 + 2 * 3")
+//	profiler.WriteText(os.Stdout)
+package trace
+
+import "github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+
+// Multi returns a tracer that forwards every event to all tracers in order.
+func Multi(tracers ...dslbuilder.Tracer) dslbuilder.Tracer {
+	return dslbuilder.TracerFunc(func(e dslbuilder.TraceEvent) {
+		for _, t := range tracers {
+			t.Event(e)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/arturoeanton/go-dsl/pkg/dslbuilder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCalculator(t *testing.T) *dslbuilder.DSL {
+	dsl := dslbuilder.New("calculator")
+	require.NoError(t, dsl.Token("NUMBER", "[0-9]+"))
+	require.NoError(t, dsl.Token("PLUS", "\\+"))
+	dsl.Rule("expr", []string{"term", "PLUS", "expr"}, "add")
+	dsl.Rule("expr", []string{"term"}, "pass")
+	dsl.Rule("term", []string{"NUMBER"}, "pass")
+	dsl.Action("add", func(args []interface{}) (interface{}, error) { return args[0], nil })
+	dsl.Action("pass", func(args []interface{}) (interface{}, error) { return args[0], nil })
+	return dsl
+}
+
+func TestPrinter(t *testing.T) {
+	dsl := newCalculator(t)
+	var out bytes.Buffer
+	dsl.SetTracer(NewPrinter(&out))
+
+	_, err := dsl.Parse("1")
+	require.NoError(t, err)
+	assert.Equal(t, `enter expr @0
+  try expr[0]: term PLUS expr
+  enter term @0
+    try term[0]: NUMBER
+    token NUMBER "1" @0
+    action pass
+  exit term @0..1
+  fail expr[0]: unexpected end of input
+  try expr[1]: term
+  action pass
+exit expr @0..1
+`, out.String())
+}
+
+func TestPrinterMemo(t *testing.T) {
+	dsl := newCalculator(t)
+	var out bytes.Buffer
+	printer := NewPrinter(&out)
+	printer.Memo = true
+	dsl.SetTracer(printer)
+
+	_, err := dsl.Parse("1")
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "memo miss expr @0\n")
+	assert.Contains(t, out.String(), "  memo hit term @0..1\n")
+}
+
+func TestChrome(t *testing.T) {
+	dsl := newCalculator(t)
+	var out bytes.Buffer
+	tracer := NewChrome(&out)
+	dsl.SetTracer(tracer)
+
+	_, err := dsl.Parse("1 + 2")
+	require.NoError(t, err)
+	require.NoError(t, tracer.Close())
+
+	var events []map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &events))
+	phases := map[string]int{}
+	for _, e := range events {
+		phases[e["ph"].(string)]++
+	}
+	assert.Equal(t, phases["B"], phases["E"])
+	assert.Equal(t, "expr", events[0]["name"])
+	assert.Equal(t, "B", events[0]["ph"])
+}
+
+func TestChromeEmpty(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, NewChrome(&out).Close())
+
+	var events []interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &events))
+	assert.Empty(t, events)
+}
+
+func TestProfiler(t *testing.T) {
+	dsl := newCalculator(t)
+	profiler := NewProfiler()
+	dsl.SetTracer(profiler)
+
+	_, err := dsl.Parse("1 + 2")
+	require.NoError(t, err)
+
+	profiles := map[string]RuleProfile{}
+	for _, p := range profiler.Profile() {
+		profiles[p.Rule] = p
+	}
+	// expr is entered at 0 and 2; at 2 the first alternative backtracks
+	assert.Equal(t, 2, profiles["expr"].Calls)
+	assert.Equal(t, 1, profiles["expr"].Backtracks)
+	assert.Equal(t, 1, profiles["expr"].Tokens)
+	assert.Equal(t, 2, profiles["term"].Calls)
+	assert.Equal(t, 1, profiles["term"].MemoHits)
+	assert.Equal(t, 2, profiles["term"].Tokens)
+	assert.GreaterOrEqual(t, profiles["expr"].Total, profiles["expr"].Self)
+
+	var out bytes.Buffer
+	require.NoError(t, profiler.WriteText(&out))
+	assert.Contains(t, out.String(), "BACKTRACK")
+
+	profiler.Reset()
+	assert.Empty(t, profiler.Profile())
+}
+
+func TestMulti(t *testing.T) {
+	dsl := newCalculator(t)
+	a, b := NewProfiler(), NewProfiler()
+	dsl.SetTracer(Multi(a, b))
+
+	_, err := dsl.Parse("1")
+	require.NoError(t, err)
+	assert.Equal(t, a.Profile()[0].Calls, b.Profile()[0].Calls)
+	assert.NotEmpty(t, a.Profile())
+}